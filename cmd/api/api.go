@@ -2,12 +2,29 @@ package api
 
 import (
 	"database/sql"
+	"educations-castle/configs"
 	"educations-castle/services/activity"
+	"educations-castle/services/booking"
+	"educations-castle/services/calendar"
+	"educations-castle/services/certificate"
+	"educations-castle/services/discount"
+	"educations-castle/services/geocoding"
+	"educations-castle/services/image"
+	"educations-castle/services/location"
+	"educations-castle/services/moderation"
+	"educations-castle/services/notification"
+	"educations-castle/services/payment"
 	"educations-castle/services/review"
+	"educations-castle/services/schedule"
+	"educations-castle/services/search"
+	"educations-castle/services/session"
+	"educations-castle/services/storage"
 	"educations-castle/services/user"
+	"educations-castle/services/voucher"
 	"educations-castle/utils/color"
 	"log"
 	"net/http"
+	"time"
 
 	_ "educations-castle/docs"
 
@@ -34,21 +51,144 @@ func (s *APIServer) Run() error {
 	subrouter := router.PathPrefix("/api/v1").Subrouter()
 	subrouter.Use(corsMiddleware) // Apply CORS middleware here
 
+	// Dates in query parameters and schedules are local to organizers
+	scheduleLocation, err := time.LoadLocation(configs.Envs.ScheduleTimezone)
+	if err != nil {
+		return err
+	}
+
 	// User
-	userCastle := user.NewCastle(s.db)
+	searchIndex := search.NewIndex()
+	userCastle := user.NewCastle(s.db, searchIndex)
+	if err := userCastle.BuildSearchIndex(); err != nil {
+		return err
+	}
 	userHandler := user.NewHandler(userCastle)
 	userHandler.RegisterRoutes(subrouter)
 
 	// Activity
-	activityCastle := activity.NewCastle(s.db)
-	activityHandler := activity.NewHandler(activityCastle, userCastle)
+	imageCastle := image.NewCastle(s.db)
+	activityCastle := activity.NewCastle(s.db, searchIndex)
+	if err := activityCastle.BuildSearchIndex(); err != nil {
+		return err
+	}
+	activityHandler := activity.NewHandler(activityCastle, userCastle, imageCastle, scheduleLocation)
 	activityHandler.RegisterRoutes(subrouter)
 
+	// Search
+	searchHandler := search.NewHandler(searchIndex)
+	searchHandler.RegisterRoutes(subrouter)
+
+	// Location
+	geocoder, err := geocoding.NewGeocoder(configs.Envs)
+	if err != nil {
+		return err
+	}
+	locationCastle := location.NewCastle(s.db)
+	locationHandler := location.NewHandler(locationCastle, userCastle, geocoder)
+	locationHandler.RegisterRoutes(subrouter)
+
+	// Session
+	sessionCastle := session.NewCastle(s.db)
+	sessionHandler := session.NewHandler(sessionCastle, activityCastle, locationCastle, userCastle, scheduleLocation)
+	sessionHandler.RegisterRoutes(subrouter)
+
+	scheduleCastle := schedule.NewCastle(s.db)
+	scheduleExpander := schedule.NewExpander(scheduleCastle, time.Duration(configs.Envs.ScheduleHorizonInDays)*24*time.Hour)
+	scheduleExpander.Start(time.Duration(configs.Envs.ScheduleIntervalInMinutes) * time.Minute)
+	scheduleHandler := schedule.NewHandler(scheduleCastle, activityCastle, locationCastle, userCastle, scheduleExpander)
+	scheduleHandler.RegisterRoutes(subrouter)
+
+	// Notification
+	notificationCastle := notification.NewCastle(s.db)
+	notificationHandler := notification.NewHandler(notificationCastle, userCastle)
+	notificationHandler.RegisterRoutes(subrouter)
+
+	// Booking
+	bookingCastle := booking.NewCastle(s.db)
+
+	// Payment
+	paymentProvider, err := payment.NewPaymentProvider(configs.Envs)
+	if err != nil {
+		return err
+	}
+	paymentCastle := payment.NewCastle(s.db)
+	paymentGateway := payment.NewGateway(paymentCastle, bookingCastle, paymentProvider,
+		configs.Envs.PaymentSuccessURL, configs.Envs.PaymentCancelURL)
+	paymentHandler := payment.NewHandler(paymentCastle, bookingCastle, sessionCastle, activityCastle, userCastle, paymentGateway)
+	paymentHandler.RegisterRoutes(subrouter)
+
+	// Waitlist sweep also retries failed refunds through payment gateway
+	waitlist := booking.NewWaitlist(bookingCastle, bookingCastle, notificationCastle, paymentGateway,
+		time.Duration(configs.Envs.WaitlistWindowInMinutes)*time.Minute,
+		time.Duration(configs.Envs.PaymentWindowInMinutes)*time.Minute, scheduleLocation)
+	waitlist.Start(time.Duration(configs.Envs.WaitlistSweepInSeconds) * time.Second)
+
+	// Discount
+	discountCastle := discount.NewCastle(s.db)
+	discountHandler := discount.NewHandler(discountCastle, activityCastle, userCastle)
+	discountHandler.RegisterRoutes(subrouter)
+
+	// Gift voucher
+	voucherCastle := voucher.NewCastle(s.db)
+	voucherHandler := voucher.NewHandler(voucherCastle, activityCastle, userCastle, paymentGateway,
+		time.Duration(configs.Envs.VoucherValidityInDays)*24*time.Hour)
+	voucherHandler.RegisterRoutes(subrouter)
+
+	ticketSigner, err := booking.NewTicketSigner(configs.Envs.TicketSigningSecret)
+	if err != nil {
+		return err
+	}
+	bookingHandler := booking.NewHandler(bookingCastle, bookingCastle, sessionCastle, activityCastle, userCastle,
+		notificationCastle, discountCastle, voucherCastle, waitlist, paymentGateway, ticketSigner)
+	bookingHandler.RegisterRoutes(subrouter)
+
+	// Certificate
+	certificateCastle := certificate.NewCastle(s.db)
+	certificateRenderer := certificate.NewRenderer(configs.Envs.CertificateFontFile, configs.Envs.CertificateVerifyURL)
+	certificateHandler := certificate.NewHandler(certificateCastle, bookingCastle, sessionCastle, activityCastle, userCastle,
+		certificateRenderer, scheduleLocation)
+	certificateHandler.RegisterRoutes(subrouter)
+
+	// Calendar
+	calendarCastle := calendar.NewCastle(s.db)
+	calendarImporter := calendar.NewImporter(calendarCastle, sessionCastle,
+		calendar.NewClient(time.Duration(configs.Envs.CalendarImportTimeoutInSeconds)*time.Second), scheduleLocation,
+		time.Duration(configs.Envs.ScheduleHorizonInDays)*24*time.Hour, configs.Envs.CalendarImportMaxSize)
+	calendarHandler := calendar.NewHandler(calendarCastle, activityCastle, locationCastle, userCastle, calendarImporter,
+		configs.Envs.CalendarFeedURL, configs.Envs.CalendarDomain, time.Duration(configs.Envs.CalendarHistoryInDays)*24*time.Hour)
+	calendarHandler.RegisterRoutes(subrouter)
+
+	// Moderation
+	moderationCastle := moderation.NewCastle(s.db)
+	moderationHandler := moderation.NewHandler(moderationCastle, activityCastle, userCastle, notificationCastle)
+	moderationHandler.RegisterRoutes(subrouter)
+
 	// Review
 	reviewCastle := review.NewCastle(s.db)
 	reviewHandler := review.NewHandler(reviewCastle, userCastle)
 	reviewHandler.RegisterRoutes(subrouter)
 
+	// Blob storage
+	blobStore, err := storage.NewBlobStore(configs.Envs.BlobBackend, configs.Envs)
+	if err != nil {
+		return err
+	}
+	if localStore, ok := blobStore.(*storage.LocalBlobStore); ok {
+		blobHandler := storage.NewHandler(localStore)
+		blobHandler.RegisterRoutes(subrouter)
+	}
+
+	// Image
+	variantSizes, err := image.ParseVariantSizes(configs.Envs.ImageVariants)
+	if err != nil {
+		return err
+	}
+	imageProcessor := image.NewProcessor(imageCastle, blobStore, variantSizes, configs.Envs.MaxImagePixels, int(configs.Envs.ImageQueueSize))
+	imageProcessor.Start(int(configs.Envs.ImageWorkers))
+	imageHandler := image.NewHandler(imageCastle, activityCastle, reviewCastle, userCastle, blobStore, imageProcessor)
+	imageHandler.RegisterRoutes(subrouter)
+
 	log.Println(color.Format(color.GREEN, "Listening on "+s.addr))
 	return http.ListenAndServe(s.addr, router)
 }
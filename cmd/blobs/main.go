@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"educations-castle/configs"
+	"educations-castle/db"
+	"educations-castle/services/image"
+	"educations-castle/services/storage"
+	"educations-castle/types"
+	"educations-castle/utils/color"
+	"flag"
+	"fmt"
+	"log"
+	"mime"
+	"path"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Copies stored files from one blob store backend to another, e.g.
+//
+//	go run cmd/blobs/main.go -from local -to s3
+//
+// Images uploaded before keys got public/ or private/ prefix are moved under
+// prefix matching their private column inside BLOB_BACKEND with
+//
+//	go run cmd/blobs/main.go -rename-legacy
+func main() {
+	from := flag.String("from", storage.BackendLocal, "source backend (local or s3)")
+	to := flag.String("to", storage.BackendS3, "destination backend (local or s3)")
+	prefix := flag.String("prefix", "", "copy only keys with this prefix")
+	deleteSource := flag.Bool("delete-source", false, "delete files from source after successful copy")
+	renameLegacy := flag.Bool("rename-legacy", false, "move images stored without visibility prefix under public/ or private/")
+	flag.Parse()
+
+	if *renameLegacy {
+		renameLegacyKeys()
+		return
+	}
+
+	if *from == *to {
+		log.Fatal("source and destination backends must differ")
+	}
+
+	source, err := storage.NewBlobStore(*from, configs.Envs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	destination, err := storage.NewBlobStore(*to, configs.Envs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	keys, err := source.List(ctx, *prefix)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	failed := 0
+	for _, key := range keys {
+		if err := copyBlob(ctx, source, destination, key); err != nil {
+			log.Println(color.Format(color.RED, fmt.Sprintf("failed to copy %s: %v", key, err)))
+			failed++
+			continue
+		}
+
+		if *deleteSource {
+			if err := source.Delete(ctx, key); err != nil {
+				log.Println(color.Format(color.RED, fmt.Sprintf("failed to delete %s from source: %v", key, err)))
+			}
+		}
+
+		log.Println(color.Format(color.GREEN, "copied "+key))
+	}
+
+	log.Println(color.Format(color.YELLOW, fmt.Sprintf("copied %d of %d files", len(keys)-failed, len(keys))))
+	if failed > 0 {
+		log.Fatalf("%d files failed to copy", failed)
+	}
+}
+
+func renameLegacyKeys() {
+	db, err := db.NewMySQLStorage(mysql.Config{
+		User:                 configs.Envs.DBUser,
+		Passwd:               configs.Envs.DBPassword,
+		Addr:                 configs.Envs.DBAddress,
+		DBName:               configs.Envs.DBName,
+		Net:                  "tcp",
+		AllowNativePasswords: true,
+		ParseTime:            true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	blobStore, err := storage.NewBlobStore(configs.Envs.BlobBackend, configs.Envs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	imageCastle := image.NewCastle(db)
+	images, err := imageCastle.ListImages()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	renamed, failed := 0, 0
+	for _, i := range images {
+		if !image.HasLegacyKey(i) {
+			continue
+		}
+
+		oldKey := i.FilePath
+		if err := image.RenameLegacyKey(ctx, imageCastle, blobStore, i); err != nil {
+			log.Println(color.Format(color.RED, fmt.Sprintf("failed to rename %s: %v", oldKey, err)))
+			failed++
+			continue
+		}
+
+		log.Println(color.Format(color.GREEN, fmt.Sprintf("renamed %s to %s", oldKey, i.FilePath)))
+		renamed++
+	}
+
+	log.Println(color.Format(color.YELLOW, fmt.Sprintf("renamed %d of %d legacy images", renamed, renamed+failed)))
+	if failed > 0 {
+		log.Fatalf("%d images failed to rename", failed)
+	}
+}
+
+func copyBlob(ctx context.Context, source, destination types.BlobStore, key string) error {
+	file, err := source.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return destination.Put(ctx, key, file, mime.TypeByExtension(path.Ext(key)))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"educations-castle/configs"
+	"educations-castle/db"
+	"educations-castle/services/image"
+	"educations-castle/services/storage"
+	"educations-castle/utils/color"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Regenerates variants of stored images, e.g. after IMAGE_VARIANTS has changed
+//
+//	go run cmd/images/main.go -id 12
+func main() {
+	imageID := flag.Int("id", 0, "regenerate only image with this ID")
+	flag.Parse()
+
+	db, err := db.NewMySQLStorage(mysql.Config{
+		User:                 configs.Envs.DBUser,
+		Passwd:               configs.Envs.DBPassword,
+		Addr:                 configs.Envs.DBAddress,
+		DBName:               configs.Envs.DBName,
+		Net:                  "tcp",
+		AllowNativePasswords: true,
+		ParseTime:            true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	blobStore, err := storage.NewBlobStore(configs.Envs.BlobBackend, configs.Envs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sizes, err := image.ParseVariantSizes(configs.Envs.ImageVariants)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	imageCastle := image.NewCastle(db)
+	processor := image.NewProcessor(imageCastle, blobStore, sizes, configs.Envs.MaxImagePixels, int(configs.Envs.ImageQueueSize))
+	processor.Start(int(configs.Envs.ImageWorkers))
+
+	ids := []int{*imageID}
+	if *imageID == 0 {
+		images, err := imageCastle.ListImages()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		ids = ids[:0]
+		for _, i := range images {
+			ids = append(ids, i.ID)
+		}
+	}
+
+	ctx := context.Background()
+	for _, id := range ids {
+		if err := processor.Submit(ctx, id); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Failures are logged by workers
+	processor.Stop()
+	log.Println(color.Format(color.GREEN, fmt.Sprintf("processed %d images", len(ids))))
+}
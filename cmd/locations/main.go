@@ -0,0 +1,38 @@
+package main
+
+import (
+	"educations-castle/configs"
+	"educations-castle/db"
+	"educations-castle/services/location"
+	"educations-castle/utils/color"
+	"fmt"
+	"log"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Fills city of locations saved before cities were derived from addresses, e.g. after migrating to
+// 20241217080000_add-location-city. Locations with city are left as they are, so it can be run again
+//
+//	go run cmd/locations/main.go
+func main() {
+	db, err := db.NewMySQLStorage(mysql.Config{
+		User:                 configs.Envs.DBUser,
+		Passwd:               configs.Envs.DBPassword,
+		Addr:                 configs.Envs.DBAddress,
+		DBName:               configs.Envs.DBName,
+		Net:                  "tcp",
+		AllowNativePasswords: true,
+		ParseTime:            true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	filled, err := location.NewCastle(db).FillMissingCities()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println(color.Format(color.GREEN, fmt.Sprintf("filled city of %d locations", filled)))
+}
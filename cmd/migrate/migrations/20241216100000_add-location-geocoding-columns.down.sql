@@ -0,0 +1,4 @@
+ALTER TABLE `location`
+  DROP COLUMN `manualOverride`,
+  DROP COLUMN `provider`,
+  DROP COLUMN `confidence`;
@@ -0,0 +1,4 @@
+ALTER TABLE `location`
+  ADD COLUMN `confidence` double NOT NULL DEFAULT 0,
+  ADD COLUMN `provider` varchar(32) NOT NULL DEFAULT '',
+  ADD COLUMN `manualOverride` tinyint(1) NOT NULL DEFAULT 0;
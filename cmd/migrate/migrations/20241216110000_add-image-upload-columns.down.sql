@@ -0,0 +1,12 @@
+ALTER TABLE `entityimage`
+  DROP KEY `entity_image`,
+  DROP KEY `entity`,
+  DROP COLUMN `position`;
+
+ALTER TABLE `image` DROP FOREIGN KEY `uploads`;
+
+ALTER TABLE `image`
+  DROP KEY `uploads`,
+  DROP COLUMN `fk_Userid`,
+  DROP COLUMN `size`,
+  DROP COLUMN `contentType`;
@@ -0,0 +1,10 @@
+ALTER TABLE `image`
+  ADD COLUMN `contentType` varchar(64) NOT NULL DEFAULT '',
+  ADD COLUMN `size` bigint NOT NULL DEFAULT 0,
+  ADD COLUMN `fk_Userid` int(11) DEFAULT NULL,
+  ADD CONSTRAINT `uploads` FOREIGN KEY (`fk_Userid`) REFERENCES `user` (`id`) ON DELETE SET NULL;
+
+ALTER TABLE `entityimage`
+  ADD COLUMN `position` int(11) NOT NULL DEFAULT 0,
+  ADD KEY `entity` (`entityType`, `entityFk`),
+  ADD UNIQUE KEY `entity_image` (`entityType`, `entityFk`, `fk_Imageid`);
@@ -0,0 +1 @@
+ALTER TABLE `image` ADD COLUMN `private` tinyint(1) NOT NULL DEFAULT 0;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `imagevariant` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_Imageid` int(11) NOT NULL,
+  `name` varchar(32) NOT NULL,
+  `format` varchar(8) NOT NULL,
+  `filePath` varchar(255) NOT NULL,
+  `width` int(11) NOT NULL,
+  `height` int(11) NOT NULL,
+  `size` bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `image_variant` (`fk_Imageid`, `name`, `format`),
+  CONSTRAINT `variant_of` FOREIGN KEY (`fk_Imageid`) REFERENCES `image` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
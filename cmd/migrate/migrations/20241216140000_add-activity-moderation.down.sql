@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `moderationdecision`;
+
+ALTER TABLE `activity`
+  DROP KEY `moderationStatus`,
+  DROP COLUMN `moderationStatus`;
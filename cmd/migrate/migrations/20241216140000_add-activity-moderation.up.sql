@@ -0,0 +1,18 @@
+ALTER TABLE `activity`
+  ADD COLUMN `moderationStatus` varchar(32) NOT NULL DEFAULT 'pending',
+  ADD KEY `moderationStatus` (`moderationStatus`);
+
+UPDATE `activity` SET `moderationStatus` = 'approved' WHERE `verified` = 1;
+
+CREATE TABLE IF NOT EXISTS `moderationdecision` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_Activityid` int(11) NOT NULL,
+  `fk_Administratorid` int(11) DEFAULT NULL,
+  `decision` varchar(32) NOT NULL,
+  `reason` varchar(1000) DEFAULT NULL,
+  `createdAt` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `fk_Activityid` (`fk_Activityid`),
+  CONSTRAINT `moderated_activity` FOREIGN KEY (`fk_Activityid`) REFERENCES `activity` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `moderator` FOREIGN KEY (`fk_Administratorid`) REFERENCES `administrator` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
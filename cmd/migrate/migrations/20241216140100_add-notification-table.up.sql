@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `notification` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_Userid` int(11) NOT NULL,
+  `type` varchar(64) NOT NULL,
+  `message` varchar(1000) NOT NULL,
+  `entityType` varchar(255) NOT NULL,
+  `entityFk` int(11) NOT NULL,
+  `createdAt` datetime NOT NULL DEFAULT current_timestamp(),
+  `readAt` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `fk_Userid` (`fk_Userid`),
+  CONSTRAINT `notified_user` FOREIGN KEY (`fk_Userid`) REFERENCES `user` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
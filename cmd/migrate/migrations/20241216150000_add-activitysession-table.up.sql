@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS `activitysession` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_Activityid` int(11) NOT NULL,
+  `startTime` datetime NOT NULL,
+  `endTime` datetime NOT NULL,
+  `fk_Locationid` int(11) DEFAULT NULL,
+  `capacity` int(11) NOT NULL,
+  `language` varchar(8) NOT NULL,
+  `price` float DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `activity_start` (`fk_Activityid`, `startTime`),
+  KEY `startTime` (`startTime`),
+  CONSTRAINT `session_of` FOREIGN KEY (`fk_Activityid`) REFERENCES `activity` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `held_at` FOREIGN KEY (`fk_Locationid`) REFERENCES `location` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
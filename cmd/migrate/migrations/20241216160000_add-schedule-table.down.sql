@@ -0,0 +1,3 @@
+ALTER TABLE `activitysession` DROP FOREIGN KEY `generated_by`;
+ALTER TABLE `activitysession` DROP INDEX `schedule_start`, DROP COLUMN `fk_Scheduleid`;
+DROP TABLE IF EXISTS `schedule`;
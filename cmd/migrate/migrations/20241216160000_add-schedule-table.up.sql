@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS `schedule` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_Activityid` int(11) NOT NULL,
+  `rrule` varchar(255) NOT NULL,
+  `startTime` datetime NOT NULL,
+  `timezone` varchar(64) NOT NULL DEFAULT 'Europe/Vilnius',
+  `durationMinutes` int(11) NOT NULL,
+  `fk_Locationid` int(11) DEFAULT NULL,
+  `capacity` int(11) NOT NULL,
+  `language` varchar(8) NOT NULL,
+  `price` float DEFAULT NULL,
+  `exDates` text NOT NULL,
+  `expandedUntil` datetime DEFAULT NULL,
+  `createdAt` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `fk_Activityid` (`fk_Activityid`),
+  CONSTRAINT `schedule_of` FOREIGN KEY (`fk_Activityid`) REFERENCES `activity` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `scheduled_at` FOREIGN KEY (`fk_Locationid`) REFERENCES `location` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+ALTER TABLE `activitysession`
+  ADD COLUMN `fk_Scheduleid` int(11) DEFAULT NULL,
+  ADD UNIQUE KEY `schedule_start` (`fk_Scheduleid`, `startTime`),
+  ADD CONSTRAINT `generated_by` FOREIGN KEY (`fk_Scheduleid`) REFERENCES `schedule` (`id`) ON DELETE SET NULL;
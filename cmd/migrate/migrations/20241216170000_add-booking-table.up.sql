@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `booking` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_ActivitySessionid` int(11) NOT NULL,
+  `fk_Userid` int(11) NOT NULL,
+  `seats` int(11) NOT NULL,
+  `status` varchar(16) NOT NULL DEFAULT 'pending',
+  `createdAt` datetime NOT NULL DEFAULT current_timestamp(),
+  `updatedAt` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `session_status` (`fk_ActivitySessionid`, `status`),
+  KEY `fk_Userid` (`fk_Userid`),
+  CONSTRAINT `booked_session` FOREIGN KEY (`fk_ActivitySessionid`) REFERENCES `activitysession` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `booked_by` FOREIGN KEY (`fk_Userid`) REFERENCES `user` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS `waitlist` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_ActivitySessionid` int(11) NOT NULL,
+  `fk_Userid` int(11) NOT NULL,
+  `seats` int(11) NOT NULL,
+  `status` varchar(16) NOT NULL DEFAULT 'waiting',
+  `fk_Bookingid` int(11) DEFAULT NULL,
+  `offerExpiresAt` datetime DEFAULT NULL,
+  `createdAt` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `session_status` (`fk_ActivitySessionid`, `status`, `createdAt`),
+  KEY `fk_Userid` (`fk_Userid`),
+  KEY `offer_expiration` (`status`, `offerExpiresAt`),
+  CONSTRAINT `waiting_for` FOREIGN KEY (`fk_ActivitySessionid`) REFERENCES `activitysession` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `waiting_user` FOREIGN KEY (`fk_Userid`) REFERENCES `user` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `offered_booking` FOREIGN KEY (`fk_Bookingid`) REFERENCES `booking` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
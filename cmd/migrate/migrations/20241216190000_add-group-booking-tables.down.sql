@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `grouppricerule`;
+DROP TABLE IF EXISTS `groupsettings`;
+DROP TABLE IF EXISTS `bookinggroup`;
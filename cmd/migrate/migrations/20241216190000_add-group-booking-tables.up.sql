@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS `bookinggroup` (
+  `fk_Bookingid` int(11) NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `school` varchar(255) DEFAULT NULL,
+  `ageBand` varchar(32) DEFAULT NULL,
+  `students` int(11) NOT NULL,
+  `adults` int(11) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`fk_Bookingid`),
+  CONSTRAINT `group_of` FOREIGN KEY (`fk_Bookingid`) REFERENCES `booking` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE IF NOT EXISTS `groupsettings` (
+  `fk_Activityid` int(11) NOT NULL,
+  `minGroupSize` int(11) NOT NULL,
+  `maxGroupSize` int(11) NOT NULL,
+  `adultPrice` float DEFAULT NULL,
+  `freeAdultsPer` int(11) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`fk_Activityid`),
+  CONSTRAINT `group_settings_of` FOREIGN KEY (`fk_Activityid`) REFERENCES `activity` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE IF NOT EXISTS `grouppricerule` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_Activityid` int(11) NOT NULL,
+  `minStudents` int(11) NOT NULL,
+  `studentPrice` float NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `activity_min_students` (`fk_Activityid`, `minStudents`),
+  CONSTRAINT `price_rule_of` FOREIGN KEY (`fk_Activityid`) REFERENCES `activity` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
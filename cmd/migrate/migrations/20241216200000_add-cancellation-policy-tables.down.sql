@@ -0,0 +1,5 @@
+ALTER TABLE `activitysession` DROP COLUMN `cancelled`;
+ALTER TABLE `booking` DROP COLUMN `amount`;
+DROP TABLE IF EXISTS `bookingcancellation`;
+DROP TABLE IF EXISTS `refundtier`;
+DROP TABLE IF EXISTS `cancellationpolicy`;
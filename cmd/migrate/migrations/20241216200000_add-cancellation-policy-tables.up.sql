@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS `cancellationpolicy` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_Activityid` int(11) DEFAULT NULL,
+  `fk_Packageid` int(11) DEFAULT NULL,
+  `freeCancellationHours` int(11) NOT NULL DEFAULT 0,
+  `noShowRefundPercent` int(11) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `fk_Activityid` (`fk_Activityid`),
+  UNIQUE KEY `fk_Packageid` (`fk_Packageid`),
+  CONSTRAINT `policy_of_activity` FOREIGN KEY (`fk_Activityid`) REFERENCES `activity` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `policy_of_package` FOREIGN KEY (`fk_Packageid`) REFERENCES `package` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE IF NOT EXISTS `refundtier` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_CancellationPolicyid` int(11) NOT NULL,
+  `daysBefore` int(11) NOT NULL,
+  `refundPercent` int(11) NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `policy_days` (`fk_CancellationPolicyid`, `daysBefore`),
+  CONSTRAINT `tier_of` FOREIGN KEY (`fk_CancellationPolicyid`) REFERENCES `cancellationpolicy` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE IF NOT EXISTS `bookingcancellation` (
+  `fk_Bookingid` int(11) NOT NULL,
+  `fk_Userid` int(11) DEFAULT NULL,
+  `reason` varchar(1000) DEFAULT NULL,
+  `refundPercent` int(11) NOT NULL,
+  `refundAmount` float NOT NULL,
+  `createdAt` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`fk_Bookingid`),
+  CONSTRAINT `cancellation_of` FOREIGN KEY (`fk_Bookingid`) REFERENCES `booking` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `cancelled_by` FOREIGN KEY (`fk_Userid`) REFERENCES `user` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+ALTER TABLE `booking` ADD COLUMN `amount` float NOT NULL DEFAULT 0;
+ALTER TABLE `activitysession` ADD COLUMN `cancelled` tinyint(1) NOT NULL DEFAULT 0;
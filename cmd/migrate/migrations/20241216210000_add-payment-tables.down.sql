@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `paymentevent`;
+DROP TABLE IF EXISTS `payment`;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS `payment` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_Bookingid` int(11) NOT NULL,
+  `provider` varchar(32) NOT NULL,
+  `providerRef` varchar(255) NOT NULL,
+  `amount` float NOT NULL,
+  `currency` char(3) NOT NULL,
+  `status` enum('pending','succeeded','failed','refunded') NOT NULL DEFAULT 'pending',
+  `refundedAmount` float NOT NULL DEFAULT 0,
+  `checkoutUrl` varchar(2048) NOT NULL,
+  `createdAt` datetime NOT NULL DEFAULT current_timestamp(),
+  `updatedAt` datetime NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `provider_ref` (`provider`, `providerRef`),
+  KEY `fk_Bookingid` (`fk_Bookingid`),
+  CONSTRAINT `paid_booking` FOREIGN KEY (`fk_Bookingid`) REFERENCES `booking` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+CREATE TABLE IF NOT EXISTS `paymentevent` (
+  `provider` varchar(32) NOT NULL,
+  `eventId` varchar(255) NOT NULL,
+  `receivedAt` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`provider`, `eventId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,41 @@
+-- Amounts of currencies without two minor unit digits can't be restored and are converted as cents
+
+ALTER TABLE `payment`
+  MODIFY `amount` decimal(17,2) NOT NULL,
+  MODIFY `refundedAmount` decimal(17,2) NOT NULL DEFAULT 0;
+UPDATE `payment` SET `amount` = `amount` / 100, `refundedAmount` = `refundedAmount` / 100;
+ALTER TABLE `payment`
+  MODIFY `amount` float NOT NULL,
+  MODIFY `refundedAmount` float NOT NULL DEFAULT 0;
+
+ALTER TABLE `bookingcancellation` MODIFY `refundAmount` decimal(17,2) NOT NULL;
+UPDATE `bookingcancellation` SET `refundAmount` = `refundAmount` / 100;
+ALTER TABLE `bookingcancellation` MODIFY `refundAmount` float NOT NULL;
+
+ALTER TABLE `booking` DROP COLUMN `currency`, MODIFY `amount` decimal(17,2) NOT NULL DEFAULT 0;
+UPDATE `booking` SET `amount` = `amount` / 100;
+ALTER TABLE `booking` MODIFY `amount` float NOT NULL DEFAULT 0;
+
+ALTER TABLE `grouppricerule` MODIFY `studentPrice` decimal(17,2) NOT NULL;
+UPDATE `grouppricerule` SET `studentPrice` = `studentPrice` / 100;
+ALTER TABLE `grouppricerule` MODIFY `studentPrice` float NOT NULL;
+
+ALTER TABLE `groupsettings` DROP COLUMN `currency`, MODIFY `adultPrice` decimal(17,2) DEFAULT NULL;
+UPDATE `groupsettings` SET `adultPrice` = `adultPrice` / 100;
+ALTER TABLE `groupsettings` MODIFY `adultPrice` float DEFAULT NULL;
+
+ALTER TABLE `schedule` DROP COLUMN `currency`, MODIFY `price` decimal(17,2) DEFAULT NULL;
+UPDATE `schedule` SET `price` = `price` / 100;
+ALTER TABLE `schedule` MODIFY `price` float DEFAULT NULL;
+
+ALTER TABLE `activitysession` DROP COLUMN `currency`, MODIFY `price` decimal(17,2) DEFAULT NULL;
+UPDATE `activitysession` SET `price` = `price` / 100;
+ALTER TABLE `activitysession` MODIFY `price` float DEFAULT NULL;
+
+ALTER TABLE `package` DROP COLUMN `currency`, MODIFY `price` decimal(17,2) NOT NULL;
+UPDATE `package` SET `price` = `price` / 100;
+ALTER TABLE `package` MODIFY `price` float NOT NULL;
+
+ALTER TABLE `activity` DROP COLUMN `currency`, MODIFY `basePrice` decimal(17,2) NOT NULL;
+UPDATE `activity` SET `basePrice` = `basePrice` / 100;
+ALTER TABLE `activity` MODIFY `basePrice` float NOT NULL;
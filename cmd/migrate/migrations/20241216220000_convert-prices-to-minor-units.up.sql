@@ -0,0 +1,56 @@
+-- Prices become integer minor units with ISO 4217 currency. Floats are rounded to cents through DECIMAL
+-- first, casting them to integers straight away would keep artifacts such as 20.499999
+
+ALTER TABLE `activity` MODIFY `basePrice` decimal(17,2) NOT NULL;
+UPDATE `activity` SET `basePrice` = `basePrice` * 100;
+ALTER TABLE `activity`
+  MODIFY `basePrice` bigint NOT NULL,
+  ADD COLUMN `currency` char(3) NOT NULL DEFAULT 'EUR' AFTER `basePrice`;
+
+ALTER TABLE `package` MODIFY `price` decimal(17,2) NOT NULL;
+UPDATE `package` SET `price` = `price` * 100;
+ALTER TABLE `package`
+  MODIFY `price` bigint NOT NULL,
+  ADD COLUMN `currency` char(3) NOT NULL DEFAULT 'EUR' AFTER `price`;
+
+ALTER TABLE `activitysession` MODIFY `price` decimal(17,2) DEFAULT NULL;
+UPDATE `activitysession` SET `price` = `price` * 100;
+ALTER TABLE `activitysession`
+  MODIFY `price` bigint DEFAULT NULL,
+  ADD COLUMN `currency` char(3) DEFAULT NULL AFTER `price`;
+UPDATE `activitysession` SET `currency` = 'EUR' WHERE `price` IS NOT NULL;
+
+ALTER TABLE `schedule` MODIFY `price` decimal(17,2) DEFAULT NULL;
+UPDATE `schedule` SET `price` = `price` * 100;
+ALTER TABLE `schedule`
+  MODIFY `price` bigint DEFAULT NULL,
+  ADD COLUMN `currency` char(3) DEFAULT NULL AFTER `price`;
+UPDATE `schedule` SET `currency` = 'EUR' WHERE `price` IS NOT NULL;
+
+ALTER TABLE `groupsettings` MODIFY `adultPrice` decimal(17,2) DEFAULT NULL;
+UPDATE `groupsettings` SET `adultPrice` = `adultPrice` * 100;
+ALTER TABLE `groupsettings`
+  MODIFY `adultPrice` bigint DEFAULT NULL,
+  ADD COLUMN `currency` char(3) NOT NULL DEFAULT 'EUR' AFTER `adultPrice`;
+
+ALTER TABLE `grouppricerule` MODIFY `studentPrice` decimal(17,2) NOT NULL;
+UPDATE `grouppricerule` SET `studentPrice` = `studentPrice` * 100;
+ALTER TABLE `grouppricerule` MODIFY `studentPrice` bigint NOT NULL;
+
+ALTER TABLE `booking` MODIFY `amount` decimal(17,2) NOT NULL DEFAULT 0;
+UPDATE `booking` SET `amount` = `amount` * 100;
+ALTER TABLE `booking`
+  MODIFY `amount` bigint NOT NULL DEFAULT 0,
+  ADD COLUMN `currency` char(3) NOT NULL DEFAULT 'EUR' AFTER `amount`;
+
+ALTER TABLE `bookingcancellation` MODIFY `refundAmount` decimal(17,2) NOT NULL;
+UPDATE `bookingcancellation` SET `refundAmount` = `refundAmount` * 100;
+ALTER TABLE `bookingcancellation` MODIFY `refundAmount` bigint NOT NULL;
+
+ALTER TABLE `payment`
+  MODIFY `amount` decimal(17,2) NOT NULL,
+  MODIFY `refundedAmount` decimal(17,2) NOT NULL DEFAULT 0;
+UPDATE `payment` SET `amount` = `amount` * 100, `refundedAmount` = `refundedAmount` * 100;
+ALTER TABLE `payment`
+  MODIFY `amount` bigint NOT NULL,
+  MODIFY `refundedAmount` bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `bookingtier`;
+DROP TABLE IF EXISTS `pricetier`;
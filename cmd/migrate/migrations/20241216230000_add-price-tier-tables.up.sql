@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS `pricetier` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_Activityid` int(11) NOT NULL,
+  `fk_ActivitySessionid` int(11) DEFAULT NULL,
+  `name` varchar(64) NOT NULL,
+  `amount` bigint NOT NULL,
+  `currency` char(3) NOT NULL,
+  `seats` int(11) NOT NULL DEFAULT 1,
+  `minAge` int(11) DEFAULT NULL,
+  `maxAge` int(11) DEFAULT NULL,
+  `eligibility` varchar(255) DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `fk_Activityid` (`fk_Activityid`),
+  KEY `fk_ActivitySessionid` (`fk_ActivitySessionid`),
+  CONSTRAINT `tier_of_activity` FOREIGN KEY (`fk_Activityid`) REFERENCES `activity` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `tier_of_session` FOREIGN KEY (`fk_ActivitySessionid`) REFERENCES `activitysession` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Name, seats and price are copied, so bookings keep what was paid when organizer changes tiers
+CREATE TABLE IF NOT EXISTS `bookingtier` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_Bookingid` int(11) NOT NULL,
+  `fk_PriceTierid` int(11) DEFAULT NULL,
+  `name` varchar(64) NOT NULL,
+  `quantity` int(11) NOT NULL,
+  `seats` int(11) NOT NULL,
+  `unitAmount` bigint NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `fk_Bookingid` (`fk_Bookingid`),
+  CONSTRAINT `tier_of_booking` FOREIGN KEY (`fk_Bookingid`) REFERENCES `booking` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `booked_tier` FOREIGN KEY (`fk_PriceTierid`) REFERENCES `pricetier` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `discountredemption`;
+DROP TABLE IF EXISTS `discount`;
@@ -0,0 +1,38 @@
+-- Discounts without code are campaigns applied automatically. Scope ID points to organizer, package,
+-- activity or category depending on scope
+CREATE TABLE IF NOT EXISTS `discount` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_Organizerid` int(11) DEFAULT NULL,
+  `code` varchar(64) DEFAULT NULL,
+  `name` varchar(255) NOT NULL,
+  `kind` enum('percent','fixed') NOT NULL,
+  `percent` int(11) DEFAULT NULL,
+  `amount` bigint DEFAULT NULL,
+  `currency` char(3) DEFAULT NULL,
+  `scope` enum('organizer','package','activity','category') NOT NULL,
+  `scopeId` int(11) NOT NULL,
+  `validFrom` datetime NOT NULL,
+  `validUntil` datetime NOT NULL,
+  `maxRedemptions` int(11) DEFAULT NULL,
+  `maxPerUser` int(11) DEFAULT NULL,
+  `active` tinyint(1) NOT NULL DEFAULT 1,
+  `createdAt` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `code` (`code`),
+  KEY `scope` (`scope`, `scopeId`),
+  CONSTRAINT `discount_of` FOREIGN KEY (`fk_Organizerid`) REFERENCES `organizer` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Discounts with redemptions can't be deleted, bookings keep showing what they were discounted by
+CREATE TABLE IF NOT EXISTS `discountredemption` (
+  `fk_Bookingid` int(11) NOT NULL,
+  `fk_Discountid` int(11) NOT NULL,
+  `fk_Userid` int(11) NOT NULL,
+  `amount` bigint NOT NULL,
+  `createdAt` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`fk_Bookingid`),
+  KEY `fk_Discountid` (`fk_Discountid`),
+  CONSTRAINT `redemption_of_booking` FOREIGN KEY (`fk_Bookingid`) REFERENCES `booking` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `redeemed_discount` FOREIGN KEY (`fk_Discountid`) REFERENCES `discount` (`id`),
+  CONSTRAINT `redeemed_by` FOREIGN KEY (`fk_Userid`) REFERENCES `user` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,9 @@
+DELETE FROM `payment` WHERE `fk_Bookingid` IS NULL;
+ALTER TABLE `payment`
+  DROP FOREIGN KEY `paid_voucher`,
+  DROP KEY `fk_GiftVoucherid`,
+  DROP COLUMN `fk_GiftVoucherid`,
+  MODIFY `fk_Bookingid` int(11) NOT NULL;
+
+DROP TABLE IF EXISTS `voucherredemption`;
+DROP TABLE IF EXISTS `giftvoucher`;
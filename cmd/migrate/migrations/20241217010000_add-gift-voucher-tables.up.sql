@@ -0,0 +1,42 @@
+-- Gift vouchers are bought for fixed amount or price of package. Package vouchers are redeemable only against
+-- activities of that package. Balance is what's left of amount after redemptions
+CREATE TABLE IF NOT EXISTS `giftvoucher` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `code` varchar(32) NOT NULL,
+  `fk_Userid` int(11) NOT NULL,
+  `fk_Packageid` int(11) DEFAULT NULL,
+  `amount` bigint NOT NULL,
+  `balance` bigint NOT NULL,
+  `currency` char(3) NOT NULL,
+  `recipientName` varchar(255) NOT NULL,
+  `recipientEmail` varchar(255) DEFAULT NULL,
+  `message` text DEFAULT NULL,
+  `status` enum('pending','active','cancelled') NOT NULL DEFAULT 'pending',
+  `expiresAt` datetime NOT NULL,
+  `createdAt` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `code` (`code`),
+  KEY `fk_Userid` (`fk_Userid`),
+  CONSTRAINT `voucher_bought_by` FOREIGN KEY (`fk_Userid`) REFERENCES `user` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `voucher_for_package` FOREIGN KEY (`fk_Packageid`) REFERENCES `package` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Part of voucher redeemed against booking. Cancelled bookings give back restored amount to voucher balance
+CREATE TABLE IF NOT EXISTS `voucherredemption` (
+  `fk_Bookingid` int(11) NOT NULL,
+  `fk_GiftVoucherid` int(11) NOT NULL,
+  `amount` bigint NOT NULL,
+  `restoredAmount` bigint NOT NULL DEFAULT 0,
+  `createdAt` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`fk_Bookingid`),
+  KEY `fk_GiftVoucherid` (`fk_GiftVoucherid`),
+  CONSTRAINT `voucher_redeemed_for` FOREIGN KEY (`fk_Bookingid`) REFERENCES `booking` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `redeemed_voucher` FOREIGN KEY (`fk_GiftVoucherid`) REFERENCES `giftvoucher` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Payments are taken for bookings or gift vouchers
+ALTER TABLE `payment`
+  MODIFY `fk_Bookingid` int(11) DEFAULT NULL,
+  ADD COLUMN `fk_GiftVoucherid` int(11) DEFAULT NULL,
+  ADD KEY `fk_GiftVoucherid` (`fk_GiftVoucherid`),
+  ADD CONSTRAINT `paid_voucher` FOREIGN KEY (`fk_GiftVoucherid`) REFERENCES `giftvoucher` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,9 @@
+-- One check-in per booking, staff member who scanned ticket is kept
+CREATE TABLE IF NOT EXISTS `bookingcheckin` (
+  `fk_Bookingid` int(11) NOT NULL,
+  `fk_Userid` int(11) DEFAULT NULL,
+  `checkedInAt` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`fk_Bookingid`),
+  CONSTRAINT `checked_in_booking` FOREIGN KEY (`fk_Bookingid`) REFERENCES `booking` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `checked_in_by` FOREIGN KEY (`fk_Userid`) REFERENCES `user` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `certificate`;
+DROP TABLE IF EXISTS `certificatetemplate`;
@@ -0,0 +1,31 @@
+-- Certificate look chosen by organizer for activities of package, packages without one use classic template
+CREATE TABLE IF NOT EXISTS `certificatetemplate` (
+  `fk_Packageid` int(11) NOT NULL,
+  `template` enum('classic','modern','minimal') NOT NULL DEFAULT 'classic',
+  `title` varchar(255) DEFAULT NULL,
+  `signatureName` varchar(255) DEFAULT NULL,
+  `signatureTitle` varchar(255) DEFAULT NULL,
+  PRIMARY KEY (`fk_Packageid`),
+  CONSTRAINT `certificate_template_of` FOREIGN KEY (`fk_Packageid`) REFERENCES `package` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Certificates keep details as they were issued, so renamed activities don't change certificates already handed out
+CREATE TABLE IF NOT EXISTS `certificate` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `code` varchar(32) NOT NULL,
+  `fk_Bookingid` int(11) NOT NULL,
+  `recipientName` varchar(512) NOT NULL,
+  `activityName` varchar(255) NOT NULL,
+  `organizerName` varchar(255) NOT NULL,
+  `sessionDate` datetime NOT NULL,
+  `participants` int(11) NOT NULL,
+  `template` enum('classic','modern','minimal') NOT NULL,
+  `title` varchar(255) DEFAULT NULL,
+  `signatureName` varchar(255) DEFAULT NULL,
+  `signatureTitle` varchar(255) DEFAULT NULL,
+  `issuedAt` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `code` (`code`),
+  UNIQUE KEY `fk_Bookingid` (`fk_Bookingid`),
+  CONSTRAINT `certificate_of_booking` FOREIGN KEY (`fk_Bookingid`) REFERENCES `booking` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
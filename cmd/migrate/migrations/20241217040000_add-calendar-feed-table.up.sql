@@ -0,0 +1,9 @@
+-- Private iCalendar feed of user bookings, resetting token invalidates previously shared URL
+CREATE TABLE IF NOT EXISTS `calendarfeed` (
+  `fk_Userid` int(11) NOT NULL,
+  `token` varchar(64) NOT NULL,
+  `createdAt` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`fk_Userid`),
+  UNIQUE KEY `token` (`token`),
+  CONSTRAINT `calendar_feed_of` FOREIGN KEY (`fk_Userid`) REFERENCES `user` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `importedsession`;
+DROP TABLE IF EXISTS `calendarimport`;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS `calendarimport` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `fk_Activityid` int(11) NOT NULL,
+  `url` varchar(2048) DEFAULT NULL,
+  `capacity` int(11) NOT NULL,
+  `language` varchar(8) NOT NULL,
+  `fk_Locationid` int(11) DEFAULT NULL,
+  `lastImportedAt` datetime DEFAULT NULL,
+  `createdAt` datetime NOT NULL DEFAULT current_timestamp(),
+  PRIMARY KEY (`id`),
+  KEY `fk_Activityid` (`fk_Activityid`),
+  CONSTRAINT `import_for` FOREIGN KEY (`fk_Activityid`) REFERENCES `activity` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `import_held_at` FOREIGN KEY (`fk_Locationid`) REFERENCES `location` (`id`) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
+
+-- Event occurrence session was created from, occurrence is empty for single events.
+-- Removing import keeps its sessions, they are just no longer synced
+CREATE TABLE IF NOT EXISTS `importedsession` (
+  `fk_CalendarImportid` int(11) NOT NULL,
+  `uid` varchar(255) NOT NULL,
+  `occurrence` varchar(16) NOT NULL DEFAULT '',
+  `fk_ActivitySessionid` int(11) NOT NULL,
+  PRIMARY KEY (`fk_CalendarImportid`, `uid`, `occurrence`),
+  UNIQUE KEY `fk_ActivitySessionid` (`fk_ActivitySessionid`),
+  CONSTRAINT `imported_by` FOREIGN KEY (`fk_CalendarImportid`) REFERENCES `calendarimport` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `imported_session` FOREIGN KEY (`fk_ActivitySessionid`) REFERENCES `activitysession` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_general_ci;
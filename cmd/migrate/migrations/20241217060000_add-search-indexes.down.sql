@@ -0,0 +1,4 @@
+ALTER TABLE `organizer` DROP INDEX `search_text`;
+ALTER TABLE `package` DROP INDEX `search_text`;
+ALTER TABLE `activity` DROP INDEX `search_text`;
+ALTER TABLE `activity` DROP INDEX `search_name`;
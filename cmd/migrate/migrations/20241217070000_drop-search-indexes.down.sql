@@ -0,0 +1,5 @@
+-- InnoDB builds one FULLTEXT index per statement. Activity name has its own index, so matches in name can weigh more
+ALTER TABLE `activity` ADD FULLTEXT KEY `search_name` (`name`);
+ALTER TABLE `activity` ADD FULLTEXT KEY `search_text` (`name`, `description`);
+ALTER TABLE `package` ADD FULLTEXT KEY `search_text` (`name`, `description`);
+ALTER TABLE `organizer` ADD FULLTEXT KEY `search_text` (`description`);
@@ -0,0 +1,4 @@
+-- Search moved to in-memory index of the API, which folds diacritics and tolerates typos
+ALTER TABLE `activity` DROP INDEX `search_name`, DROP INDEX `search_text`;
+ALTER TABLE `package` DROP INDEX `search_text`;
+ALTER TABLE `organizer` DROP INDEX `search_text`;
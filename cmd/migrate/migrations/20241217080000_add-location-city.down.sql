@@ -0,0 +1 @@
+ALTER TABLE `location` DROP KEY `location_city`, DROP COLUMN `city`;
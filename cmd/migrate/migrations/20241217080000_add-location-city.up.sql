@@ -0,0 +1,3 @@
+-- City is derived from address by the API, existing locations get it by running cmd/locations once
+ALTER TABLE `location` ADD COLUMN `city` varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE `location` ADD KEY `location_city` (`city`);
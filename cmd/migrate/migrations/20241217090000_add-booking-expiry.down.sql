@@ -0,0 +1,3 @@
+ALTER TABLE `booking`
+  DROP KEY `status_expiresAt`,
+  DROP COLUMN `expiresAt`;
@@ -0,0 +1,9 @@
+-- Unpaid bookings hold their seats only until payment deadline. Bookings already waiting for payment
+-- get the default 30 minute window from now, offers of waitlist keep their own deadline
+ALTER TABLE `booking`
+  ADD COLUMN `expiresAt` datetime DEFAULT NULL AFTER `currency`,
+  ADD KEY `status_expiresAt` (`status`, `expiresAt`);
+
+UPDATE `booking` SET `expiresAt` = UTC_TIMESTAMP() + INTERVAL 30 MINUTE
+WHERE `status` = 'pending' AND `amount` > 0
+  AND `id` NOT IN (SELECT `fk_Bookingid` FROM `waitlist` WHERE `status` = 'offered' AND `fk_Bookingid` IS NOT NULL);
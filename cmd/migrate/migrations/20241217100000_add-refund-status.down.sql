@@ -0,0 +1 @@
+ALTER TABLE `bookingcancellation` DROP KEY `refundStatus`, DROP COLUMN `refundStatus`;
@@ -0,0 +1,11 @@
+-- Refunds owed to cancelled bookings are tracked until provider confirms them, so failed ones are retried.
+-- Earlier cancellations whose payment wasn't refunded as much as they are owed are retried as well
+ALTER TABLE `bookingcancellation` ADD COLUMN `refundStatus` varchar(20) NOT NULL DEFAULT 'none' AFTER `refundAmount`,
+  ADD KEY `refundStatus` (`refundStatus`);
+
+UPDATE `bookingcancellation` SET `refundStatus` = 'completed' WHERE `refundAmount` > 0;
+
+UPDATE `bookingcancellation`
+JOIN `payment` ON `payment`.`fk_Bookingid` = `bookingcancellation`.`fk_Bookingid` AND `payment`.`status` = 'succeeded'
+SET `bookingcancellation`.`refundStatus` = 'pending'
+WHERE `bookingcancellation`.`refundAmount` > `payment`.`refundedAmount`;
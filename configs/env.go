@@ -20,6 +20,64 @@ type Config struct {
 	RefreshTokenExpirationInSeconds int64
 	SslMode                         string
 	CACertPath                      string
+
+	GeocoderProvider         string
+	GeocoderURL              string
+	GeocoderUserAgent        string
+	GeocoderLanguage         string
+	GeocoderStaticFile       string
+	GeocoderTimeoutInSeconds int64
+
+	MaxImageUploadSize int64
+	MaxImagePixels     int64
+	ImageVariants      string
+	ImageWorkers       int64
+	ImageQueueSize     int64
+
+	BlobBackend                string
+	BlobLocalDir               string
+	BlobLocalURL               string
+	BlobSigningSecret          string
+	BlobURLExpirationInSeconds int64
+	S3Endpoint                 string
+	S3Region                   string
+	S3Bucket                   string
+	S3AccessKey                string
+	S3SecretKey                string
+	S3PublicURL                string
+	S3PathStyle                bool
+
+	ScheduleTimezone          string
+	ScheduleHorizonInDays     int64
+	ScheduleIntervalInMinutes int64
+
+	WaitlistWindowInMinutes int64
+	WaitlistSweepInSeconds  int64
+	PaymentWindowInMinutes  int64
+
+	TicketSigningSecret string
+
+	PaymentProvider         string
+	PaymentSuccessURL       string
+	PaymentCancelURL        string
+	PaymentWebhookSecret    string
+	PaymentMockURL          string
+	PaymentMockAllowed      bool
+	PaymentTimeoutInSeconds int64
+	StripeURL               string
+	StripeSecretKey         string
+
+	VoucherValidityInDays int64
+
+	CertificateFontFile  string
+	CertificateVerifyURL string
+
+	CalendarFeedURL       string
+	CalendarDomain        string
+	CalendarHistoryInDays int64
+
+	CalendarImportMaxSize          int64
+	CalendarImportTimeoutInSeconds int64
 }
 
 var Envs = initConfig()
@@ -40,6 +98,64 @@ func initConfig() Config {
 		RefreshTokenExpirationInSeconds: getEnvAsInt("REFRESH_TOKEN_EXP", 86400),
 		SslMode:                         getEnv("SSL_MODE", "disable"),
 		CACertPath:                      getEnv("CA_CERT_PATH", ""),
+
+		GeocoderProvider:         getEnv("GEOCODER_PROVIDER", "nominatim"),
+		GeocoderURL:              getEnv("GEOCODER_URL", "https://nominatim.openstreetmap.org"),
+		GeocoderUserAgent:        getEnv("GEOCODER_USER_AGENT", "educations-castle"),
+		GeocoderLanguage:         getEnv("GEOCODER_LANGUAGE", "lt"),
+		GeocoderStaticFile:       getEnv("GEOCODER_STATIC_FILE", ""),
+		GeocoderTimeoutInSeconds: getEnvAsInt("GEOCODER_TIMEOUT", 5),
+
+		MaxImageUploadSize: getEnvAsInt("MAX_IMAGE_UPLOAD_SIZE", 5<<20),
+		MaxImagePixels:     getEnvAsInt("MAX_IMAGE_PIXELS", 50_000_000),
+		ImageVariants:      getEnv("IMAGE_VARIANTS", "thumbnail:160x160,card:640x480,full:1920x1920"),
+		ImageWorkers:       getEnvAsInt("IMAGE_WORKERS", 2),
+		ImageQueueSize:     getEnvAsInt("IMAGE_QUEUE_SIZE", 100),
+
+		BlobBackend:                getEnv("BLOB_BACKEND", "local"),
+		BlobLocalDir:               getEnv("BLOB_LOCAL_DIR", "resources"),
+		BlobLocalURL:               getEnv("BLOB_LOCAL_URL", "/api/v1/blobs"),
+		BlobSigningSecret:          getEnv("BLOB_SIGNING_SECRET", ""),
+		BlobURLExpirationInSeconds: getEnvAsInt("BLOB_URL_EXP", 900),
+		S3Endpoint:                 getEnv("S3_ENDPOINT", "https://s3.eu-central-1.amazonaws.com"),
+		S3Region:                   getEnv("S3_REGION", "eu-central-1"),
+		S3Bucket:                   getEnv("S3_BUCKET", ""),
+		S3AccessKey:                getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:                getEnv("S3_SECRET_KEY", ""),
+		S3PublicURL:                getEnv("S3_PUBLIC_URL", ""),
+		S3PathStyle:                getEnvAsBool("S3_PATH_STYLE", false),
+
+		ScheduleTimezone:          getEnv("SCHEDULE_TIMEZONE", "Europe/Vilnius"),
+		ScheduleHorizonInDays:     getEnvAsInt("SCHEDULE_HORIZON_DAYS", 90),
+		ScheduleIntervalInMinutes: getEnvAsInt("SCHEDULE_INTERVAL", 60),
+
+		WaitlistWindowInMinutes: getEnvAsInt("WAITLIST_CONFIRM_WINDOW", 1440),
+		WaitlistSweepInSeconds:  getEnvAsInt("WAITLIST_SWEEP_INTERVAL", 60),
+		PaymentWindowInMinutes:  getEnvAsInt("BOOKING_PAYMENT_WINDOW", 30),
+
+		TicketSigningSecret: getEnv("TICKET_SIGNING_SECRET", ""),
+
+		PaymentProvider:         getEnv("PAYMENT_PROVIDER", ""),
+		PaymentSuccessURL:       getEnv("PAYMENT_SUCCESS_URL", "http://localhost:3000/bookings/my"),
+		PaymentCancelURL:        getEnv("PAYMENT_CANCEL_URL", "http://localhost:3000/bookings/my"),
+		PaymentWebhookSecret:    getEnv("PAYMENT_WEBHOOK_SECRET", ""),
+		PaymentMockURL:          getEnv("PAYMENT_MOCK_URL", "/api/v1/payments/mock"),
+		PaymentMockAllowed:      getEnvAsBool("PAYMENT_MOCK_ALLOWED", false),
+		PaymentTimeoutInSeconds: getEnvAsInt("PAYMENT_TIMEOUT", 10),
+		StripeURL:               getEnv("STRIPE_URL", "https://api.stripe.com"),
+		StripeSecretKey:         getEnv("STRIPE_SECRET_KEY", ""),
+
+		VoucherValidityInDays: getEnvAsInt("VOUCHER_VALIDITY_DAYS", 365),
+
+		CertificateFontFile:  getEnv("CERTIFICATE_FONT", ""),
+		CertificateVerifyURL: getEnv("CERTIFICATE_VERIFY_URL", "http://localhost:8080/api/v1/certificates/verify"),
+
+		CalendarFeedURL:       getEnv("CALENDAR_FEED_URL", "http://localhost:8080/api/v1/calendar/feeds"),
+		CalendarDomain:        getEnv("CALENDAR_DOMAIN", "educations-castle"),
+		CalendarHistoryInDays: getEnvAsInt("CALENDAR_HISTORY_DAYS", 30),
+
+		CalendarImportMaxSize:          getEnvAsInt("CALENDAR_IMPORT_MAX_SIZE", 5<<20),
+		CalendarImportTimeoutInSeconds: getEnvAsInt("CALENDAR_IMPORT_TIMEOUT", 15),
 	}
 }
 
@@ -61,3 +177,15 @@ func getEnvAsInt(key string, fallback int64) int64 {
 
 	return fallback
 }
+
+func getEnvAsBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fallback
+		}
+		return b
+	}
+
+	return fallback
+}
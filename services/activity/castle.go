@@ -2,16 +2,29 @@ package activity
 
 import (
 	"database/sql"
+	"educations-castle/services/search"
 	"educations-castle/types"
+	"educations-castle/utils"
 	"fmt"
+	"math"
+	"strings"
+	"time"
 )
 
+// lowestPrice is the cheapest price activity can be booked for. Tiers of activity and of its upcoming sessions
+// count, activities without tiers cost their base price
+const lowestPrice = `COALESCE((SELECT MIN(pricetier.amount) FROM pricetier
+		LEFT JOIN activitysession ON activitysession.id = pricetier.fk_ActivitySessionid
+		WHERE pricetier.fk_Activityid = activity.id AND (pricetier.fk_ActivitySessionid IS NULL
+			OR (activitysession.cancelled = 0 AND activitysession.startTime > UTC_TIMESTAMP()))), activity.basePrice)`
+
 type Castle struct {
-	db *sql.DB
+	db    *sql.DB
+	index *search.Index
 }
 
-func NewCastle(db *sql.DB) *Castle {
-	return &Castle{db: db}
+func NewCastle(db *sql.DB, index *search.Index) *Castle {
+	return &Castle{db: db, index: index}
 }
 
 func scanRowIntoActivity(rows *sql.Rows) (*types.Activity, error) {
@@ -21,13 +34,15 @@ func scanRowIntoActivity(rows *sql.Rows) (*types.Activity, error) {
 		&activity.ID,
 		&activity.Name,
 		&activity.Description,
-		&activity.BasePrice,
+		&activity.BasePrice.Amount,
+		&activity.BasePrice.Currency,
 		&activity.CreationDate,
 		&activity.Hidden,
 		&activity.Verified,
 		&activity.Category,
 		&activity.AverageRating,
 		&activity.FkPackageID,
+		&activity.ModerationStatus,
 	)
 
 	if err != nil {
@@ -44,7 +59,8 @@ func scanRowIntoPackage(rows *sql.Rows) (*types.Package, error) {
 		&p.ID,
 		&p.Name,
 		&p.Description,
-		&p.Price,
+		&p.Price.Amount,
+		&p.Price.Currency,
 		&p.FkOrganizerID,
 	)
 
@@ -55,8 +71,9 @@ func scanRowIntoPackage(rows *sql.Rows) (*types.Package, error) {
 	return p, nil
 }
 
-func (c *Castle) ListActivities() ([]*types.Activity, error) {
-	rows, err := c.db.Query("SELECT * FROM activity")
+func (c *Castle) ListActivities(viewer types.Viewer) ([]*types.Activity, error) {
+	query, params := visibleActivitiesQuery(viewer, "")
+	rows, err := c.db.Query(query, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -79,8 +96,9 @@ func (c *Castle) ListActivities() ([]*types.Activity, error) {
 	return activities, nil
 }
 
-func (c *Castle) ListActivitiesInPackage(packageID int) ([]*types.Activity, error) {
-	rows, err := c.db.Query("SELECT * FROM activity WHERE fk_Packageid = ?", packageID)
+func (c *Castle) ListActivitiesInPackage(packageID int, viewer types.Viewer) ([]*types.Activity, error) {
+	query, params := visibleActivitiesQuery(viewer, "activity.fk_Packageid = ?", packageID)
+	rows, err := c.db.Query(query, params...)
 	if err != nil {
 		return nil, err
 	}
@@ -110,14 +128,20 @@ func (c *Castle) CreateActivity(activity types.ActivityPayload) error {
 		return fmt.Errorf("failed to find category '%s': %v", activity.Category, err)
 	}
 
-	_, err = c.db.Exec(
-		"INSERT INTO activity (name, description, basePrice, hidden, category, fk_Packageid) VALUES (?,?,?,?,?,?)",
-		activity.Name, activity.Description, activity.BasePrice, activity.Hidden, categoryID, activity.FkPackageID)
+	result, err := c.db.Exec(
+		"INSERT INTO activity (name, description, basePrice, currency, hidden, category, fk_Packageid) VALUES (?,?,?,?,?,?,?)",
+		activity.Name, activity.Description, activity.BasePrice.Amount, activity.BasePrice.Currency, activity.Hidden,
+		categoryID, activity.FkPackageID)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	return c.indexActivities("activity.id = ?", id)
 }
 
 func (c *Castle) GetActivityByID(id int) (*types.Activity, error) {
@@ -140,6 +164,30 @@ func (c *Castle) GetActivityByID(id int) (*types.Activity, error) {
 	return a, nil
 }
 
+// GetVisibleActivityByID returns activity only when viewer is allowed to see it, otherwise sql.ErrNoRows
+func (c *Castle) GetVisibleActivityByID(id int, viewer types.Viewer) (*types.Activity, error) {
+	query, params := visibleActivitiesQuery(viewer, "activity.id = ?", id)
+	rows, err := c.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	a := new(types.Activity)
+	for rows.Next() {
+		a, err = scanRowIntoActivity(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if a.ID == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return a, nil
+}
+
 func (c *Castle) GetActivityInsidePackageByName(activityName string, packageID int) (*types.Activity, error) {
 	rows, err := c.db.Query("SELECT * FROM activity WHERE name = ? AND fk_Packageid = ?", activityName, packageID)
 	if err != nil {
@@ -186,18 +234,51 @@ func (c *Castle) UpdateActivity(activity types.Activity) error {
 		return fmt.Errorf("failed to find category '%s': %v", activity.Category, err)
 	}
 
+	// Check whether fields checked by moderators are changing
+	var status string
+	var verified, moderatedChanged bool
+	err = c.db.QueryRow(
+		`SELECT moderationStatus, verified,
+			NOT (name <=> ? AND description <=> ? AND basePrice <=> ? AND currency <=> ? AND category <=> ?)
+		FROM activity WHERE id = ?`,
+		activity.Name, activity.Description, activity.BasePrice.Amount, activity.BasePrice.Currency, categoryID,
+		activity.ID).Scan(&status, &verified, &moderatedChanged)
+	if err != nil {
+		return err
+	}
+
+	status = moderationStatusAfterEdit(status, moderatedChanged)
+	if status == types.ModerationPending {
+		verified = false
+	}
+
 	// Update the existing activity
 	_, err = c.db.Exec(
 		`UPDATE activity 
-		SET name = ?, description = ?, basePrice = ?, hidden = ?, category = ?, fk_Packageid = ? 
+		SET name = ?, description = ?, basePrice = ?, currency = ?, hidden = ?, category = ?, fk_Packageid = ?,
+			moderationStatus = ?, verified = ?
 		WHERE id = ?`,
-		activity.Name, activity.Description, activity.BasePrice, activity.Hidden, categoryID,
-		activity.FkPackageID, activity.ID)
+		activity.Name, activity.Description, activity.BasePrice.Amount, activity.BasePrice.Currency, activity.Hidden,
+		categoryID, activity.FkPackageID, status, verified, activity.ID)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return c.indexActivities("activity.id = ?", activity.ID)
+}
+
+// moderationStatusAfterEdit returns status of edited activity. Rejected activities return to moderation
+// queue once they are changed, approved ones only when name, description, price or category changes
+func moderationStatusAfterEdit(status string, moderatedChanged bool) string {
+	switch status {
+	case types.ModerationRejected, types.ModerationChangesRequested:
+		return types.ModerationPending
+	case types.ModerationApproved:
+		if moderatedChanged {
+			return types.ModerationPending
+		}
+	}
+	return status
 }
 
 func (c *Castle) DeleteActivity(id int) error {
@@ -207,61 +288,273 @@ func (c *Castle) DeleteActivity(id int) error {
 		return err
 	}
 
+	c.index.Remove(search.KindActivity, id)
+
 	return nil
 }
 
-func (c *Castle) FilterActivities(a types.ActivityFilterPayload) ([]*types.Activity, error) {
+func (c *Castle) FilterActivities(a types.ActivityFilterPayload, viewer types.Viewer) ([]*types.Activity, error) {
+	conditions, params, err := c.filterConditions(a, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Execute the query with the dynamic parameters
+	rows, err := c.db.Query("SELECT activity.*"+filterJoins+" WHERE "+conditions, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var activities []*types.Activity
+
+	// Iterate over the result set
+	for rows.Next() {
+		a := new(types.Activity)
+		a, err = scanRowIntoActivity(rows) // Custom method to scan a row into Activity object
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return activities, nil
+}
+
+// SearchActivities returns page of activities matching query and filter, most relevant first, together with
+// total count of matching activities. Query is matched by search index, filter by database
+func (c *Castle) SearchActivities(query string, a types.ActivityFilterPayload, viewer types.Viewer, limit, offset int) ([]*types.ActivitySearchHit, int, error) {
+	q := search.ParseQuery(query)
+	found := c.index.Search(search.KindActivity, q)
+	if len(found) == 0 {
+		return nil, 0, nil
+	}
+
+	conditions, params, err := c.filterConditions(a, viewer)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]interface{}, len(found))
+	for i, f := range found {
+		ids[i] = f.ID
+	}
+
+	rows, err := c.db.Query(
+		`SELECT activity.*, package.name, COALESCE(package.description, ''), COALESCE(organizer.description, '')`+filterJoins+`
+		WHERE activity.id IN (?`+strings.Repeat(",?", len(ids)-1)+`) AND `+conditions,
+		append(ids, params...)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	matching := map[int]*types.ActivitySearchHit{}
+
+	for rows.Next() {
+		a := new(types.Activity)
+		var packageName, packageDescription, organizerDescription string
+
+		err := rows.Scan(
+			&a.ID,
+			&a.Name,
+			&a.Description,
+			&a.BasePrice.Amount,
+			&a.BasePrice.Currency,
+			&a.CreationDate,
+			&a.Hidden,
+			&a.Verified,
+			&a.Category,
+			&a.AverageRating,
+			&a.FkPackageID,
+			&a.ModerationStatus,
+			&packageName,
+			&packageDescription,
+			&organizerDescription,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		matching[a.ID] = &types.ActivitySearchHit{
+			Activity: a,
+			Highlights: highlights(map[string]string{
+				"name":        a.Name,
+				"description": a.Description,
+				"package":     strings.TrimSpace(packageName + " " + packageDescription),
+				"organizer":   organizerDescription,
+			}, q),
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	// Hits keep order of search index
+	var hits []*types.ActivitySearchHit
+	for _, f := range found {
+		if hit, ok := matching[f.ID]; ok {
+			hit.Score = math.Round(f.Score*100) / 100
+			hits = append(hits, hit)
+		}
+	}
+
+	return page(hits, limit, offset), len(hits), nil
+}
+
+// SearchPackages returns page of packages matching query, most relevant first, together with total count of them
+func (c *Castle) SearchPackages(query string, limit, offset int) ([]*types.PackageSearchHit, int, error) {
+	q := search.ParseQuery(query)
+	found := c.index.Search(search.KindPackage, q)
+	if len(found) == 0 {
+		return nil, 0, nil
+	}
+
+	ids := make([]interface{}, len(found))
+	for i, f := range found {
+		ids[i] = f.ID
+	}
+
+	rows, err := c.db.Query("SELECT * FROM package WHERE id IN (?"+strings.Repeat(",?", len(ids)-1)+")", ids...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	packages := map[int]*types.Package{}
+
+	for rows.Next() {
+		p, err := scanRowIntoPackage(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		packages[p.ID] = p
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	// Hits keep order of search index
+	var hits []*types.PackageSearchHit
+	for _, f := range found {
+		if p, ok := packages[f.ID]; ok {
+			hits = append(hits, &types.PackageSearchHit{
+				Package:    p,
+				Score:      math.Round(f.Score*100) / 100,
+				Highlights: highlights(map[string]string{"name": p.Name, "description": p.Description}, q),
+			})
+		}
+	}
+
+	return page(hits, limit, offset), len(hits), nil
+}
+
+// Filtered activities are joined with their package and organizer
+const filterJoins = `
+		FROM activity
+		JOIN package ON activity.fk_Packageid = package.id
+		JOIN organizer ON package.fk_Organizerid = organizer.id
+		JOIN user ON organizer.id = user.id`
+
+// filterConditions builds WHERE conditions of activity filter together with their parameters
+func (c *Castle) filterConditions(a types.ActivityFilterPayload, viewer types.Viewer) (string, []interface{}, error) {
 	var categoryID int
 
 	// Check if category is provided, and retrieve its ID from the category table
 	if a.Category != "" {
 		err := c.db.QueryRow("SELECT id_Category FROM category WHERE name = ?", a.Category).Scan(&categoryID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to retrieve category ID: %w", err)
+			return "", nil, fmt.Errorf("failed to retrieve category ID: %w", err)
 		}
 	}
 
-	// Prepare the SQL query with conditional filtering and necessary joins
-	query := `
-		SELECT activity.*
-		FROM activity
-		JOIN package ON activity.fk_Packageid = package.id
-		JOIN organizer ON package.fk_Organizerid = organizer.id
-		JOIN user ON organizer.id = user.id
-		WHERE
+	// Prepare conditional filtering
+	conditions := `
 			(activity.name LIKE COALESCE(NULLIF(?, ''), activity.name))
-			AND (activity.basePrice >= COALESCE(NULLIF(?, 0), activity.basePrice))
-			AND (activity.basePrice <= COALESCE(NULLIF(?, 0), activity.basePrice))
+			AND (` + lowestPrice + ` >= ?)
+			AND (` + lowestPrice + ` <= COALESCE(NULLIF(?, 0), ` + lowestPrice + `))
+			AND (activity.currency = COALESCE(NULLIF(?, ''), activity.currency))
 			AND (activity.averageRating >= COALESCE(NULLIF(?, 0), activity.averageRating))
 			AND (activity.averageRating <= COALESCE(NULLIF(?, 0), activity.averageRating))
-			AND (user.username LIKE COALESCE(NULLIF(?, ''), user.username))
-			AND (activity.creationDate >= COALESCE(NULLIF(?, ''), '1970-01-01'))
-			AND (activity.creationDate <= COALESCE(NULLIF(?, ''), '9999-12-31'))`
+			AND (user.username LIKE COALESCE(NULLIF(?, ''), user.username))`
+
+	// Date range matches activities having at least one session starting inside it
+	if a.StartDate != "" || a.EndDate != "" {
+		conditions += `
+			AND EXISTS (
+				SELECT 1 FROM activitysession
+				WHERE activitysession.fk_Activityid = activity.id
+					AND activitysession.startTime >= COALESCE(NULLIF(?, ''), '1970-01-01')
+					AND activitysession.startTime <= COALESCE(NULLIF(?, ''), '9999-12-31'))`
+	}
 
 	// If category ID is found, add a filter for it
 	if a.Category != "" {
-		query += " AND activity.category = ?"
+		conditions += " AND activity.category = ?"
+	}
+
+	if a.City != "" {
+		conditions += " AND EXISTS (SELECT 1 FROM location WHERE location.fk_Activityid = activity.id AND location.city = ?)"
+	}
+
+	// Month matches activities having upcoming session starting in it
+	var month time.Time
+	if a.Month != "" {
+		var err error
+		month, err = time.Parse("2006-01", a.Month)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid month '%s'", a.Month)
+		}
+		conditions += `
+			AND EXISTS (
+				SELECT 1 FROM activitysession
+				WHERE activitysession.fk_Activityid = activity.id AND activitysession.cancelled = 0
+					AND activitysession.startTime >= GREATEST(?, UTC_TIMESTAMP())
+					AND activitysession.startTime < ?)`
 	}
 
+	clause, visibilityParams := visibilityClause(viewer)
+	conditions += " AND " + clause
+
 	// Build the query parameters list
 	params := []interface{}{
 		"%" + a.Name + "%",      // Partial match for name
 		a.MinPrice,              // Minimum price filter
 		a.MaxPrice,              // Maximum price filter
+		a.Currency,              // Prices are compared only within currency
 		a.MinRating,             // Minimum rating filter
 		a.MaxRating,             // Maximum rating filter
 		"%" + a.Organizer + "%", // Partial match for organizer (user) name
-		a.StartDate,             // Start date for creationDate filter
-		a.EndDate,               // End date for creationDate filter
+	}
+
+	if a.StartDate != "" || a.EndDate != "" {
+		params = append(params, a.StartDate, a.EndDate)
 	}
 
 	// Add the category ID as a parameter only if it's provided
 	if a.Category != "" {
 		params = append(params, categoryID)
 	}
+	if a.City != "" {
+		params = append(params, a.City)
+	}
+	if a.Month != "" {
+		params = append(params, month.Format(time.DateTime), month.AddDate(0, 1, 0).Format(time.DateTime))
+	}
+	params = append(params, visibilityParams...)
 
-	// Execute the query with the dynamic parameters
-	rows, err := c.db.Query(query, params...)
+	return conditions, params, nil
+}
+
+// ListActivitiesByModerationStatus returns moderation queue, oldest activities first
+func (c *Castle) ListActivitiesByModerationStatus(status string) ([]*types.Activity, error) {
+	rows, err := c.db.Query("SELECT * FROM activity WHERE moderationStatus = ? ORDER BY creationDate, id", status)
 	if err != nil {
 		return nil, err
 	}
@@ -269,10 +562,8 @@ func (c *Castle) FilterActivities(a types.ActivityFilterPayload) ([]*types.Activ
 
 	var activities []*types.Activity
 
-	// Iterate over the result set
 	for rows.Next() {
-		a := new(types.Activity)
-		a, err = scanRowIntoActivity(rows) // Custom method to scan a row into Activity object
+		a, err := scanRowIntoActivity(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -289,8 +580,8 @@ func (c *Castle) FilterActivities(a types.ActivityFilterPayload) ([]*types.Activ
 func (c *Castle) CreatePackage(p types.Package) (int64, error) {
 	// Execute the SQL query and get the result
 	result, err := c.db.Exec(
-		"INSERT INTO package (name, description, price, fk_Organizerid) VALUES (?,?,?,?)",
-		p.Name, p.Description, p.Price, p.FkOrganizerID)
+		"INSERT INTO package (name, description, price, currency, fk_Organizerid) VALUES (?,?,?,?,?)",
+		p.Name, p.Description, p.Price.Amount, p.Price.Currency, p.FkOrganizerID)
 	if err != nil {
 		return 0, err
 	}
@@ -301,6 +592,9 @@ func (c *Castle) CreatePackage(p types.Package) (int64, error) {
 		return 0, err
 	}
 
+	p.ID = int(packageID)
+	c.indexPackage(p)
+
 	return packageID, nil
 }
 
@@ -394,21 +688,172 @@ func (c *Castle) ListPackagesByOrganizerID(organizerID int) ([]*types.Package, e
 
 func (c *Castle) UpdatePackage(p types.Package) error {
 	_, err := c.db.Exec(
-		"UPDATE package SET name = ?, description = ?, price = ?, fk_Organizerid = ? WHERE id = ?",
-		p.Name, p.Description, p.Price, p.FkOrganizerID, p.ID)
+		"UPDATE package SET name = ?, description = ?, price = ?, currency = ?, fk_Organizerid = ? WHERE id = ?",
+		p.Name, p.Description, p.Price.Amount, p.Price.Currency, p.FkOrganizerID, p.ID)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Activities are found by texts of their package too
+	c.indexPackage(p)
+	return c.indexActivities("package.id = ?", p.ID)
 }
 
 func (c *Castle) DeletePackage(id int) error {
-	_, err := c.db.Exec(
+	// Activities are deleted together with package, so they are collected first to be removed from index too
+	activityIDs, err := utils.QueryIDs(c.db, "SELECT id FROM activity WHERE fk_Packageid = ?", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.Exec(
 		"DELETE FROM package WHERE id = ?", id)
 	if err != nil {
 		return err
 	}
 
+	for _, activityID := range activityIDs {
+		c.index.Remove(search.KindActivity, activityID)
+	}
+	c.index.Remove(search.KindPackage, id)
+
 	return nil
 }
+
+func scanRowIntoPriceTier(rows *sql.Rows) (*types.PriceTier, error) {
+	t := new(types.PriceTier)
+
+	err := rows.Scan(
+		&t.ID,
+		&t.FkActivityID,
+		&t.FkActivitySessionID,
+		&t.Name,
+		&t.Price.Amount,
+		&t.Price.Currency,
+		&t.Seats,
+		&t.MinAge,
+		&t.MaxAge,
+		&t.Eligibility,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// ListPriceTiers returns price tiers of activities, without those of their sessions
+func (c *Castle) ListPriceTiers(activityIDs []int) (map[int][]*types.PriceTier, error) {
+	tiers := make(map[int][]*types.PriceTier)
+	if len(activityIDs) == 0 {
+		return tiers, nil
+	}
+
+	params := make([]interface{}, 0, len(activityIDs))
+	for _, id := range activityIDs {
+		params = append(params, id)
+	}
+
+	query := fmt.Sprintf(`SELECT * FROM pricetier
+		WHERE fk_Activityid IN (%s) AND fk_ActivitySessionid IS NULL
+		ORDER BY fk_Activityid, id`,
+		strings.TrimSuffix(strings.Repeat("?,", len(activityIDs)), ","))
+
+	rows, err := c.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t, err := scanRowIntoPriceTier(rows)
+		if err != nil {
+			return nil, err
+		}
+		tiers[t.FkActivityID] = append(tiers[t.FkActivityID], t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tiers, nil
+}
+
+// ListSessionPriceTiers returns price tables sessions are booked by. Sessions without their own tiers
+// get tiers of their activity
+func (c *Castle) ListSessionPriceTiers(sessionIDs []int) (map[int][]*types.PriceTier, error) {
+	tiers := make(map[int][]*types.PriceTier)
+	if len(sessionIDs) == 0 {
+		return tiers, nil
+	}
+
+	params := make([]interface{}, 0, len(sessionIDs))
+	for _, id := range sessionIDs {
+		params = append(params, id)
+	}
+
+	query := fmt.Sprintf(`SELECT activitysession.id, pricetier.*
+		FROM activitysession
+		JOIN pricetier ON pricetier.fk_Activityid = activitysession.fk_Activityid
+		WHERE activitysession.id IN (%s) AND (pricetier.fk_ActivitySessionid = activitysession.id
+			OR (pricetier.fk_ActivitySessionid IS NULL AND NOT EXISTS (
+				SELECT 1 FROM pricetier AS own WHERE own.fk_ActivitySessionid = activitysession.id)))
+		ORDER BY activitysession.id, pricetier.id`,
+		strings.TrimSuffix(strings.Repeat("?,", len(sessionIDs)), ","))
+
+	rows, err := c.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t := new(types.PriceTier)
+		var sessionID int
+		err := rows.Scan(&sessionID, &t.ID, &t.FkActivityID, &t.FkActivitySessionID, &t.Name, &t.Price.Amount,
+			&t.Price.Currency, &t.Seats, &t.MinAge, &t.MaxAge, &t.Eligibility)
+		if err != nil {
+			return nil, err
+		}
+		tiers[sessionID] = append(tiers[sessionID], t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tiers, nil
+}
+
+// SavePriceTiers replaces price tiers of activity or, when session is given, of session. Session without
+// tiers is booked by tiers of its activity again
+func (c *Castle) SavePriceTiers(activityID int, sessionID *int, tiers []types.PriceTier) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if sessionID == nil {
+		_, err = tx.Exec("DELETE FROM pricetier WHERE fk_Activityid = ? AND fk_ActivitySessionid IS NULL", activityID)
+	} else {
+		_, err = tx.Exec("DELETE FROM pricetier WHERE fk_ActivitySessionid = ?", *sessionID)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tiers {
+		_, err = tx.Exec(
+			`INSERT INTO pricetier (fk_Activityid, fk_ActivitySessionid, name, amount, currency, seats, minAge, maxAge, eligibility)
+			VALUES (?,?,?,?,?,?,?,?,?)`,
+			activityID, sessionID, t.Name, t.Price.Amount, t.Price.Currency, t.Seats, t.MinAge, t.MaxAge, t.Eligibility)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
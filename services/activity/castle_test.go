@@ -0,0 +1,26 @@
+package activity
+
+import (
+	"educations-castle/types"
+	"testing"
+)
+
+func TestModerationStatusAfterEdit(t *testing.T) {
+	tests := []struct {
+		status           string
+		moderatedChanged bool
+		expected         string
+	}{
+		{types.ModerationApproved, true, types.ModerationPending},
+		{types.ModerationApproved, false, types.ModerationApproved},
+		{types.ModerationRejected, false, types.ModerationPending},
+		{types.ModerationChangesRequested, true, types.ModerationPending},
+		{types.ModerationPending, true, types.ModerationPending},
+	}
+
+	for _, test := range tests {
+		if status := moderationStatusAfterEdit(test.status, test.moderatedChanged); status != test.expected {
+			t.Errorf("%s (changed %v): expected %s, got %s", test.status, test.moderatedChanged, test.expected, status)
+		}
+	}
+}
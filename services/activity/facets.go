@@ -0,0 +1,172 @@
+package activity
+
+import (
+	"educations-castle/services/search"
+	"educations-castle/types"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// priceBuckets are lower bounds of price facet buckets in minor units of currency,
+// each bucket ends where the next one starts
+var priceBuckets = []int64{0, 1000, 2500, 5000, 10000}
+
+// Rating facet counts activities rated at least 1 to maxRating
+const maxRating = 5
+
+// FacetActivities counts activities matching query and filter by category, price, rating, organizer, city
+// and month of upcoming session. Counts of each facet ignore filter of that facet only. Empty query counts
+// all filtered activities
+func (c *Castle) FacetActivities(query string, a types.ActivityFilterPayload, viewer types.Viewer) (*types.ActivityFacets, error) {
+	facets := &types.ActivityFacets{
+		Category:  []types.FacetCount{},
+		Price:     make([]types.PriceFacetCount, len(priceBuckets)),
+		Rating:    make([]types.RatingFacetCount, maxRating),
+		Organizer: []types.FacetCount{},
+		City:      []types.FacetCount{},
+		Month:     []types.FacetCount{},
+	}
+	for i, bound := range priceBuckets {
+		facets.Price[i].MinPrice = bound
+		if i+1 < len(priceBuckets) {
+			facets.Price[i].MaxPrice = &priceBuckets[i+1]
+		}
+	}
+	for i := range facets.Rating {
+		facets.Rating[i].MinRating = i + 1
+	}
+
+	// Search restricts activities to those found by search index
+	var ids []interface{}
+	if query != "" {
+		found := c.index.Search(search.KindActivity, search.ParseQuery(query))
+		if len(found) == 0 {
+			return facets, nil
+		}
+		for _, f := range found {
+			ids = append(ids, f.ID)
+		}
+	}
+
+	conditions := func(f types.ActivityFilterPayload) (string, []interface{}, error) {
+		conditions, params, err := c.filterConditions(f, viewer)
+		if err != nil || ids == nil {
+			return conditions, params, err
+		}
+		return "activity.id IN (?" + strings.Repeat(",?", len(ids)-1) + ") AND " + conditions,
+			append(append([]interface{}{}, ids...), params...), nil
+	}
+
+	var err error
+
+	f := a
+	f.Category = ""
+	facets.Category, err = c.countFacet("category.name",
+		" JOIN category ON category.id_Category = activity.category", nil, f, conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	f = a
+	f.Organizer = ""
+	facets.Organizer, err = c.countFacet("user.username", "", nil, f, conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	f = a
+	f.City = ""
+	facets.City, err = c.countFacet("location.city",
+		" JOIN location ON location.fk_Activityid = activity.id AND location.city <> ''", nil, f, conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Months of upcoming sessions inside session date range
+	f = a
+	f.Month = ""
+	facets.Month, err = c.countFacet("DATE_FORMAT(activitysession.startTime, '%Y-%m')", `
+		JOIN activitysession ON activitysession.fk_Activityid = activity.id AND activitysession.cancelled = 0
+			AND activitysession.startTime >= GREATEST(COALESCE(NULLIF(?, ''), '1970-01-01'), UTC_TIMESTAMP())
+			AND activitysession.startTime <= COALESCE(NULLIF(?, ''), '9999-12-31')`,
+		[]interface{}{a.StartDate, a.EndDate}, f, conditions)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(facets.Month, func(i, j int) bool { return facets.Month[i].Value < facets.Month[j].Value })
+
+	// INTERVAL returns index of bucket price falls into
+	f = a
+	f.MinPrice, f.MaxPrice = 0, 0
+	bounds := make([]string, len(priceBuckets)-1)
+	for i, bound := range priceBuckets[1:] {
+		bounds[i] = strconv.FormatInt(bound, 10)
+	}
+	prices, err := c.countFacet("INTERVAL("+lowestPrice+", "+strings.Join(bounds, ", ")+")", "", nil, f, conditions)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range prices {
+		i, err := strconv.Atoi(p.Value)
+		if err != nil || i < 0 || i >= len(facets.Price) {
+			return nil, fmt.Errorf("unexpected price bucket '%s'", p.Value)
+		}
+		facets.Price[i].Count = p.Count
+	}
+
+	f = a
+	f.MinRating, f.MaxRating = 0, 0
+	where, params, err := conditions(f)
+	if err != nil {
+		return nil, err
+	}
+	sums := make([]string, maxRating)
+	counts := make([]interface{}, maxRating)
+	for i := range facets.Rating {
+		sums[i] = fmt.Sprintf("COALESCE(SUM(activity.averageRating >= %d), 0)", i+1)
+		counts[i] = &facets.Rating[i].Count
+	}
+	err = c.db.QueryRow("SELECT "+strings.Join(sums, ", ")+filterJoins+" WHERE "+where, params...).Scan(counts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// countFacet counts distinct activities matching filter by value of expression, most common values first.
+// Joins add tables expression needs and take joinParams
+func (c *Castle) countFacet(value, joins string, joinParams []interface{}, f types.ActivityFilterPayload,
+	conditions func(types.ActivityFilterPayload) (string, []interface{}, error)) ([]types.FacetCount, error) {
+	where, params, err := conditions(f)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.db.Query(
+		"SELECT "+value+" AS facetValue, COUNT(DISTINCT activity.id) AS facetCount"+filterJoins+joins+
+			" WHERE "+where+" GROUP BY facetValue ORDER BY facetCount DESC, facetValue",
+		append(append([]interface{}{}, joinParams...), params...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []types.FacetCount{}
+
+	for rows.Next() {
+		var fc types.FacetCount
+		if err := rows.Scan(&fc.Value, &fc.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, fc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,37 @@
+package activity
+
+import (
+	"educations-castle/types"
+	"strings"
+	"testing"
+)
+
+func TestFilterConditions(t *testing.T) {
+	c := &Castle{}
+
+	t.Run("Should pass parameter for every placeholder", func(t *testing.T) {
+		filter := types.ActivityFilterPayload{City: "Kaunas", Month: "2025-01", StartDate: "2025-01-01 00:00:00"}
+		conditions, params, err := c.filterConditions(filter, types.Viewer{UserID: 3, Role: "organizer"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n := strings.Count(conditions, "?"); n != len(params) {
+			t.Errorf("expected %d params, got %d", n, len(params))
+		}
+		if !strings.Contains(conditions, "location.city = ?") {
+			t.Errorf("expected city condition in %s", conditions)
+		}
+	})
+
+	t.Run("Should match whole month of sessions", func(t *testing.T) {
+		_, params, err := c.filterConditions(types.ActivityFilterPayload{Month: "2024-12"}, types.Viewer{UserID: -1})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if params[len(params)-2] != "2024-12-01 00:00:00" || params[len(params)-1] != "2025-01-01 00:00:00" {
+			t.Errorf("unexpected month params %v", params)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package activity
+
+import (
+	"educations-castle/services/search"
+	"educations-castle/types"
+)
+
+// Activities are found by their own texts and by texts of their package and organizer
+const indexedActivities = `SELECT activity.id, activity.name, activity.description, activity.hidden, activity.verified,
+		package.name, COALESCE(package.description, ''), COALESCE(organizer.description, '')` + filterJoins
+
+// BuildSearchIndex loads all activities, packages and categories into search index, castle keeps it in sync afterwards
+func (c *Castle) BuildSearchIndex() error {
+	if err := c.indexActivities("1 = 1"); err != nil {
+		return err
+	}
+
+	rows, err := c.db.Query("SELECT id_Category, name FROM category")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		c.index.Put(search.Document{Kind: search.KindCategory, ID: id, Title: name, Fields: []search.Field{{Text: name, Weight: 1}}})
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	packages, err := c.ListPackages()
+	if err != nil {
+		return err
+	}
+	for _, p := range packages {
+		c.indexPackage(*p)
+	}
+
+	return nil
+}
+
+// indexActivities puts activities matching condition into search index
+func (c *Castle) indexActivities(condition string, params ...interface{}) error {
+	rows, err := c.db.Query(indexedActivities+" WHERE "+condition, params...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var hidden, verified bool
+		var name, description, packageName, packageDescription, organizerDescription string
+		err := rows.Scan(&id, &name, &description, &hidden, &verified, &packageName, &packageDescription, &organizerDescription)
+		if err != nil {
+			return err
+		}
+
+		// Matches in activity name weigh the most, matches in package and organizer the least.
+		// Activities outside of public catalog are searched with visibility checks, but never suggested
+		c.index.Put(search.Document{Kind: search.KindActivity, ID: id, Title: name, Hidden: hidden || !verified, Fields: []search.Field{
+			{Text: name, Weight: 3},
+			{Text: description, Weight: 1},
+			{Text: packageName + " " + packageDescription, Weight: 0.5},
+			{Text: organizerDescription, Weight: 0.5},
+		}})
+	}
+
+	return rows.Err()
+}
+
+// IndexActivity refreshes activity in search index after it was changed outside of castle, such as by moderation
+func (c *Castle) IndexActivity(id int) error {
+	return c.indexActivities("activity.id = ?", id)
+}
+
+func (c *Castle) indexPackage(p types.Package) {
+	c.index.Put(search.Document{Kind: search.KindPackage, ID: p.ID, Title: p.Name, Fields: []search.Field{
+		{Text: p.Name, Weight: 3},
+		{Text: p.Description, Weight: 1},
+	}})
+}
@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
@@ -17,29 +19,39 @@ import (
 type Handler struct {
 	activityCastle types.ActivityCastle
 	userCastle     types.UserCastle
+	imageCastle    types.ImageCastle
+	location       *time.Location
 }
 
-func NewHandler(activityCastle types.ActivityCastle, userCastle types.UserCastle) *Handler {
+func NewHandler(activityCastle types.ActivityCastle, userCastle types.UserCastle, imageCastle types.ImageCastle,
+	location *time.Location) *Handler {
 	return &Handler{
 		activityCastle: activityCastle,
-		userCastle:     userCastle}
+		userCastle:     userCastle,
+		imageCastle:    imageCastle,
+		location:       location}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/activities", h.handleListActivities).Methods(("GET"))
-	router.HandleFunc("/activities", h.handleListActivities).Methods(("GET"))
+	router.HandleFunc("/activities", auth.WithOptionalJWTAuth(h.handleListActivities, h.userCastle)).Methods(("GET"))
 
 	router.HandleFunc("/activities/create", auth.WithJWTAuth(h.handleCreateActivity, h.userCastle, "administrator", "organizer")).Methods("POST", "OPTIONS")
-	router.HandleFunc("/activities/{activityID:[0-9]+}", h.handleGetActivity).Methods(("GET"))
+	router.HandleFunc("/activities/{activityID:[0-9]+}", auth.WithOptionalJWTAuth(h.handleGetActivity, h.userCastle)).Methods(("GET"))
 	router.HandleFunc("/activities/update/{activityID:[0-9]+}", auth.WithJWTAuth(h.handleUpdateActivity, h.userCastle, "administrator", "organizer")).Methods("PUT", "OPTIONS")
 	router.HandleFunc("/activities/delete/{activityID:[0-9]+}", auth.WithJWTAuth(h.handleDeleteActivity, h.userCastle, "administrator", "organizer")).Methods("DELETE", "OPTIONS")
 
-	router.HandleFunc("/activities/filter", h.handleFilterActivities).Methods(("GET"))
+	router.HandleFunc("/activities/filter", auth.WithOptionalJWTAuth(h.handleFilterActivities, h.userCastle)).Methods(("GET"))
+	router.HandleFunc("/activities/search", auth.WithOptionalJWTAuth(h.handleSearchActivities, h.userCastle)).Methods("GET")
+	router.HandleFunc("/activities/facets", auth.WithOptionalJWTAuth(h.handleFacetActivities, h.userCastle)).Methods("GET")
+
+	router.HandleFunc("/activities/{activityID:[0-9]+}/price-tiers", auth.WithOptionalJWTAuth(h.handleGetPriceTiers, h.userCastle)).Methods("GET")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/price-tiers/update", auth.WithJWTAuth(h.handleUpdatePriceTiers, h.userCastle, "administrator", "organizer")).Methods("PUT", "OPTIONS")
 
 	router.HandleFunc("/packages", h.handleListPackages).Methods("GET")
 	router.HandleFunc("/packages/{packageID:[0-9]+}", h.handleGetPackage).Methods("GET")
+	router.HandleFunc("/packages/search", h.handleSearchPackages).Methods("GET")
 	router.HandleFunc("/organizer/{organizerID:[0-9]+}/packages", h.handleListPackagesByOrganizer).Methods("GET")
-	router.HandleFunc("/packages/{packageID:[0-9]+}/activities", h.handleListActivitiesInPackage).Methods("GET")
+	router.HandleFunc("/packages/{packageID:[0-9]+}/activities", auth.WithOptionalJWTAuth(h.handleListActivitiesInPackage, h.userCastle)).Methods("GET")
 	router.HandleFunc("/packages/create", auth.WithJWTAuth(h.handleCreatePackage, h.userCastle, "administrator", "organizer")).Methods("POST", "OPTIONS")
 	router.HandleFunc("/packages/update/{packageID:[0-9]+}", auth.WithJWTAuth(h.handleUpdatePackage, h.userCastle, "administrator", "organizer")).Methods("PUT", "OPTIONS")
 	router.HandleFunc("/packages/delete/{packageID:[0-9]+}", auth.WithJWTAuth(h.handleDeletePackage, h.userCastle, "administrator", "organizer")).Methods("DELETE", "OPTIONS")
@@ -48,19 +60,28 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 
 // ListActivities godoc
 // @Summary      List all activities
-// @Description  Returns list of all registered activities
+// @Description  Returns list of visible and verified activities. Organizers also get their own hidden and unverified activities, administrators get all of them
 // @Tags         activity
 // @Produce      json
 // @Success      200  {array}    types.Activity
 // @Failure      500  {object}   types.ErrorResponse "Internal server error"
 // @Router       /activities [get]
 func (h *Handler) handleListActivities(w http.ResponseWriter, r *http.Request) {
-	activities, err := h.activityCastle.ListActivities()
+	activities, err := h.activityCastle.ListActivities(auth.GetViewerFromContext(r.Context()))
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	if err := h.attachActivityImages(activities); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.attachPriceTiers(activities); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	// If no activities found, return an empty array
 	if len(activities) == 0 {
 		utils.WriteJSON(w, http.StatusOK, []types.Activity{})
@@ -73,7 +94,7 @@ func (h *Handler) handleListActivities(w http.ResponseWriter, r *http.Request) {
 
 // ListActivitiesInPackage godoc
 // @Summary      List activities in package
-// @Description  Returns a list of all activities within the specified package
+// @Description  Returns a list of activities within the specified package that are visible to the caller
 // @Tags         package
 // @Produce      json
 // @Param        packageID  query  int  true  "Package ID"
@@ -100,12 +121,21 @@ func (h *Handler) handleListActivitiesInPackage(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	activities, err := h.activityCastle.ListActivitiesInPackage(packageID)
+	activities, err := h.activityCastle.ListActivitiesInPackage(packageID, auth.GetViewerFromContext(r.Context()))
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	if err := h.attachActivityImages(activities); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.attachPriceTiers(activities); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	// If no activities found, return an empty array
 	if len(activities) == 0 {
 		utils.WriteJSON(w, http.StatusOK, []types.Activity{})
@@ -178,7 +208,7 @@ func (h *Handler) handleCreateActivity(w http.ResponseWriter, r *http.Request) {
 
 // GetActivity godoc
 // @Summary      Get activity by ID
-// @Description  Get activity data by ID from the database
+// @Description  Get activity data by ID from the database. Hidden and unverified activities are found only by their organizer and administrators
 // @Tags         activity
 // @Produce      json
 // @Param        activityID path int true "Activity ID"
@@ -201,7 +231,7 @@ func (h *Handler) handleGetActivity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	activity, err := h.activityCastle.GetActivityByID(activityID)
+	activity, err := h.activityCastle.GetVisibleActivityByID(activityID, auth.GetViewerFromContext(r.Context()))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity not found"))
@@ -211,12 +241,22 @@ func (h *Handler) handleGetActivity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.attachActivityImages([]*types.Activity{activity}); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.attachPriceTiers([]*types.Activity{activity}); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	utils.WriteJSON(w, http.StatusOK, activity)
 }
 
 // UpdateActivity godoc
 // @Summary      Update activity by ID
-// @Description  Update activity data by ID and specifying the new values
+// @Description  Update activity data by ID and specifying the new values. Approved activities return to moderation
+// @Description  queue when name, description, price or category changes, rejected ones on any change
 // @Tags         activity
 // @Produce      json
 // @Param        activityID path int true "Activity ID"
@@ -356,7 +396,9 @@ func (h *Handler) handleDeleteActivity(w http.ResponseWriter, r *http.Request) {
 
 // FilterActivities godoc
 // @Summary      Filter activities
-// @Description  Filter activities by category, rating, price, and hidden status
+// @Description  Filter activities by category, rating, price, city, session date range, month of upcoming session and hidden status. Hidden and unverified activities are returned only to their organizer and administrators
+// @Description  Prices minPrice and maxPrice are decimal amounts such as 15.50, with currency only activities priced in it are compared
+// @Description  Price of activity is its lowest price tier, including tiers of upcoming sessions, or base price when it has no tiers
 // @Tags         activity
 // @Produce      json
 // @Param        payload body types.ActivityFilterPayload true "Filter payload"
@@ -365,86 +407,305 @@ func (h *Handler) handleDeleteActivity(w http.ResponseWriter, r *http.Request) {
 // @Failure      500  {object}   types.ErrorResponse "Internal server error"
 // @Router       /activities/filter [get]
 func (h *Handler) handleFilterActivities(w http.ResponseWriter, r *http.Request) {
-	// Define the payload
-	var payload types.ActivityFilterPayload
+	payload, err := parseFilterPayload(r, h.location)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
 
-	// Get query parameters and populate the payload
-	payload.Name = r.URL.Query().Get("name")
+	// Call the FilterActivities method with the constructed payload
+	activities, err := h.activityCastle.FilterActivities(payload, auth.GetViewerFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 
-	// TODO: handle category as enum
-	// payload.Category = r.URL.Query().Get("Category")
-	payload.Category = ""
-	payload.Organizer = r.URL.Query().Get("Organizer")
+	if err := h.attachActivityImages(activities); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.attachPriceTiers(activities); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
 
-	// Convert string parameters to integers
-	var err error
-	if minPrice := r.URL.Query().Get("minPrice"); minPrice != "" {
-		payload.MinPrice, err = utils.ParseStringToFloat32(minPrice)
-		if err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid minPrice"))
-			return
-		}
+	// If no activities are found, return an empty list
+	if len(activities) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.Activity{})
+		return
 	}
-	if maxPrice := r.URL.Query().Get("maxPrice"); maxPrice != "" {
-		payload.MaxPrice, err = utils.ParseStringToFloat32(maxPrice)
-		if err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid maxPrice"))
-			return
-		}
+
+	// Return the activities as a JSON response
+	utils.WriteJSON(w, http.StatusOK, activities)
+}
+
+// SearchActivities godoc
+// @Summary      Search activities
+// @Description  Search over activity name and description, package name and description and organizer description, most relevant activities first
+// @Description  Letters with diacritics match their base letters, Lithuanian word endings are ignored and small typos are tolerated, last word of query also matches words starting with it
+// @Description  Matches in activity name weigh the most. Highlights contain HTML-escaped snippets of matched texts with matched words wrapped in <mark>
+// @Description  Search takes the same filters as activity filter, hidden and unverified activities are returned only to their organizer and administrators
+// @Tags         activity
+// @Produce      json
+// @Param        q          query  string  true   "Search query"
+// @Param        limit      query  int     false  "Number of hits, 20 by default and at most 100"
+// @Param        offset     query  int     false  "Number of hits to skip"
+// @Param        category   query  string  false  "Category" Enums(Education, Event, Service, Other)
+// @Param        minPrice   query  string  false  "Minimum price"
+// @Param        maxPrice   query  string  false  "Maximum price"
+// @Param        currency   query  string  false  "Currency of prices"
+// @Param        minRating  query  int     false  "Minimum rating"
+// @Param        maxRating  query  int     false  "Maximum rating"
+// @Param        city       query  string  false  "City of activity location"
+// @Param        month      query  string  false  "Month of upcoming session, such as 2025-01"
+// @Success      200  {object}   types.ActivitySearchResults
+// @Failure      400  {object}   types.ErrorResponse "Invalid query or filter"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/search [get]
+func (h *Handler) handleSearchActivities(w http.ResponseWriter, r *http.Request) {
+	query, limit, offset, err := parseSearchParams(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
 	}
-	if minRating := r.URL.Query().Get("minRating"); minRating != "" {
-		payload.MinRating, err = strconv.Atoi(minRating)
-		if err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid minRating"))
-			return
-		}
+
+	payload, err := parseFilterPayload(r, h.location)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
 	}
-	if maxRating := r.URL.Query().Get("maxRating"); maxRating != "" {
-		payload.MaxRating, err = strconv.Atoi(maxRating)
-		if err != nil {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid maxRating"))
-			return
+
+	hits, total, err := h.activityCastle.SearchActivities(query, payload, auth.GetViewerFromContext(r.Context()), limit, offset)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	activities := make([]*types.Activity, len(hits))
+	for i, hit := range hits {
+		activities[i] = hit.Activity
+	}
+	if err := h.attachActivityImages(activities); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.attachPriceTiers(activities); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no activities found, return an empty array
+	if len(hits) == 0 {
+		hits = []*types.ActivitySearchHit{}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.ActivitySearchResults{Query: query, Total: total, Hits: hits})
+}
+
+// FacetActivities godoc
+// @Summary      Count activities by facets
+// @Description  Counts activities by category, price bucket, rating, organizer, city and month of upcoming session, taking the same filters as activity filter and optional search query
+// @Description  Counts of each facet respect every active filter except the filter of that facet, so they show how many activities selecting another value would return
+// @Description  Price buckets are in minor units of currency, rating counts activities rated at least minRating and months are in UTC
+// @Tags         activity
+// @Produce      json
+// @Param        q          query  string  false  "Search query"
+// @Param        category   query  string  false  "Category" Enums(Education, Event, Service, Other)
+// @Param        minPrice   query  string  false  "Minimum price"
+// @Param        maxPrice   query  string  false  "Maximum price"
+// @Param        currency   query  string  false  "Currency of prices"
+// @Param        minRating  query  int     false  "Minimum rating"
+// @Param        maxRating  query  int     false  "Maximum rating"
+// @Param        Organizer  query  string  false  "Organizer username"
+// @Param        city       query  string  false  "City of activity location"
+// @Param        month      query  string  false  "Month of upcoming session, such as 2025-01"
+// @Param        startDate  query  string  false  "Earliest session start"
+// @Param        endDate    query  string  false  "Latest session start"
+// @Success      200  {object}   types.ActivityFacets
+// @Failure      400  {object}   types.ErrorResponse "Invalid query or filter"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/facets [get]
+func (h *Handler) handleFacetActivities(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len(query) > 255 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("search query must be at most 255 characters"))
+		return
+	}
+
+	payload, err := parseFilterPayload(r, h.location)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	facets, err := h.activityCastle.FacetActivities(query, payload, auth.GetViewerFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, facets)
+}
+
+// SearchPackages godoc
+// @Summary      Search packages
+// @Description  Search over package name and description, most relevant packages first. Query is matched the same way as in activity search
+// @Tags         package
+// @Produce      json
+// @Param        q       query  string  true   "Search query"
+// @Param        limit   query  int     false  "Number of hits, 20 by default and at most 100"
+// @Param        offset  query  int     false  "Number of hits to skip"
+// @Success      200  {object}   types.PackageSearchResults
+// @Failure      400  {object}   types.ErrorResponse "Invalid query"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /packages/search [get]
+func (h *Handler) handleSearchPackages(w http.ResponseWriter, r *http.Request) {
+	query, limit, offset, err := parseSearchParams(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	hits, total, err := h.activityCastle.SearchPackages(query, limit, offset)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	packages := make([]*types.Package, len(hits))
+	for i, hit := range hits {
+		packages[i] = hit.Package
+	}
+	if err := h.attachPackageImages(packages); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no packages found, return an empty array
+	if len(hits) == 0 {
+		hits = []*types.PackageSearchHit{}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.PackageSearchResults{Query: query, Total: total, Hits: hits})
+}
+
+// GetPriceTiers godoc
+// @Summary      Get price tiers of activity
+// @Description  Returns price table of activity, such as adult, child, student, senior and family prices. Sessions can replace it with their own tiers
+// @Tags         activity
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Success      200  {array}    types.PriceTier
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid activity ID"
+// @Failure      404  {object}   types.ErrorResponse "activity not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/price-tiers [get]
+func (h *Handler) handleGetPriceTiers(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	if _, err := h.activityCastle.GetVisibleActivityByID(activityID, auth.GetViewerFromContext(r.Context())); err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
 		}
+		return
+	}
+
+	tiers, err := h.activityCastle.ListPriceTiers([]int{activityID})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if len(tiers[activityID]) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.PriceTier{})
+		return
 	}
 
-	// New: Extract startDate and endDate query parameters
-	payload.StartDate = r.URL.Query().Get("startDate")
-	payload.EndDate = r.URL.Query().Get("endDate")
+	utils.WriteJSON(w, http.StatusOK, tiers[activityID])
+}
+
+// UpdatePriceTiers godoc
+// @Summary      Set price tiers of activity
+// @Description  Replaces price table of activity. Tiers have to be in currency of activity, empty list leaves only base price
+// @Tags         activity
+// @Accept       json
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        payload body types.PriceTiersPayload true "Price tiers"
+// @Success      200  {array}    types.PriceTier
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "activity not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/price-tiers/update [put]
+func (h *Handler) handleUpdatePriceTiers(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
 
-	// TODO: Validate the date format
-	// Optionally validate the date format
-	// Uncomment and implement if you have a utility function for date validation
-	// if payload.StartDate != "" && !utils.IsValidDate(payload.StartDate) {
-	// 	utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid startDate"))
-	// 	return
-	// }
-	// if payload.EndDate != "" && !utils.IsValidDate(payload.EndDate) {
-	// 	utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid endDate"))
-	// 	return
-	// }
+	var payload types.PriceTiersPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
 
-	// Validate the payload (if needed)
 	if err := utils.Validate.Struct(payload); err != nil {
 		errors := err.(validator.ValidationErrors)
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
 		return
 	}
 
-	// Call the FilterActivities method with the constructed payload
-	activities, err := h.activityCastle.FilterActivities(payload)
+	activity, err := h.activityCastle.GetActivityByID(activityID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	organizer, err := h.userCastle.GetOrganizerByActivityID(activityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity organizer not found"))
+		return
+	}
+	if !auth.CheckOwnership(r, organizer.ID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	tiers, err := NewPriceTiers(payload, activity.BasePrice.Currency)
 	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.activityCastle.SavePriceTiers(activityID, nil, tiers); err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	// If no activities are found, return an empty list
-	if len(activities) == 0 {
-		utils.WriteJSON(w, http.StatusOK, []types.Activity{})
+	saved, err := h.activityCastle.ListPriceTiers([]int{activityID})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	// Return the activities as a JSON response
-	utils.WriteJSON(w, http.StatusOK, activities)
+	if len(saved[activityID]) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.PriceTier{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, saved[activityID])
 }
 
 // ListPackages godoc
@@ -462,6 +723,11 @@ func (h *Handler) handleListPackages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.attachPackageImages(packages); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	// If no packages found, return an empty array
 	if len(packages) == 0 {
 		utils.WriteJSON(w, http.StatusOK, []types.Package{})
@@ -507,6 +773,11 @@ func (h *Handler) handleListPackagesByOrganizer(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if err := h.attachPackageImages(packages); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	// If no packages found, return an empty array
 	if len(packages) == 0 {
 		utils.WriteJSON(w, http.StatusOK, []types.Package{})
@@ -541,6 +812,11 @@ func (h *Handler) handleGetPackage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.attachPackageImages([]*types.Package{pkg}); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
 	utils.WriteJSON(w, http.StatusOK, pkg)
 }
 
@@ -728,3 +1004,164 @@ func (h *Handler) handleDeletePackage(w http.ResponseWriter, r *http.Request) {
 	// TODO: status NO content
 	utils.WriteJSON(w, http.StatusAccepted, fmt.Sprintf("package with id %d successfully deleted", packageID))
 }
+
+// attachActivityImages loads images of all activities with single query
+func (h *Handler) attachActivityImages(activities []*types.Activity) error {
+	ids := make([]int, 0, len(activities))
+	for _, a := range activities {
+		ids = append(ids, a.ID)
+	}
+
+	images, err := h.imageCastle.ListImagesForEntities(types.EntityTypeActivity, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range activities {
+		a.Images = images[a.ID]
+		if a.Images == nil {
+			a.Images = []*types.AttachedImage{}
+		}
+	}
+
+	return nil
+}
+
+// attachPriceTiers loads price tables of all activities with single query
+func (h *Handler) attachPriceTiers(activities []*types.Activity) error {
+	ids := make([]int, 0, len(activities))
+	for _, a := range activities {
+		ids = append(ids, a.ID)
+	}
+
+	tiers, err := h.activityCastle.ListPriceTiers(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range activities {
+		a.PriceTiers = tiers[a.ID]
+		if a.PriceTiers == nil {
+			a.PriceTiers = []*types.PriceTier{}
+		}
+	}
+
+	return nil
+}
+
+// attachPackageImages loads images of all packages with single query
+func (h *Handler) attachPackageImages(packages []*types.Package) error {
+	ids := make([]int, 0, len(packages))
+	for _, p := range packages {
+		ids = append(ids, p.ID)
+	}
+
+	images, err := h.imageCastle.ListImagesForEntities(types.EntityTypePackage, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range packages {
+		p.Images = images[p.ID]
+		if p.Images == nil {
+			p.Images = []*types.AttachedImage{}
+		}
+	}
+
+	return nil
+}
+
+// parseFilterPayload reads activity filter from query parameters and validates it
+func parseFilterPayload(r *http.Request, loc *time.Location) (types.ActivityFilterPayload, error) {
+	// Define the payload
+	var payload types.ActivityFilterPayload
+	var err error
+
+	// Get query parameters and populate the payload
+	payload.Name = r.URL.Query().Get("name")
+	payload.Category = r.URL.Query().Get("category")
+	payload.Organizer = r.URL.Query().Get("Organizer")
+	payload.City = r.URL.Query().Get("city")
+	payload.Month = r.URL.Query().Get("month")
+
+	// Prices are decimal amounts such as 15.50, converted into minor units of currency
+	payload.Currency = strings.ToUpper(r.URL.Query().Get("currency"))
+	if minPrice := r.URL.Query().Get("minPrice"); minPrice != "" {
+		price, err := types.ParseMoney(minPrice, payload.Currency)
+		if err != nil {
+			return payload, fmt.Errorf("invalid minPrice")
+		}
+		payload.MinPrice = price.Amount
+	}
+	if maxPrice := r.URL.Query().Get("maxPrice"); maxPrice != "" {
+		price, err := types.ParseMoney(maxPrice, payload.Currency)
+		if err != nil {
+			return payload, fmt.Errorf("invalid maxPrice")
+		}
+		payload.MaxPrice = price.Amount
+	}
+	if minRating := r.URL.Query().Get("minRating"); minRating != "" {
+		payload.MinRating, err = strconv.Atoi(minRating)
+		if err != nil {
+			return payload, fmt.Errorf("invalid minRating")
+		}
+	}
+	if maxRating := r.URL.Query().Get("maxRating"); maxRating != "" {
+		payload.MaxRating, err = strconv.Atoi(maxRating)
+		if err != nil {
+			return payload, fmt.Errorf("invalid maxRating")
+		}
+	}
+
+	// Session date range, dates without time include the whole day in schedule timezone
+	if startDate := r.URL.Query().Get("startDate"); startDate != "" {
+		t, err := utils.ParseTimeParam(startDate, loc, false)
+		if err != nil {
+			return payload, fmt.Errorf("invalid startDate")
+		}
+		payload.StartDate = t.UTC().Format(time.DateTime)
+	}
+	if endDate := r.URL.Query().Get("endDate"); endDate != "" {
+		t, err := utils.ParseTimeParam(endDate, loc, true)
+		if err != nil {
+			return payload, fmt.Errorf("invalid endDate")
+		}
+		payload.EndDate = t.UTC().Format(time.DateTime)
+	}
+
+	// Validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		return payload, fmt.Errorf("invalid payload %v", errors)
+	}
+
+	return payload, nil
+}
+
+// parseSearchParams reads search query and page of hits from query parameters
+func parseSearchParams(r *http.Request) (string, int, int, error) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" || len(query) > 255 {
+		return "", 0, 0, fmt.Errorf("search query must be between 1 and 255 characters")
+	}
+
+	limit := defaultSearchLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			return "", 0, 0, fmt.Errorf("limit must be between 1 and %d", maxSearchLimit)
+		}
+		limit = n
+	}
+
+	offset := 0
+	if value := r.URL.Query().Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return "", 0, 0, fmt.Errorf("invalid offset")
+		}
+		offset = n
+	}
+
+	return query, limit, offset, nil
+}
@@ -0,0 +1,123 @@
+package activity
+
+import (
+	"educations-castle/services/search"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// snippetLength is the number of characters of text shown around matched words
+const snippetLength = 160
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type span struct {
+	start, end int
+}
+
+// words returns positions of letter and digit runs in text
+func words(text []rune) []span {
+	var spans []span
+
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+
+	return spans
+}
+
+// highlight returns HTML-escaped snippet of text around its first matched word, with words matching query
+// wrapped in <mark>. Empty string is returned when nothing in text matches
+func highlight(text string, q search.Query, width int) string {
+	runes := []rune(text)
+
+	var marked []span
+	for _, w := range words(runes) {
+		if q.Matches(string(runes[w.start:w.end])) {
+			marked = append(marked, w)
+		}
+	}
+	if len(marked) == 0 {
+		return ""
+	}
+
+	// Snippet starts a little before the first match and breaks text at spaces
+	start, end := 0, len(runes)
+	if len(runes) > width {
+		start = max(0, marked[0].start-width/4)
+		end = min(len(runes), start+width)
+		start = max(0, end-width)
+		if start > 0 {
+			for i := start; i < marked[0].start; i++ {
+				if runes[i] == ' ' {
+					start = i + 1
+					break
+				}
+			}
+		}
+		if end < len(runes) {
+			for i := end - 1; i >= marked[0].end; i-- {
+				if runes[i] == ' ' {
+					end = i
+					break
+				}
+			}
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	position := start
+	for _, m := range marked {
+		if m.start < start || m.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[position:m.start])))
+		b.WriteString("<mark>" + html.EscapeString(string(runes[m.start:m.end])) + "</mark>")
+		position = m.end
+	}
+	b.WriteString(html.EscapeString(string(runes[position:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
+
+// highlights replaces texts of hit with their snippets, dropping texts without matches
+func highlights(texts map[string]string, q search.Query) map[string]string {
+	snippets := map[string]string{}
+	for field, text := range texts {
+		if snippet := highlight(text, q, snippetLength); snippet != "" {
+			snippets[field] = snippet
+		}
+	}
+
+	return snippets
+}
+
+// page returns hits between offset and offset+limit
+func page[T any](hits []T, limit, offset int) []T {
+	if offset >= len(hits) {
+		return nil
+	}
+
+	return hits[offset:min(offset+limit, len(hits))]
+}
@@ -0,0 +1,52 @@
+package activity
+
+import (
+	"educations-castle/services/search"
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	q := search.ParseQuery("gintaras")
+
+	t.Run("Should mark words matching query", func(t *testing.T) {
+		snippet := highlight("Gintaro <istorija> ir gintarą", q, snippetLength)
+		if snippet != "<mark>Gintaro</mark> &lt;istorija&gt; ir <mark>gintarą</mark>" {
+			t.Errorf("unexpected snippet %q", snippet)
+		}
+	})
+
+	t.Run("Should return nothing without matches", func(t *testing.T) {
+		if snippet := highlight("Baltijos jūra", q, snippetLength); snippet != "" {
+			t.Errorf("unexpected snippet %q", snippet)
+		}
+	})
+
+	t.Run("Should cut long text around first match", func(t *testing.T) {
+		text := strings.Repeat("jūra ", 50) + "Gintaro muziejus " + strings.Repeat("ąžuolas ", 50)
+		snippet := highlight(text, q, 60)
+
+		if !strings.HasPrefix(snippet, "…jūra ") || !strings.HasSuffix(snippet, "ąžuolas…") {
+			t.Errorf("unexpected snippet %q", snippet)
+		}
+		if !strings.Contains(snippet, "<mark>Gintaro</mark> muziejus") {
+			t.Errorf("expected match in snippet %q", snippet)
+		}
+		if n := len([]rune(strings.NewReplacer("<mark>", "", "</mark>", "").Replace(snippet))); n > 62 {
+			t.Errorf("snippet has %d characters", n)
+		}
+	})
+}
+
+func TestPage(t *testing.T) {
+	hits := []int{1, 2, 3, 4, 5}
+	if p := page(hits, 2, 1); len(p) != 2 || p[0] != 2 {
+		t.Errorf("unexpected page %v", p)
+	}
+	if p := page(hits, 10, 3); len(p) != 2 || p[1] != 5 {
+		t.Errorf("unexpected page %v", p)
+	}
+	if p := page(hits, 2, 5); len(p) != 0 {
+		t.Errorf("unexpected page %v", p)
+	}
+}
@@ -0,0 +1,45 @@
+package activity
+
+import (
+	"educations-castle/types"
+	"fmt"
+	"strings"
+)
+
+// NewPriceTiers builds price tiers from payload. Tiers replace base price of activity, so they have to be
+// in its currency, and their names have to be unique
+func NewPriceTiers(payload types.PriceTiersPayload, currency string) ([]types.PriceTier, error) {
+	tiers := []types.PriceTier{}
+	seen := make(map[string]bool)
+
+	for _, t := range payload.Tiers {
+		name := strings.TrimSpace(t.Name)
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("duplicate price tier %s", name)
+		}
+		seen[strings.ToLower(name)] = true
+
+		if t.Price.Currency != currency {
+			return nil, fmt.Errorf("price of tier %s has to be in %s like activity price", name, currency)
+		}
+		if t.MinAge != nil && t.MaxAge != nil && *t.MinAge > *t.MaxAge {
+			return nil, fmt.Errorf("minimum age of tier %s is above its maximum age", name)
+		}
+
+		seats := t.Seats
+		if seats == 0 {
+			seats = 1
+		}
+
+		tiers = append(tiers, types.PriceTier{
+			Name:        name,
+			Price:       t.Price,
+			Seats:       seats,
+			MinAge:      t.MinAge,
+			MaxAge:      t.MaxAge,
+			Eligibility: t.Eligibility,
+		})
+	}
+
+	return tiers, nil
+}
@@ -0,0 +1,37 @@
+package activity
+
+import (
+	"educations-castle/types"
+	"testing"
+)
+
+func TestNewPriceTiers(t *testing.T) {
+	adult := types.PriceTierPayload{Name: "Adult", Price: types.Money{Amount: 1000, Currency: "EUR"}}
+	family := types.PriceTierPayload{Name: " Family ", Price: types.Money{Amount: 3000, Currency: "EUR"}, Seats: 4}
+
+	t.Run("Should default seats to one", func(t *testing.T) {
+		tiers, err := NewPriceTiers(types.PriceTiersPayload{Tiers: []types.PriceTierPayload{adult, family}}, "EUR")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(tiers) != 2 || tiers[0].Seats != 1 || tiers[1].Seats != 4 || tiers[1].Name != "Family" {
+			t.Errorf("unexpected tiers %+v", tiers)
+		}
+	})
+
+	t.Run("Should reject invalid tiers", func(t *testing.T) {
+		minAge, maxAge := 18, 7
+		tests := map[string][]types.PriceTierPayload{
+			"duplicate name": {adult, {Name: "adult", Price: adult.Price}},
+			"other currency": {{Name: "Adult", Price: types.Money{Amount: 1000, Currency: "USD"}}},
+			"ages reversed":  {{Name: "Child", Price: adult.Price, MinAge: &minAge, MaxAge: &maxAge}},
+		}
+
+		for name, payload := range tests {
+			if _, err := NewPriceTiers(types.PriceTiersPayload{Tiers: payload}, "EUR"); err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+	})
+}
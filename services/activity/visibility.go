@@ -0,0 +1,37 @@
+package activity
+
+import "educations-castle/types"
+
+// Public catalog shows only activities which are not hidden and passed moderation.
+// Organizers additionally see their own hidden and draft activities, administrators see everything.
+
+// visibilityClause returns condition limiting activities to those viewer can see,
+// query has to join package table for ownership check
+func visibilityClause(viewer types.Viewer) (string, []interface{}) {
+	if viewer.Role == "administrator" {
+		return "TRUE", nil
+	}
+
+	if viewer.Role == "organizer" {
+		return "((activity.hidden = 0 AND activity.verified = 1) OR package.fk_Organizerid = ?)", []interface{}{viewer.UserID}
+	}
+
+	return "(activity.hidden = 0 AND activity.verified = 1)", nil
+}
+
+// visibleActivitiesQuery builds query selecting activities visible to viewer,
+// optionally narrowed by additional condition and its parameters
+func visibleActivitiesQuery(viewer types.Viewer, condition string, params ...interface{}) (string, []interface{}) {
+	clause, visibilityParams := visibilityClause(viewer)
+
+	query := `
+		SELECT activity.*
+		FROM activity
+		JOIN package ON activity.fk_Packageid = package.id
+		WHERE ` + clause
+	if condition != "" {
+		query += " AND " + condition
+	}
+
+	return query, append(visibilityParams, params...)
+}
@@ -0,0 +1,202 @@
+package activity
+
+import (
+	"database/sql"
+	"educations-castle/configs"
+	"educations-castle/services/auth"
+	"educations-castle/types"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	guest          = types.Viewer{UserID: -1}
+	owner          = types.Viewer{UserID: 3, Role: "organizer"}
+	otherOrganizer = types.Viewer{UserID: 4, Role: "organizer"}
+	administrator  = types.Viewer{UserID: 1, Role: "administrator"}
+)
+
+func TestVisibleActivitiesQuery(t *testing.T) {
+	t.Run("Should show guests only visible and verified activities", func(t *testing.T) {
+		query, params := visibleActivitiesQuery(guest, "activity.id = ?", 5)
+
+		if !strings.Contains(query, "activity.hidden = 0 AND activity.verified = 1") || strings.Contains(query, "fk_Organizerid") {
+			t.Errorf("unexpected guest query %s", query)
+		}
+		if len(params) != 1 || params[0] != 5 {
+			t.Errorf("unexpected params %v", params)
+		}
+	})
+
+	t.Run("Should show organizer their own activities", func(t *testing.T) {
+		query, params := visibleActivitiesQuery(owner, "activity.id = ?", 5)
+
+		if !strings.Contains(query, "OR package.fk_Organizerid = ?") {
+			t.Errorf("unexpected organizer query %s", query)
+		}
+		// Visibility parameters come first because clause precedes condition
+		if len(params) != 2 || params[0] != 3 || params[1] != 5 {
+			t.Errorf("unexpected params %v", params)
+		}
+	})
+
+	t.Run("Should show administrators everything", func(t *testing.T) {
+		query, params := visibleActivitiesQuery(administrator, "")
+
+		if strings.Contains(query, "hidden") || len(params) != 0 {
+			t.Errorf("unexpected administrator query %s %v", query, params)
+		}
+	})
+
+	t.Run("Should pair every placeholder with parameter", func(t *testing.T) {
+		for _, viewer := range []types.Viewer{guest, owner, administrator} {
+			query, params := visibleActivitiesQuery(viewer, "activity.id = ? AND activity.fk_Packageid = ?", 5, 6)
+			assertPlaceholders(t, viewer, query, params)
+
+			if params[len(params)-2] != 5 || params[len(params)-1] != 6 {
+				t.Errorf("%v: expected condition parameters last, got %v", viewer, params)
+			}
+		}
+	})
+}
+
+func TestFilterConditionsVisibility(t *testing.T) {
+	c := &Castle{}
+	filter := types.ActivityFilterPayload{StartDate: "2025-01-01", City: "Kaunas", Month: "2025-02"}
+
+	for _, viewer := range []types.Viewer{guest, owner, administrator} {
+		conditions, params, err := c.filterConditions(filter, viewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertPlaceholders(t, viewer, conditions, params)
+
+		// Organizer ID has to land on ownership placeholder, which is the last one
+		if viewer == owner && params[len(params)-1] != owner.UserID {
+			t.Errorf("expected organizer ID as last parameter, got %v", params)
+		}
+	}
+}
+
+func TestVisibilityOfActivityHandler(t *testing.T) {
+	configs.Envs.JWTSecret = "secret"
+	activityCastle := &mockActivityCastle{activities: map[int]mockActivity{
+		1: {hidden: false, verified: true, ownerID: 3},
+		2: {hidden: true, verified: true, ownerID: 3},
+		3: {hidden: false, verified: false, ownerID: 3},
+	}}
+	userCastle := &mockUserCastle{}
+	handler := NewHandler(activityCastle, userCastle, &mockImageCastle{}, time.UTC)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/activities/{activityID:[0-9]+}", auth.WithOptionalJWTAuth(handler.handleGetActivity, userCastle))
+
+	tests := []struct {
+		name     string
+		viewer   types.Viewer
+		token    string
+		expected map[int]int
+	}{
+		{"guest", guest, "", map[int]int{1: http.StatusOK, 2: http.StatusNotFound, 3: http.StatusNotFound}},
+		{"invalid token", guest, "invalid", map[int]int{1: http.StatusOK, 2: http.StatusNotFound, 3: http.StatusNotFound}},
+		{"owner", owner, token(t, owner), map[int]int{1: http.StatusOK, 2: http.StatusOK, 3: http.StatusOK}},
+		{"other organizer", otherOrganizer, token(t, otherOrganizer), map[int]int{1: http.StatusOK, 2: http.StatusNotFound, 3: http.StatusNotFound}},
+		{"administrator", administrator, token(t, administrator), map[int]int{1: http.StatusOK, 2: http.StatusOK, 3: http.StatusOK}},
+	}
+
+	for _, test := range tests {
+		t.Run("Should apply visibility rules for "+test.name, func(t *testing.T) {
+			for activityID, expected := range test.expected {
+				req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/activities/%d", activityID), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if test.token != "" {
+					req.Header.Set("Authorization", test.token)
+				}
+
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, req)
+
+				if rr.Code != expected {
+					t.Errorf("activity %d: expected status code %d, got %d", activityID, expected, rr.Code)
+				}
+				if activityCastle.viewer != test.viewer {
+					t.Errorf("expected castle to get viewer %v, got %v", test.viewer, activityCastle.viewer)
+				}
+			}
+		})
+	}
+}
+
+func assertPlaceholders(t *testing.T, viewer types.Viewer, query string, params []interface{}) {
+	t.Helper()
+	if placeholders := strings.Count(query, "?"); placeholders != len(params) {
+		t.Errorf("%v: %d placeholders but %d parameters", viewer, placeholders, len(params))
+	}
+}
+
+func token(t *testing.T, viewer types.Viewer) string {
+	t.Helper()
+	token, err := auth.CreateJWT([]byte(configs.Envs.JWTSecret), viewer.UserID, viewer.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+type mockActivity struct {
+	hidden   bool
+	verified bool
+	ownerID  int
+}
+
+// mockActivityCastle applies the same rules as visibilityClause to in-memory activities
+type mockActivityCastle struct {
+	types.ActivityCastle
+	activities map[int]mockActivity
+	viewer     types.Viewer
+}
+
+func (m *mockActivityCastle) GetVisibleActivityByID(id int, viewer types.Viewer) (*types.Activity, error) {
+	m.viewer = viewer
+	a, ok := m.activities[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	visible := (!a.hidden && a.verified) ||
+		viewer.Role == "administrator" ||
+		(viewer.Role == "organizer" && viewer.UserID == a.ownerID)
+	if !visible {
+		return nil, sql.ErrNoRows
+	}
+
+	return &types.Activity{ID: id, Hidden: a.hidden, Verified: a.verified}, nil
+}
+
+func (m *mockActivityCastle) ListPriceTiers(activityIDs []int) (map[int][]*types.PriceTier, error) {
+	return map[int][]*types.PriceTier{}, nil
+}
+
+type mockUserCastle struct {
+	types.UserCastle
+}
+
+func (m *mockUserCastle) GetUserByID(id int) (*types.User, error) {
+	return &types.User{ID: id}, nil
+}
+
+type mockImageCastle struct {
+	types.ImageCastle
+}
+
+func (m *mockImageCastle) ListImagesForEntities(entityType string, entityIDs []int) (map[int][]*types.AttachedImage, error) {
+	return map[int][]*types.AttachedImage{}, nil
+}
@@ -66,6 +66,42 @@ func WithJWTAuth(handlerFunc http.HandlerFunc, castle types.UserCastle, required
 	}
 }
 
+// WithOptionalJWTAuth adds user ID and role to context when valid token is provided,
+// requests without token or with invalid one continue as guests
+func WithOptionalJWTAuth(handlerFunc http.HandlerFunc, castle types.UserCastle) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenString := utils.GetTokenFromRequest(r)
+		if tokenString == "" {
+			handlerFunc(w, r)
+			return
+		}
+
+		token, err := ValidateJWT(tokenString)
+		if err != nil || !token.Valid {
+			handlerFunc(w, r)
+			return
+		}
+
+		claims := token.Claims.(jwt.MapClaims)
+		userIDClaim, _ := claims["userID"].(string)
+		role, ok := claims["role"].(string)
+		userID, err := strconv.Atoi(userIDClaim)
+		if err != nil || !ok {
+			handlerFunc(w, r)
+			return
+		}
+
+		if user, err := castle.GetUserByID(userID); err != nil || user == nil {
+			handlerFunc(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), UserKey, userID)
+		ctx = context.WithValue(ctx, RoleKey, role)
+		handlerFunc(w, r.WithContext(ctx))
+	}
+}
+
 func CreateJWT(secret []byte, userID int, role string) (string, error) {
 	expiration := time.Second * time.Duration(configs.Envs.JWTExpirationInSeconds)
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
@@ -121,6 +157,16 @@ func GetUserIDFromContext(ctx context.Context) int {
 	return userID
 }
 
+// GetViewerFromContext returns user making the request, guest when request is not authenticated
+func GetViewerFromContext(ctx context.Context) types.Viewer {
+	role, _ := ctx.Value(RoleKey).(string)
+
+	return types.Viewer{
+		UserID: GetUserIDFromContext(ctx),
+		Role:   role,
+	}
+}
+
 func hasRequiredRole(userRole string, requiredRoles []string) bool {
 	// If no roles are required, all valid users should pass
 	if len(requiredRoles) == 0 {
@@ -0,0 +1,1373 @@
+package booking
+
+import (
+	"database/sql"
+	"educations-castle/services/discount"
+	"educations-castle/services/voucher"
+	"educations-castle/types"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSessionFull       = errors.New("not enough free seats in session")
+	ErrAlreadyBooked     = errors.New("session is already booked by user")
+	ErrInvalidTransition = errors.New("booking status can't be changed")
+	ErrSeatsAvailable    = errors.New("session has free seats, book them instead")
+	ErrAlreadyWaitlisted = errors.New("user is already on waitlist of session")
+	ErrOfferExpired      = errors.New("waitlist offer has expired")
+	ErrSessionCancelled  = errors.New("session is cancelled")
+	ErrAlreadyCheckedIn  = errors.New("booking is already checked in")
+)
+
+// Bookings are selected together with group they were made for, cancellation, discount and gift voucher, if any
+const selectBookings = `SELECT booking.*, bookinggroup.name, bookinggroup.school, bookinggroup.ageBand,
+	bookinggroup.students, bookinggroup.adults, bookingcancellation.fk_Bookingid, bookingcancellation.fk_Userid,
+	bookingcancellation.reason, bookingcancellation.refundPercent, bookingcancellation.refundAmount, bookingcancellation.refundStatus,
+	bookingcancellation.createdAt,
+	discountredemption.fk_Discountid, discountredemption.amount, discount.code, discount.name,
+	voucherredemption.fk_GiftVoucherid, voucherredemption.amount, giftvoucher.code
+	FROM booking
+	LEFT JOIN bookinggroup ON bookinggroup.fk_Bookingid = booking.id
+	LEFT JOIN bookingcancellation ON bookingcancellation.fk_Bookingid = booking.id
+	LEFT JOIN discountredemption ON discountredemption.fk_Bookingid = booking.id
+	LEFT JOIN discount ON discount.id = discountredemption.fk_Discountid
+	LEFT JOIN voucherredemption ON voucherredemption.fk_Bookingid = booking.id
+	LEFT JOIN giftvoucher ON giftvoucher.id = voucherredemption.fk_GiftVoucherid`
+
+type Castle struct {
+	db *sql.DB
+}
+
+func NewCastle(db *sql.DB) *Castle {
+	return &Castle{db: db}
+}
+
+func scanRowIntoBooking(rows *sql.Rows) (*types.Booking, error) {
+	b := new(types.Booking)
+	var groupName *string
+	var students, adults *int
+	group := new(types.BookingGroup)
+	var cancelledBooking, refundPercent *int
+	var refundAmount *int64
+	var refundStatus *string
+	var cancelledAt *time.Time
+	cancellation := new(types.BookingCancellation)
+	var discountID *int
+	var discountAmount *int64
+	var discountName *string
+	bookingDiscount := new(types.BookingDiscount)
+	var voucherID *int
+	var voucherAmount *int64
+	var voucherCode *string
+
+	err := rows.Scan(
+		&b.ID,
+		&b.FkActivitySessionID,
+		&b.FkUserID,
+		&b.Seats,
+		&b.Status,
+		&b.CreatedAt,
+		&b.UpdatedAt,
+		&b.Amount.Amount,
+		&b.Amount.Currency,
+		&b.ExpiresAt,
+		&groupName,
+		&group.School,
+		&group.AgeBand,
+		&students,
+		&adults,
+		&cancelledBooking,
+		&cancellation.FkUserID,
+		&cancellation.Reason,
+		&refundPercent,
+		&refundAmount,
+		&refundStatus,
+		&cancelledAt,
+		&discountID,
+		&discountAmount,
+		&bookingDiscount.Code,
+		&discountName,
+		&voucherID,
+		&voucherAmount,
+		&voucherCode,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if groupName != nil {
+		group.Name = *groupName
+		group.Students = *students
+		group.Adults = *adults
+		b.Group = group
+	}
+	if cancelledBooking != nil {
+		cancellation.RefundPercent = *refundPercent
+		cancellation.RefundAmount = types.Money{Amount: *refundAmount, Currency: b.Amount.Currency}
+		cancellation.RefundStatus = *refundStatus
+		cancellation.CreatedAt = *cancelledAt
+		b.Cancellation = cancellation
+	}
+	b.OriginalAmount = b.Amount
+	if discountID != nil {
+		bookingDiscount.FkDiscountID = *discountID
+		bookingDiscount.Name = *discountName
+		bookingDiscount.Amount = types.Money{Amount: *discountAmount, Currency: b.Amount.Currency}
+		b.Discount = bookingDiscount
+		b.OriginalAmount = b.OriginalAmount.Add(bookingDiscount.Amount)
+	}
+	if voucherID != nil {
+		b.Voucher = &types.BookingVoucher{
+			FkGiftVoucherID: *voucherID,
+			Code:            *voucherCode,
+			Amount:          types.Money{Amount: *voucherAmount, Currency: b.Amount.Currency},
+		}
+		b.OriginalAmount = b.OriginalAmount.Add(b.Voucher.Amount)
+	}
+
+	return b, nil
+}
+
+// CreateBooking reserves seats while session row is locked, so concurrent bookings can't exceed its capacity
+func (c *Castle) CreateBooking(b types.Booking) (int64, error) {
+	return c.createBooking(b, nil)
+}
+
+// CreateGroupBooking reserves seats for students and adults of group. Unlike individual bookings,
+// one user can book several groups into the same session
+func (c *Castle) CreateGroupBooking(b types.Booking, g types.BookingGroup) (int64, error) {
+	b.Seats = g.Students + g.Adults
+	return c.createBooking(b, &g)
+}
+
+func (c *Castle) createBooking(b types.Booking, group *types.BookingGroup) (int64, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	session, err := lockSession(tx, b.FkActivitySessionID)
+	if err != nil {
+		return 0, err
+	}
+	if session.cancelled {
+		return 0, ErrSessionCancelled
+	}
+
+	if group == nil {
+		// Group amount comes from group invoice and amount of price tiers from their quote,
+		// other bookings pay the session price for each seat
+		if len(b.Tiers) == 0 {
+			b.Amount = session.price.Mul(b.Seats)
+		}
+
+		booked, err := hasActiveBooking(tx, b.FkActivitySessionID, b.FkUserID)
+		if err != nil {
+			return 0, err
+		}
+		if booked {
+			return 0, ErrAlreadyBooked
+		}
+	}
+	if b.Seats > session.free {
+		return 0, ErrSessionFull
+	}
+
+	if b.Discount != nil {
+		if err := applyDiscount(tx, &b); err != nil {
+			return 0, err
+		}
+	}
+	if b.Voucher != nil {
+		if err := applyVoucher(tx, &b); err != nil {
+			return 0, err
+		}
+	}
+
+	// Only bookings waiting for payment hold seats until deadline, which can't be later than session start
+	if b.Status != types.BookingPending || b.Amount.Amount <= 0 || b.ExpiresAt == nil {
+		b.ExpiresAt = nil
+	} else if b.ExpiresAt.After(session.startTime) {
+		b.ExpiresAt = &session.startTime
+	}
+
+	bookingID, err := insertBooking(tx, b)
+	if err != nil {
+		return 0, err
+	}
+
+	if b.Discount != nil {
+		_, err = tx.Exec("INSERT INTO discountredemption (fk_Bookingid, fk_Discountid, fk_Userid, amount) VALUES (?,?,?,?)",
+			bookingID, b.Discount.FkDiscountID, b.FkUserID, b.Discount.Amount.Amount)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if b.Voucher != nil {
+		_, err = tx.Exec("INSERT INTO voucherredemption (fk_Bookingid, fk_GiftVoucherid, amount) VALUES (?,?,?)",
+			bookingID, b.Voucher.FkGiftVoucherID, b.Voucher.Amount.Amount)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, t := range b.Tiers {
+		_, err = tx.Exec(
+			"INSERT INTO bookingtier (fk_Bookingid, fk_PriceTierid, name, quantity, seats, unitAmount) VALUES (?,?,?,?,?,?)",
+			bookingID, t.FkPriceTierID, t.Name, t.Quantity, t.Seats, t.UnitPrice.Amount)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if group != nil {
+		_, err = tx.Exec(
+			"INSERT INTO bookinggroup (fk_Bookingid, name, school, ageBand, students, adults) VALUES (?,?,?,?,?,?)",
+			bookingID, group.Name, group.School, group.AgeBand, group.Students, group.Adults)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return bookingID, nil
+}
+
+// lockedSession is state of session read while its row is locked
+type lockedSession struct {
+	free      int
+	startTime time.Time
+	price     types.Money
+	cancelled bool
+}
+
+// lockSession locks session row until transaction ends and returns its free seats, start time and seat price
+func lockSession(tx *sql.Tx, sessionID int) (*lockedSession, error) {
+	s := new(lockedSession)
+	var capacity int
+	err := tx.QueryRow(
+		`SELECT activitysession.capacity, activitysession.startTime, COALESCE(activitysession.price, activity.basePrice),
+			COALESCE(activitysession.currency, activity.currency), activitysession.cancelled
+		FROM activitysession
+		JOIN activity ON activity.id = activitysession.fk_Activityid
+		WHERE activitysession.id = ? FOR UPDATE`, sessionID).Scan(&capacity, &s.startTime, &s.price.Amount, &s.price.Currency, &s.cancelled)
+	if err != nil {
+		return nil, err
+	}
+
+	var bookedSeats int
+	err = tx.QueryRow(
+		"SELECT COALESCE(SUM(seats), 0) FROM booking WHERE fk_ActivitySessionid = ? AND status <> ?",
+		sessionID, types.BookingCancelled).Scan(&bookedSeats)
+	if err != nil {
+		return nil, err
+	}
+	s.free = capacity - bookedSeats
+
+	return s, nil
+}
+
+// applyDiscount locks discount of booking until transaction ends and takes it off booking amount.
+// discount.ErrUsedUp is returned when discount reached its usage limits meanwhile
+func applyDiscount(tx *sql.Tx, b *types.Booking) error {
+	d := &types.Discount{ID: b.Discount.FkDiscountID}
+	var amount *int64
+	err := tx.QueryRow("SELECT kind, percent, amount, maxRedemptions, maxPerUser FROM discount WHERE id = ? FOR UPDATE", d.ID).
+		Scan(&d.Kind, &d.Percent, &amount, &d.MaxRedemptions, &d.MaxPerUser)
+	if err != nil {
+		return err
+	}
+	if amount != nil {
+		d.Amount = &types.Money{Amount: *amount, Currency: b.Amount.Currency}
+	}
+
+	var used, usedByUser int
+	err = tx.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(booking.fk_Userid = ?), 0)
+		FROM discountredemption
+		JOIN booking ON booking.id = discountredemption.fk_Bookingid
+		WHERE discountredemption.fk_Discountid = ? AND booking.status <> ?`,
+		b.FkUserID, d.ID, types.BookingCancelled).Scan(&used, &usedByUser)
+	if err != nil {
+		return err
+	}
+	if (d.MaxRedemptions != nil && used >= *d.MaxRedemptions) || (d.MaxPerUser != nil && usedByUser >= *d.MaxPerUser) {
+		return discount.ErrUsedUp
+	}
+
+	b.Discount.Amount = discount.Off(d, b.Amount)
+	b.Amount = b.Amount.Sub(b.Discount.Amount)
+
+	return nil
+}
+
+// applyVoucher locks gift voucher of booking until transaction ends and takes as much of its balance off booking
+// amount as covers it. Bookings paid by voucher entirely are confirmed right away, voucher isn't used for free ones.
+// voucher.ErrEmpty is returned when balance ran out meanwhile
+func applyVoucher(tx *sql.Tx, b *types.Booking) error {
+	v := &types.GiftVoucher{ID: b.Voucher.FkGiftVoucherID}
+	err := tx.QueryRow("SELECT balance, currency, status, expiresAt FROM giftvoucher WHERE id = ? FOR UPDATE", v.ID).
+		Scan(&v.Balance.Amount, &v.Balance.Currency, &v.Status, &v.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case v.Status != types.VoucherActive:
+		return voucher.ErrInvalidVoucher
+	case !v.ExpiresAt.After(time.Now()):
+		return voucher.ErrExpired
+	case v.Balance.Amount <= 0:
+		return voucher.ErrEmpty
+	case v.Balance.Currency != b.Amount.Currency:
+		return voucher.ErrWrongCurrency
+	}
+
+	if b.Amount.Amount <= 0 {
+		b.Voucher = nil
+		return nil
+	}
+
+	b.Voucher.Amount = voucher.Redeemable(v.Balance, b.Amount)
+	b.Amount = b.Amount.Sub(b.Voucher.Amount)
+	if b.Amount.IsZero() {
+		b.Status = types.BookingConfirmed
+	}
+
+	_, err = tx.Exec("UPDATE giftvoucher SET balance = balance - ? WHERE id = ?", b.Voucher.Amount.Amount, v.ID)
+	return err
+}
+
+func hasActiveBooking(tx *sql.Tx, sessionID, userID int) (bool, error) {
+	var count int
+	err := tx.QueryRow(
+		"SELECT COUNT(*) FROM booking WHERE fk_ActivitySessionid = ? AND fk_Userid = ? AND status <> ?",
+		sessionID, userID, types.BookingCancelled).Scan(&count)
+	return count > 0, err
+}
+
+func insertBooking(tx *sql.Tx, b types.Booking) (int64, error) {
+	result, err := tx.Exec(
+		"INSERT INTO booking (fk_ActivitySessionid, fk_Userid, seats, status, amount, currency, expiresAt) VALUES (?,?,?,?,?,?,?)",
+		b.FkActivitySessionID, b.FkUserID, b.Seats, b.Status, b.Amount.Amount, b.Amount.Currency, utcTime(b.ExpiresAt))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+func (c *Castle) GetBookingByID(id int) (*types.Booking, error) {
+	rows, err := c.db.Query(selectBookings+" WHERE booking.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	b := new(types.Booking)
+	for rows.Next() {
+		b, err = scanRowIntoBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if b.ID == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	if err := c.attachBookingTiers([]*types.Booking{b}); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// ListBookingsByUserID returns bookings of user, those of the latest sessions first
+func (c *Castle) ListBookingsByUserID(userID int) ([]*types.Booking, error) {
+	rows, err := c.db.Query(
+		selectBookings+`
+		JOIN activitysession ON activitysession.id = booking.fk_ActivitySessionid
+		WHERE booking.fk_Userid = ?
+		ORDER BY activitysession.startTime DESC, booking.id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookings []*types.Booking
+
+	for rows.Next() {
+		b, err := scanRowIntoBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := c.attachBookingTiers(bookings); err != nil {
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+// attachBookingTiers loads price tiers of all bookings with single query
+func (c *Castle) attachBookingTiers(bookings []*types.Booking) error {
+	if len(bookings) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*types.Booking, len(bookings))
+	params := make([]interface{}, 0, len(bookings))
+	for _, b := range bookings {
+		b.Tiers = []*types.BookingTier{}
+		byID[b.ID] = b
+		params = append(params, b.ID)
+	}
+
+	query := fmt.Sprintf(`SELECT fk_Bookingid, fk_PriceTierid, name, quantity, seats, unitAmount FROM bookingtier
+		WHERE fk_Bookingid IN (%s) ORDER BY fk_Bookingid, id`,
+		strings.TrimSuffix(strings.Repeat("?,", len(bookings)), ","))
+
+	rows, err := c.db.Query(query, params...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookingID int
+		t := new(types.BookingTier)
+		if err := rows.Scan(&bookingID, &t.FkPriceTierID, &t.Name, &t.Quantity, &t.Seats, &t.UnitPrice.Amount); err != nil {
+			return err
+		}
+
+		b := byID[bookingID]
+		t.UnitPrice.Currency = b.Amount.Currency
+		t.Total = t.UnitPrice.Mul(t.Quantity)
+		b.Tiers = append(b.Tiers, t)
+	}
+
+	return rows.Err()
+}
+
+// Attendees are selected together with group name and time of check-in, if any
+const selectAttendees = `SELECT booking.id, user.id, user.username, user.email, booking.seats, booking.status, bookinggroup.name,
+	booking.createdAt, bookingcheckin.checkedInAt
+	FROM booking
+	JOIN user ON user.id = booking.fk_Userid
+	LEFT JOIN bookinggroup ON bookinggroup.fk_Bookingid = booking.id
+	LEFT JOIN bookingcheckin ON bookingcheckin.fk_Bookingid = booking.id`
+
+// ListAttendeesBySessionID returns bookings of session which are not cancelled, in order they were made
+func (c *Castle) ListAttendeesBySessionID(sessionID int) ([]*types.Attendee, error) {
+	return c.listAttendees(selectAttendees+" WHERE booking.fk_ActivitySessionid = ? AND booking.status <> ? ORDER BY booking.createdAt, booking.id",
+		sessionID, types.BookingCancelled)
+}
+
+func (c *Castle) GetAttendeeByBookingID(bookingID int) (*types.Attendee, error) {
+	attendees, err := c.listAttendees(selectAttendees+" WHERE booking.id = ?", bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(attendees) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return attendees[0], nil
+}
+
+func (c *Castle) listAttendees(query string, params ...interface{}) ([]*types.Attendee, error) {
+	rows, err := c.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attendees []*types.Attendee
+
+	for rows.Next() {
+		a := new(types.Attendee)
+		err := rows.Scan(&a.BookingID, &a.UserID, &a.Username, &a.Email, &a.Seats, &a.Status, &a.GroupName, &a.CreatedAt, &a.CheckedInAt)
+		if err != nil {
+			return nil, err
+		}
+		attendees = append(attendees, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return attendees, nil
+}
+
+// UpdateBookingStatus moves booking into status, ErrInvalidTransition is returned when current status doesn't allow it
+func (c *Castle) UpdateBookingStatus(id int, status string) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRow("SELECT status FROM booking WHERE id = ? FOR UPDATE", id).Scan(&current)
+	if err != nil {
+		return err
+	}
+
+	if !CanTransition(current, status) {
+		return ErrInvalidTransition
+	}
+
+	_, err = tx.Exec("UPDATE booking SET status = ? WHERE id = ?", status, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CheckInBooking marks confirmed booking as attended and records who checked it in. Booking row stays locked
+// until check-in is stored, so when several staff members scan the same ticket only the first one succeeds
+// and the others get ErrAlreadyCheckedIn
+func (c *Castle) CheckInBooking(id, userID int) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRow("SELECT status FROM booking WHERE id = ? FOR UPDATE", id).Scan(&current)
+	if err != nil {
+		return err
+	}
+
+	if current == types.BookingAttended {
+		return ErrAlreadyCheckedIn
+	}
+	if !CanTransition(current, types.BookingAttended) {
+		return ErrInvalidTransition
+	}
+
+	_, err = tx.Exec("UPDATE booking SET status = ? WHERE id = ?", types.BookingAttended, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO bookingcheckin (fk_Bookingid, fk_Userid) VALUES (?, ?)", id, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func scanRowIntoWaitlistEntry(rows *sql.Rows) (*types.WaitlistEntry, error) {
+	e := new(types.WaitlistEntry)
+
+	err := rows.Scan(
+		&e.ID,
+		&e.FkActivitySessionID,
+		&e.FkUserID,
+		&e.Seats,
+		&e.Status,
+		&e.FkBookingID,
+		&e.OfferExpiresAt,
+		&e.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// JoinWaitlist adds user to waitlist of session which doesn't have enough free seats for them
+func (c *Castle) JoinWaitlist(e types.WaitlistEntry) (int64, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	session, err := lockSession(tx, e.FkActivitySessionID)
+	if err != nil {
+		return 0, err
+	}
+	if session.cancelled {
+		return 0, ErrSessionCancelled
+	}
+	if e.Seats <= session.free {
+		return 0, ErrSeatsAvailable
+	}
+
+	booked, err := hasActiveBooking(tx, e.FkActivitySessionID, e.FkUserID)
+	if err != nil {
+		return 0, err
+	}
+	if booked {
+		return 0, ErrAlreadyBooked
+	}
+
+	var waiting int
+	err = tx.QueryRow(
+		"SELECT COUNT(*) FROM waitlist WHERE fk_ActivitySessionid = ? AND fk_Userid = ? AND status IN (?, ?)",
+		e.FkActivitySessionID, e.FkUserID, types.WaitlistWaiting, types.WaitlistOffered).Scan(&waiting)
+	if err != nil {
+		return 0, err
+	}
+	if waiting > 0 {
+		return 0, ErrAlreadyWaitlisted
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO waitlist (fk_ActivitySessionid, fk_Userid, seats, status) VALUES (?,?,?,?)",
+		e.FkActivitySessionID, e.FkUserID, e.Seats, types.WaitlistWaiting)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (c *Castle) GetWaitlistEntryByID(id int) (*types.WaitlistEntry, error) {
+	rows, err := c.db.Query("SELECT * FROM waitlist WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	e := new(types.WaitlistEntry)
+	for rows.Next() {
+		e, err = scanRowIntoWaitlistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if e.ID == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return e, nil
+}
+
+// ListWaitlistByUserID returns waitlist entries of user, newest first
+func (c *Castle) ListWaitlistByUserID(userID int) ([]*types.WaitlistEntry, error) {
+	return c.listWaitlist("SELECT * FROM waitlist WHERE fk_Userid = ? ORDER BY createdAt DESC, id DESC", userID)
+}
+
+// ListWaitlistBySessionID returns active waitlist entries of session in order users joined
+func (c *Castle) ListWaitlistBySessionID(sessionID int) ([]*types.WaitlistEntry, error) {
+	return c.listWaitlist(
+		"SELECT * FROM waitlist WHERE fk_ActivitySessionid = ? AND status IN (?, ?) ORDER BY createdAt, id",
+		sessionID, types.WaitlistWaiting, types.WaitlistOffered)
+}
+
+func (c *Castle) listWaitlist(query string, params ...interface{}) ([]*types.WaitlistEntry, error) {
+	rows, err := c.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*types.WaitlistEntry
+
+	for rows.Next() {
+		e, err := scanRowIntoWaitlistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// ListWaitlistedSessionIDs returns upcoming sessions which have users waiting
+func (c *Castle) ListWaitlistedSessionIDs() ([]int, error) {
+	rows, err := c.db.Query(
+		`SELECT DISTINCT waitlist.fk_ActivitySessionid FROM waitlist
+		JOIN activitysession ON activitysession.id = waitlist.fk_ActivitySessionid
+		WHERE waitlist.status = ? AND activitysession.startTime > UTC_TIMESTAMP() AND activitysession.cancelled = 0`,
+		types.WaitlistWaiting)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// PromoteWaitlist offers free seats of upcoming session to waiting users in order they joined. Users who want
+// more seats than are free are skipped, so smaller requests behind them can be served. Seats of every offer
+// are held by pending booking until offer is accepted or expires after window, at the latest when session starts
+func (c *Castle) PromoteWaitlist(sessionID int, window time.Duration) ([]*types.WaitlistEntry, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	session, err := lockSession(tx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	free := session.free
+	if free <= 0 || session.cancelled || !session.startTime.After(now) {
+		return nil, nil
+	}
+
+	rows, err := tx.Query(
+		"SELECT * FROM waitlist WHERE fk_ActivitySessionid = ? AND status = ? ORDER BY createdAt, id FOR UPDATE",
+		sessionID, types.WaitlistWaiting)
+	if err != nil {
+		return nil, err
+	}
+
+	var waiting []*types.WaitlistEntry
+	for rows.Next() {
+		e, err := scanRowIntoWaitlistEntry(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		waiting = append(waiting, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	expiresAt := now.Add(window)
+	if expiresAt.After(session.startTime) {
+		expiresAt = session.startTime
+	}
+
+	// Users who booked seats meanwhile don't need them anymore
+	offers, left, err := pickWaitlistOffers(waiting, free, func(userID int) (bool, error) {
+		return hasActiveBooking(tx, sessionID, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range left {
+		if _, err := tx.Exec("UPDATE waitlist SET status = ? WHERE id = ?", types.WaitlistLeft, e.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, e := range offers {
+		bookingID, err := insertBooking(tx, types.Booking{
+			FkActivitySessionID: sessionID,
+			FkUserID:            e.FkUserID,
+			Seats:               e.Seats,
+			Status:              types.BookingPending,
+			Amount:              session.price.Mul(e.Seats),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec("UPDATE waitlist SET status = ?, fk_Bookingid = ?, offerExpiresAt = ? WHERE id = ?",
+			types.WaitlistOffered, bookingID, expiresAt, e.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		id := int(bookingID)
+		e.Status = types.WaitlistOffered
+		e.FkBookingID = &id
+		e.OfferExpiresAt = &expiresAt
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return offers, nil
+}
+
+// AcceptWaitlistOffer keeps booking held by offer, ErrOfferExpired is returned when it was released meanwhile.
+// Unpaid booking keeps holding seats until payment deadline, at the latest until session starts
+func (c *Castle) AcceptWaitlistOffer(id int, paymentDeadline time.Time) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var expiresAt *time.Time
+	var bookingID *int
+	var bookingStatus *string
+	err = tx.QueryRow(
+		`SELECT waitlist.status, waitlist.offerExpiresAt, waitlist.fk_Bookingid, booking.status FROM waitlist
+		LEFT JOIN booking ON booking.id = waitlist.fk_Bookingid
+		WHERE waitlist.id = ? FOR UPDATE`, id).Scan(&status, &expiresAt, &bookingID, &bookingStatus)
+	if err != nil {
+		return err
+	}
+
+	if err := checkOfferAcceptable(status, expiresAt, bookingStatus, time.Now()); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE waitlist SET status = ? WHERE id = ?", types.WaitlistAccepted, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE booking
+		JOIN activitysession ON activitysession.id = booking.fk_ActivitySessionid
+		SET booking.expiresAt = LEAST(?, activitysession.startTime)
+		WHERE booking.id = ? AND booking.status = ? AND booking.amount > 0`,
+		paymentDeadline.UTC(), *bookingID, types.BookingPending)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// LeaveWaitlist removes user from waitlist, seats held by pending offer are released
+func (c *Castle) LeaveWaitlist(id int) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var bookingID *int
+	err = tx.QueryRow("SELECT status, fk_Bookingid FROM waitlist WHERE id = ? FOR UPDATE", id).Scan(&status, &bookingID)
+	if err != nil {
+		return err
+	}
+
+	if status != types.WaitlistWaiting && status != types.WaitlistOffered {
+		return ErrInvalidTransition
+	}
+
+	if bookingID != nil {
+		_, err = tx.Exec("UPDATE booking SET status = ? WHERE id = ? AND status = ?",
+			types.BookingCancelled, *bookingID, types.BookingPending)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE waitlist SET status = ? WHERE id = ?", types.WaitlistLeft, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ExpireWaitlistOffers releases seats of offers which weren't accepted until now. Offers whose booking
+// was confirmed by organizer meanwhile count as accepted. Expired entries are returned
+func (c *Castle) ExpireWaitlistOffers(now time.Time) ([]*types.WaitlistEntry, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT * FROM waitlist WHERE status = ? AND offerExpiresAt <= ? FOR UPDATE",
+		types.WaitlistOffered, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	var offers []*types.WaitlistEntry
+	for rows.Next() {
+		e, err := scanRowIntoWaitlistEntry(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		offers = append(offers, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var expired []*types.WaitlistEntry
+	for _, e := range offers {
+		status := types.WaitlistExpired
+		if e.FkBookingID != nil {
+			result, err := tx.Exec("UPDATE booking SET status = ? WHERE id = ? AND status = ?",
+				types.BookingCancelled, *e.FkBookingID, types.BookingPending)
+			if err != nil {
+				return nil, err
+			}
+
+			var bookingStatus string
+			err = tx.QueryRow("SELECT status FROM booking WHERE id = ?", *e.FkBookingID).Scan(&bookingStatus)
+			if err != nil {
+				return nil, err
+			}
+			if affected, _ := result.RowsAffected(); affected == 0 && bookingStatus != types.BookingCancelled {
+				status = types.WaitlistAccepted
+			}
+		}
+
+		if _, err := tx.Exec("UPDATE waitlist SET status = ? WHERE id = ?", status, e.ID); err != nil {
+			return nil, err
+		}
+
+		if status == types.WaitlistExpired {
+			e.Status = status
+			expired = append(expired, e)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return expired, nil
+}
+
+// ExpireBookingHolds cancels pending bookings which weren't paid until their deadline, so their seats can be
+// booked again. Gift voucher balance they used is restored and waitlist entries they came from expire.
+// Cancelled bookings are returned
+func (c *Castle) ExpireBookingHolds(now time.Time) ([]*types.Booking, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(
+		"SELECT id, fk_ActivitySessionid, fk_Userid, seats, amount, currency FROM booking WHERE status = ? AND expiresAt <= ? FOR UPDATE",
+		types.BookingPending, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []*types.Booking
+	for rows.Next() {
+		b := &types.Booking{Status: types.BookingPending}
+		if err := rows.Scan(&b.ID, &b.FkActivitySessionID, &b.FkUserID, &b.Seats, &b.Amount.Amount, &b.Amount.Currency); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		expired = append(expired, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	reason := "Booking wasn't paid in time"
+	for _, b := range expired {
+		err := cancelBooking(tx, b.ID, types.BookingCancelled, b.Status, b.Amount, types.BookingCancellation{Reason: &reason})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec("UPDATE waitlist SET status = ? WHERE fk_Bookingid = ? AND status IN (?, ?)",
+			types.WaitlistExpired, b.ID, types.WaitlistOffered, types.WaitlistAccepted)
+		if err != nil {
+			return nil, err
+		}
+		b.Status = types.BookingCancelled
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return expired, nil
+}
+
+// GetGroupSettings returns group booking rules of activity with price rules ordered by group size
+func (c *Castle) GetGroupSettings(activityID int) (*types.GroupSettings, error) {
+	settings := &types.GroupSettings{FkActivityID: activityID}
+	var adultPrice *int64
+	var currency string
+	err := c.db.QueryRow(
+		"SELECT minGroupSize, maxGroupSize, adultPrice, currency, freeAdultsPer FROM groupsettings WHERE fk_Activityid = ?",
+		activityID).Scan(&settings.MinGroupSize, &settings.MaxGroupSize, &adultPrice, &currency, &settings.FreeAdultsPer)
+	if err != nil {
+		return nil, err
+	}
+	settings.AdultPrice = types.NullableMoney(adultPrice, &currency)
+
+	rows, err := c.db.Query(
+		"SELECT minStudents, studentPrice FROM grouppricerule WHERE fk_Activityid = ? ORDER BY minStudents", activityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings.PriceRules = []*types.GroupPriceRule{}
+	for rows.Next() {
+		rule := &types.GroupPriceRule{StudentPrice: types.Money{Currency: currency}}
+		if err := rows.Scan(&rule.MinStudents, &rule.StudentPrice.Amount); err != nil {
+			return nil, err
+		}
+		settings.PriceRules = append(settings.PriceRules, rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// SaveGroupSettings creates or replaces group booking rules of activity together with its price rules
+func (c *Castle) SaveGroupSettings(settings types.GroupSettings) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Prices of settings are in currency of activity
+	adultPrice, _ := types.MoneyColumns(settings.AdultPrice)
+	_, err = tx.Exec(
+		`INSERT INTO groupsettings (fk_Activityid, minGroupSize, maxGroupSize, adultPrice, currency, freeAdultsPer)
+		SELECT ?, ?, ?, ?, activity.currency, ? FROM activity WHERE activity.id = ?
+		ON DUPLICATE KEY UPDATE minGroupSize = VALUES(minGroupSize), maxGroupSize = VALUES(maxGroupSize),
+			adultPrice = VALUES(adultPrice), currency = VALUES(currency), freeAdultsPer = VALUES(freeAdultsPer)`,
+		settings.FkActivityID, settings.MinGroupSize, settings.MaxGroupSize, adultPrice, settings.FreeAdultsPer,
+		settings.FkActivityID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM grouppricerule WHERE fk_Activityid = ?", settings.FkActivityID)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range settings.PriceRules {
+		_, err = tx.Exec("INSERT INTO grouppricerule (fk_Activityid, minStudents, studentPrice) VALUES (?,?,?)",
+			settings.FkActivityID, rule.MinStudents, rule.StudentPrice.Amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CancelBooking moves booking into cancelled or no-show status and records cancellation. Only confirmed
+// bookings were paid, so refund amount of pending ones is zero
+func (c *Castle) CancelBooking(id int, status string, cancellation types.BookingCancellation) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current string
+	var amount types.Money
+	err = tx.QueryRow("SELECT status, amount, currency FROM booking WHERE id = ? FOR UPDATE", id).
+		Scan(&current, &amount.Amount, &amount.Currency)
+	if err != nil {
+		return err
+	}
+
+	if !CanTransition(current, status) {
+		return ErrInvalidTransition
+	}
+
+	if err := cancelBooking(tx, id, status, current, amount, cancellation); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func cancelBooking(tx *sql.Tx, id int, status, current string, amount types.Money, cancellation types.BookingCancellation) error {
+	_, err := tx.Exec("UPDATE booking SET status = ? WHERE id = ?", status, id)
+	if err != nil {
+		return err
+	}
+
+	refundAmount := int64(0)
+	if current == types.BookingConfirmed {
+		refundAmount = amount.Percent(cancellation.RefundPercent).Amount
+	}
+	refundStatus := types.RefundNone
+	if refundAmount > 0 {
+		refundStatus = types.RefundPending
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO bookingcancellation (fk_Bookingid, fk_Userid, reason, refundPercent, refundAmount, refundStatus) VALUES (?,?,?,?,?,?)",
+		id, cancellation.FkUserID, cancellation.Reason, cancellation.RefundPercent, refundAmount, refundStatus)
+	if err != nil {
+		return err
+	}
+
+	return restoreVoucher(tx, id, current, cancellation.RefundPercent)
+}
+
+// restoreVoucher gives part of gift voucher redeemed against cancelled booking back to voucher balance. Voucher was
+// spent on confirmed bookings, so refund percent applies to them the same way as to payments
+func restoreVoucher(tx *sql.Tx, bookingID int, current string, refundPercent int) error {
+	var voucherID int
+	var redeemed types.Money
+	err := tx.QueryRow("SELECT fk_GiftVoucherid, amount FROM voucherredemption WHERE fk_Bookingid = ?", bookingID).
+		Scan(&voucherID, &redeemed.Amount)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	restored := redeemed
+	if current == types.BookingConfirmed {
+		restored = redeemed.Percent(refundPercent)
+	}
+	if restored.Amount <= 0 {
+		return nil
+	}
+
+	_, err = tx.Exec("UPDATE voucherredemption SET restoredAmount = ? WHERE fk_Bookingid = ?", restored.Amount, bookingID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE giftvoucher SET balance = balance + ? WHERE id = ?", restored.Amount, voucherID)
+	return err
+}
+
+// CancelSession marks session as cancelled and cancels all its active bookings with full refund. Waitlist of session
+// expires. Cancelled bookings are returned
+func (c *Castle) CancelSession(sessionID int, cancellation types.BookingCancellation) ([]*types.Booking, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	session, err := lockSession(tx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.cancelled {
+		return nil, ErrSessionCancelled
+	}
+
+	if _, err := tx.Exec("UPDATE activitysession SET cancelled = 1 WHERE id = ?", sessionID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(
+		"SELECT id, fk_Userid, status, amount, currency FROM booking WHERE fk_ActivitySessionid = ? AND status IN (?, ?) FOR UPDATE",
+		sessionID, types.BookingPending, types.BookingConfirmed)
+	if err != nil {
+		return nil, err
+	}
+
+	var bookings []*types.Booking
+	for rows.Next() {
+		b := &types.Booking{FkActivitySessionID: sessionID}
+		if err := rows.Scan(&b.ID, &b.FkUserID, &b.Status, &b.Amount.Amount, &b.Amount.Currency); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		bookings = append(bookings, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cancellation.RefundPercent = 100
+	for _, b := range bookings {
+		if err := cancelBooking(tx, b.ID, types.BookingCancelled, b.Status, b.Amount, cancellation); err != nil {
+			return nil, err
+		}
+		b.Status = types.BookingCancelled
+	}
+
+	_, err = tx.Exec("UPDATE waitlist SET status = ? WHERE fk_ActivitySessionid = ? AND status IN (?, ?)",
+		types.WaitlistExpired, sessionID, types.WaitlistWaiting, types.WaitlistOffered)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+// OweRefund records that cancelled booking is owed amount, such as payment which succeeded after cancellation
+func (c *Castle) OweRefund(bookingID int, amount types.Money) error {
+	_, err := c.db.Exec("UPDATE bookingcancellation SET refundAmount = ?, refundStatus = ? WHERE fk_Bookingid = ?",
+		amount.Amount, types.RefundPending, bookingID)
+	return err
+}
+
+// CompleteRefund marks refund of cancelled booking as paid back
+func (c *Castle) CompleteRefund(bookingID int) error {
+	_, err := c.db.Exec("UPDATE bookingcancellation SET refundStatus = ? WHERE fk_Bookingid = ? AND refundStatus = ?",
+		types.RefundCompleted, bookingID, types.RefundPending)
+	return err
+}
+
+// ListPendingRefunds returns cancelled bookings whose refund wasn't confirmed by provider yet, oldest first
+func (c *Castle) ListPendingRefunds() ([]*types.Booking, error) {
+	rows, err := c.db.Query(
+		selectBookings+" WHERE bookingcancellation.refundStatus = ? ORDER BY bookingcancellation.createdAt", types.RefundPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookings []*types.Booking
+	for rows.Next() {
+		b, err := scanRowIntoBooking(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return bookings, nil
+}
+
+// GetCancellationPolicy returns policy which applies to activity, either its own or policy of its package
+func (c *Castle) GetCancellationPolicy(activityID int) (*types.CancellationPolicy, error) {
+	return c.getCancellationPolicy(
+		`SELECT cancellationpolicy.* FROM cancellationpolicy
+		LEFT JOIN activity ON activity.fk_Packageid = cancellationpolicy.fk_Packageid
+		WHERE cancellationpolicy.fk_Activityid = ? OR activity.id = ?
+		ORDER BY cancellationpolicy.fk_Activityid IS NULL
+		LIMIT 1`, activityID, activityID)
+}
+
+func (c *Castle) GetPackageCancellationPolicy(packageID int) (*types.CancellationPolicy, error) {
+	return c.getCancellationPolicy("SELECT * FROM cancellationpolicy WHERE fk_Packageid = ?", packageID)
+}
+
+func (c *Castle) getCancellationPolicy(query string, params ...interface{}) (*types.CancellationPolicy, error) {
+	p := new(types.CancellationPolicy)
+	err := c.db.QueryRow(query, params...).Scan(&p.ID, &p.FkActivityID, &p.FkPackageID, &p.FreeCancellationHours, &p.NoShowRefundPercent)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.db.Query(
+		"SELECT daysBefore, refundPercent FROM refundtier WHERE fk_CancellationPolicyid = ? ORDER BY daysBefore DESC", p.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	p.Tiers = []*types.RefundTier{}
+	for rows.Next() {
+		tier := new(types.RefundTier)
+		if err := rows.Scan(&tier.DaysBefore, &tier.RefundPercent); err != nil {
+			return nil, err
+		}
+		p.Tiers = append(p.Tiers, tier)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// SaveCancellationPolicy creates or replaces policy of activity or package, exactly one of them has to be set
+func (c *Castle) SaveCancellationPolicy(p types.CancellationPolicy) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`INSERT INTO cancellationpolicy (fk_Activityid, fk_Packageid, freeCancellationHours, noShowRefundPercent) VALUES (?,?,?,?)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), freeCancellationHours = VALUES(freeCancellationHours),
+			noShowRefundPercent = VALUES(noShowRefundPercent)`,
+		p.FkActivityID, p.FkPackageID, p.FreeCancellationHours, p.NoShowRefundPercent)
+	if err != nil {
+		return err
+	}
+
+	policyID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM refundtier WHERE fk_CancellationPolicyid = ?", policyID); err != nil {
+		return err
+	}
+
+	for _, tier := range p.Tiers {
+		_, err = tx.Exec("INSERT INTO refundtier (fk_CancellationPolicyid, daysBefore, refundPercent) VALUES (?,?,?)",
+			policyID, tier.DaysBefore, tier.RefundPercent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
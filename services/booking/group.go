@@ -0,0 +1,60 @@
+package booking
+
+import (
+	"educations-castle/types"
+	"fmt"
+)
+
+// checkGroupSize returns error when number of students is outside limits of activity
+func checkGroupSize(group types.BookingGroup, settings *types.GroupSettings) error {
+	if settings == nil {
+		return nil
+	}
+
+	if group.Students < settings.MinGroupSize || group.Students > settings.MaxGroupSize {
+		return fmt.Errorf("group has to have from %d to %d students", settings.MinGroupSize, settings.MaxGroupSize)
+	}
+
+	return nil
+}
+
+// groupInvoice calculates prices of group booking. Without settings students and adults pay the session price
+func groupInvoice(b *types.Booking, sessionPrice types.Money, settings *types.GroupSettings) types.GroupInvoice {
+	g := b.Group
+	studentPrice := sessionPrice
+	adultPrice := sessionPrice
+	freeAdults := 0
+
+	if settings != nil {
+		// Rules are ordered by group size, the largest matching one wins
+		for _, rule := range settings.PriceRules {
+			if g.Students >= rule.MinStudents {
+				studentPrice = rule.StudentPrice
+			}
+		}
+		if settings.AdultPrice != nil {
+			adultPrice = *settings.AdultPrice
+		}
+		if settings.FreeAdultsPer > 0 {
+			freeAdults = min(g.Students/settings.FreeAdultsPer, g.Adults)
+		}
+	}
+
+	studentsTotal := studentPrice.Mul(g.Students)
+	adultsTotal := adultPrice.Mul(g.Adults - freeAdults)
+	total := studentsTotal.Add(adultsTotal)
+
+	return types.GroupInvoice{
+		BookingID:     b.ID,
+		GroupName:     g.Name,
+		Students:      g.Students,
+		Adults:        g.Adults,
+		FreeAdults:    freeAdults,
+		StudentPrice:  studentPrice,
+		AdultPrice:    adultPrice,
+		StudentsTotal: studentsTotal,
+		AdultsTotal:   adultsTotal,
+		Total:         total,
+		PerStudent:    total.Div(g.Students),
+	}
+}
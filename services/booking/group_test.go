@@ -0,0 +1,57 @@
+package booking
+
+import (
+	"educations-castle/types"
+	"testing"
+)
+
+func TestGroupInvoice(t *testing.T) {
+	adultPrice := types.Money{Amount: 500, Currency: "EUR"}
+	settings := &types.GroupSettings{
+		MinGroupSize:  10,
+		MaxGroupSize:  30,
+		AdultPrice:    &adultPrice,
+		FreeAdultsPer: 10,
+		PriceRules: []*types.GroupPriceRule{
+			{MinStudents: 10, StudentPrice: types.Money{Amount: 950, Currency: "EUR"}},
+			{MinStudents: 20, StudentPrice: types.Money{Amount: 850, Currency: "EUR"}},
+		},
+	}
+
+	t.Run("Should apply the largest matching price rule and free adults", func(t *testing.T) {
+		b := &types.Booking{ID: 1, Group: &types.BookingGroup{Name: "5B", Students: 24, Adults: 3}}
+
+		invoice := groupInvoice(b, types.Money{Amount: 1200, Currency: "EUR"}, settings)
+
+		if invoice.StudentPrice.Amount != 850 || invoice.FreeAdults != 2 {
+			t.Errorf("unexpected prices %+v", invoice)
+		}
+		if invoice.StudentsTotal.Amount != 20400 || invoice.AdultsTotal.Amount != 500 || invoice.Total.Amount != 20900 {
+			t.Errorf("unexpected totals %+v", invoice)
+		}
+		if invoice.PerStudent.String() != "8.71 EUR" {
+			t.Errorf("expected 8.71 EUR per student, got %v", invoice.PerStudent)
+		}
+	})
+
+	t.Run("Should use session price without settings", func(t *testing.T) {
+		b := &types.Booking{ID: 1, Group: &types.BookingGroup{Name: "2A", Students: 3, Adults: 1}}
+
+		invoice := groupInvoice(b, types.Money{Amount: 1010, Currency: "EUR"}, nil)
+
+		if invoice.Total.Amount != 4040 || invoice.FreeAdults != 0 || invoice.PerStudent.Amount != 1347 {
+			t.Errorf("unexpected invoice %+v", invoice)
+		}
+	})
+}
+
+func TestCheckGroupSize(t *testing.T) {
+	settings := &types.GroupSettings{MinGroupSize: 10, MaxGroupSize: 30}
+
+	for students, valid := range map[int]bool{9: false, 10: true, 30: true, 31: false} {
+		err := checkGroupSize(types.BookingGroup{Students: students}, settings)
+		if (err == nil) != valid {
+			t.Errorf("%d students: unexpected result %v", students, err)
+		}
+	}
+}
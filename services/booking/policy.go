@@ -0,0 +1,36 @@
+package booking
+
+import (
+	"educations-castle/types"
+	"time"
+)
+
+// refundPercent returns part of price refunded when user cancels booking of session starting at start.
+// Without policy everything is refunded
+func refundPercent(policy *types.CancellationPolicy, start, now time.Time) int {
+	if policy == nil {
+		return 100
+	}
+
+	left := start.Sub(now)
+	if policy.FreeCancellationHours > 0 && left >= time.Duration(policy.FreeCancellationHours)*time.Hour {
+		return 100
+	}
+
+	percent, bestDays := 0, -1
+	for _, tier := range policy.Tiers {
+		if left >= time.Duration(tier.DaysBefore)*24*time.Hour && tier.DaysBefore > bestDays {
+			percent, bestDays = tier.RefundPercent, tier.DaysBefore
+		}
+	}
+
+	return percent
+}
+
+// noShowRefundPercent returns part of price refunded when user didn't come to session, nothing without policy
+func noShowRefundPercent(policy *types.CancellationPolicy) int {
+	if policy == nil {
+		return 0
+	}
+	return policy.NoShowRefundPercent
+}
@@ -0,0 +1,42 @@
+package booking
+
+import (
+	"educations-castle/types"
+	"testing"
+	"time"
+)
+
+func TestRefundPercent(t *testing.T) {
+	start := time.Date(2025, 5, 10, 10, 0, 0, 0, time.UTC)
+	policy := &types.CancellationPolicy{
+		FreeCancellationHours: 72,
+		Tiers: []*types.RefundTier{
+			{DaysBefore: 1, RefundPercent: 50},
+			{DaysBefore: 2, RefundPercent: 75},
+			{DaysBefore: 0, RefundPercent: 10},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		policy   *types.CancellationPolicy
+		before   time.Duration
+		expected int
+	}{
+		{"Should refund fully without policy", nil, time.Hour, 100},
+		{"Should refund fully within free cancellation", policy, 72 * time.Hour, 100},
+		{"Should use tier with the most days met", policy, 71 * time.Hour, 75},
+		{"Should use tier of one day", policy, 24 * time.Hour, 50},
+		{"Should use tier of the last day", policy, time.Minute, 10},
+		{"Should refund nothing after start", policy, -time.Minute, 0},
+		{"Should refund nothing without tiers", &types.CancellationPolicy{FreeCancellationHours: 24}, 23 * time.Hour, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if percent := refundPercent(test.policy, start, start.Add(-test.before)); percent != test.expected {
+				t.Errorf("expected %d%%, got %d%%", test.expected, percent)
+			}
+		})
+	}
+}
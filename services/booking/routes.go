@@ -0,0 +1,1593 @@
+package booking
+
+import (
+	"database/sql"
+	"educations-castle/services/auth"
+	"educations-castle/services/discount"
+	"educations-castle/services/payment"
+	"educations-castle/services/voucher"
+	"educations-castle/types"
+	"educations-castle/utils"
+	"educations-castle/utils/color"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// Statuses set through booking action routes
+var actionStatuses = map[string]string{
+	"confirm": types.BookingConfirmed,
+	"cancel":  types.BookingCancelled,
+	"attend":  types.BookingAttended,
+	"no-show": types.BookingNoShow,
+}
+
+type Handler struct {
+	bookingCastle      types.BookingCastle
+	waitlistCastle     types.WaitlistCastle
+	sessionCastle      types.SessionCastle
+	activityCastle     types.ActivityCastle
+	userCastle         types.UserCastle
+	notificationCastle types.NotificationCastle
+	discountCastle     types.DiscountCastle
+	voucherCastle      types.VoucherCastle
+	waitlist           *Waitlist
+	payments           *payment.Gateway
+	tickets            *TicketSigner
+}
+
+func NewHandler(bookingCastle types.BookingCastle, waitlistCastle types.WaitlistCastle, sessionCastle types.SessionCastle,
+	activityCastle types.ActivityCastle, userCastle types.UserCastle, notificationCastle types.NotificationCastle,
+	discountCastle types.DiscountCastle, voucherCastle types.VoucherCastle, waitlist *Waitlist, payments *payment.Gateway,
+	tickets *TicketSigner) *Handler {
+	return &Handler{
+		bookingCastle:      bookingCastle,
+		waitlistCastle:     waitlistCastle,
+		sessionCastle:      sessionCastle,
+		activityCastle:     activityCastle,
+		userCastle:         userCastle,
+		notificationCastle: notificationCastle,
+		discountCastle:     discountCastle,
+		voucherCastle:      voucherCastle,
+		waitlist:           waitlist,
+		payments:           payments,
+		tickets:            tickets}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/sessions/{sessionID:[0-9]+}/bookings/create", auth.WithJWTAuth(h.handleCreateBooking, h.userCastle, "administrator", "organizer", "user")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/sessions/{sessionID:[0-9]+}/bookings/group/create", auth.WithJWTAuth(h.handleCreateGroupBooking, h.userCastle, "administrator", "organizer", "user")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/bookings/{bookingID:[0-9]+}/invoice", auth.WithJWTAuth(h.handleGetGroupInvoice, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/group-settings", auth.WithOptionalJWTAuth(h.handleGetGroupSettings, h.userCastle)).Methods("GET")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/group-settings/update", auth.WithJWTAuth(h.handleUpdateGroupSettings, h.userCastle, "administrator", "organizer")).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/sessions/{sessionID:[0-9]+}/attendees", auth.WithJWTAuth(h.handleListAttendees, h.userCastle, "administrator", "organizer")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/sessions/{sessionID:[0-9]+}/attendees/export", auth.WithJWTAuth(h.handleExportAttendees, h.userCastle, "administrator", "organizer")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/bookings/my", auth.WithJWTAuth(h.handleListMyBookings, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/bookings/{bookingID:[0-9]+}", auth.WithJWTAuth(h.handleGetBooking, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/bookings/{bookingID:[0-9]+}/ticket", auth.WithJWTAuth(h.handleGetTicket, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/bookings/{bookingID:[0-9]+}/ticket/qr", auth.WithJWTAuth(h.handleGetTicketQR, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/sessions/{sessionID:[0-9]+}/check-in", auth.WithJWTAuth(h.handleCheckIn, h.userCastle, "administrator", "organizer")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/bookings/{bookingID:[0-9]+}/{action:confirm|cancel|attend|no-show}", auth.WithJWTAuth(h.handleChangeBookingStatus, h.userCastle, "administrator", "organizer", "user")).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/sessions/{sessionID:[0-9]+}/cancel", auth.WithJWTAuth(h.handleCancelSession, h.userCastle, "administrator", "organizer")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/cancellation-policy", h.handleGetCancellationPolicy).Methods("GET")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/cancellation-policy/update", auth.WithJWTAuth(h.handleUpdateCancellationPolicy, h.userCastle, "administrator", "organizer")).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/packages/{packageID:[0-9]+}/cancellation-policy", h.handleGetPackageCancellationPolicy).Methods("GET")
+	router.HandleFunc("/packages/{packageID:[0-9]+}/cancellation-policy/update", auth.WithJWTAuth(h.handleUpdatePackageCancellationPolicy, h.userCastle, "administrator", "organizer")).Methods("PUT", "OPTIONS")
+
+	router.HandleFunc("/sessions/{sessionID:[0-9]+}/waitlist/join", auth.WithJWTAuth(h.handleJoinWaitlist, h.userCastle, "administrator", "organizer", "user")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/sessions/{sessionID:[0-9]+}/waitlist", auth.WithJWTAuth(h.handleListSessionWaitlist, h.userCastle, "administrator", "organizer")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/waitlist/my", auth.WithJWTAuth(h.handleListMyWaitlist, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/waitlist/{entryID:[0-9]+}/{action:accept|leave}", auth.WithJWTAuth(h.handleChangeWaitlistEntry, h.userCastle, "administrator", "organizer", "user")).Methods("PUT", "OPTIONS")
+}
+
+// CreateBooking godoc
+// @Summary      Book seats in activity session
+// @Description  Reserves seats in upcoming session, booking starts as pending. Each user can have one active booking per session
+// @Description  Sessions with price tiers are booked by quantity of each tier, other sessions by number of seats.
+// @Description  Promo code replaces campaigns, without it the campaign taking the most off price is applied.
+// @Description  Gift voucher pays as much of discounted price as its balance covers, bookings paid by it entirely are confirmed.
+// @Description  Unpaid bookings are cancelled after expiresAt and their seats are released
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Param        sessionID path int true "Session ID"
+// @Param        payload body types.BookingPayload true "Number of seats or quantities of price tiers"
+// @Success      201  {object}   types.Booking
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload, promo code, voucher or session already started"
+// @Failure      401  {object}   types.ErrorResponse "unauthorized"
+// @Failure      404  {object}   types.ErrorResponse "session not found"
+// @Failure      409  {object}   types.ErrorResponse "not enough free seats, session already booked, discount or voucher used up"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /sessions/{sessionID}/bookings/create [post]
+func (h *Handler) handleCreateBooking(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := strconv.Atoi(mux.Vars(r)["sessionID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid session ID"))
+		return
+	}
+
+	var payload types.BookingPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	var activity *types.Activity
+	session, err := h.sessionCastle.GetSessionByID(sessionID)
+	if err == nil {
+		// Sessions of activities user can't see can't be booked either
+		activity, err = h.activityCastle.GetVisibleActivityByID(session.FkActivityID, auth.GetViewerFromContext(r.Context()))
+	}
+	if err != nil {
+		writeNotFoundError(w, err, "session not found")
+		return
+	}
+
+	if !session.StartTime.After(time.Now()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("session has already started"))
+		return
+	}
+
+	deadline := h.waitlist.PaymentDeadline(time.Now())
+	booking := types.Booking{
+		FkActivitySessionID: sessionID,
+		FkUserID:            auth.GetUserIDFromContext(r.Context()),
+		Seats:               payload.Seats,
+		Status:              types.BookingPending,
+		ExpiresAt:           &deadline,
+	}
+
+	tiers, err := h.activityCastle.ListSessionPriceTiers([]int{sessionID})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	switch {
+	case len(tiers[sessionID]) > 0 && len(payload.Tiers) == 0:
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("session is booked by price tiers"))
+		return
+	case len(tiers[sessionID]) == 0 && len(payload.Tiers) > 0:
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("session has no price tiers"))
+		return
+	case len(payload.Tiers) > 0:
+		booking.Tiers, booking.Seats, booking.Amount, err = quoteTiers(tiers[sessionID], payload.Tiers)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	// Amount of plain booking is set while session is locked, its estimate is enough to pick discount
+	price := booking.Amount
+	if len(booking.Tiers) == 0 {
+		price = sessionPrice(session, activity).Mul(booking.Seats)
+	}
+	booking.Discount, err = h.discountFor(activity.ID, booking.FkUserID, payload.PromoCode, price)
+	if err == nil {
+		booking.Voucher, err = h.voucherFor(activity, payload.VoucherCode, price.Currency)
+	}
+	if err != nil {
+		writeRedemptionError(w, err)
+		return
+	}
+
+	bookingID, err := h.bookingCastle.CreateBooking(booking)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrSessionFull), errors.Is(err, ErrAlreadyBooked), errors.Is(err, ErrSessionCancelled),
+			errors.Is(err, discount.ErrUsedUp), errors.Is(err, voucher.ErrEmpty), errors.Is(err, voucher.ErrExpired):
+			utils.WriteError(w, http.StatusConflict, err)
+		case errors.Is(err, sql.ErrNoRows):
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("session not found"))
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	created, err := h.bookingCastle.GetBookingByID(int(bookingID))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, created)
+}
+
+// CreateGroupBooking godoc
+// @Summary      Book seats for school class or other group
+// @Description  Reserves seats for students and accompanying adults of group in upcoming session. Number of students has to fit
+// @Description  group size limits of activity. One user can book several groups into the same session.
+// @Description  Promo codes, campaigns and gift vouchers apply the same way as to individual bookings, unpaid bookings expire too
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Param        sessionID path int true "Session ID"
+// @Param        payload body types.GroupBookingPayload true "Group data"
+// @Success      201  {object}   types.Booking
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload, group size, promo code, voucher or session already started"
+// @Failure      401  {object}   types.ErrorResponse "unauthorized"
+// @Failure      404  {object}   types.ErrorResponse "session not found"
+// @Failure      409  {object}   types.ErrorResponse "not enough free seats, discount or voucher used up"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /sessions/{sessionID}/bookings/group/create [post]
+func (h *Handler) handleCreateGroupBooking(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := strconv.Atoi(mux.Vars(r)["sessionID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid session ID"))
+		return
+	}
+
+	var payload types.GroupBookingPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	var activity *types.Activity
+	session, err := h.sessionCastle.GetSessionByID(sessionID)
+	if err == nil {
+		activity, err = h.activityCastle.GetVisibleActivityByID(session.FkActivityID, auth.GetViewerFromContext(r.Context()))
+	}
+	if err != nil {
+		writeNotFoundError(w, err, "session not found")
+		return
+	}
+
+	if !session.StartTime.After(time.Now()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("session has already started"))
+		return
+	}
+
+	settings, err := h.getGroupSettings(session.FkActivityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	group := types.BookingGroup{
+		Name:     payload.Name,
+		School:   payload.School,
+		AgeBand:  payload.AgeBand,
+		Students: payload.Students,
+		Adults:   payload.Adults,
+	}
+	if err := checkGroupSize(group, settings); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	deadline := h.waitlist.PaymentDeadline(time.Now())
+	booking := types.Booking{
+		FkActivitySessionID: sessionID,
+		FkUserID:            auth.GetUserIDFromContext(r.Context()),
+		Status:              types.BookingPending,
+		Group:               &group,
+		ExpiresAt:           &deadline,
+	}
+	booking.Amount = groupInvoice(&booking, sessionPrice(session, activity), settings).Total
+
+	booking.Discount, err = h.discountFor(activity.ID, booking.FkUserID, payload.PromoCode, booking.Amount)
+	if err == nil {
+		booking.Voucher, err = h.voucherFor(activity, payload.VoucherCode, booking.Amount.Currency)
+	}
+	if err != nil {
+		writeRedemptionError(w, err)
+		return
+	}
+
+	bookingID, err := h.bookingCastle.CreateGroupBooking(booking, group)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrSessionFull), errors.Is(err, ErrSessionCancelled), errors.Is(err, discount.ErrUsedUp),
+			errors.Is(err, voucher.ErrEmpty), errors.Is(err, voucher.ErrExpired):
+			utils.WriteError(w, http.StatusConflict, err)
+		case errors.Is(err, sql.ErrNoRows):
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("session not found"))
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	created, err := h.bookingCastle.GetBookingByID(int(bookingID))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, created)
+}
+
+// GetGroupInvoice godoc
+// @Summary      Get price summary of group booking
+// @Description  Returns student and adult prices after group price rules and free adults, total and price per student
+// @Tags         booking
+// @Produce      json
+// @Param        bookingID path int true "Booking ID"
+// @Success      200  {object}   types.GroupInvoice
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid booking ID or booking is not for group"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "booking not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /bookings/{bookingID}/invoice [get]
+func (h *Handler) handleGetGroupInvoice(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := strconv.Atoi(mux.Vars(r)["bookingID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid booking ID"))
+		return
+	}
+
+	booking, err := h.bookingCastle.GetBookingByID(bookingID)
+	if err != nil {
+		writeNotFoundError(w, err, "booking not found")
+		return
+	}
+
+	session, err := h.sessionCastle.GetSessionByID(booking.FkActivitySessionID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !auth.CheckOwnership(r, booking.FkUserID) && !h.organizesSession(r, session) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	if booking.Group == nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("booking is not for group"))
+		return
+	}
+
+	activity, err := h.activityCastle.GetActivityByID(session.FkActivityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	settings, err := h.getGroupSettings(session.FkActivityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, groupInvoice(booking, sessionPrice(session, activity), settings))
+}
+
+// discountFor picks discount of booking. Promo code has to be valid for activity, without code the campaign
+// taking the most off price is used. Nil is returned when there is nothing to take off
+func (h *Handler) discountFor(activityID, userID int, code string, price types.Money) (*types.BookingDiscount, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+
+	discounts, err := h.discountCastle.ListApplicableDiscounts(activityID, userID, code, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if code != "" && len(discounts) == 0 {
+		return nil, discount.ErrInvalidCode
+	}
+
+	best := discount.Best(discounts, price)
+	if best == nil {
+		return nil, nil
+	}
+
+	return &types.BookingDiscount{FkDiscountID: best.ID, Code: best.Code, Name: best.Name}, nil
+}
+
+// voucherFor checks gift voucher of code can pay for booking of activity in currency. Nil is returned without code
+func (h *Handler) voucherFor(activity *types.Activity, code, currency string) (*types.BookingVoucher, error) {
+	if strings.TrimSpace(code) == "" {
+		return nil, nil
+	}
+
+	v, err := h.voucherCastle.GetVoucherByCode(voucher.NormalizeCode(code))
+	if err == sql.ErrNoRows {
+		return nil, voucher.ErrInvalidVoucher
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := voucher.Check(v, activity, currency, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &types.BookingVoucher{FkGiftVoucherID: v.ID, Code: v.Code}, nil
+}
+
+func writeRedemptionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, discount.ErrInvalidCode), errors.Is(err, voucher.ErrInvalidVoucher), errors.Is(err, voucher.ErrExpired),
+		errors.Is(err, voucher.ErrEmpty), errors.Is(err, voucher.ErrWrongPackage), errors.Is(err, voucher.ErrWrongCurrency):
+		utils.WriteError(w, http.StatusBadRequest, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// sessionPrice returns price of one seat in session, which defaults to base price of activity
+func sessionPrice(session *types.ActivitySession, activity *types.Activity) types.Money {
+	if session.Price != nil {
+		return *session.Price
+	}
+	return activity.BasePrice
+}
+
+// GetGroupSettings godoc
+// @Summary      Get group booking rules of activity
+// @Description  Returns group size limits and group prices. Activities without rules accept groups of any size for session price
+// @Tags         booking
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Success      200  {object}   types.GroupSettings
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid activity ID"
+// @Failure      404  {object}   types.ErrorResponse "activity or group settings not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/group-settings [get]
+func (h *Handler) handleGetGroupSettings(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	if _, err := h.activityCastle.GetVisibleActivityByID(activityID, auth.GetViewerFromContext(r.Context())); err != nil {
+		writeNotFoundError(w, err, "activity not found")
+		return
+	}
+
+	settings, err := h.bookingCastle.GetGroupSettings(activityID)
+	if err != nil {
+		writeNotFoundError(w, err, "group settings not found")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, settings)
+}
+
+// UpdateGroupSettings godoc
+// @Summary      Set group booking rules of activity
+// @Description  Creates or replaces group size limits, adult price, free adults and student price rules of activity
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        payload body types.GroupSettingsPayload true "Group settings"
+// @Success      200  {object}   types.GroupSettings
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "activity not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/group-settings/update [put]
+func (h *Handler) handleUpdateGroupSettings(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	var payload types.GroupSettingsPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	settings := types.GroupSettings{
+		FkActivityID:  activityID,
+		MinGroupSize:  payload.MinGroupSize,
+		MaxGroupSize:  payload.MaxGroupSize,
+		AdultPrice:    payload.AdultPrice,
+		FreeAdultsPer: payload.FreeAdultsPer,
+		PriceRules:    []*types.GroupPriceRule{},
+	}
+	seen := make(map[int]bool)
+	for _, rule := range payload.PriceRules {
+		if seen[rule.MinStudents] {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("duplicate price rule for %d students", rule.MinStudents))
+			return
+		}
+		seen[rule.MinStudents] = true
+		settings.PriceRules = append(settings.PriceRules, &types.GroupPriceRule{MinStudents: rule.MinStudents, StudentPrice: rule.StudentPrice})
+	}
+
+	if _, err := h.userCastle.GetOrganizerByActivityID(activityID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity not found"))
+		return
+	}
+	if !h.organizesActivity(r, activityID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	// Group prices replace session price, so they have to be in currency of activity
+	activity, err := h.activityCastle.GetActivityByID(activityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	currency := activity.BasePrice.Currency
+	if settings.AdultPrice != nil && settings.AdultPrice.Currency != currency {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("adult price has to be in %s like activity price", currency))
+		return
+	}
+	for _, rule := range settings.PriceRules {
+		if rule.StudentPrice.Currency != currency {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("student price has to be in %s like activity price", currency))
+			return
+		}
+	}
+
+	if err := h.bookingCastle.SaveGroupSettings(settings); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	saved, err := h.bookingCastle.GetGroupSettings(activityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, saved)
+}
+
+// getGroupSettings returns group settings of activity or nil when organizer didn't set any
+func (h *Handler) getGroupSettings(activityID int) (*types.GroupSettings, error) {
+	settings, err := h.bookingCastle.GetGroupSettings(activityID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return settings, err
+}
+
+// ListMyBookings godoc
+// @Summary      List bookings of current user
+// @Description  Returns bookings of authenticated user, latest sessions first
+// @Tags         booking
+// @Produce      json
+// @Success      200  {array}    types.Booking
+// @Failure      401  {object}   types.ErrorResponse "unauthorized"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /bookings/my [get]
+func (h *Handler) handleListMyBookings(w http.ResponseWriter, r *http.Request) {
+	bookings, err := h.bookingCastle.ListBookingsByUserID(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no bookings found, return an empty array
+	if len(bookings) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.Booking{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, bookings)
+}
+
+// GetBooking godoc
+// @Summary      Get booking by ID
+// @Description  Booking can be seen by user who made it and organizer of the activity
+// @Tags         booking
+// @Produce      json
+// @Param        bookingID path int true "Booking ID"
+// @Success      200  {object}   types.Booking
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid booking ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "booking not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /bookings/{bookingID} [get]
+func (h *Handler) handleGetBooking(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := strconv.Atoi(mux.Vars(r)["bookingID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid booking ID"))
+		return
+	}
+
+	booking, err := h.bookingCastle.GetBookingByID(bookingID)
+	if err != nil {
+		writeNotFoundError(w, err, "booking not found")
+		return
+	}
+
+	session, err := h.sessionCastle.GetSessionByID(booking.FkActivitySessionID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !auth.CheckOwnership(r, booking.FkUserID) && !h.organizesSession(r, session) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, booking)
+}
+
+// GetTicket godoc
+// @Summary      Get ticket of booking
+// @Description  Returns signed ticket code of confirmed booking together with URL of its QR image, which is scanned at entrance.
+// @Description  Tickets can be seen by user who made booking and organizer of the activity
+// @Tags         booking
+// @Produce      json
+// @Param        bookingID path int true "Booking ID"
+// @Success      200  {object}   types.Ticket
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid booking ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "booking not found"
+// @Failure      409  {object}   types.ErrorResponse "booking is not confirmed"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /bookings/{bookingID}/ticket [get]
+func (h *Handler) handleGetTicket(w http.ResponseWriter, r *http.Request) {
+	booking, ok := h.getTicketBooking(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.Ticket{
+		BookingID: booking.ID,
+		SessionID: booking.FkActivitySessionID,
+		Seats:     booking.Seats,
+		Code:      h.tickets.Sign(booking.ID, booking.FkActivitySessionID),
+		QRCodeURL: fmt.Sprintf("/api/v1/bookings/%d/ticket/qr", booking.ID),
+	})
+}
+
+// GetTicketQR godoc
+// @Summary      Get QR image of ticket
+// @Description  Returns PNG image of QR code holding signed ticket code of confirmed booking
+// @Tags         booking
+// @Produce      png
+// @Param        bookingID path int true "Booking ID"
+// @Success      200  {file}     file
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid booking ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "booking not found"
+// @Failure      409  {object}   types.ErrorResponse "booking is not confirmed"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /bookings/{bookingID}/ticket/qr [get]
+func (h *Handler) handleGetTicketQR(w http.ResponseWriter, r *http.Request) {
+	booking, ok := h.getTicketBooking(w, r)
+	if !ok {
+		return
+	}
+
+	image, err := ticketQR(h.tickets.Sign(booking.ID, booking.FkActivitySessionID))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(image)
+}
+
+// CheckIn godoc
+// @Summary      Check in ticket at entrance
+// @Description  Verifies signature of scanned ticket code and marks its booking as attended. Every ticket is checked in once,
+// @Description  when several staff members scan the same ticket only the first scan succeeds. Available to organizer of the session
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Param        sessionID path int true "Session ID"
+// @Param        payload body types.CheckInPayload true "Scanned ticket code"
+// @Success      200  {object}   types.Attendee
+// @Failure      400  {object}   types.ErrorResponse "invalid payload, ticket code or ticket of another session"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "session or booking not found"
+// @Failure      409  {object}   types.ErrorResponse "already checked in, booking not confirmed or session cancelled"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /sessions/{sessionID}/check-in [post]
+func (h *Handler) handleCheckIn(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := strconv.Atoi(mux.Vars(r)["sessionID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid session ID"))
+		return
+	}
+
+	var payload types.CheckInPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	session, err := h.sessionCastle.GetSessionByID(sessionID)
+	if err != nil {
+		writeNotFoundError(w, err, "session not found")
+		return
+	}
+
+	if !h.organizesSession(r, session) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+	if session.Cancelled {
+		utils.WriteError(w, http.StatusConflict, ErrSessionCancelled)
+		return
+	}
+
+	bookingID, ticketSessionID, err := h.tickets.Verify(payload.Code)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if ticketSessionID != sessionID {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("ticket is for another session"))
+		return
+	}
+
+	err = h.bookingCastle.CheckInBooking(bookingID, auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrAlreadyCheckedIn):
+			h.writeAlreadyCheckedIn(w, bookingID)
+		case errors.Is(err, ErrInvalidTransition):
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("booking isn't confirmed"))
+		default:
+			writeNotFoundError(w, err, "booking not found")
+		}
+		return
+	}
+
+	attendee, err := h.bookingCastle.GetAttendeeByBookingID(bookingID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, attendee)
+}
+
+// writeAlreadyCheckedIn tells staff when ticket was scanned before, so they can tell copied ticket from repeated scan
+func (h *Handler) writeAlreadyCheckedIn(w http.ResponseWriter, bookingID int) {
+	attendee, err := h.bookingCastle.GetAttendeeByBookingID(bookingID)
+	if err != nil || attendee.CheckedInAt == nil {
+		utils.WriteError(w, http.StatusConflict, ErrAlreadyCheckedIn)
+		return
+	}
+
+	utils.WriteError(w, http.StatusConflict, fmt.Errorf("%w at %s", ErrAlreadyCheckedIn, attendee.CheckedInAt.UTC().Format(time.RFC3339)))
+}
+
+// getTicketBooking returns booking from URL, writing error response unless it's confirmed and user made it or organizes it
+func (h *Handler) getTicketBooking(w http.ResponseWriter, r *http.Request) (*types.Booking, bool) {
+	bookingID, err := strconv.Atoi(mux.Vars(r)["bookingID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid booking ID"))
+		return nil, false
+	}
+
+	booking, err := h.bookingCastle.GetBookingByID(bookingID)
+	if err != nil {
+		writeNotFoundError(w, err, "booking not found")
+		return nil, false
+	}
+
+	session, err := h.sessionCastle.GetSessionByID(booking.FkActivitySessionID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	if !auth.CheckOwnership(r, booking.FkUserID) && !h.organizesSession(r, session) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return nil, false
+	}
+
+	// Attended bookings keep their ticket, so it can still be shown after check-in
+	if booking.Status != types.BookingConfirmed && booking.Status != types.BookingAttended {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("booking is %s and has no ticket", booking.Status))
+		return nil, false
+	}
+
+	return booking, true
+}
+
+// ChangeBookingStatus godoc
+// @Summary      Confirm, cancel or mark booking as attended or no-show
+// @Description  Organizers confirm free bookings and mark attendance, paid bookings are confirmed by successful payment. Users can cancel their own bookings until session starts, organizers can cancel any time.
+// @Description  Pending bookings can be confirmed or cancelled, confirmed ones cancelled, attended or no-show. Users get refund by cancellation
+// @Description  policy of activity, bookings cancelled by organizer are refunded fully and no-shows get no-show refund of policy
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Param        bookingID path int    true "Booking ID"
+// @Param        action    path string true "confirm, cancel, attend or no-show"
+// @Param        payload body types.CancelBookingPayload false "Reason of cancellation"
+// @Success      200  {object}   types.Booking
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid booking ID or payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "booking not found"
+// @Failure      409  {object}   types.ErrorResponse "booking status can't be changed"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /bookings/{bookingID}/{action} [put]
+func (h *Handler) handleChangeBookingStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	bookingID, err := strconv.Atoi(vars["bookingID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid booking ID"))
+		return
+	}
+	status := actionStatuses[vars["action"]]
+
+	booking, err := h.bookingCastle.GetBookingByID(bookingID)
+	if err != nil {
+		writeNotFoundError(w, err, "booking not found")
+		return
+	}
+
+	session, err := h.sessionCastle.GetSessionByID(booking.FkActivitySessionID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	organizer := h.organizesSession(r, session)
+	if !organizer {
+		// Users who made booking can only cancel it before session starts
+		if status != types.BookingCancelled || auth.GetUserIDFromContext(r.Context()) != booking.FkUserID {
+			utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+			return
+		}
+		if !session.StartTime.After(time.Now()) {
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("session has already started"))
+			return
+		}
+	}
+	if status == types.BookingConfirmed && booking.Amount.Amount > 0 {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("booking is confirmed once it's paid"))
+		return
+	}
+	if status == types.BookingNoShow && session.StartTime.After(time.Now()) {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("session hasn't started yet"))
+		return
+	}
+
+	if status == types.BookingCancelled || status == types.BookingNoShow {
+		var payload types.CancelBookingPayload
+		if err := utils.ParseJSON(r, &payload); err != nil && err != io.EOF {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := utils.Validate.Struct(payload); err != nil {
+			errors := err.(validator.ValidationErrors)
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+			return
+		}
+
+		cancellation := newCancellation(r, payload)
+		if status == types.BookingNoShow || !organizer {
+			policy, err := h.getCancellationPolicy(session.FkActivityID)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, err)
+				return
+			}
+
+			if status == types.BookingNoShow {
+				cancellation.RefundPercent = noShowRefundPercent(policy)
+			} else {
+				cancellation.RefundPercent = refundPercent(policy, session.StartTime, time.Now())
+			}
+		}
+
+		err = h.bookingCastle.CancelBooking(bookingID, status, cancellation)
+	} else {
+		err = h.bookingCastle.UpdateBookingStatus(bookingID, status)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("booking is %s and can't be changed to %s", booking.Status, status))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	// Freed seats are offered to users on waitlist
+	if status == types.BookingCancelled {
+		h.promoteWaitlist(session.ID)
+	}
+
+	updated, err := h.bookingCastle.GetBookingByID(bookingID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.refund(r, updated)
+
+	utils.WriteJSON(w, http.StatusOK, updated)
+}
+
+// refund pays cancellation refund of booking back, failed refund stays pending and is retried by waitlist sweep
+func (h *Handler) refund(r *http.Request, b *types.Booking) {
+	if b.Cancellation == nil || b.Cancellation.RefundAmount.Amount <= 0 {
+		return
+	}
+
+	if err := h.payments.RefundBooking(r.Context(), b.ID, b.Cancellation.RefundAmount); err != nil {
+		log.Println(color.Format(color.RED, fmt.Sprintf("booking %d: refund failed: %v", b.ID, err)))
+	}
+}
+
+// JoinWaitlist godoc
+// @Summary      Join waitlist of full session
+// @Description  Adds user to waitlist of upcoming session which doesn't have enough free seats. When seats become free,
+// @Description  the first waiting user they are enough for gets pending booking and has to accept it within confirmation window
+// @Tags         waitlist
+// @Accept       json
+// @Produce      json
+// @Param        sessionID path int true "Session ID"
+// @Param        payload body types.BookingPayload true "Number of seats"
+// @Success      201  {object}   types.WaitlistEntry
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload or session already started"
+// @Failure      401  {object}   types.ErrorResponse "unauthorized"
+// @Failure      404  {object}   types.ErrorResponse "session not found"
+// @Failure      409  {object}   types.ErrorResponse "session has free seats or user already booked or waits"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /sessions/{sessionID}/waitlist/join [post]
+func (h *Handler) handleJoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := strconv.Atoi(mux.Vars(r)["sessionID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid session ID"))
+		return
+	}
+
+	var payload types.BookingPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	session, err := h.sessionCastle.GetSessionByID(sessionID)
+	if err == nil {
+		_, err = h.activityCastle.GetVisibleActivityByID(session.FkActivityID, auth.GetViewerFromContext(r.Context()))
+	}
+	if err != nil {
+		writeNotFoundError(w, err, "session not found")
+		return
+	}
+
+	if !session.StartTime.After(time.Now()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("session has already started"))
+		return
+	}
+	if payload.Seats > session.Capacity {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("session has only %d seats", session.Capacity))
+		return
+	}
+
+	entryID, err := h.waitlistCastle.JoinWaitlist(types.WaitlistEntry{
+		FkActivitySessionID: sessionID,
+		FkUserID:            auth.GetUserIDFromContext(r.Context()),
+		Seats:               payload.Seats,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrSeatsAvailable), errors.Is(err, ErrAlreadyBooked), errors.Is(err, ErrAlreadyWaitlisted),
+			errors.Is(err, ErrSessionCancelled):
+			utils.WriteError(w, http.StatusConflict, err)
+		case errors.Is(err, sql.ErrNoRows):
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("session not found"))
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	entry, err := h.waitlistCastle.GetWaitlistEntryByID(int(entryID))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, entry)
+}
+
+// ListMyWaitlist godoc
+// @Summary      List waitlist entries of current user
+// @Description  Returns waitlist entries of authenticated user, newest first
+// @Tags         waitlist
+// @Produce      json
+// @Success      200  {array}    types.WaitlistEntry
+// @Failure      401  {object}   types.ErrorResponse "unauthorized"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /waitlist/my [get]
+func (h *Handler) handleListMyWaitlist(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.waitlistCastle.ListWaitlistByUserID(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no entries found, return an empty array
+	if len(entries) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.WaitlistEntry{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, entries)
+}
+
+// ListSessionWaitlist godoc
+// @Summary      List waitlist of session
+// @Description  Returns users waiting for seats and those with pending offers, in order they joined
+// @Tags         waitlist
+// @Produce      json
+// @Param        sessionID path int true "Session ID"
+// @Success      200  {array}    types.WaitlistEntry
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid session ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "session not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /sessions/{sessionID}/waitlist [get]
+func (h *Handler) handleListSessionWaitlist(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := strconv.Atoi(mux.Vars(r)["sessionID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid session ID"))
+		return
+	}
+
+	session, err := h.sessionCastle.GetSessionByID(sessionID)
+	if err != nil {
+		writeNotFoundError(w, err, "session not found")
+		return
+	}
+
+	if !h.organizesSession(r, session) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	entries, err := h.waitlistCastle.ListWaitlistBySessionID(sessionID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no entries found, return an empty array
+	if len(entries) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.WaitlistEntry{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, entries)
+}
+
+// ChangeWaitlistEntry godoc
+// @Summary      Accept waitlist offer or leave waitlist
+// @Description  Accepting keeps booking offered to user, unpaid booking then has to be paid until its expiresAt.
+// @Description  Leaving releases offered seats, which are passed to the next waiting user
+// @Tags         waitlist
+// @Produce      json
+// @Param        entryID path int    true "Waitlist entry ID"
+// @Param        action  path string true "accept or leave"
+// @Success      200  {object}   types.WaitlistEntry
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid waitlist entry ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "waitlist entry not found"
+// @Failure      409  {object}   types.ErrorResponse "entry can't be changed or offer expired"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /waitlist/{entryID}/{action} [put]
+func (h *Handler) handleChangeWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	entryID, err := strconv.Atoi(vars["entryID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid waitlist entry ID"))
+		return
+	}
+
+	entry, err := h.waitlistCastle.GetWaitlistEntryByID(entryID)
+	if err != nil {
+		writeNotFoundError(w, err, "waitlist entry not found")
+		return
+	}
+
+	if !auth.CheckOwnership(r, entry.FkUserID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	if vars["action"] == "accept" {
+		err = h.waitlistCastle.AcceptWaitlistOffer(entryID, h.waitlist.PaymentDeadline(time.Now()))
+	} else {
+		err = h.waitlistCastle.LeaveWaitlist(entryID)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrOfferExpired):
+			utils.WriteError(w, http.StatusConflict, err)
+		case errors.Is(err, ErrInvalidTransition):
+			utils.WriteError(w, http.StatusConflict, fmt.Errorf("waitlist entry is %s and can't be changed", entry.Status))
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if vars["action"] == "leave" && entry.Status == types.WaitlistOffered {
+		h.promoteWaitlist(entry.FkActivitySessionID)
+	}
+
+	updated, err := h.waitlistCastle.GetWaitlistEntryByID(entryID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, updated)
+}
+
+// CancelSession godoc
+// @Summary      Cancel activity session
+// @Description  Cancels session with all its bookings, which are refunded fully. Waiting users are removed from waitlist and
+// @Description  every user with booking is notified. Cancelled session can't be booked anymore
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Param        sessionID path int true "Session ID"
+// @Param        payload body types.CancelBookingPayload false "Reason of cancellation"
+// @Success      200  {array}    types.Booking
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid session ID or payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "session not found"
+// @Failure      409  {object}   types.ErrorResponse "session is already cancelled"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /sessions/{sessionID}/cancel [post]
+func (h *Handler) handleCancelSession(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := strconv.Atoi(mux.Vars(r)["sessionID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid session ID"))
+		return
+	}
+
+	var payload types.CancelBookingPayload
+	if err := utils.ParseJSON(r, &payload); err != nil && err != io.EOF {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	session, err := h.sessionCastle.GetSessionByID(sessionID)
+	if err != nil {
+		writeNotFoundError(w, err, "session not found")
+		return
+	}
+
+	if !h.organizesSession(r, session) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	bookings, err := h.bookingCastle.CancelSession(sessionID, newCancellation(r, payload))
+	if err != nil {
+		if errors.Is(err, ErrSessionCancelled) {
+			utils.WriteError(w, http.StatusConflict, err)
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	message := fmt.Sprintf("Session on %s was cancelled by organizer and your booking was cancelled with full refund",
+		session.StartTime.In(h.waitlist.loc).Format("2006-01-02 15:04"))
+	if payload.Reason != "" {
+		message += ". Reason: " + payload.Reason
+	}
+
+	cancelled := []*types.Booking{}
+	for _, b := range bookings {
+		_, err := h.notificationCastle.CreateNotification(types.Notification{
+			FkUserID:   b.FkUserID,
+			Type:       "session_cancelled",
+			Message:    message,
+			EntityType: types.EntityTypeBooking,
+			EntityFk:   b.ID,
+		})
+		if err != nil {
+			log.Println(color.Format(color.RED, fmt.Sprintf("booking %d: failed to notify user: %v", b.ID, err)))
+		}
+
+		// Session is already cancelled, so remaining bookings are processed even when one of them fails
+		updated, err := h.bookingCastle.GetBookingByID(b.ID)
+		if err != nil {
+			log.Println(color.Format(color.RED, fmt.Sprintf("booking %d: failed to load cancelled booking for refund: %v", b.ID, err)))
+			cancelled = append(cancelled, b)
+			continue
+		}
+		h.refund(r, updated)
+		cancelled = append(cancelled, updated)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, cancelled)
+}
+
+// newCancellation records current user as the one who cancelled
+func newCancellation(r *http.Request, payload types.CancelBookingPayload) types.BookingCancellation {
+	userID := auth.GetUserIDFromContext(r.Context())
+	cancellation := types.BookingCancellation{FkUserID: &userID, RefundPercent: 100}
+	if payload.Reason != "" {
+		cancellation.Reason = &payload.Reason
+	}
+
+	return cancellation
+}
+
+// GetCancellationPolicy godoc
+// @Summary      Get cancellation policy of activity
+// @Description  Returns policy of activity or, when it has none, policy of its package
+// @Tags         booking
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Success      200  {object}   types.CancellationPolicy
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid activity ID"
+// @Failure      404  {object}   types.ErrorResponse "cancellation policy not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/cancellation-policy [get]
+func (h *Handler) handleGetCancellationPolicy(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	policy, err := h.bookingCastle.GetCancellationPolicy(activityID)
+	if err != nil {
+		writeNotFoundError(w, err, "cancellation policy not found")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, policy)
+}
+
+// UpdateCancellationPolicy godoc
+// @Summary      Set cancellation policy of activity
+// @Description  Creates or replaces policy of activity. Cancellations made at least free cancellation hours before session are
+// @Description  refunded fully, later ones by tier with the most days they still meet
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        payload body types.CancellationPolicyPayload true "Cancellation policy"
+// @Success      200  {object}   types.CancellationPolicy
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "activity not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/cancellation-policy/update [put]
+func (h *Handler) handleUpdateCancellationPolicy(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	policy, ok := parseCancellationPolicy(w, r)
+	if !ok {
+		return
+	}
+	policy.FkActivityID = &activityID
+
+	if _, err := h.userCastle.GetOrganizerByActivityID(activityID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity not found"))
+		return
+	}
+	if !h.organizesActivity(r, activityID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	if err := h.bookingCastle.SaveCancellationPolicy(*policy); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	saved, err := h.bookingCastle.GetCancellationPolicy(activityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, saved)
+}
+
+// GetPackageCancellationPolicy godoc
+// @Summary      Get cancellation policy of package
+// @Description  Returns policy applied to activities of package which don't have their own
+// @Tags         booking
+// @Produce      json
+// @Param        packageID path int true "Package ID"
+// @Success      200  {object}   types.CancellationPolicy
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid package ID"
+// @Failure      404  {object}   types.ErrorResponse "cancellation policy not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /packages/{packageID}/cancellation-policy [get]
+func (h *Handler) handleGetPackageCancellationPolicy(w http.ResponseWriter, r *http.Request) {
+	packageID, err := strconv.Atoi(mux.Vars(r)["packageID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid package ID"))
+		return
+	}
+
+	policy, err := h.bookingCastle.GetPackageCancellationPolicy(packageID)
+	if err != nil {
+		writeNotFoundError(w, err, "cancellation policy not found")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, policy)
+}
+
+// UpdatePackageCancellationPolicy godoc
+// @Summary      Set cancellation policy of package
+// @Description  Creates or replaces policy used by activities of package which don't have their own
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Param        packageID path int true "Package ID"
+// @Param        payload body types.CancellationPolicyPayload true "Cancellation policy"
+// @Success      200  {object}   types.CancellationPolicy
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "package not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /packages/{packageID}/cancellation-policy/update [put]
+func (h *Handler) handleUpdatePackageCancellationPolicy(w http.ResponseWriter, r *http.Request) {
+	packageID, err := strconv.Atoi(mux.Vars(r)["packageID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid package ID"))
+		return
+	}
+
+	policy, ok := parseCancellationPolicy(w, r)
+	if !ok {
+		return
+	}
+	policy.FkPackageID = &packageID
+
+	pkg, err := h.activityCastle.GetPackageByID(packageID)
+	if err != nil {
+		writeNotFoundError(w, err, "package not found")
+		return
+	}
+	if !auth.CheckOwnership(r, pkg.FkOrganizerID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	if err := h.bookingCastle.SaveCancellationPolicy(*policy); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	saved, err := h.bookingCastle.GetPackageCancellationPolicy(packageID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, saved)
+}
+
+// parseCancellationPolicy reads policy from request body, writing error response when it's invalid
+func parseCancellationPolicy(w http.ResponseWriter, r *http.Request) (*types.CancellationPolicy, bool) {
+	var payload types.CancellationPolicyPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return nil, false
+	}
+
+	policy := &types.CancellationPolicy{
+		FreeCancellationHours: payload.FreeCancellationHours,
+		NoShowRefundPercent:   payload.NoShowRefundPercent,
+		Tiers:                 []*types.RefundTier{},
+	}
+	seen := make(map[int]bool)
+	for _, tier := range payload.Tiers {
+		if seen[tier.DaysBefore] {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("duplicate refund tier for %d days", tier.DaysBefore))
+			return nil, false
+		}
+		seen[tier.DaysBefore] = true
+		policy.Tiers = append(policy.Tiers, &types.RefundTier{DaysBefore: tier.DaysBefore, RefundPercent: tier.RefundPercent})
+	}
+
+	return policy, true
+}
+
+// getCancellationPolicy returns policy applied to activity or nil when neither activity nor its package has one
+func (h *Handler) getCancellationPolicy(activityID int) (*types.CancellationPolicy, error) {
+	policy, err := h.bookingCastle.GetCancellationPolicy(activityID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return policy, err
+}
+
+// promoteWaitlist offers freed seats right away, failure is left for the next sweep
+func (h *Handler) promoteWaitlist(sessionID int) {
+	if err := h.waitlist.Promote(sessionID); err != nil {
+		log.Println(color.Format(color.RED, fmt.Sprintf("session %d: waitlist promotion failed: %v", sessionID, err)))
+	}
+}
+
+// ListAttendees godoc
+// @Summary      List attendees of session
+// @Description  Returns bookings of session which are not cancelled together with contacts of users, in order they were made
+// @Tags         booking
+// @Produce      json
+// @Param        sessionID path int true "Session ID"
+// @Success      200  {array}    types.Attendee
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid session ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "session not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /sessions/{sessionID}/attendees [get]
+func (h *Handler) handleListAttendees(w http.ResponseWriter, r *http.Request) {
+	attendees, ok := h.getAttendees(w, r)
+	if !ok {
+		return
+	}
+
+	// If no attendees found, return an empty array
+	if len(attendees) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.Attendee{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, attendees)
+}
+
+// ExportAttendees godoc
+// @Summary      Export attendees of session as CSV
+// @Description  Returns the same attendee list as CSV file
+// @Tags         booking
+// @Produce      text/csv
+// @Param        sessionID path int true "Session ID"
+// @Success      200  {file}     file
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid session ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "session not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /sessions/{sessionID}/attendees/export [get]
+func (h *Handler) handleExportAttendees(w http.ResponseWriter, r *http.Request) {
+	attendees, ok := h.getAttendees(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"session-%s-attendees.csv\"", mux.Vars(r)["sessionID"]))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"booking_id", "user_id", "username", "email", "seats", "status", "group", "booked_at", "checked_in_at"})
+	for _, a := range attendees {
+		groupName := ""
+		if a.GroupName != nil {
+			groupName = *a.GroupName
+		}
+		checkedInAt := ""
+		if a.CheckedInAt != nil {
+			checkedInAt = a.CheckedInAt.UTC().Format(time.RFC3339)
+		}
+		writer.Write([]string{
+			strconv.Itoa(a.BookingID),
+			strconv.Itoa(a.UserID),
+			a.Username,
+			a.Email,
+			strconv.Itoa(a.Seats),
+			a.Status,
+			groupName,
+			a.CreatedAt.UTC().Format(time.RFC3339),
+			checkedInAt,
+		})
+	}
+	writer.Flush()
+}
+
+// getAttendees returns attendees of session from URL, writing error response unless user organizes it
+func (h *Handler) getAttendees(w http.ResponseWriter, r *http.Request) ([]*types.Attendee, bool) {
+	sessionID, err := strconv.Atoi(mux.Vars(r)["sessionID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid session ID"))
+		return nil, false
+	}
+
+	session, err := h.sessionCastle.GetSessionByID(sessionID)
+	if err != nil {
+		writeNotFoundError(w, err, "session not found")
+		return nil, false
+	}
+
+	if !h.organizesSession(r, session) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return nil, false
+	}
+
+	attendees, err := h.bookingCastle.ListAttendeesBySessionID(sessionID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+
+	return attendees, true
+}
+
+// organizesSession reports whether user is administrator or organizer of session activity
+func (h *Handler) organizesSession(r *http.Request, session *types.ActivitySession) bool {
+	return h.organizesActivity(r, session.FkActivityID)
+}
+
+func (h *Handler) organizesActivity(r *http.Request, activityID int) bool {
+	organizer, err := h.userCastle.GetOrganizerByActivityID(activityID)
+	if err != nil {
+		return false
+	}
+
+	return auth.CheckOwnership(r, organizer.ID)
+}
+
+func writeNotFoundError(w http.ResponseWriter, err error, message string) {
+	if err == sql.ErrNoRows {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("%s", message))
+	} else {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
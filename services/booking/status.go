@@ -0,0 +1,18 @@
+package booking
+
+import "educations-castle/types"
+
+// Statuses booking can move into from its current status, cancelled, attended and no-show bookings are final
+var transitions = map[string][]string{
+	types.BookingPending:   {types.BookingConfirmed, types.BookingCancelled},
+	types.BookingConfirmed: {types.BookingCancelled, types.BookingAttended, types.BookingNoShow},
+}
+
+func CanTransition(from, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package booking
+
+import (
+	"educations-castle/types"
+	"testing"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{types.BookingPending, types.BookingConfirmed, true},
+		{types.BookingPending, types.BookingCancelled, true},
+		{types.BookingPending, types.BookingAttended, false},
+		{types.BookingConfirmed, types.BookingAttended, true},
+		{types.BookingConfirmed, types.BookingCancelled, true},
+		{types.BookingConfirmed, types.BookingPending, false},
+		{types.BookingCancelled, types.BookingConfirmed, false},
+		{types.BookingAttended, types.BookingCancelled, false},
+	}
+
+	for _, test := range tests {
+		if got := CanTransition(test.from, test.to); got != test.allowed {
+			t.Errorf("%s -> %s: expected %v, got %v", test.from, test.to, test.allowed, got)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package booking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	ticketVersion = "T1"
+
+	// Half of HMAC-SHA256 is plenty against forging and keeps QR code easy to scan on small screens
+	ticketSignatureSize = 16
+	ticketQRSize        = 320
+)
+
+var ErrInvalidTicket = errors.New("invalid ticket code")
+
+// TicketSigner signs ticket codes of bookings. Code names booking and its session in plain text, so staff can
+// read it when scanning fails, and signature makes any change to them evident
+type TicketSigner struct {
+	secret []byte
+}
+
+// NewTicketSigner creates signer with secret of TICKET_SIGNING_SECRET, which has no default as anyone knowing it
+// can check in bookings of other users
+func NewTicketSigner(secret string) (*TicketSigner, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("TICKET_SIGNING_SECRET is required")
+	}
+
+	return &TicketSigner{secret: []byte(secret)}, nil
+}
+
+// Sign returns ticket code of booking in session, e.g. T1.42.7.Xk3...
+func (s *TicketSigner) Sign(bookingID, sessionID int) string {
+	payload := fmt.Sprintf("%s.%d.%d", ticketVersion, bookingID, sessionID)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.signature(payload))
+}
+
+// Verify checks signature of ticket code and returns booking and session it was issued for
+func (s *TicketSigner) Verify(code string) (int, int, error) {
+	code = strings.TrimSpace(code)
+	i := strings.LastIndex(code, ".")
+	if i < 0 {
+		return 0, 0, ErrInvalidTicket
+	}
+	payload := code[:i]
+
+	signature, err := base64.RawURLEncoding.DecodeString(code[i+1:])
+	if err != nil || !hmac.Equal(signature, s.signature(payload)) {
+		return 0, 0, ErrInvalidTicket
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 || parts[0] != ticketVersion {
+		return 0, 0, ErrInvalidTicket
+	}
+	bookingID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, ErrInvalidTicket
+	}
+	sessionID, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return 0, 0, ErrInvalidTicket
+	}
+
+	return bookingID, sessionID, nil
+}
+
+func (s *TicketSigner) signature(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)[:ticketSignatureSize]
+}
+
+// ticketQR renders ticket code as PNG image of QR code
+func ticketQR(code string) ([]byte, error) {
+	return qrcode.Encode(code, qrcode.Medium, ticketQRSize)
+}
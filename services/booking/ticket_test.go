@@ -0,0 +1,62 @@
+package booking
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTicketSigner(t *testing.T) {
+	signer, err := NewTicketSigner("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := signer.Sign(42, 7)
+
+	t.Run("Should refuse empty secret", func(t *testing.T) {
+		if _, err := NewTicketSigner(""); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("Should verify signed code", func(t *testing.T) {
+		bookingID, sessionID, err := signer.Verify(code)
+		if err != nil || bookingID != 42 || sessionID != 7 {
+			t.Errorf("expected booking 42 of session 7, got %d, %d, %v", bookingID, sessionID, err)
+		}
+	})
+
+	t.Run("Should reject changed code", func(t *testing.T) {
+		for _, tampered := range []string{
+			strings.Replace(code, "T1.42.", "T1.43.", 1),
+			strings.Replace(code, ".7.", ".8.", 1),
+			code[:len(code)-1],
+			"T1.42.7",
+			"",
+		} {
+			if _, _, err := signer.Verify(tampered); err != ErrInvalidTicket {
+				t.Errorf("%s: expected invalid ticket, got %v", tampered, err)
+			}
+		}
+	})
+
+	t.Run("Should reject code signed with another secret", func(t *testing.T) {
+		other, err := NewTicketSigner("other")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := other.Verify(code); err != ErrInvalidTicket {
+			t.Errorf("expected invalid ticket, got %v", err)
+		}
+	})
+
+	t.Run("Should render code as PNG", func(t *testing.T) {
+		image, err := ticketQR(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(image, []byte("\x89PNG")) {
+			t.Errorf("expected PNG image")
+		}
+	})
+}
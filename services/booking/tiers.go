@@ -0,0 +1,54 @@
+package booking
+
+import (
+	"educations-castle/types"
+	"fmt"
+)
+
+// maxTierSeats limits seats of booking made by price tiers like seats of plain booking
+const maxTierSeats = 50
+
+// quoteTiers prices quantities of price tiers booked in session and counts seats they take
+func quoteTiers(tiers []*types.PriceTier, items []types.BookingTierPayload) ([]*types.BookingTier, int, types.Money, error) {
+	offered := make(map[int]*types.PriceTier, len(tiers))
+	for _, t := range tiers {
+		offered[t.ID] = t
+	}
+
+	seen := make(map[int]bool, len(items))
+	booked := []*types.BookingTier{}
+	seats := 0
+	var total types.Money
+	for _, item := range items {
+		tier, ok := offered[item.PriceTierID]
+		if !ok {
+			return nil, 0, total, fmt.Errorf("price tier %d is not offered in session", item.PriceTierID)
+		}
+		if seen[tier.ID] {
+			return nil, 0, total, fmt.Errorf("price tier %d is booked twice", tier.ID)
+		}
+		seen[tier.ID] = true
+
+		id := tier.ID
+		line := &types.BookingTier{
+			FkPriceTierID: &id,
+			Name:          tier.Name,
+			Quantity:      item.Quantity,
+			Seats:         tier.Seats,
+			UnitPrice:     tier.Price,
+			Total:         tier.Price.Mul(item.Quantity),
+		}
+		if len(booked) == 0 {
+			total = types.Money{Currency: tier.Price.Currency}
+		}
+		total = total.Add(line.Total)
+		seats += tier.Seats * item.Quantity
+		booked = append(booked, line)
+	}
+
+	if seats > maxTierSeats {
+		return nil, 0, total, fmt.Errorf("booking can't have more than %d seats", maxTierSeats)
+	}
+
+	return booked, seats, total, nil
+}
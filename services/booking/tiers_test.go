@@ -0,0 +1,43 @@
+package booking
+
+import (
+	"educations-castle/types"
+	"testing"
+)
+
+func TestQuoteTiers(t *testing.T) {
+	tiers := []*types.PriceTier{
+		{ID: 1, Name: "Adult", Price: types.Money{Amount: 1200, Currency: "EUR"}, Seats: 1},
+		{ID: 2, Name: "Child", Price: types.Money{Amount: 600, Currency: "EUR"}, Seats: 1},
+		{ID: 3, Name: "Family", Price: types.Money{Amount: 3000, Currency: "EUR"}, Seats: 4},
+	}
+
+	t.Run("Should sum prices and seats of tiers", func(t *testing.T) {
+		booked, seats, total, err := quoteTiers(tiers, []types.BookingTierPayload{
+			{PriceTierID: 3, Quantity: 1},
+			{PriceTierID: 2, Quantity: 2},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if seats != 6 || total.String() != "42.00 EUR" {
+			t.Errorf("unexpected quote %d seats, %v", seats, total)
+		}
+		if len(booked) != 2 || booked[1].Name != "Child" || booked[1].Total.Amount != 1200 {
+			t.Errorf("unexpected tiers %+v", booked)
+		}
+	})
+
+	t.Run("Should reject unknown and repeated tiers", func(t *testing.T) {
+		for name, items := range map[string][]types.BookingTierPayload{
+			"unknown":  {{PriceTierID: 4, Quantity: 1}},
+			"repeated": {{PriceTierID: 1, Quantity: 1}, {PriceTierID: 1, Quantity: 2}},
+			"too many": {{PriceTierID: 3, Quantity: 13}},
+		} {
+			if _, _, _, err := quoteTiers(tiers, items); err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+	})
+}
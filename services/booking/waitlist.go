@@ -0,0 +1,217 @@
+package booking
+
+import (
+	"context"
+	"educations-castle/services/payment"
+	"educations-castle/types"
+	"educations-castle/utils/color"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Waitlist promotes waiting users when seats become free and releases offers and unpaid bookings which weren't
+// accepted or paid in time. Its sweep also retries refunds which failed before
+type Waitlist struct {
+	bookingCastle      types.BookingCastle
+	waitlistCastle     types.WaitlistCastle
+	notificationCastle types.NotificationCastle
+	payments           *payment.Gateway
+	window             time.Duration
+	paymentWindow      time.Duration
+	loc                *time.Location
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewWaitlist creates waitlist giving promoted users window to accept offer and paymentWindow to pay for booking,
+// times in notifications are shown in loc
+func NewWaitlist(bookingCastle types.BookingCastle, waitlistCastle types.WaitlistCastle, notificationCastle types.NotificationCastle,
+	payments *payment.Gateway, window, paymentWindow time.Duration, loc *time.Location) *Waitlist {
+	return &Waitlist{
+		bookingCastle:      bookingCastle,
+		waitlistCastle:     waitlistCastle,
+		notificationCastle: notificationCastle,
+		payments:           payments,
+		window:             window,
+		paymentWindow:      paymentWindow,
+		loc:                loc,
+	}
+}
+
+// PaymentDeadline returns time until which booking made at now has to be paid
+func (wl *Waitlist) PaymentDeadline(now time.Time) time.Time {
+	return now.Add(wl.paymentWindow)
+}
+
+// Start sweeps expired offers every interval until Stop is called
+func (wl *Waitlist) Start(interval time.Duration) {
+	wl.stop = make(chan struct{})
+	wl.wg.Add(1)
+
+	go func() {
+		defer wl.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-wl.stop:
+				return
+			case <-ticker.C:
+				if err := wl.Sweep(time.Now()); err != nil {
+					log.Println(color.Format(color.RED, fmt.Sprintf("waitlist sweep failed: %v", err)))
+				}
+			}
+		}
+	}()
+}
+
+func (wl *Waitlist) Stop() {
+	if wl.stop == nil {
+		return
+	}
+	close(wl.stop)
+	wl.wg.Wait()
+	wl.stop = nil
+}
+
+// Promote offers free seats of session to waiting users and notifies them
+func (wl *Waitlist) Promote(sessionID int) error {
+	promoted, err := wl.waitlistCastle.PromoteWaitlist(sessionID, wl.window)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range promoted {
+		wl.notify(e, fmt.Sprintf("%d seat(s) became available in session you are waiting for, accept them until %s",
+			e.Seats, e.OfferExpiresAt.In(wl.loc).Format("2006-01-02 15:04")))
+	}
+
+	return nil
+}
+
+// Sweep cancels bookings which weren't paid until now and expires offers which weren't accepted, their seats are
+// passed to the next users. Sessions with free seats are promoted as well, which covers seats freed by capacity changes.
+// Failed refunds are retried last
+func (wl *Waitlist) Sweep(now time.Time) error {
+	unpaid, err := wl.bookingCastle.ExpireBookingHolds(now)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range unpaid {
+		_, err := wl.notificationCastle.CreateNotification(types.Notification{
+			FkUserID:   b.FkUserID,
+			Type:       "booking_expired",
+			Message:    "Booking wasn't paid in time and was cancelled, its seats were released",
+			EntityType: types.EntityTypeBooking,
+			EntityFk:   b.ID,
+		})
+		if err != nil {
+			log.Println(color.Format(color.RED, fmt.Sprintf("booking %d: failed to notify user: %v", b.ID, err)))
+		}
+	}
+
+	expired, err := wl.waitlistCastle.ExpireWaitlistOffers(now)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range expired {
+		wl.notify(e, "Offer of seats from waitlist expired and seats were passed to the next user")
+	}
+
+	sessionIDs, err := wl.waitlistCastle.ListWaitlistedSessionIDs()
+	if err != nil {
+		return err
+	}
+
+	for _, sessionID := range sessionIDs {
+		if err := wl.Promote(sessionID); err != nil {
+			log.Println(color.Format(color.RED, fmt.Sprintf("session %d: waitlist promotion failed: %v", sessionID, err)))
+		}
+	}
+
+	return wl.RetryRefunds(context.Background())
+}
+
+// RetryRefunds repeats refunds of cancelled bookings which provider didn't confirm, they stay pending until it does
+func (wl *Waitlist) RetryRefunds(ctx context.Context) error {
+	bookings, err := wl.bookingCastle.ListPendingRefunds()
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bookings {
+		if err := wl.payments.RefundBooking(ctx, b.ID, b.Cancellation.RefundAmount); err != nil {
+			log.Println(color.Format(color.RED, fmt.Sprintf("booking %d: refund failed again: %v", b.ID, err)))
+		}
+	}
+
+	return nil
+}
+
+// notify informs user about change of their waitlist entry, failure doesn't revert the change
+func (wl *Waitlist) notify(e *types.WaitlistEntry, message string) {
+	_, err := wl.notificationCastle.CreateNotification(types.Notification{
+		FkUserID:   e.FkUserID,
+		Type:       "waitlist_" + e.Status,
+		Message:    message,
+		EntityType: types.EntityTypeWaitlist,
+		EntityFk:   e.ID,
+	})
+	if err != nil {
+		log.Println(color.Format(color.RED, fmt.Sprintf("waitlist entry %d: failed to notify user: %v", e.ID, err)))
+	}
+}
+
+// pickWaitlistOffers walks waiting entries in the order users joined and picks those whose seats fit into
+// free seats. Entries asking for more seats than are left are skipped, so they don't block smaller requests
+// behind them. Entries of users who already booked the session are returned as left instead
+func pickWaitlistOffers(waiting []*types.WaitlistEntry, free int,
+	hasBooking func(userID int) (bool, error)) ([]*types.WaitlistEntry, []*types.WaitlistEntry, error) {
+	var offers, left []*types.WaitlistEntry
+	for _, e := range waiting {
+		if free == 0 {
+			break
+		}
+		if e.Seats > free {
+			continue
+		}
+
+		booked, err := hasBooking(e.FkUserID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if booked {
+			left = append(left, e)
+			continue
+		}
+
+		offers = append(offers, e)
+		free -= e.Seats
+	}
+
+	return offers, left, nil
+}
+
+// checkOfferAcceptable returns ErrInvalidTransition unless entry has an offer and ErrOfferExpired when
+// seats of the offer were released. Offers whose booking was confirmed meanwhile stay acceptable after
+// their deadline, like sweep treats them as accepted
+func checkOfferAcceptable(status string, expiresAt *time.Time, bookingStatus *string, now time.Time) error {
+	if status != types.WaitlistOffered {
+		return ErrInvalidTransition
+	}
+	if bookingStatus == nil || *bookingStatus == types.BookingCancelled {
+		return ErrOfferExpired
+	}
+	if *bookingStatus == types.BookingPending && (expiresAt == nil || !expiresAt.After(now)) {
+		return ErrOfferExpired
+	}
+
+	return nil
+}
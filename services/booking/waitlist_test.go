@@ -0,0 +1,355 @@
+package booking
+
+import (
+	"educations-castle/services/payment"
+	"educations-castle/types"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPickWaitlistOffers(t *testing.T) {
+	noBookings := func(int) (bool, error) { return false, nil }
+
+	t.Run("Should offer seats in order users joined", func(t *testing.T) {
+		waiting := []*types.WaitlistEntry{{ID: 1, FkUserID: 1, Seats: 2}, {ID: 2, FkUserID: 2, Seats: 1}, {ID: 3, FkUserID: 3, Seats: 1}}
+
+		offers, left, err := pickWaitlistOffers(waiting, 3, noBookings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := entryIDs(offers); ids != "1,2" || len(left) != 0 {
+			t.Errorf("expected offers to entries 1,2, got %s", ids)
+		}
+	})
+
+	t.Run("Should skip users asking for more seats than are free", func(t *testing.T) {
+		waiting := []*types.WaitlistEntry{{ID: 1, FkUserID: 1, Seats: 5}, {ID: 2, FkUserID: 2, Seats: 2}, {ID: 3, FkUserID: 3, Seats: 2}, {ID: 4, FkUserID: 4, Seats: 1}}
+
+		offers, _, err := pickWaitlistOffers(waiting, 3, noBookings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := entryIDs(offers); ids != "2,4" {
+			t.Errorf("expected offers to entries 2,4, got %s", ids)
+		}
+	})
+
+	t.Run("Should pass seats of users who booked meanwhile to next users", func(t *testing.T) {
+		waiting := []*types.WaitlistEntry{{ID: 1, FkUserID: 1, Seats: 2}, {ID: 2, FkUserID: 2, Seats: 2}}
+		hasBooking := func(userID int) (bool, error) { return userID == 1, nil }
+
+		offers, left, err := pickWaitlistOffers(waiting, 2, hasBooking)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := entryIDs(offers); ids != "2" {
+			t.Errorf("expected offer to entry 2, got %s", ids)
+		}
+		if ids := entryIDs(left); ids != "1" {
+			t.Errorf("expected entry 1 to leave, got %s", ids)
+		}
+	})
+
+	t.Run("Should fail if booking check fails", func(t *testing.T) {
+		waiting := []*types.WaitlistEntry{{ID: 1, FkUserID: 1, Seats: 1}}
+		hasBooking := func(int) (bool, error) { return false, fmt.Errorf("connection lost") }
+
+		if _, _, err := pickWaitlistOffers(waiting, 1, hasBooking); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func TestCheckOfferAcceptable(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	later, earlier := now.Add(time.Hour), now.Add(-time.Hour)
+	status := func(s string) *string { return &s }
+
+	tests := []struct {
+		name          string
+		status        string
+		expiresAt     *time.Time
+		bookingStatus *string
+		expected      error
+	}{
+		{"valid offer", types.WaitlistOffered, &later, status(types.BookingPending), nil},
+		{"expired offer", types.WaitlistOffered, &earlier, status(types.BookingPending), ErrOfferExpired},
+		{"cancelled booking", types.WaitlistOffered, &later, status(types.BookingCancelled), ErrOfferExpired},
+		{"missing booking", types.WaitlistOffered, &later, nil, ErrOfferExpired},
+		{"booking confirmed before deadline", types.WaitlistOffered, &later, status(types.BookingConfirmed), nil},
+		{"booking confirmed after deadline", types.WaitlistOffered, &earlier, status(types.BookingConfirmed), nil},
+		{"entry still waiting", types.WaitlistWaiting, nil, nil, ErrInvalidTransition},
+		{"offer already accepted", types.WaitlistAccepted, &later, status(types.BookingConfirmed), ErrInvalidTransition},
+	}
+
+	for _, test := range tests {
+		if err := checkOfferAcceptable(test.status, test.expiresAt, test.bookingStatus, now); err != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, err)
+		}
+	}
+}
+
+func TestWaitlist(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Vilnius")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Should notify promoted users about offer deadline", func(t *testing.T) {
+		waitlistCastle := &mockWaitlistCastle{promoted: map[int][]*types.WaitlistEntry{
+			1: {
+				{ID: 1, FkUserID: 5, Seats: 2, Status: types.WaitlistOffered, OfferExpiresAt: &expiresAt},
+				{ID: 2, FkUserID: 6, Seats: 1, Status: types.WaitlistOffered, OfferExpiresAt: &expiresAt},
+			},
+		}}
+		notificationCastle := &mockNotificationCastle{}
+		waitlist := NewWaitlist(&mockBookingCastle{}, waitlistCastle, notificationCastle, nil, time.Hour, 30*time.Minute, loc)
+
+		if err := waitlist.Promote(1); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(waitlistCastle.windows) != 1 || waitlistCastle.windows[0] != time.Hour {
+			t.Errorf("expected promotion with window of an hour, got %v", waitlistCastle.windows)
+		}
+		if len(notificationCastle.notifications) != 2 {
+			t.Fatalf("expected 2 notifications, got %d", len(notificationCastle.notifications))
+		}
+		n := notificationCastle.notifications[0]
+		if n.FkUserID != 5 || n.Type != "waitlist_offered" || n.EntityType != types.EntityTypeWaitlist || n.EntityFk != 1 {
+			t.Errorf("unexpected notification %+v", n)
+		}
+		// Deadline is shown in local time
+		if !strings.Contains(n.Message, "2 seat(s)") || !strings.Contains(n.Message, "2025-01-15 12:00") {
+			t.Errorf("unexpected message %q", n.Message)
+		}
+	})
+
+	t.Run("Should expire offers and promote waitlisted sessions", func(t *testing.T) {
+		waitlistCastle := &mockWaitlistCastle{
+			expired:    []*types.WaitlistEntry{{ID: 3, FkUserID: 7, Status: types.WaitlistExpired}},
+			sessionIDs: []int{1, 2},
+			promoted: map[int][]*types.WaitlistEntry{
+				2: {{ID: 4, FkUserID: 8, Seats: 1, Status: types.WaitlistOffered, OfferExpiresAt: &expiresAt}},
+			},
+		}
+		notificationCastle := &mockNotificationCastle{}
+		waitlist := NewWaitlist(&mockBookingCastle{}, waitlistCastle, notificationCastle, nil, time.Hour, 30*time.Minute, loc)
+
+		now := time.Now()
+		if err := waitlist.Sweep(now); err != nil {
+			t.Fatal(err)
+		}
+
+		if !waitlistCastle.expiredAt.Equal(now) {
+			t.Errorf("expected offers to be expired at %v, got %v", now, waitlistCastle.expiredAt)
+		}
+		if ids := fmt.Sprint(waitlistCastle.promotedSessions); ids != "[1 2]" {
+			t.Errorf("expected sessions 1 and 2 to be promoted, got %s", ids)
+		}
+		if len(notificationCastle.notifications) != 2 {
+			t.Fatalf("expected 2 notifications, got %d", len(notificationCastle.notifications))
+		}
+		if n := notificationCastle.notifications[0]; n.FkUserID != 7 || n.Type != "waitlist_expired" {
+			t.Errorf("unexpected expiry notification %+v", n)
+		}
+		if n := notificationCastle.notifications[1]; n.FkUserID != 8 || n.Type != "waitlist_offered" {
+			t.Errorf("unexpected offer notification %+v", n)
+		}
+	})
+
+	t.Run("Should cancel unpaid bookings before promoting their sessions", func(t *testing.T) {
+		bookingCastle := &mockBookingCastle{unpaid: []*types.Booking{{ID: 9, FkUserID: 7, FkActivitySessionID: 1, Status: types.BookingCancelled}}}
+		waitlistCastle := &mockWaitlistCastle{
+			sessionIDs: []int{1},
+			promoted: map[int][]*types.WaitlistEntry{
+				1: {{ID: 4, FkUserID: 8, Seats: 1, Status: types.WaitlistOffered, OfferExpiresAt: &expiresAt}},
+			},
+		}
+		notificationCastle := &mockNotificationCastle{}
+		waitlist := NewWaitlist(bookingCastle, waitlistCastle, notificationCastle, nil, time.Hour, 30*time.Minute, loc)
+
+		now := time.Now()
+		if err := waitlist.Sweep(now); err != nil {
+			t.Fatal(err)
+		}
+
+		if !bookingCastle.expiredAt.Equal(now) {
+			t.Errorf("expected holds to be expired at %v, got %v", now, bookingCastle.expiredAt)
+		}
+		if len(notificationCastle.notifications) != 2 {
+			t.Fatalf("expected 2 notifications, got %d", len(notificationCastle.notifications))
+		}
+		n := notificationCastle.notifications[0]
+		if n.FkUserID != 7 || n.Type != "booking_expired" || n.EntityType != types.EntityTypeBooking || n.EntityFk != 9 {
+			t.Errorf("unexpected expiry notification %+v", n)
+		}
+		if n := notificationCastle.notifications[1]; n.FkUserID != 8 || n.Type != "waitlist_offered" {
+			t.Errorf("unexpected offer notification %+v", n)
+		}
+	})
+
+	t.Run("Should fail sweep if holds can't be expired", func(t *testing.T) {
+		waitlistCastle := &mockWaitlistCastle{sessionIDs: []int{1}}
+		waitlist := NewWaitlist(&mockBookingCastle{err: fmt.Errorf("connection lost")}, waitlistCastle, &mockNotificationCastle{}, nil,
+			time.Hour, 30*time.Minute, loc)
+
+		if err := waitlist.Sweep(time.Now()); err == nil {
+			t.Errorf("expected error")
+		}
+		if len(waitlistCastle.promotedSessions) != 0 {
+			t.Errorf("expected no promotion, got %v", waitlistCastle.promotedSessions)
+		}
+	})
+
+	t.Run("Should give payment window from now", func(t *testing.T) {
+		waitlist := NewWaitlist(&mockBookingCastle{}, &mockWaitlistCastle{}, &mockNotificationCastle{}, nil, time.Hour, 30*time.Minute, loc)
+
+		if deadline := waitlist.PaymentDeadline(expiresAt); !deadline.Equal(expiresAt.Add(30 * time.Minute)) {
+			t.Errorf("expected deadline half an hour later, got %v", deadline)
+		}
+	})
+
+	t.Run("Should retry pending refunds", func(t *testing.T) {
+		bookingCastle := &mockBookingCastle{pendingRefunds: []*types.Booking{{ID: 9, Cancellation: &types.BookingCancellation{
+			RefundAmount: types.Money{Amount: 500, Currency: "EUR"}, RefundStatus: types.RefundPending,
+		}}}}
+		provider := payment.NewMockProvider("", "secret")
+		paymentCastle := &mockPaymentCastle{payments: []*types.Payment{{ID: 1, ProviderRef: "mock_1", Status: types.PaymentSucceeded,
+			Amount: types.Money{Amount: 1000, Currency: "EUR"}}}}
+		gateway := payment.NewGateway(paymentCastle, bookingCastle, provider, "", "")
+		waitlist := NewWaitlist(bookingCastle, &mockWaitlistCastle{}, &mockNotificationCastle{}, gateway, time.Hour, 30*time.Minute, loc)
+
+		if err := waitlist.Sweep(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if provider.Refunded("mock_1") != 500 {
+			t.Errorf("expected 500 to be refunded, got %d", provider.Refunded("mock_1"))
+		}
+		if fmt.Sprint(bookingCastle.completed) != "[9]" {
+			t.Errorf("expected refund of booking 9 to be completed, got %v", bookingCastle.completed)
+		}
+	})
+
+	t.Run("Should keep promoting other sessions when one fails", func(t *testing.T) {
+		waitlistCastle := &mockWaitlistCastle{
+			sessionIDs: []int{1, 2},
+			failing:    map[int]bool{1: true},
+			promoted: map[int][]*types.WaitlistEntry{
+				2: {{ID: 4, FkUserID: 8, Seats: 1, Status: types.WaitlistOffered, OfferExpiresAt: &expiresAt}},
+			},
+		}
+		notificationCastle := &mockNotificationCastle{}
+		waitlist := NewWaitlist(&mockBookingCastle{}, waitlistCastle, notificationCastle, nil, time.Hour, 30*time.Minute, loc)
+
+		if err := waitlist.Sweep(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if len(notificationCastle.notifications) != 1 || notificationCastle.notifications[0].FkUserID != 8 {
+			t.Errorf("expected user of session 2 to be notified, got %+v", notificationCastle.notifications)
+		}
+	})
+
+	t.Run("Should keep promotion when notification fails", func(t *testing.T) {
+		waitlistCastle := &mockWaitlistCastle{promoted: map[int][]*types.WaitlistEntry{
+			1: {{ID: 1, FkUserID: 5, Seats: 1, Status: types.WaitlistOffered, OfferExpiresAt: &expiresAt}},
+		}}
+		waitlist := NewWaitlist(&mockBookingCastle{}, waitlistCastle, &mockNotificationCastle{err: fmt.Errorf("connection lost")}, nil, time.Hour, 30*time.Minute, loc)
+
+		if err := waitlist.Promote(1); err != nil {
+			t.Errorf("expected promotion to succeed, got %v", err)
+		}
+	})
+}
+
+func entryIDs(entries []*types.WaitlistEntry) string {
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = fmt.Sprint(e.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+type mockWaitlistCastle struct {
+	types.WaitlistCastle
+	promoted   map[int][]*types.WaitlistEntry
+	failing    map[int]bool
+	expired    []*types.WaitlistEntry
+	sessionIDs []int
+
+	windows          []time.Duration
+	promotedSessions []int
+	expiredAt        time.Time
+}
+
+func (m *mockWaitlistCastle) PromoteWaitlist(sessionID int, window time.Duration) ([]*types.WaitlistEntry, error) {
+	m.windows = append(m.windows, window)
+	m.promotedSessions = append(m.promotedSessions, sessionID)
+	if m.failing[sessionID] {
+		return nil, fmt.Errorf("session %d is locked", sessionID)
+	}
+	return m.promoted[sessionID], nil
+}
+
+func (m *mockWaitlistCastle) ExpireWaitlistOffers(now time.Time) ([]*types.WaitlistEntry, error) {
+	m.expiredAt = now
+	return m.expired, nil
+}
+
+func (m *mockWaitlistCastle) ListWaitlistedSessionIDs() ([]int, error) {
+	return m.sessionIDs, nil
+}
+
+type mockBookingCastle struct {
+	types.BookingCastle
+	unpaid         []*types.Booking
+	pendingRefunds []*types.Booking
+	err            error
+
+	expiredAt time.Time
+	completed []int
+}
+
+func (m *mockBookingCastle) ExpireBookingHolds(now time.Time) ([]*types.Booking, error) {
+	m.expiredAt = now
+	return m.unpaid, m.err
+}
+
+func (m *mockBookingCastle) ListPendingRefunds() ([]*types.Booking, error) {
+	return m.pendingRefunds, nil
+}
+
+func (m *mockBookingCastle) CompleteRefund(bookingID int) error {
+	m.completed = append(m.completed, bookingID)
+	return nil
+}
+
+type mockPaymentCastle struct {
+	types.PaymentCastle
+	payments []*types.Payment
+}
+
+func (m *mockPaymentCastle) ListPaymentsByBookingID(bookingID int) ([]*types.Payment, error) {
+	return m.payments, nil
+}
+
+func (m *mockPaymentCastle) AddPaymentRefund(id int, amount types.Money) error {
+	return nil
+}
+
+type mockNotificationCastle struct {
+	types.NotificationCastle
+	notifications []types.Notification
+	err           error
+}
+
+func (m *mockNotificationCastle) CreateNotification(n types.Notification) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	m.notifications = append(m.notifications, n)
+	return int64(len(m.notifications)), nil
+}
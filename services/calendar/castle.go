@@ -0,0 +1,346 @@
+package calendar
+
+import (
+	"database/sql"
+	"educations-castle/types"
+	"time"
+)
+
+// Events are sessions of activities shown in public catalog, with location of session or first location of activity
+const selectEvents = `SELECT activitysession.id, activity.id, activity.name, activity.description, user.username,
+	activitysession.startTime, activitysession.endTime, activitysession.cancelled,
+	location.address, location.latitude, location.longitude`
+
+const fromEvents = `
+	FROM activitysession
+	JOIN activity ON activitysession.fk_Activityid = activity.id
+	JOIN package ON activity.fk_Packageid = package.id
+	JOIN user ON package.fk_Organizerid = user.id
+	LEFT JOIN location ON location.id = COALESCE(activitysession.fk_Locationid,
+		(SELECT MIN(id) FROM location WHERE location.fk_Activityid = activity.id))`
+
+const publicEvents = " AND activity.hidden = 0 AND activity.verified = 1"
+
+type Castle struct {
+	db *sql.DB
+}
+
+func NewCastle(db *sql.DB) *Castle {
+	return &Castle{db: db}
+}
+
+func scanRowIntoEvent(rows *sql.Rows, booked bool) (*types.CalendarEvent, error) {
+	e := new(types.CalendarEvent)
+
+	dest := []interface{}{
+		&e.SessionID,
+		&e.ActivityID,
+		&e.ActivityName,
+		&e.Description,
+		&e.OrganizerName,
+		&e.StartTime,
+		&e.EndTime,
+		&e.Cancelled,
+		&e.Address,
+		&e.Latitude,
+		&e.Longitude,
+	}
+	if booked {
+		dest = append(dest, &e.BookingID, &e.BookingStatus, &e.Seats, &e.UpdatedAt)
+	}
+
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (c *Castle) ListActivityEvents(activityID int, from time.Time) ([]*types.CalendarEvent, error) {
+	return c.listEvents(selectEvents+fromEvents+" WHERE activity.id = ? AND activitysession.startTime >= ?"+publicEvents,
+		false, activityID, from.UTC())
+}
+
+func (c *Castle) ListPackageEvents(packageID int, from time.Time) ([]*types.CalendarEvent, error) {
+	return c.listEvents(selectEvents+fromEvents+" WHERE package.id = ? AND activitysession.startTime >= ?"+publicEvents,
+		false, packageID, from.UTC())
+}
+
+func (c *Castle) ListOrganizerEvents(organizerID int, from time.Time) ([]*types.CalendarEvent, error) {
+	return c.listEvents(selectEvents+fromEvents+" WHERE package.fk_Organizerid = ? AND activitysession.startTime >= ?"+publicEvents,
+		false, organizerID, from.UTC())
+}
+
+// ListUserEvents returns confirmed and attended bookings of user. Cancelled bookings are listed too,
+// so calendars which imported them before remove them
+func (c *Castle) ListUserEvents(userID int, from time.Time) ([]*types.CalendarEvent, error) {
+	return c.listEvents(selectEvents+", booking.id, booking.status, booking.seats, booking.updatedAt"+fromEvents+`
+		JOIN booking ON booking.fk_ActivitySessionid = activitysession.id
+		WHERE booking.fk_Userid = ? AND activitysession.startTime >= ? AND booking.status <> 'pending'`,
+		true, userID, from.UTC())
+}
+
+func (c *Castle) listEvents(query string, booked bool, params ...interface{}) ([]*types.CalendarEvent, error) {
+	rows, err := c.db.Query(query+" ORDER BY activitysession.startTime, activitysession.id", params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*types.CalendarEvent
+
+	for rows.Next() {
+		e, err := scanRowIntoEvent(rows, booked)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetFeedToken returns token of user booking feed and time it was created
+func (c *Castle) GetFeedToken(userID int) (string, time.Time, error) {
+	var token string
+	var createdAt time.Time
+
+	err := c.db.QueryRow("SELECT token, createdAt FROM calendarfeed WHERE fk_Userid = ?", userID).Scan(&token, &createdAt)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, createdAt, nil
+}
+
+// SetFeedToken creates booking feed of user or replaces its token, so URL shared before stops working
+func (c *Castle) SetFeedToken(userID int, token string) error {
+	_, err := c.db.Exec(
+		`INSERT INTO calendarfeed (fk_Userid, token) VALUES (?,?)
+		ON DUPLICATE KEY UPDATE token = VALUES(token), createdAt = current_timestamp()`,
+		userID, token)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Castle) GetUserIDByFeedToken(token string) (int, error) {
+	var userID int
+
+	err := c.db.QueryRow("SELECT fk_Userid FROM calendarfeed WHERE token = ?", token).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func scanRowIntoCalendarImport(rows *sql.Rows) (*types.CalendarImport, error) {
+	ci := new(types.CalendarImport)
+
+	err := rows.Scan(
+		&ci.ID,
+		&ci.FkActivityID,
+		&ci.URL,
+		&ci.Capacity,
+		&ci.Language,
+		&ci.FkLocationID,
+		&ci.LastImportedAt,
+		&ci.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ci, nil
+}
+
+func (c *Castle) CreateCalendarImport(ci types.CalendarImport) (int64, error) {
+	result, err := c.db.Exec(
+		"INSERT INTO calendarimport (fk_Activityid, url, capacity, language, fk_Locationid) VALUES (?,?,?,?,?)",
+		ci.FkActivityID, ci.URL, ci.Capacity, ci.Language, ci.FkLocationID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (c *Castle) GetCalendarImportByID(id int) (*types.CalendarImport, error) {
+	rows, err := c.db.Query("SELECT * FROM calendarimport WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ci *types.CalendarImport
+	for rows.Next() {
+		ci, err = scanRowIntoCalendarImport(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if ci == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	return ci, nil
+}
+
+func (c *Castle) ListCalendarImports(activityID int) ([]*types.CalendarImport, error) {
+	rows, err := c.db.Query("SELECT * FROM calendarimport WHERE fk_Activityid = ? ORDER BY id", activityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var imports []*types.CalendarImport
+
+	for rows.Next() {
+		ci, err := scanRowIntoCalendarImport(rows)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, ci)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return imports, nil
+}
+
+// DeleteCalendarImport stops syncing of import, sessions created by it are kept
+func (c *Castle) DeleteCalendarImport(id int) error {
+	_, err := c.db.Exec("DELETE FROM calendarimport WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Castle) SetCalendarImported(id int, at time.Time) error {
+	_, err := c.db.Exec("UPDATE calendarimport SET lastImportedAt = ? WHERE id = ?", at.UTC(), id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListImportedSessions returns sessions of import with seats held by bookings which are not cancelled
+func (c *Castle) ListImportedSessions(importID int) ([]*types.ImportedSession, error) {
+	rows, err := c.db.Query(
+		`SELECT importedsession.uid, importedsession.occurrence, activitysession.id,
+			activitysession.startTime, activitysession.endTime,
+			COALESCE((SELECT SUM(booking.seats) FROM booking
+				WHERE booking.fk_ActivitySessionid = activitysession.id AND booking.status <> 'cancelled'), 0)
+		FROM importedsession
+		JOIN activitysession ON importedsession.fk_ActivitySessionid = activitysession.id
+		WHERE importedsession.fk_CalendarImportid = ?`, importID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*types.ImportedSession
+
+	for rows.Next() {
+		s := new(types.ImportedSession)
+		if err := rows.Scan(&s.UID, &s.Occurrence, &s.FkActivitySessionID, &s.StartTime, &s.EndTime, &s.BookedSeats); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// CreateImportedSession creates session of event occurrence and links it to import
+func (c *Castle) CreateImportedSession(importID int, uid, occurrence string, s types.ActivitySession) (int64, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	price, currency := types.MoneyColumns(s.Price)
+	result, err := tx.Exec(
+		`INSERT INTO activitysession (fk_Activityid, startTime, endTime, fk_Locationid, capacity, language, price, currency)
+		VALUES (?,?,?,?,?,?,?,?)`,
+		s.FkActivityID, s.StartTime.UTC(), s.EndTime.UTC(), s.FkLocationID, s.Capacity, s.Language, price, currency)
+	if err != nil {
+		return 0, err
+	}
+
+	sessionID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO importedsession (fk_CalendarImportid, uid, occurrence, fk_ActivitySessionid) VALUES (?,?,?,?)",
+		importID, uid, occurrence, sessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return sessionID, nil
+}
+
+// Sessions of import are changed only while nobody holds seats in them
+const notBooked = ` AND NOT EXISTS (SELECT 1 FROM booking
+	WHERE booking.fk_ActivitySessionid = activitysession.id AND booking.status <> 'cancelled')`
+
+// MoveImportedSession changes times of session, false is returned when session has bookings
+func (c *Castle) MoveImportedSession(sessionID int, start, end time.Time) (bool, error) {
+	result, err := c.db.Exec("UPDATE activitysession SET startTime = ?, endTime = ? WHERE id = ?"+notBooked,
+		start.UTC(), end.UTC(), sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// DeleteImportedSession deletes session, false is returned when session has bookings
+func (c *Castle) DeleteImportedSession(sessionID int) (bool, error) {
+	result, err := c.db.Exec("DELETE FROM activitysession WHERE id = ?"+notBooked, sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
@@ -0,0 +1,86 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned for calendar URLs pointing to this host or to private network
+var ErrPrivateAddress = errors.New("calendar URL must point to public address")
+
+// NewClient creates client for downloading calendars of organizers. It connects only to public addresses,
+// which are checked after host is resolved, so redirects and hosts resolving to private addresses are refused too
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: refusePrivateAddress}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// ParseURL checks calendar URL and returns it ready for download, webcal URLs are fetched over https.
+// Hosts given as IP addresses have to be public, names are checked once client resolves them
+func ParseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calendar URL")
+	}
+	if strings.EqualFold(u.Scheme, "webcal") {
+		u.Scheme = "https"
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported calendar URL scheme '%s'", u.Scheme)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return nil, fmt.Errorf("calendar URL has no host")
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return nil, ErrPrivateAddress
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !isPublic(ip) {
+		return nil, ErrPrivateAddress
+	}
+
+	return u, nil
+}
+
+// refusePrivateAddress is called for every connection with address host was resolved to
+func refusePrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !isPublic(ip) {
+		return ErrPrivateAddress
+	}
+
+	return nil
+}
+
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() &&
+		!sharedAddressSpace.Contains(ip)
+}
+
+// Carrier-grade NAT addresses aren't reachable from internet either
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
@@ -0,0 +1,85 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseURL(t *testing.T) {
+	valid := map[string]string{
+		"https://calendar.google.com/calendar/ical/basic.ics": "https://calendar.google.com/calendar/ical/basic.ics",
+		"webcal://example.com/cal.ics":                        "https://example.com/cal.ics",
+		"http://93.184.216.34/cal.ics":                        "http://93.184.216.34/cal.ics",
+	}
+	for rawURL, expected := range valid {
+		u, err := ParseURL(rawURL)
+		if err != nil || u.String() != expected {
+			t.Errorf("%s: expected %s, got %v (%v)", rawURL, expected, u, err)
+		}
+	}
+
+	private := []string{
+		"http://localhost:8080/cal.ics",
+		"http://api.localhost/cal.ics",
+		"http://127.0.0.1/cal.ics",
+		"http://10.0.0.5/cal.ics",
+		"http://192.168.1.1/cal.ics",
+		"http://172.16.0.1/cal.ics",
+		"http://100.64.0.1/cal.ics",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/cal.ics",
+		"http://[::1]/cal.ics",
+		"http://[fe80::1]/cal.ics",
+		"http://[fd00::1]/cal.ics",
+		"http://[::ffff:127.0.0.1]/cal.ics",
+	}
+	for _, rawURL := range private {
+		if _, err := ParseURL(rawURL); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("%s: expected private address error, got %v", rawURL, err)
+		}
+	}
+
+	for _, rawURL := range []string{"file:///etc/passwd", "ftp://example.com/cal.ics", "https:///cal.ics", "://"} {
+		if _, err := ParseURL(rawURL); err == nil {
+			t.Errorf("%s: expected error", rawURL)
+		}
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(organizerCalendar))
+	}))
+	defer server.Close()
+
+	t.Run("Should refuse to connect to loopback address", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := NewClient(time.Second).Do(req); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("expected private address error, got %v", err)
+		}
+	})
+
+	t.Run("Should check every address host resolves to", func(t *testing.T) {
+		for address, public := range map[string]bool{
+			"93.184.216.34:443":        true,
+			"[2606:2800:220:1::]:443":  true,
+			"127.0.0.1:80":             false,
+			"10.1.2.3:443":             false,
+			"169.254.169.254:80":       false,
+			"[::1]:443":                false,
+			"[::ffff:192.168.0.1]:443": false,
+		} {
+			if err := refusePrivateAddress("tcp", address, nil); (err == nil) != public {
+				t.Errorf("%s: expected public %t, got %v", address, public, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package calendar
+
+import (
+	"educations-castle/types"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Content lines longer than this many octets are folded (RFC 5545 section 3.1)
+const lineLimit = 75
+
+const timeFormat = "20060102T150405Z"
+
+// Calendar builds published iCalendar document (RFC 5545). All times are written in UTC,
+// so no time zone definitions are needed
+type Calendar struct {
+	b      strings.Builder
+	domain string
+	stamp  time.Time
+}
+
+// NewCalendar starts calendar of given name, domain makes event UIDs globally unique
+// and stamp is time document was generated
+func NewCalendar(name, domain string, stamp time.Time) *Calendar {
+	c := &Calendar{domain: domain, stamp: stamp.UTC()}
+
+	c.line("BEGIN", "VCALENDAR")
+	c.line("VERSION", "2.0")
+	c.line("PRODID", "-//educations-castle//calendar//EN")
+	c.line("CALSCALE", "GREGORIAN")
+	c.line("METHOD", "PUBLISH")
+	c.line("X-WR-CALNAME", escape(name))
+	c.line("REFRESH-INTERVAL;VALUE=DURATION", "PT1H")
+	c.line("X-PUBLISHED-TTL", "PT1H")
+
+	return c
+}
+
+// AddEvent writes event of session or booking. UID stays the same for the life of session or booking,
+// cancelled ones are kept with CANCELLED status and higher sequence so subscribed calendars update them
+func (c *Calendar) AddEvent(e *types.CalendarEvent) {
+	uid := fmt.Sprintf("session-%d@%s", e.SessionID, c.domain)
+	cancelled := e.Cancelled
+	if e.BookingID != nil {
+		uid = fmt.Sprintf("booking-%d@%s", *e.BookingID, c.domain)
+		cancelled = cancelled || (e.BookingStatus != nil && *e.BookingStatus == types.BookingCancelled)
+	}
+
+	c.line("BEGIN", "VEVENT")
+	c.line("UID", uid)
+	c.line("DTSTAMP", c.stamp.Format(timeFormat))
+	if e.UpdatedAt != nil {
+		c.line("LAST-MODIFIED", e.UpdatedAt.UTC().Format(timeFormat))
+	}
+	c.line("DTSTART", e.StartTime.UTC().Format(timeFormat))
+	c.line("DTEND", e.EndTime.UTC().Format(timeFormat))
+	c.line("SUMMARY", escape(e.ActivityName))
+	c.line("DESCRIPTION", escape(description(e)))
+	if e.Address != nil {
+		c.line("LOCATION", escape(*e.Address))
+	}
+	if e.Latitude != nil && e.Longitude != nil {
+		c.line("GEO", fmt.Sprintf("%.6f;%.6f", *e.Latitude, *e.Longitude))
+	}
+	if cancelled {
+		c.line("STATUS", "CANCELLED")
+		c.line("SEQUENCE", "1")
+	} else {
+		c.line("STATUS", "CONFIRMED")
+		c.line("SEQUENCE", "0")
+	}
+	c.line("END", "VEVENT")
+}
+
+// Bytes ends calendar and returns the document
+func (c *Calendar) Bytes() []byte {
+	c.line("END", "VCALENDAR")
+	return []byte(c.b.String())
+}
+
+// line writes content line, folding it into continuation lines starting with space
+func (c *Calendar) line(name, value string) {
+	line := name + ":" + value
+	limit := lineLimit
+
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		c.b.WriteString(line[:cut])
+		c.b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = lineLimit - 1
+	}
+
+	c.b.WriteString(line)
+	c.b.WriteString("\r\n")
+}
+
+func description(e *types.CalendarEvent) string {
+	text := e.Description + "\n\nOrganizer: " + e.OrganizerName
+	if e.Seats != nil {
+		text += fmt.Sprintf("\nSeats: %d", *e.Seats)
+	}
+	return text
+}
+
+// escape escapes TEXT value (RFC 5545 section 3.3.11)
+func escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(text)
+}
@@ -0,0 +1,114 @@
+package calendar
+
+import (
+	"educations-castle/types"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	text := escape("Amber; sun, sea\\sand\r\nand more\n")
+	if text != `Amber\; sun\, sea\\sand\nand more\n` {
+		t.Errorf("unexpected text %q", text)
+	}
+}
+
+func TestFolding(t *testing.T) {
+	c := &Calendar{}
+	c.line("DESCRIPTION", strings.Repeat("ąžuolas ", 30))
+
+	lines := strings.Split(strings.TrimSuffix(c.b.String(), "\r\n"), "\r\n")
+	if len(lines) < 2 {
+		t.Fatalf("expected folded line, got %q", c.b.String())
+	}
+
+	var unfolded string
+	for i, line := range lines {
+		if len(line) > lineLimit {
+			t.Errorf("line %d has %d octets", i, len(line))
+		}
+		if i > 0 {
+			if !strings.HasPrefix(line, " ") {
+				t.Errorf("continuation line %d doesn't start with space", i)
+			}
+			line = line[1:]
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("line %d splits character", i)
+		}
+		unfolded += line
+	}
+
+	if unfolded != "DESCRIPTION:"+strings.Repeat("ąžuolas ", 30) {
+		t.Errorf("unfolded line differs: %q", unfolded)
+	}
+}
+
+func TestAddEvent(t *testing.T) {
+	stamp := time.Date(2024, 12, 17, 8, 0, 0, 0, time.UTC)
+	address := "Pilies g. 1, Palanga"
+	latitude, longitude := 55.9175, 21.0686
+	vilnius := time.FixedZone("EET", 2*60*60)
+
+	session := &types.CalendarEvent{
+		SessionID:     7,
+		ActivityName:  "Amber history",
+		Description:   "Education about amber",
+		OrganizerName: "amber_museum",
+		StartTime:     time.Date(2025, 1, 15, 12, 0, 0, 0, vilnius),
+		EndTime:       time.Date(2025, 1, 15, 14, 0, 0, 0, vilnius),
+		Address:       &address,
+		Latitude:      &latitude,
+		Longitude:     &longitude,
+	}
+
+	t.Run("Should write session in UTC with location", func(t *testing.T) {
+		c := NewCalendar("Amber", "example.org", stamp)
+		c.AddEvent(session)
+		document := string(c.Bytes())
+
+		for _, expected := range []string{
+			"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+			"UID:session-7@example.org\r\n",
+			"DTSTAMP:20241217T080000Z\r\n",
+			"DTSTART:20250115T100000Z\r\n",
+			"DTEND:20250115T120000Z\r\n",
+			"LOCATION:Pilies g. 1\\, Palanga\r\n",
+			"GEO:55.917500;21.068600\r\n",
+			"STATUS:CONFIRMED\r\nSEQUENCE:0\r\n",
+			"END:VEVENT\r\nEND:VCALENDAR\r\n",
+		} {
+			if !strings.Contains(document, expected) {
+				t.Errorf("expected %q in\n%s", expected, document)
+			}
+		}
+	})
+
+	t.Run("Should write cancelled booking", func(t *testing.T) {
+		bookingID, seats, status := 42, 3, types.BookingCancelled
+		updatedAt := time.Date(2024, 12, 16, 9, 30, 0, 0, time.UTC)
+		booking := *session
+		booking.Address, booking.Latitude, booking.Longitude = nil, nil, nil
+		booking.BookingID, booking.Seats, booking.BookingStatus, booking.UpdatedAt = &bookingID, &seats, &status, &updatedAt
+
+		c := NewCalendar("My bookings", "example.org", stamp)
+		c.AddEvent(&booking)
+		document := string(c.Bytes())
+
+		for _, expected := range []string{
+			"UID:booking-42@example.org\r\n",
+			"LAST-MODIFIED:20241216T093000Z\r\n",
+			"Seats: 3",
+			"STATUS:CANCELLED\r\nSEQUENCE:1\r\n",
+		} {
+			if !strings.Contains(document, expected) {
+				t.Errorf("expected %q in\n%s", expected, document)
+			}
+		}
+		if strings.Contains(document, "GEO:") || strings.Contains(document, "LOCATION:") {
+			t.Errorf("unexpected location in\n%s", document)
+		}
+	})
+}
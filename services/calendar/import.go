@@ -0,0 +1,331 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"educations-castle/services/schedule"
+	"educations-castle/types"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Occurrence is single session of imported event. Key identifies it across imports together with UID,
+// it is original start of recurring event occurrence and empty for single events
+type Occurrence struct {
+	UID       string
+	Key       string
+	Summary   string
+	Start     time.Time
+	End       time.Time
+	Cancelled bool
+}
+
+// Occurrences expands events into occurrences starting between from and to (both inclusive). Recurring events
+// skip exception dates and take times and status of their overridden occurrences. Events which can't become
+// sessions are returned as conflicts
+func Occurrences(events []*Event, from, to time.Time) ([]*Occurrence, []*types.ImportConflict) {
+	var occurrences []*Occurrence
+	var conflicts []*types.ImportConflict
+
+	masters := map[string]*Event{}
+	overrides := map[string]map[string]*Event{}
+	var uids []string
+	for _, e := range events {
+		if _, ok := masters[e.UID]; !ok && overrides[e.UID] == nil {
+			uids = append(uids, e.UID)
+		}
+		if e.RecurrenceID == nil {
+			masters[e.UID] = e
+			continue
+		}
+		if overrides[e.UID] == nil {
+			overrides[e.UID] = map[string]*Event{}
+		}
+		overrides[e.UID][e.RecurrenceID.UTC().Format(timeFormat)] = e
+	}
+
+	inRange := func(t time.Time) bool { return !t.Before(from) && !t.After(to) }
+	conflict := func(e *Event, reason string) {
+		start := e.Start
+		conflicts = append(conflicts, &types.ImportConflict{UID: e.UID, Summary: e.Summary, StartTime: &start, Reason: reason})
+	}
+	add := func(e *Event, key string) {
+		if e.AllDay {
+			conflict(e, "all-day events are not imported")
+			return
+		}
+		if !e.End.After(e.Start) {
+			conflict(e, "event has no duration")
+			return
+		}
+		occurrences = append(occurrences, &Occurrence{UID: e.UID, Key: key, Summary: e.Summary,
+			Start: e.Start, End: e.End, Cancelled: e.Cancelled})
+	}
+
+	for _, uid := range uids {
+		master, instances := masters[uid], overrides[uid]
+
+		switch {
+		case master == nil:
+			// Invitations to single occurrence come without the recurring event
+			for key, e := range instances {
+				if inRange(e.Start) {
+					add(e, key)
+				}
+			}
+			continue
+		case master.RRule == "":
+			if inRange(master.Start) {
+				add(master, "")
+			}
+			continue
+		case master.AllDay:
+			conflict(master, "all-day events are not imported")
+			continue
+		}
+
+		rule, err := schedule.ParseRRule(master.RRule, master.Start.Location())
+		if err != nil {
+			conflict(master, fmt.Sprintf("recurrence is not supported: %v", err))
+			continue
+		}
+
+		// Unlike schedules, DTSTART of event is always its first occurrence
+		starts := rule.Expand(master.Start, from, to)
+		if inRange(master.Start) && (len(starts) == 0 || !starts[0].Equal(master.Start)) {
+			starts = append([]time.Time{master.Start}, starts...)
+		}
+
+		duration := master.End.Sub(master.Start)
+		used := map[string]bool{}
+		for _, start := range starts {
+			if isExcluded(start, master.ExDates) {
+				continue
+			}
+
+			key := start.UTC().Format(timeFormat)
+			if e, ok := instances[key]; ok {
+				used[key] = true
+				add(e, key)
+				continue
+			}
+
+			add(&Event{UID: uid, Summary: master.Summary, Start: start, End: start.Add(duration), Cancelled: master.Cancelled}, key)
+		}
+
+		// Occurrences moved into range from outside of it
+		for key, e := range instances {
+			if !used[key] && inRange(e.Start) {
+				add(e, key)
+			}
+		}
+	}
+
+	sort.SliceStable(occurrences, func(i, j int) bool { return occurrences[i].Start.Before(occurrences[j].Start) })
+
+	return occurrences, conflicts
+}
+
+func isExcluded(start time.Time, exDates []time.Time) bool {
+	for _, exDate := range exDates {
+		if exDate.Equal(start) {
+			return true
+		}
+		// Dates exclude the whole day
+		if exDate.Hour() == 0 && exDate.Minute() == 0 && exDate.Second() == 0 &&
+			start.In(exDate.Location()).Format(dateFormat) == exDate.Format(dateFormat) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Importer keeps sessions of activity in sync with events of organizer calendar. Sessions with bookings
+// are never moved or deleted, such changes are reported as conflicts instead
+type Importer struct {
+	calendarCastle types.CalendarCastle
+	sessionCastle  types.SessionCastle
+	client         *http.Client
+	location       *time.Location
+	horizon        time.Duration
+	maxSize        int64
+}
+
+// NewImporter creates importer of sessions starting no later than horizon from now. Floating times
+// are read in location and calendar files larger than maxSize are refused. Client should come from
+// NewClient, so calendar URLs can't reach internal services
+func NewImporter(calendarCastle types.CalendarCastle, sessionCastle types.SessionCastle, client *http.Client,
+	location *time.Location, horizon time.Duration, maxSize int64) *Importer {
+	return &Importer{
+		calendarCastle: calendarCastle,
+		sessionCastle:  sessionCastle,
+		client:         client,
+		location:       location,
+		horizon:        horizon,
+		maxSize:        maxSize}
+}
+
+// Fetch downloads calendar file of import URL, webcal URLs are fetched over https
+func (i *Importer) Fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/calendar")
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("calendar URL responded with status %d", resp.StatusCode)
+	}
+
+	return i.Read(resp.Body)
+}
+
+// Read reads calendar file, refusing files larger than limit
+func (i *Importer) Read(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, i.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > i.maxSize {
+		return nil, fmt.Errorf("calendar file is too large, limit is %d bytes", i.maxSize)
+	}
+
+	return data, nil
+}
+
+// Import applies calendar file to sessions of import starting between now and horizon. Sessions of new
+// occurrences are created, moved and cancelled occurrences update their sessions and sessions of occurrences
+// which disappeared from calendar are deleted
+func (i *Importer) Import(ci *types.CalendarImport, data []byte, now time.Time) (*types.CalendarImportReport, error) {
+	events, err := ParseEvents(bytes.NewReader(data), i.location)
+	if err != nil {
+		return nil, &ParseError{Err: err}
+	}
+
+	from := now.UTC().Truncate(time.Second)
+	to := now.Add(i.horizon).UTC().Truncate(time.Second)
+	occurrences, conflicts := Occurrences(events, from, to)
+	report := &types.CalendarImportReport{Conflicts: conflicts}
+
+	imported, err := i.calendarCastle.ListImportedSessions(ci.ID)
+	if err != nil {
+		return nil, err
+	}
+	linked := map[string]*types.ImportedSession{}
+	linkedIDs := map[int]bool{}
+	for _, s := range imported {
+		linked[s.UID+"\x00"+s.Occurrence] = s
+		linkedIDs[s.FkActivitySessionID] = true
+	}
+
+	// Sessions created by hand or by schedules aren't duplicated
+	sessions, err := i.sessionCastle.ListSessionsByActivityID(ci.FkActivityID, &from, &to)
+	if err != nil {
+		return nil, err
+	}
+	taken := map[int64]*types.ActivitySession{}
+	for _, s := range sessions {
+		if !linkedIDs[s.ID] {
+			taken[s.StartTime.Unix()] = s
+		}
+	}
+
+	conflict := func(o *Occurrence, sessionID *int, reason string) {
+		start := o.Start
+		report.Conflicts = append(report.Conflicts, &types.ImportConflict{UID: o.UID, Summary: o.Summary,
+			StartTime: &start, FkActivitySessionID: sessionID, Reason: reason})
+	}
+
+	for _, o := range occurrences {
+		key := o.UID + "\x00" + o.Key
+		s, ok := linked[key]
+		delete(linked, key)
+
+		switch {
+		case o.Cancelled && !ok:
+		case o.Cancelled:
+			deleted, err := i.calendarCastle.DeleteImportedSession(s.FkActivitySessionID)
+			if err != nil {
+				return nil, err
+			}
+			if deleted {
+				report.Deleted++
+			} else {
+				conflict(o, &s.FkActivitySessionID, "cancelled in calendar, but session has bookings")
+			}
+		case ok && s.StartTime.Equal(o.Start) && s.EndTime.Equal(o.End):
+			report.Unchanged++
+		case ok:
+			moved, err := i.calendarCastle.MoveImportedSession(s.FkActivitySessionID, o.Start, o.End)
+			if err != nil {
+				return nil, err
+			}
+			if moved {
+				report.Updated++
+			} else {
+				conflict(o, &s.FkActivitySessionID, "moved in calendar, but session has bookings")
+			}
+		case taken[o.Start.Unix()] != nil:
+			conflict(o, &taken[o.Start.Unix()].ID, "another session of activity starts at the same time")
+		default:
+			_, err := i.calendarCastle.CreateImportedSession(ci.ID, o.UID, o.Key, types.ActivitySession{
+				FkActivityID: ci.FkActivityID,
+				StartTime:    o.Start,
+				EndTime:      o.End,
+				FkLocationID: ci.FkLocationID,
+				Capacity:     ci.Capacity,
+				Language:     ci.Language,
+			})
+			if err != nil {
+				return nil, err
+			}
+			report.Created++
+		}
+	}
+
+	// Past sessions stay as they were
+	removed := make([]*types.ImportedSession, 0, len(linked))
+	for _, s := range linked {
+		if !s.StartTime.Before(from) && !s.StartTime.After(to) {
+			removed = append(removed, s)
+		}
+	}
+	sort.Slice(removed, func(a, b int) bool { return removed[a].StartTime.Before(removed[b].StartTime) })
+
+	for _, s := range removed {
+		deleted, err := i.calendarCastle.DeleteImportedSession(s.FkActivitySessionID)
+		if err != nil {
+			return nil, err
+		}
+		if deleted {
+			report.Deleted++
+		} else {
+			conflict(&Occurrence{UID: s.UID, Start: s.StartTime}, &s.FkActivitySessionID, "removed from calendar, but session has bookings")
+		}
+	}
+
+	if err := i.calendarCastle.SetCalendarImported(ci.ID, now); err != nil {
+		return nil, err
+	}
+
+	if report.Conflicts == nil {
+		report.Conflicts = []*types.ImportConflict{}
+	}
+
+	return report, nil
+}
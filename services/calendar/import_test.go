@@ -0,0 +1,194 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const organizerCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Google Inc//Google Calendar 70.9054//EN\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Europe/Vilnius\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:19701025T040000\r\n" +
+	"TZOFFSETFROM:+0300\r\n" +
+	"TZOFFSETTO:+0200\r\n" +
+	"END:STANDARD\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:weekly@google.com\r\n" +
+	"DTSTART;TZID=Europe/Vilnius:20250106T100000\r\n" +
+	"DTEND;TZID=Europe/Vilnius:20250106T113000\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=5\r\n" +
+	"EXDATE;TZID=Europe/Vilnius:20250113T100000\r\n" +
+	"SUMMARY:Amber history\\, for 5th\r\n" +
+	"  grade\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"TRIGGER:-PT30M\r\n" +
+	"DESCRIPTION:Reminder\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:weekly@google.com\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Vilnius:20250120T100000\r\n" +
+	"DTSTART;TZID=Europe/Vilnius:20250121T120000\r\n" +
+	"DURATION:PT2H\r\n" +
+	"SUMMARY:Amber history (moved)\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:weekly@google.com\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Vilnius:20250127T100000\r\n" +
+	"DTSTART;TZID=Europe/Vilnius:20250127T100000\r\n" +
+	"DTEND;TZID=Europe/Vilnius:20250127T113000\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:outlook-1\r\n" +
+	"DTSTART;TZID=\"FLE Standard Time\":20250110T090000\r\n" +
+	"DTEND;TZID=\"FLE Standard Time\":20250110T100000\r\n" +
+	"SUMMARY:Workshop\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:holiday\r\n" +
+	"DTSTART;VALUE=DATE:20250116\r\n" +
+	"SUMMARY:Museum closed\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:yearly\r\n" +
+	"DTSTART:20250105T080000Z\r\n" +
+	"DTEND:20250105T090000Z\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseEvents(t *testing.T) {
+	vilnius, err := time.LoadLocation("Europe/Vilnius")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := ParseEvents(strings.NewReader(organizerCalendar), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 6 {
+		t.Fatalf("expected 6 events, got %d", len(events))
+	}
+
+	weekly := events[0]
+	if weekly.Summary != "Amber history, for 5th grade" {
+		t.Errorf("unexpected summary %q", weekly.Summary)
+	}
+	if !weekly.Start.Equal(time.Date(2025, 1, 6, 10, 0, 0, 0, vilnius)) || weekly.End.Sub(weekly.Start) != 90*time.Minute {
+		t.Errorf("unexpected times %v - %v", weekly.Start, weekly.End)
+	}
+	if weekly.RRule != "FREQ=WEEKLY;BYDAY=MO;COUNT=5" || len(weekly.ExDates) != 1 {
+		t.Errorf("unexpected recurrence %q %v", weekly.RRule, weekly.ExDates)
+	}
+
+	if moved := events[1]; moved.RecurrenceID == nil || moved.End.Sub(moved.Start) != 2*time.Hour {
+		t.Errorf("unexpected override %+v", moved)
+	}
+	if !events[2].Cancelled {
+		t.Errorf("expected cancelled occurrence")
+	}
+	if !events[3].Start.Equal(time.Date(2025, 1, 10, 9, 0, 0, 0, vilnius)) {
+		t.Errorf("expected Windows time zone to be read as Vilnius, got %v", events[3].Start)
+	}
+	if !events[4].AllDay || !events[4].End.Equal(events[4].Start.AddDate(0, 0, 1)) {
+		t.Errorf("expected all-day event, got %+v", events[4])
+	}
+
+	t.Run("Should refuse files which aren't calendars", func(t *testing.T) {
+		if _, err := ParseEvents(strings.NewReader("<html></html>\n"), time.UTC); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("Should refuse events without start", func(t *testing.T) {
+		_, err := ParseEvents(strings.NewReader("BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:1\nEND:VEVENT\nEND:VCALENDAR\n"), time.UTC)
+		if err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"PT1H30M": 90 * time.Minute,
+		"P1D":     24 * time.Hour,
+		"P1W":     7 * 24 * time.Hour,
+		"P1DT2H":  26 * time.Hour,
+		"-PT15M":  -15 * time.Minute,
+	}
+	for value, expected := range tests {
+		if d, err := parseDuration(value); err != nil || d != expected {
+			t.Errorf("%s: expected %v, got %v (%v)", value, expected, d, err)
+		}
+	}
+
+	for _, value := range []string{"P", "PT", "1H", "PT1X"} {
+		if _, err := parseDuration(value); err == nil {
+			t.Errorf("%s: expected error", value)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	vilnius, err := time.LoadLocation("Europe/Vilnius")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := ParseEvents(strings.NewReader(organizerCalendar), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	occurrences, conflicts := Occurrences(events, from, to)
+
+	type expected struct {
+		uid, key  string
+		start     time.Time
+		cancelled bool
+	}
+	wanted := []expected{
+		{"weekly@google.com", "20250106T080000Z", time.Date(2025, 1, 6, 10, 0, 0, 0, vilnius), false},
+		{"outlook-1", "", time.Date(2025, 1, 10, 9, 0, 0, 0, vilnius), false},
+		{"weekly@google.com", "20250120T080000Z", time.Date(2025, 1, 21, 12, 0, 0, 0, vilnius), false},
+		{"weekly@google.com", "20250127T080000Z", time.Date(2025, 1, 27, 10, 0, 0, 0, vilnius), true},
+		{"weekly@google.com", "20250203T080000Z", time.Date(2025, 2, 3, 10, 0, 0, 0, vilnius), false},
+	}
+
+	if len(occurrences) != len(wanted) {
+		for _, o := range occurrences {
+			t.Logf("%+v", o)
+		}
+		t.Fatalf("expected %d occurrences, got %d", len(wanted), len(occurrences))
+	}
+	for i, w := range wanted {
+		o := occurrences[i]
+		if o.UID != w.uid || o.Key != w.key || !o.Start.Equal(w.start) || o.Cancelled != w.cancelled {
+			t.Errorf("occurrence %d: expected %+v, got %+v", i, w, o)
+		}
+	}
+
+	reasons := map[string]string{}
+	for _, c := range conflicts {
+		reasons[c.UID] = c.Reason
+	}
+	if len(conflicts) != 2 || reasons["holiday"] != "all-day events are not imported" || !strings.HasPrefix(reasons["yearly"], "recurrence is not supported") {
+		t.Errorf("unexpected conflicts %v", reasons)
+	}
+
+	t.Run("Should return only occurrences inside range", func(t *testing.T) {
+		occurrences, _ := Occurrences(events, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC))
+		if len(occurrences) != 2 || occurrences[0].Key != "20250120T080000Z" || occurrences[1].Key != "20250127T080000Z" {
+			t.Errorf("unexpected occurrences %+v", occurrences)
+		}
+	})
+}
@@ -0,0 +1,297 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dateFormat          = "20060102"
+	floatingTimeFormat  = "20060102T150405"
+	maxContentLineBytes = 1 << 20
+)
+
+// Outlook names time zones by Windows names instead of IANA ones
+var windowsZones = map[string]string{
+	"FLE Standard Time":              "Europe/Vilnius",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"GTB Standard Time":              "Europe/Bucharest",
+	"Central European Standard Time": "Europe/Warsaw",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Romance Standard Time":          "Europe/Paris",
+	"GMT Standard Time":              "Europe/London",
+	"Russian Standard Time":          "Europe/Moscow",
+	"UTC":                            "UTC",
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// Event is VEVENT of imported calendar. Overridden occurrence of recurring event has RecurrenceID
+// set to original start of the occurrence
+type Event struct {
+	UID          string
+	Summary      string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID *time.Time
+	Cancelled    bool
+
+	duration *time.Duration
+}
+
+// ParseError is invalid calendar file, as opposed to failure of applying its events
+type ParseError struct {
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return "invalid calendar file: " + e.Err.Error()
+}
+
+// property is content line split into name, parameters and value
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ParseEvents reads VEVENTs of iCalendar document (RFC 5545). Floating times and times of unknown
+// time zones are read in loc
+func ParseEvents(r io.Reader, loc *time.Location) ([]*Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*Event
+	var event *Event
+	calendar := false
+	depth := 0
+
+	for n, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		p, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VCALENDAR"):
+			calendar = true
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT") && depth == 0:
+			event = &Event{}
+		case p.name == "BEGIN":
+			// Alarms and other components nested in event or calendar are skipped
+			depth++
+		case p.name == "END" && depth > 0:
+			depth--
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT") && event != nil:
+			if event.UID == "" || event.Start.IsZero() {
+				return nil, fmt.Errorf("line %d: event without UID or DTSTART", n+1)
+			}
+			if event.End.IsZero() {
+				if event.duration != nil {
+					event.End = event.Start.Add(*event.duration)
+				} else if event.AllDay {
+					event.End = event.Start.AddDate(0, 0, 1)
+				} else {
+					event.End = event.Start
+				}
+			}
+			events = append(events, event)
+			event = nil
+		case event != nil && depth == 0:
+			if err := event.set(p, loc); err != nil {
+				return nil, fmt.Errorf("line %d: %v", n+1, err)
+			}
+		}
+	}
+
+	if !calendar {
+		return nil, fmt.Errorf("not an iCalendar document")
+	}
+
+	return events, nil
+}
+
+func (e *Event) set(p property, loc *time.Location) error {
+	var err error
+
+	switch p.name {
+	case "UID":
+		e.UID = p.value
+	case "SUMMARY":
+		e.Summary = unescape(p.value)
+	case "DTSTART":
+		e.Start, e.AllDay, err = parseTime(p, loc)
+	case "DTEND":
+		e.End, _, err = parseTime(p, loc)
+	case "DURATION":
+		var d time.Duration
+		d, err = parseDuration(p.value)
+		e.duration = &d
+	case "RRULE":
+		e.RRule = p.value
+	case "EXDATE":
+		for _, value := range strings.Split(p.value, ",") {
+			var t time.Time
+			t, _, err = parseTime(property{name: p.name, params: p.params, value: value}, loc)
+			if err != nil {
+				break
+			}
+			e.ExDates = append(e.ExDates, t)
+		}
+	case "RECURRENCE-ID":
+		var t time.Time
+		t, _, err = parseTime(p, loc)
+		e.RecurrenceID = &t
+	case "STATUS":
+		e.Cancelled = strings.EqualFold(p.value, "CANCELLED")
+	}
+
+	return err
+}
+
+// unfold joins continuation lines, which start with space or tab, to lines they continue
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxContentLineBytes)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// parseProperty splits content line such as DTSTART;TZID="Europe/Vilnius":20250115T100000,
+// parameter values in quotes may contain colons and semicolons
+func parseProperty(line string) (property, error) {
+	p := property{params: map[string]string{}}
+
+	quoted := false
+	start := 0
+	var parts []string
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ';', ':':
+			if quoted {
+				continue
+			}
+			parts = append(parts, line[start:i])
+			start = i + 1
+			if line[i] == ':' {
+				p.value = line[start:]
+				i = len(line)
+			}
+		}
+	}
+	if len(parts) == 0 || start == 0 || line[start-1] != ':' {
+		return p, fmt.Errorf("invalid content line")
+	}
+
+	p.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		p.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+
+	return p, nil
+}
+
+// parseTime reads DATE or DATE-TIME value, true is returned for dates
+func parseTime(p property, loc *time.Location) (time.Time, bool, error) {
+	value := strings.TrimSpace(p.value)
+
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(value) == len(dateFormat) {
+		t, err := time.ParseInLocation(dateFormat, value, loc)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s date", p.name)
+		}
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(timeFormat, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s time", p.name)
+		}
+		return t, false, nil
+	}
+
+	t, err := time.ParseInLocation(floatingTimeFormat, value, zone(p.params["TZID"], loc))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid %s time", p.name)
+	}
+	return t, false, nil
+}
+
+// zone returns location of TZID, falling back to loc for unknown zones and floating times
+func zone(tzid string, loc *time.Location) *time.Location {
+	if tzid == "" {
+		return loc
+	}
+	if name, ok := windowsZones[tzid]; ok {
+		tzid = name
+	}
+	if l, err := time.LoadLocation(tzid); err == nil {
+		return l
+	}
+	return loc
+}
+
+// parseDuration reads DURATION value such as PT1H30M, P1D or P2W
+func parseDuration(value string) (time.Duration, error) {
+	m := durationPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("invalid DURATION")
+	}
+
+	var d time.Duration
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	for i, unit := range units {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, fmt.Errorf("invalid DURATION")
+		}
+		d += time.Duration(n) * unit
+	}
+
+	if m[1] == "-" {
+		d = -d
+	}
+
+	return d, nil
+}
+
+// unescape reverses escaping of TEXT value
+func unescape(text string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(text)
+}
@@ -0,0 +1,558 @@
+package calendar
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"educations-castle/services/auth"
+	"educations-castle/types"
+	"educations-castle/utils"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// Calendar clients fetch feeds without signing in, so feeds show what guests see
+var guest = types.Viewer{UserID: -1}
+
+type Handler struct {
+	calendarCastle types.CalendarCastle
+	activityCastle types.ActivityCastle
+	locationCastle types.LocationCastle
+	userCastle     types.UserCastle
+	importer       *Importer
+	feedURL        string
+	domain         string
+	history        time.Duration
+}
+
+// NewHandler creates handler of iCalendar feeds and imports. Feeds list sessions which started no earlier than
+// history ago, private feed URLs start with feedURL and event UIDs end with domain
+func NewHandler(calendarCastle types.CalendarCastle, activityCastle types.ActivityCastle, locationCastle types.LocationCastle,
+	userCastle types.UserCastle, importer *Importer, feedURL, domain string, history time.Duration) *Handler {
+	return &Handler{
+		calendarCastle: calendarCastle,
+		activityCastle: activityCastle,
+		locationCastle: locationCastle,
+		userCastle:     userCastle,
+		importer:       importer,
+		feedURL:        strings.TrimRight(feedURL, "/"),
+		domain:         domain,
+		history:        history}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/activities/{activityID:[0-9]+}/sessions.ics", h.handleActivityFeed).Methods("GET")
+	router.HandleFunc("/packages/{packageID:[0-9]+}/sessions.ics", h.handlePackageFeed).Methods("GET")
+	router.HandleFunc("/organizer/{organizerID:[0-9]+}/sessions.ics", h.handleOrganizerFeed).Methods("GET")
+	router.HandleFunc("/calendar/feeds/{token:[A-Za-z0-9_-]+}.ics", h.handleUserFeed).Methods("GET")
+	router.HandleFunc("/calendar/my", auth.WithJWTAuth(h.handleGetMyFeed, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/calendar/my/reset", auth.WithJWTAuth(h.handleResetMyFeed, h.userCastle, "administrator", "organizer", "user")).Methods("POST", "OPTIONS")
+
+	router.HandleFunc("/activities/{activityID:[0-9]+}/calendar-imports", auth.WithJWTAuth(h.handleListImports, h.userCastle, "administrator", "organizer")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/calendar-imports/create", auth.WithJWTAuth(h.handleCreateImport, h.userCastle, "administrator", "organizer")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/calendar-imports/delete/{importID:[0-9]+}", auth.WithJWTAuth(h.handleDeleteImport, h.userCastle, "administrator", "organizer")).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/calendar-imports/{importID:[0-9]+}/upload", auth.WithJWTAuth(h.handleUploadImport, h.userCastle, "administrator", "organizer")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/calendar-imports/{importID:[0-9]+}/sync", auth.WithJWTAuth(h.handleSyncImport, h.userCastle, "administrator", "organizer")).Methods("POST", "OPTIONS")
+}
+
+// ActivityFeed godoc
+// @Summary      iCalendar feed of activity sessions
+// @Description  Returns sessions of activity as iCalendar (RFC 5545) document for subscribing in calendar applications.
+// @Description  Cancelled sessions stay in feed with CANCELLED status
+// @Tags         calendar
+// @Produce      text/calendar
+// @Param        activityID path int true "Activity ID"
+// @Success      200  {file}     file
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid activity ID"
+// @Failure      404  {object}   types.ErrorResponse "activity not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/sessions.ics [get]
+func (h *Handler) handleActivityFeed(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	activity, err := h.activityCastle.GetVisibleActivityByID(activityID, guest)
+	if err != nil {
+		writeNotFoundError(w, err, "activity not found")
+		return
+	}
+
+	events, err := h.calendarCastle.ListActivityEvents(activityID, time.Now().Add(-h.history))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeCalendar(w, activity.Name, fmt.Sprintf("activity-%d", activityID), events)
+}
+
+// PackageFeed godoc
+// @Summary      iCalendar feed of package sessions
+// @Description  Returns sessions of all public activities in package as iCalendar (RFC 5545) document
+// @Tags         calendar
+// @Produce      text/calendar
+// @Param        packageID path int true "Package ID"
+// @Success      200  {file}     file
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid package ID"
+// @Failure      404  {object}   types.ErrorResponse "package not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /packages/{packageID}/sessions.ics [get]
+func (h *Handler) handlePackageFeed(w http.ResponseWriter, r *http.Request) {
+	packageID, err := strconv.Atoi(mux.Vars(r)["packageID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid package ID"))
+		return
+	}
+
+	pkg, err := h.activityCastle.GetPackageByID(packageID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("package not found"))
+		return
+	}
+
+	events, err := h.calendarCastle.ListPackageEvents(packageID, time.Now().Add(-h.history))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeCalendar(w, pkg.Name, fmt.Sprintf("package-%d", packageID), events)
+}
+
+// OrganizerFeed godoc
+// @Summary      iCalendar feed of organizer sessions
+// @Description  Returns sessions of all public activities of organizer as iCalendar (RFC 5545) document
+// @Tags         calendar
+// @Produce      text/calendar
+// @Param        organizerID path int true "Organizer ID"
+// @Success      200  {file}     file
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid organizer ID"
+// @Failure      404  {object}   types.ErrorResponse "organizer not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /organizer/{organizerID}/sessions.ics [get]
+func (h *Handler) handleOrganizerFeed(w http.ResponseWriter, r *http.Request) {
+	organizerID, err := strconv.Atoi(mux.Vars(r)["organizerID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid organizer ID"))
+		return
+	}
+
+	if _, err := h.userCastle.GetOrganizerByID(organizerID); err != nil {
+		writeNotFoundError(w, err, "organizer not found")
+		return
+	}
+
+	user, err := h.userCastle.GetUserByID(organizerID)
+	if err != nil {
+		writeNotFoundError(w, err, "organizer not found")
+		return
+	}
+
+	events, err := h.calendarCastle.ListOrganizerEvents(organizerID, time.Now().Add(-h.history))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeCalendar(w, user.Username, fmt.Sprintf("organizer-%d", organizerID), events)
+}
+
+// UserFeed godoc
+// @Summary      Private iCalendar feed of user bookings
+// @Description  Returns confirmed and attended bookings of user as iCalendar (RFC 5545) document. Feed is reached by
+// @Description  secret token instead of signing in, so calendar applications can subscribe to it. Cancelled bookings
+// @Description  stay in feed with CANCELLED status
+// @Tags         calendar
+// @Produce      text/calendar
+// @Param        token path string true "Feed token"
+// @Success      200  {file}     file
+// @Failure      404  {object}   types.ErrorResponse "feed not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /calendar/feeds/{token}.ics [get]
+func (h *Handler) handleUserFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.calendarCastle.GetUserIDByFeedToken(mux.Vars(r)["token"])
+	if err != nil {
+		writeNotFoundError(w, err, "feed not found")
+		return
+	}
+
+	events, err := h.calendarCastle.ListUserEvents(userID, time.Now().Add(-h.history))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.writeCalendar(w, "My bookings", "bookings", events)
+}
+
+// GetMyFeed godoc
+// @Summary      Get URL of my booking feed
+// @Description  Returns private iCalendar feed URL of user bookings, creating it on first request
+// @Tags         calendar
+// @Produce      json
+// @Success      200  {object}   types.CalendarFeed
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /calendar/my [get]
+func (h *Handler) handleGetMyFeed(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r.Context())
+
+	token, createdAt, err := h.calendarCastle.GetFeedToken(userID)
+	if err == sql.ErrNoRows {
+		token, createdAt, err = h.resetFeed(userID)
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.CalendarFeed{URL: h.feedURL + "/" + token + ".ics", CreatedAt: createdAt})
+}
+
+// ResetMyFeed godoc
+// @Summary      Reset URL of my booking feed
+// @Description  Replaces token of private booking feed, URL shared before stops working
+// @Tags         calendar
+// @Produce      json
+// @Success      200  {object}   types.CalendarFeed
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /calendar/my/reset [post]
+func (h *Handler) handleResetMyFeed(w http.ResponseWriter, r *http.Request) {
+	token, createdAt, err := h.resetFeed(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.CalendarFeed{URL: h.feedURL + "/" + token + ".ics", CreatedAt: createdAt})
+}
+
+// ListCalendarImports godoc
+// @Summary      List calendar imports of activity
+// @Description  Returns calendars whose events are imported as sessions of activity
+// @Tags         calendar
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Success      200  {array}    types.CalendarImport
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid activity ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "activity not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/calendar-imports [get]
+func (h *Handler) handleListImports(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	if !h.checkActivityOwnership(w, r, activityID) {
+		return
+	}
+
+	imports, err := h.calendarCastle.ListCalendarImports(activityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no imports found, return an empty array
+	if len(imports) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.CalendarImport{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, imports)
+}
+
+// CreateCalendarImport godoc
+// @Summary      Register calendar import of activity
+// @Description  Registers organizer calendar whose events become sessions of activity. Sessions get capacity, language
+// @Description  and location of import. URL is optional, calendars without it are imported by uploading the file.
+// @Description  URL has to be http, https or webcal address of public host
+// @Tags         calendar
+// @Accept       json
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        payload body types.CalendarImportPayload true "Calendar import"
+// @Success      201  {object}   types.CalendarImport
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload or calendar URL"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "activity not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/calendar-imports/create [post]
+func (h *Handler) handleCreateImport(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	var payload types.CalendarImportPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	if payload.URL != nil {
+		if _, err := ParseURL(*payload.URL); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if !h.checkActivityOwnership(w, r, activityID) {
+		return
+	}
+
+	if payload.FkLocationID != nil {
+		location, err := h.locationCastle.GetLocationByID(*payload.FkLocationID)
+		if err != nil || location.FkActivityID != activityID {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("location %d does not belong to activity", *payload.FkLocationID))
+			return
+		}
+	}
+
+	id, err := h.calendarCastle.CreateCalendarImport(types.CalendarImport{
+		FkActivityID: activityID,
+		URL:          payload.URL,
+		Capacity:     payload.Capacity,
+		Language:     payload.Language,
+		FkLocationID: payload.FkLocationID,
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	created, err := h.calendarCastle.GetCalendarImportByID(int(id))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, created)
+}
+
+// DeleteCalendarImport godoc
+// @Summary      Delete calendar import
+// @Description  Stops syncing calendar, sessions it created are kept
+// @Tags         calendar
+// @Produce      json
+// @Param        importID path int true "Calendar import ID"
+// @Success      200  {string}   string "calendar import deleted"
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid calendar import ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "calendar import not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /calendar-imports/delete/{importID} [delete]
+func (h *Handler) handleDeleteImport(w http.ResponseWriter, r *http.Request) {
+	ci, ok := h.getManagedImport(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.calendarCastle.DeleteCalendarImport(ci.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "calendar import deleted")
+}
+
+// UploadCalendarImport godoc
+// @Summary      Import uploaded calendar file
+// @Description  Applies uploaded .ics file to sessions of activity. File is sent as multipart form field "file" or as
+// @Description  request body. Events, including recurring ones, become sessions starting until schedule horizon and are
+// @Description  matched to sessions by UID on every import. Sessions with bookings are never moved or deleted,
+// @Description  such changes are reported as conflicts
+// @Tags         calendar
+// @Accept       text/calendar
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        importID path int true "Calendar import ID"
+// @Param        file formData file false "Calendar file"
+// @Success      200  {object}   types.CalendarImportReport
+// @Failure      400  {object}   types.ErrorResponse "invalid calendar file"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "calendar import not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /calendar-imports/{importID}/upload [post]
+func (h *Handler) handleUploadImport(w http.ResponseWriter, r *http.Request) {
+	ci, ok := h.getManagedImport(w, r)
+	if !ok {
+		return
+	}
+
+	// Room for multipart headers besides the file itself
+	r.Body = http.MaxBytesReader(w, r.Body, h.importer.maxSize+64<<10)
+	body := io.Reader(r.Body)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing calendar file"))
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	data, err := h.importer.Read(body)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	h.runImport(w, ci, data)
+}
+
+// SyncCalendarImport godoc
+// @Summary      Import calendar from its URL
+// @Description  Downloads calendar from URL of import and applies it to sessions of activity like uploaded file
+// @Tags         calendar
+// @Produce      json
+// @Param        importID path int true "Calendar import ID"
+// @Success      200  {object}   types.CalendarImportReport
+// @Failure      400  {object}   types.ErrorResponse "calendar import has no URL, URL isn't public or invalid calendar file"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "calendar import not found"
+// @Failure      502  {object}   types.ErrorResponse "calendar couldn't be downloaded"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /calendar-imports/{importID}/sync [post]
+func (h *Handler) handleSyncImport(w http.ResponseWriter, r *http.Request) {
+	ci, ok := h.getManagedImport(w, r)
+	if !ok {
+		return
+	}
+
+	if ci.URL == nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("calendar import has no URL, upload the file instead"))
+		return
+	}
+
+	data, err := h.importer.Fetch(r.Context(), *ci.URL)
+	if err != nil {
+		if errors.Is(err, ErrPrivateAddress) {
+			// Dial errors would tell which address host resolved to
+			utils.WriteError(w, http.StatusBadRequest, ErrPrivateAddress)
+		} else {
+			utils.WriteError(w, http.StatusBadGateway, fmt.Errorf("calendar couldn't be downloaded: %v", err))
+		}
+		return
+	}
+
+	h.runImport(w, ci, data)
+}
+
+func (h *Handler) runImport(w http.ResponseWriter, ci *types.CalendarImport, data []byte) {
+	report, err := h.importer.Import(ci, data, time.Now())
+	if err != nil {
+		var parseError *ParseError
+		if errors.As(err, &parseError) {
+			utils.WriteError(w, http.StatusBadRequest, err)
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, report)
+}
+
+// getManagedImport returns calendar import from URL, writing error response unless user organizes its activity
+func (h *Handler) getManagedImport(w http.ResponseWriter, r *http.Request) (*types.CalendarImport, bool) {
+	importID, err := strconv.Atoi(mux.Vars(r)["importID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid calendar import ID"))
+		return nil, false
+	}
+
+	ci, err := h.calendarCastle.GetCalendarImportByID(importID)
+	if err != nil {
+		writeNotFoundError(w, err, "calendar import not found")
+		return nil, false
+	}
+
+	if !h.checkActivityOwnership(w, r, ci.FkActivityID) {
+		return nil, false
+	}
+
+	return ci, true
+}
+
+// checkActivityOwnership writes error response unless user organizes the activity
+func (h *Handler) checkActivityOwnership(w http.ResponseWriter, r *http.Request, activityID int) bool {
+	organizer, err := h.userCastle.GetOrganizerByActivityID(activityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity not found"))
+		return false
+	}
+
+	if !auth.CheckOwnership(r, organizer.ID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return false
+	}
+
+	return true
+}
+
+func (h *Handler) resetFeed(userID int) (string, time.Time, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if err := h.calendarCastle.SetFeedToken(userID, token); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return h.calendarCastle.GetFeedToken(userID)
+}
+
+func (h *Handler) writeCalendar(w http.ResponseWriter, name, filename string, events []*types.CalendarEvent) {
+	calendar := NewCalendar(name, h.domain, time.Now())
+	for _, e := range events {
+		calendar.AddEvent(e)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"%s.ics\"", filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(calendar.Bytes())
+}
+
+// newToken returns random URL safe token of 256 bits
+func newToken() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+func writeNotFoundError(w http.ResponseWriter, err error, message string) {
+	if err == sql.ErrNoRows {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("%s", message))
+	} else {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,117 @@
+package certificate
+
+import (
+	"database/sql"
+	"educations-castle/types"
+)
+
+type Castle struct {
+	db *sql.DB
+}
+
+func NewCastle(db *sql.DB) *Castle {
+	return &Castle{db: db}
+}
+
+func scanRowIntoCertificate(rows *sql.Rows) (*types.Certificate, error) {
+	c := new(types.Certificate)
+
+	err := rows.Scan(
+		&c.ID,
+		&c.Code,
+		&c.FkBookingID,
+		&c.RecipientName,
+		&c.ActivityName,
+		&c.OrganizerName,
+		&c.SessionDate,
+		&c.Participants,
+		&c.Template,
+		&c.Title,
+		&c.SignatureName,
+		&c.SignatureTitle,
+		&c.IssuedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// IssueCertificate stores certificate of booking unless it was issued already. Certificate of booking is
+// returned either way, so concurrent downloads end up with the same code
+func (c *Castle) IssueCertificate(cert types.Certificate) (*types.Certificate, error) {
+	_, err := c.db.Exec(
+		`INSERT IGNORE INTO certificate (code, fk_Bookingid, recipientName, activityName, organizerName, sessionDate,
+			participants, template, title, signatureName, signatureTitle)
+		VALUES (?,?,?,?,?,?,?,?,?,?,?)`,
+		cert.Code, cert.FkBookingID, cert.RecipientName, cert.ActivityName, cert.OrganizerName, cert.SessionDate.UTC(),
+		cert.Participants, cert.Template, cert.Title, cert.SignatureName, cert.SignatureTitle)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.GetCertificateByBookingID(cert.FkBookingID)
+}
+
+func (c *Castle) GetCertificateByBookingID(bookingID int) (*types.Certificate, error) {
+	return c.getCertificate("SELECT * FROM certificate WHERE fk_Bookingid = ?", bookingID)
+}
+
+func (c *Castle) GetCertificateByCode(code string) (*types.Certificate, error) {
+	return c.getCertificate("SELECT * FROM certificate WHERE code = ?", code)
+}
+
+func (c *Castle) getCertificate(query string, params ...interface{}) (*types.Certificate, error) {
+	rows, err := c.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cert *types.Certificate
+	for rows.Next() {
+		cert, err = scanRowIntoCertificate(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if cert == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	return cert, nil
+}
+
+// GetCertificateTemplate returns template chosen for package, classic template without title and signature
+// when organizer didn't choose any
+func (c *Castle) GetCertificateTemplate(packageID int) (*types.CertificateTemplate, error) {
+	t := &types.CertificateTemplate{FkPackageID: packageID, Template: types.CertificateClassic}
+
+	err := c.db.QueryRow("SELECT template, title, signatureName, signatureTitle FROM certificatetemplate WHERE fk_Packageid = ?", packageID).
+		Scan(&t.Template, &t.Title, &t.SignatureName, &t.SignatureTitle)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (c *Castle) SaveCertificateTemplate(t types.CertificateTemplate) error {
+	_, err := c.db.Exec(
+		`INSERT INTO certificatetemplate (fk_Packageid, template, title, signatureName, signatureTitle) VALUES (?,?,?,?,?)
+		ON DUPLICATE KEY UPDATE template = VALUES(template), title = VALUES(title), signatureName = VALUES(signatureName),
+			signatureTitle = VALUES(signatureTitle)`,
+		t.FkPackageID, t.Template, t.Title, t.SignatureName, t.SignatureTitle)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package certificate
+
+import (
+	"crypto/rand"
+	"educations-castle/types"
+	"encoding/base32"
+	"strings"
+)
+
+// newCode returns random verification code of 80 bits, base32 characters in dash separated groups of four,
+// e.g. 7MZQ-4K2D-XR6P-AVNE
+func newCode() (string, error) {
+	random := make([]byte, 10)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	encoded := base32.StdEncoding.EncodeToString(random)
+	groups := make([]string, 0, len(encoded)/4)
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+
+	return strings.Join(groups, "-"), nil
+}
+
+// recipientName returns name certificate is issued to, group with its school for group bookings
+func recipientName(b *types.Booking, user *types.User) string {
+	if b.Group == nil {
+		return user.Username
+	}
+	if b.Group.School != nil && *b.Group.School != "" {
+		return b.Group.Name + ", " + *b.Group.School
+	}
+	return b.Group.Name
+}
@@ -0,0 +1,101 @@
+package certificate
+
+import (
+	"bytes"
+	"educations-castle/types"
+	"regexp"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestCode(t *testing.T) {
+	t.Run("Should generate codes of four base32 groups", func(t *testing.T) {
+		pattern := regexp.MustCompile(`^[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}$`)
+		seen := map[string]bool{}
+		for range 100 {
+			code, err := newCode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !pattern.MatchString(code) {
+				t.Errorf("unexpected code %s", code)
+			}
+			if seen[code] {
+				t.Errorf("code %s generated twice", code)
+			}
+			seen[code] = true
+		}
+	})
+}
+
+func TestRecipientName(t *testing.T) {
+	user := &types.User{Username: "jonas"}
+	school := "Vilniaus licėjus"
+
+	tests := []struct {
+		name     string
+		booking  *types.Booking
+		expected string
+	}{
+		{"user booking", &types.Booking{}, "jonas"},
+		{"group booking", &types.Booking{Group: &types.BookingGroup{Name: "5B"}}, "5B"},
+		{"group booking with school", &types.Booking{Group: &types.BookingGroup{Name: "5B", School: &school}}, "5B, Vilniaus licėjus"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if name := recipientName(test.booking, user); name != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, name)
+			}
+		})
+	}
+}
+
+func TestWinAnsi(t *testing.T) {
+	t.Run("Should drop diacritics outside of Windows-1252", func(t *testing.T) {
+		decoded, err := charmap.Windows1252.NewDecoder().String(winAnsi("Ąžuolas Čiurlionis – Kęstutis"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != "Ažuolas Ciurlionis – Kestutis" {
+			t.Errorf("unexpected text %q", decoded)
+		}
+	})
+
+	t.Run("Should replace letters without Latin base", func(t *testing.T) {
+		if text := winAnsi("Ω"); text != "?" {
+			t.Errorf("expected ?, got %q", text)
+		}
+	})
+}
+
+func TestRender(t *testing.T) {
+	title := "Pažymėjimas"
+	signature := "Ona Onaitytė"
+	cert := &types.Certificate{
+		Code:          "7MZQ-4K2D-XR6P-AVNE",
+		RecipientName: "5B, Vilniaus licėjus",
+		ActivityName:  "Gintaro kelias",
+		OrganizerName: "organizer",
+		SessionDate:   time.Date(2024, 12, 17, 9, 0, 0, 0, time.UTC),
+		Participants:  26,
+		Title:         &title,
+		SignatureName: &signature,
+		IssuedAt:      time.Date(2024, 12, 18, 9, 0, 0, 0, time.UTC),
+	}
+
+	for _, template := range []string{types.CertificateClassic, types.CertificateModern, types.CertificateMinimal, ""} {
+		t.Run("Should render "+template+" template", func(t *testing.T) {
+			cert.Template = template
+			var document bytes.Buffer
+			if err := NewRenderer("", "http://localhost/verify/").Render(&document, cert, time.UTC); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(document.Bytes(), []byte("%PDF")) {
+				t.Errorf("expected PDF document")
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package certificate
+
+import (
+	"educations-castle/types"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Custom font is registered under this family, it has regular style only
+const customFont = "certificate"
+
+// style is look of certificate template
+type style struct {
+	family string
+	accent [3]int
+	frame  bool
+	band   bool
+	title  string
+}
+
+var styles = map[string]style{
+	types.CertificateClassic: {family: "Times", accent: [3]int{122, 74, 28}, frame: true, title: "Certificate of Completion"},
+	types.CertificateModern:  {family: "Helvetica", accent: [3]int{216, 154, 43}, band: true, title: "Certificate of Completion"},
+	types.CertificateMinimal: {family: "Helvetica", accent: [3]int{60, 60, 60}, title: "Certificate"},
+}
+
+// Renderer draws certificates as A4 landscape PDF. Core PDF fonts cover Western European letters only,
+// TrueType font file renders any name as it is
+type Renderer struct {
+	fontFile  string
+	verifyURL string
+}
+
+func NewRenderer(fontFile, verifyURL string) *Renderer {
+	return &Renderer{fontFile: fontFile, verifyURL: strings.TrimRight(verifyURL, "/")}
+}
+
+// Render writes PDF of certificate, session date is shown in given location
+func (r *Renderer) Render(w io.Writer, c *types.Certificate, location *time.Location) error {
+	s, ok := styles[c.Template]
+	if !ok {
+		s = styles[types.CertificateClassic]
+	}
+	title := s.title
+	if c.Title != nil {
+		title = *c.Title
+	}
+
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("educations-castle", true)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+	width, height := pdf.GetPageSize()
+
+	text := winAnsi
+	bold := "B"
+	if r.fontFile != "" {
+		pdf.AddUTF8Font(customFont, "", r.fontFile)
+		s.family, bold = customFont, ""
+		text = func(t string) string { return t }
+	}
+	setFont := func(style string, size float64) {
+		pdf.SetFont(s.family, style, size)
+	}
+	centered := func(y, lineHeight float64, t string) {
+		pdf.SetXY(20, y)
+		pdf.CellFormat(width-40, lineHeight, text(t), "", 0, "C", false, 0, "")
+	}
+
+	pdf.SetDrawColor(s.accent[0], s.accent[1], s.accent[2])
+	pdf.SetFillColor(s.accent[0], s.accent[1], s.accent[2])
+	if s.frame {
+		pdf.SetLineWidth(1.2)
+		pdf.Rect(10, 10, width-20, height-20, "D")
+		pdf.SetLineWidth(0.4)
+		pdf.Rect(14, 14, width-28, height-28, "D")
+	}
+	if s.band {
+		pdf.Rect(0, 0, width, 18, "F")
+		pdf.Rect(0, height-8, width, 8, "F")
+	}
+
+	pdf.SetTextColor(s.accent[0], s.accent[1], s.accent[2])
+	setFont(bold, 34)
+	centered(38, 16, title)
+
+	pdf.SetTextColor(40, 40, 40)
+	setFont("", 14)
+	centered(66, 8, "This certifies that")
+
+	pdf.SetTextColor(s.accent[0], s.accent[1], s.accent[2])
+	setFont(bold, 26)
+	centered(78, 12, c.RecipientName)
+
+	pdf.SetTextColor(40, 40, 40)
+	setFont("", 14)
+	attended := "attended the education program"
+	if c.Participants > 1 {
+		attended = fmt.Sprintf("with %d participants attended the education program", c.Participants)
+	}
+	centered(96, 8, attended)
+
+	setFont(bold, 20)
+	centered(106, 10, c.ActivityName)
+
+	setFont("", 13)
+	centered(120, 8, fmt.Sprintf("organized by %s on %s", c.OrganizerName, c.SessionDate.In(location).Format("2 January 2006")))
+
+	if c.SignatureName != nil {
+		pdf.SetLineWidth(0.3)
+		pdf.Line(30, 168, 110, 168)
+		setFont("", 12)
+		pdf.SetXY(30, 170)
+		pdf.CellFormat(80, 6, text(*c.SignatureName), "", 2, "C", false, 0, "")
+		if c.SignatureTitle != nil {
+			setFont("", 10)
+			pdf.CellFormat(80, 5, text(*c.SignatureTitle), "", 0, "C", false, 0, "")
+		}
+	}
+
+	setFont("", 10)
+	pdf.SetXY(width-130, 166)
+	pdf.CellFormat(100, 5, text("Verification code: "+c.Code), "", 2, "R", false, 0, "")
+	pdf.CellFormat(100, 5, text(r.verifyURL+"/"+c.Code), "", 2, "R", false, 0, "")
+	pdf.CellFormat(100, 5, text("Issued "+c.IssuedAt.In(location).Format("2006-01-02")), "", 0, "R", false, 0, "")
+
+	return pdf.Output(w)
+}
+
+// winAnsi converts text for core PDF fonts, which cover Windows-1252 only. Letters outside of it lose their
+// diacritics, so Lithuanian ą, č and ė become a, c and e, while š and ž are kept
+func winAnsi(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if c, ok := charmap.Windows1252.EncodeRune(r); ok {
+			b.WriteByte(c)
+			continue
+		}
+
+		written := false
+		for _, d := range norm.NFD.String(string(r)) {
+			if c, ok := charmap.Windows1252.EncodeRune(d); ok && !unicode.Is(unicode.Mn, d) {
+				b.WriteByte(c)
+				written = true
+			}
+		}
+		if !written {
+			b.WriteByte('?')
+		}
+	}
+
+	return b.String()
+}
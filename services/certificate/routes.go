@@ -0,0 +1,299 @@
+package certificate
+
+import (
+	"bytes"
+	"database/sql"
+	"educations-castle/services/auth"
+	"educations-castle/types"
+	"educations-castle/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	certificateCastle types.CertificateCastle
+	bookingCastle     types.BookingCastle
+	sessionCastle     types.SessionCastle
+	activityCastle    types.ActivityCastle
+	userCastle        types.UserCastle
+	renderer          *Renderer
+	location          *time.Location
+}
+
+func NewHandler(certificateCastle types.CertificateCastle, bookingCastle types.BookingCastle, sessionCastle types.SessionCastle,
+	activityCastle types.ActivityCastle, userCastle types.UserCastle, renderer *Renderer, location *time.Location) *Handler {
+	return &Handler{
+		certificateCastle: certificateCastle,
+		bookingCastle:     bookingCastle,
+		sessionCastle:     sessionCastle,
+		activityCastle:    activityCastle,
+		userCastle:        userCastle,
+		renderer:          renderer,
+		location:          location}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/bookings/{bookingID:[0-9]+}/certificate", auth.WithJWTAuth(h.handleGetCertificate, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/certificates/verify/{code}", h.handleVerifyCertificate).Methods("GET")
+	router.HandleFunc("/packages/{packageID:[0-9]+}/certificate-template", auth.WithJWTAuth(h.handleGetTemplate, h.userCastle, "administrator", "organizer")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/packages/{packageID:[0-9]+}/certificate-template/update", auth.WithJWTAuth(h.handleUpdateTemplate, h.userCastle, "administrator", "organizer")).Methods("PUT", "OPTIONS")
+}
+
+// GetCertificate godoc
+// @Summary      Download certificate of attended booking
+// @Description  Returns PDF certificate confirming booking attended activity, with activity, organizer, date, attendee or group
+// @Description  name and verification code. Certificate is issued on first download in template of activity package and stays the same afterwards.
+// @Description  Certificates can be downloaded by user who made booking and organizer of the activity
+// @Tags         certificate
+// @Produce      application/pdf
+// @Param        bookingID path int true "Booking ID"
+// @Success      200  {file}     file
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid booking ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "booking not found"
+// @Failure      409  {object}   types.ErrorResponse "booking is not attended"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /bookings/{bookingID}/certificate [get]
+func (h *Handler) handleGetCertificate(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := strconv.Atoi(mux.Vars(r)["bookingID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid booking ID"))
+		return
+	}
+
+	booking, err := h.bookingCastle.GetBookingByID(bookingID)
+	if err != nil {
+		writeNotFoundError(w, err, "booking not found")
+		return
+	}
+
+	session, err := h.sessionCastle.GetSessionByID(booking.FkActivitySessionID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	organizer, err := h.userCastle.GetOrganizerByActivityID(session.FkActivityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !auth.CheckOwnership(r, booking.FkUserID) && !auth.CheckOwnership(r, organizer.ID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	if booking.Status != types.BookingAttended {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("booking is %s, certificates are issued for attended bookings", booking.Status))
+		return
+	}
+
+	cert, err := h.certificateCastle.GetCertificateByBookingID(booking.ID)
+	if err == sql.ErrNoRows {
+		cert, err = h.issue(booking, session, organizer)
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var document bytes.Buffer
+	if err := h.renderer.Render(&document, cert, h.location); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"certificate-%s.pdf\"", cert.Code))
+	w.WriteHeader(http.StatusOK)
+	w.Write(document.Bytes())
+}
+
+// issue stores certificate of booking with details of its activity and template of activity package
+func (h *Handler) issue(booking *types.Booking, session *types.ActivitySession, organizer *types.Organizer) (*types.Certificate, error) {
+	activity, err := h.activityCastle.GetActivityByID(session.FkActivityID)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := h.certificateCastle.GetCertificateTemplate(activity.FkPackageID)
+	if err != nil {
+		return nil, err
+	}
+
+	organizerUser, err := h.userCastle.GetUserByID(organizer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := h.userCastle.GetUserByID(booking.FkUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := newCode()
+	if err != nil {
+		return nil, err
+	}
+
+	return h.certificateCastle.IssueCertificate(types.Certificate{
+		Code:           code,
+		FkBookingID:    booking.ID,
+		RecipientName:  recipientName(booking, user),
+		ActivityName:   activity.Name,
+		OrganizerName:  organizerUser.Username,
+		SessionDate:    session.StartTime,
+		Participants:   booking.Seats,
+		Template:       template.Template,
+		Title:          template.Title,
+		SignatureName:  template.SignatureName,
+		SignatureTitle: template.SignatureTitle,
+	})
+}
+
+// VerifyCertificate godoc
+// @Summary      Verify certificate
+// @Description  Confirms that certificate with verification code was issued and returns details printed on it
+// @Tags         certificate
+// @Produce      json
+// @Param        code path string true "Verification code"
+// @Success      200  {object}   types.CertificateVerification
+// @Failure      404  {object}   types.ErrorResponse "certificate not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /certificates/verify/{code} [get]
+func (h *Handler) handleVerifyCertificate(w http.ResponseWriter, r *http.Request) {
+	cert, err := h.certificateCastle.GetCertificateByCode(strings.ToUpper(strings.TrimSpace(mux.Vars(r)["code"])))
+	if err != nil {
+		writeNotFoundError(w, err, "certificate not found")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.CertificateVerification{
+		Valid:         true,
+		Code:          cert.Code,
+		RecipientName: cert.RecipientName,
+		ActivityName:  cert.ActivityName,
+		OrganizerName: cert.OrganizerName,
+		SessionDate:   cert.SessionDate,
+		Participants:  cert.Participants,
+		IssuedAt:      cert.IssuedAt,
+	})
+}
+
+// GetCertificateTemplate godoc
+// @Summary      Get certificate template of package
+// @Description  Returns template of certificates issued for activities of package, classic template when organizer didn't choose any
+// @Tags         certificate
+// @Produce      json
+// @Param        packageID path int true "Package ID"
+// @Success      200  {object}   types.CertificateTemplate
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid package ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "package not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /packages/{packageID}/certificate-template [get]
+func (h *Handler) handleGetTemplate(w http.ResponseWriter, r *http.Request) {
+	packageID, ok := h.getManagedPackageID(w, r)
+	if !ok {
+		return
+	}
+
+	template, err := h.certificateCastle.GetCertificateTemplate(packageID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, template)
+}
+
+// UpdateCertificateTemplate godoc
+// @Summary      Choose certificate template of package
+// @Description  Sets template, title and signature of certificates issued for activities of package from now on.
+// @Description  Certificates issued already keep their look
+// @Tags         certificate
+// @Accept       json
+// @Produce      json
+// @Param        packageID path int true "Package ID"
+// @Param        payload body types.CertificateTemplatePayload true "Certificate template"
+// @Success      200  {object}   types.CertificateTemplate
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "package not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /packages/{packageID}/certificate-template/update [put]
+func (h *Handler) handleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	packageID, ok := h.getManagedPackageID(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.CertificateTemplatePayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	err := h.certificateCastle.SaveCertificateTemplate(types.CertificateTemplate{
+		FkPackageID:    packageID,
+		Template:       payload.Template,
+		Title:          payload.Title,
+		SignatureName:  payload.SignatureName,
+		SignatureTitle: payload.SignatureTitle,
+	})
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	saved, err := h.certificateCastle.GetCertificateTemplate(packageID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, saved)
+}
+
+// getManagedPackageID returns package ID from URL, writing error response unless user organizes the package
+func (h *Handler) getManagedPackageID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	packageID, err := strconv.Atoi(mux.Vars(r)["packageID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid package ID"))
+		return 0, false
+	}
+
+	pkg, err := h.activityCastle.GetPackageByID(packageID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("package not found"))
+		return 0, false
+	}
+
+	if !auth.CheckOwnership(r, pkg.FkOrganizerID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return 0, false
+	}
+
+	return packageID, true
+}
+
+func writeNotFoundError(w http.ResponseWriter, err error, message string) {
+	if err == sql.ErrNoRows {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("%s", message))
+	} else {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,227 @@
+package discount
+
+import (
+	"database/sql"
+	"educations-castle/types"
+	"time"
+)
+
+// Discounts are selected together with number of redemptions in bookings which are not cancelled
+const selectDiscounts = `SELECT discount.*,
+	(SELECT COUNT(*) FROM discountredemption
+		JOIN booking ON booking.id = discountredemption.fk_Bookingid
+		WHERE discountredemption.fk_Discountid = discount.id AND booking.status <> 'cancelled') AS redemptions
+	FROM discount`
+
+type Castle struct {
+	db *sql.DB
+}
+
+func NewCastle(db *sql.DB) *Castle {
+	return &Castle{db: db}
+}
+
+func scanRowIntoDiscount(rows *sql.Rows) (*types.Discount, error) {
+	d := new(types.Discount)
+	var amount *int64
+	var currency *string
+
+	err := rows.Scan(
+		&d.ID,
+		&d.FkOrganizerID,
+		&d.Code,
+		&d.Name,
+		&d.Kind,
+		&d.Percent,
+		&amount,
+		&currency,
+		&d.Scope,
+		&d.ScopeID,
+		&d.ValidFrom,
+		&d.ValidUntil,
+		&d.MaxRedemptions,
+		&d.MaxPerUser,
+		&d.Active,
+		&d.CreatedAt,
+		&d.Redemptions,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	d.Amount = types.NullableMoney(amount, currency)
+
+	return d, nil
+}
+
+func (c *Castle) CreateDiscount(d types.Discount) (int64, error) {
+	amount, currency := types.MoneyColumns(d.Amount)
+	result, err := c.db.Exec(
+		`INSERT INTO discount (fk_Organizerid, code, name, kind, percent, amount, currency, scope, scopeId, validFrom,
+			validUntil, maxRedemptions, maxPerUser, active)
+		VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+		d.FkOrganizerID, d.Code, d.Name, d.Kind, d.Percent, amount, currency, d.Scope, d.ScopeID, d.ValidFrom.UTC(),
+		d.ValidUntil.UTC(), d.MaxRedemptions, d.MaxPerUser, d.Active)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (c *Castle) GetDiscountByID(id int) (*types.Discount, error) {
+	return c.getDiscount(selectDiscounts+" WHERE discount.id = ?", id)
+}
+
+func (c *Castle) GetDiscountByCode(code string) (*types.Discount, error) {
+	return c.getDiscount(selectDiscounts+" WHERE discount.code = ?", code)
+}
+
+func (c *Castle) getDiscount(query string, params ...interface{}) (*types.Discount, error) {
+	discounts, err := c.listDiscounts(query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(discounts) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return discounts[0], nil
+}
+
+// ListDiscounts returns discounts of organizer or, without organizer, all discounts. Latest discounts come first
+func (c *Castle) ListDiscounts(organizerID *int) ([]*types.Discount, error) {
+	if organizerID == nil {
+		return c.listDiscounts(selectDiscounts + " ORDER BY discount.validFrom DESC, discount.id DESC")
+	}
+
+	return c.listDiscounts(selectDiscounts+" WHERE discount.fk_Organizerid = ? ORDER BY discount.validFrom DESC, discount.id DESC",
+		*organizerID)
+}
+
+// ListApplicableDiscounts returns active discounts valid at given time which user can still use for activity.
+// With code only discount of that code is returned, otherwise campaigns applied automatically
+func (c *Castle) ListApplicableDiscounts(activityID, userID int, code string, at time.Time) ([]*types.Discount, error) {
+	query := `SELECT discount.*, used.redemptions
+		FROM discount
+		JOIN activity ON activity.id = ?
+		JOIN package ON package.id = activity.fk_Packageid
+		JOIN (SELECT discount.id,
+				COUNT(booking.id) AS redemptions,
+				COALESCE(SUM(booking.fk_Userid = ?), 0) AS userRedemptions
+			FROM discount
+			LEFT JOIN discountredemption ON discountredemption.fk_Discountid = discount.id
+			LEFT JOIN booking ON booking.id = discountredemption.fk_Bookingid AND booking.status <> 'cancelled'
+			GROUP BY discount.id) AS used ON used.id = discount.id
+		WHERE discount.active = 1 AND discount.validFrom <= ? AND discount.validUntil > ?
+			AND (discount.fk_Organizerid IS NULL OR discount.fk_Organizerid = package.fk_Organizerid)
+			AND ((discount.scope = 'organizer' AND discount.scopeId = package.fk_Organizerid)
+				OR (discount.scope = 'package' AND discount.scopeId = package.id)
+				OR (discount.scope = 'activity' AND discount.scopeId = activity.id)
+				OR (discount.scope = 'category' AND discount.scopeId = activity.category))
+			AND (discount.currency IS NULL OR discount.currency = activity.currency)
+			AND (discount.maxRedemptions IS NULL OR used.redemptions < discount.maxRedemptions)
+			AND (discount.maxPerUser IS NULL OR used.userRedemptions < discount.maxPerUser)`
+	params := []interface{}{activityID, userID, at.UTC(), at.UTC()}
+
+	if code != "" {
+		query += " AND discount.code = ?"
+		params = append(params, code)
+	} else {
+		query += " AND discount.code IS NULL"
+	}
+	query += " ORDER BY discount.id"
+
+	return c.listDiscounts(query, params...)
+}
+
+func (c *Castle) listDiscounts(query string, params ...interface{}) ([]*types.Discount, error) {
+	rows, err := c.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var discounts []*types.Discount
+
+	for rows.Next() {
+		d, err := scanRowIntoDiscount(rows)
+		if err != nil {
+			return nil, err
+		}
+		discounts = append(discounts, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return discounts, nil
+}
+
+func (c *Castle) UpdateDiscount(d types.Discount) error {
+	amount, currency := types.MoneyColumns(d.Amount)
+	_, err := c.db.Exec(
+		`UPDATE discount
+		SET code = ?, name = ?, kind = ?, percent = ?, amount = ?, currency = ?, scope = ?, scopeId = ?, validFrom = ?,
+			validUntil = ?, maxRedemptions = ?, maxPerUser = ?, active = ?
+		WHERE id = ?`,
+		d.Code, d.Name, d.Kind, d.Percent, amount, currency, d.Scope, d.ScopeID, d.ValidFrom.UTC(), d.ValidUntil.UTC(),
+		d.MaxRedemptions, d.MaxPerUser, d.Active, d.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Castle) DeleteDiscount(id int) error {
+	_, err := c.db.Exec("DELETE FROM discount WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListDiscountRedemptions returns bookings discount was used in, in order they were made
+func (c *Castle) ListDiscountRedemptions(discountID int) ([]*types.DiscountRedemption, error) {
+	rows, err := c.db.Query(
+		`SELECT booking.id, user.id, user.username, discountredemption.amount, booking.amount, booking.currency,
+			booking.status, discountredemption.createdAt
+		FROM discountredemption
+		JOIN booking ON booking.id = discountredemption.fk_Bookingid
+		JOIN user ON user.id = discountredemption.fk_Userid
+		WHERE discountredemption.fk_Discountid = ?
+		ORDER BY discountredemption.createdAt, booking.id`, discountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var redemptions []*types.DiscountRedemption
+
+	for rows.Next() {
+		r := new(types.DiscountRedemption)
+		err := rows.Scan(&r.BookingID, &r.UserID, &r.Username, &r.Discount.Amount, &r.BookingAmount.Amount,
+			&r.BookingAmount.Currency, &r.BookingStatus, &r.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		r.Discount.Currency = r.BookingAmount.Currency
+		redemptions = append(redemptions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return redemptions, nil
+}
+
+func (c *Castle) CategoryExists(id int) (bool, error) {
+	var count int
+	err := c.db.QueryRow("SELECT COUNT(*) FROM category WHERE id_Category = ?", id).Scan(&count)
+	return count > 0, err
+}
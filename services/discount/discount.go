@@ -0,0 +1,37 @@
+package discount
+
+import (
+	"educations-castle/types"
+	"errors"
+)
+
+var (
+	ErrInvalidCode = errors.New("promo code is not valid for this booking")
+	ErrUsedUp      = errors.New("discount has reached its usage limit")
+)
+
+// Off returns amount discount takes off price. Fixed discounts never take more than the whole price
+func Off(d *types.Discount, price types.Money) types.Money {
+	switch {
+	case d.Kind == types.DiscountPercent && d.Percent != nil:
+		return price.Percent(*d.Percent)
+	case d.Kind == types.DiscountFixed && d.Amount != nil:
+		return types.Money{Amount: min(d.Amount.Amount, price.Amount), Currency: price.Currency}
+	}
+
+	return types.Money{Currency: price.Currency}
+}
+
+// Best returns discount taking the most off price, discounts don't add up. Nil is returned when none takes anything
+func Best(discounts []*types.Discount, price types.Money) *types.Discount {
+	var best *types.Discount
+	var bestOff int64
+
+	for _, d := range discounts {
+		if off := Off(d, price).Amount; off > bestOff {
+			best, bestOff = d, off
+		}
+	}
+
+	return best
+}
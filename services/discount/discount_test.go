@@ -0,0 +1,59 @@
+package discount
+
+import (
+	"educations-castle/types"
+	"testing"
+)
+
+func TestOff(t *testing.T) {
+	percent := 20
+	price := types.Money{Amount: 1999, Currency: "EUR"}
+
+	tests := []struct {
+		name     string
+		discount *types.Discount
+		expected int64
+	}{
+		{"Should round percent down", &types.Discount{Kind: types.DiscountPercent, Percent: &percent}, 399},
+		{"Should take fixed amount", &types.Discount{Kind: types.DiscountFixed, Amount: &types.Money{Amount: 500, Currency: "EUR"}}, 500},
+		{"Should not take more than price", &types.Discount{Kind: types.DiscountFixed, Amount: &types.Money{Amount: 5000, Currency: "EUR"}}, 1999},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if off := Off(test.discount, price); off.Amount != test.expected || off.Currency != "EUR" {
+				t.Errorf("expected %d, got %v", test.expected, off)
+			}
+		})
+	}
+}
+
+func TestBest(t *testing.T) {
+	percent := 20
+	campaign := &types.Discount{ID: 1, Kind: types.DiscountPercent, Percent: &percent}
+	fixed := &types.Discount{ID: 2, Kind: types.DiscountFixed, Amount: &types.Money{Amount: 500, Currency: "EUR"}}
+
+	if best := Best([]*types.Discount{campaign, fixed}, types.Money{Amount: 2000, Currency: "EUR"}); best != fixed {
+		t.Errorf("expected fixed discount on cheap booking, got %+v", best)
+	}
+	if best := Best([]*types.Discount{campaign, fixed}, types.Money{Amount: 10000, Currency: "EUR"}); best != campaign {
+		t.Errorf("expected percent discount on expensive booking, got %+v", best)
+	}
+	if best := Best([]*types.Discount{campaign}, types.Money{Currency: "EUR"}); best != nil {
+		t.Errorf("expected no discount of free booking, got %+v", best)
+	}
+}
+
+func TestReport(t *testing.T) {
+	redemptions := []*types.DiscountRedemption{
+		{BookingID: 1, Discount: types.Money{Amount: 400, Currency: "EUR"}, BookingStatus: types.BookingConfirmed},
+		{BookingID: 2, Discount: types.Money{Amount: 400, Currency: "EUR"}, BookingStatus: types.BookingCancelled},
+		{BookingID: 3, Discount: types.Money{Amount: 250, Currency: "EUR"}, BookingStatus: types.BookingPending},
+	}
+
+	r := report(&types.Discount{ID: 1}, redemptions)
+
+	if r.Redemptions != 2 || len(r.TotalDiscount) != 1 || r.TotalDiscount[0].Amount != 650 || len(r.Bookings) != 3 {
+		t.Errorf("unexpected report %+v", r)
+	}
+}
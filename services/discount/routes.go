@@ -0,0 +1,379 @@
+package discount
+
+import (
+	"database/sql"
+	"educations-castle/services/auth"
+	"educations-castle/types"
+	"educations-castle/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	discountCastle types.DiscountCastle
+	activityCastle types.ActivityCastle
+	userCastle     types.UserCastle
+}
+
+func NewHandler(discountCastle types.DiscountCastle, activityCastle types.ActivityCastle, userCastle types.UserCastle) *Handler {
+	return &Handler{
+		discountCastle: discountCastle,
+		activityCastle: activityCastle,
+		userCastle:     userCastle}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/discounts", auth.WithJWTAuth(h.handleListDiscounts, h.userCastle, "administrator", "organizer")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/discounts/create", auth.WithJWTAuth(h.handleCreateDiscount, h.userCastle, "administrator", "organizer")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/discounts/update/{discountID:[0-9]+}", auth.WithJWTAuth(h.handleUpdateDiscount, h.userCastle, "administrator", "organizer")).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/discounts/delete/{discountID:[0-9]+}", auth.WithJWTAuth(h.handleDeleteDiscount, h.userCastle, "administrator", "organizer")).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/discounts/{discountID:[0-9]+}/report", auth.WithJWTAuth(h.handleGetDiscountReport, h.userCastle, "administrator", "organizer")).Methods("GET", "OPTIONS")
+}
+
+// ListDiscounts godoc
+// @Summary      List discounts
+// @Description  Returns promo codes and campaigns of organizer, administrators get discounts of all organizers
+// @Tags         discount
+// @Produce      json
+// @Success      200  {array}    types.Discount
+// @Failure      401  {object}   types.ErrorResponse "unauthorized"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /discounts [get]
+func (h *Handler) handleListDiscounts(w http.ResponseWriter, r *http.Request) {
+	viewer := auth.GetViewerFromContext(r.Context())
+
+	var organizerID *int
+	if viewer.Role != "administrator" {
+		organizerID = &viewer.UserID
+	}
+
+	discounts, err := h.discountCastle.ListDiscounts(organizerID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if len(discounts) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.Discount{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, discounts)
+}
+
+// CreateDiscount godoc
+// @Summary      Create discount
+// @Description  Creates promo code or, without code, campaign applied to bookings automatically. Discount takes percent or fixed
+// @Description  amount off bookings of organizer, package, activity or category made within validity window. Organizers can scope
+// @Description  discounts only to their own packages and activities, discounts of administrators apply to all organizers
+// @Tags         discount
+// @Accept       json
+// @Produce      json
+// @Param        payload body types.DiscountPayload true "Discount data"
+// @Success      201  {object}   types.Discount
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      422  {object}   types.ErrorResponse "promo code already exists"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /discounts/create [post]
+func (h *Handler) handleCreateDiscount(w http.ResponseWriter, r *http.Request) {
+	viewer := auth.GetViewerFromContext(r.Context())
+
+	d, ok := h.parsePayload(w, r, viewer)
+	if !ok {
+		return
+	}
+	if viewer.Role != "administrator" {
+		d.FkOrganizerID = &viewer.UserID
+	}
+
+	if d.Code != nil {
+		if _, err := h.discountCastle.GetDiscountByCode(*d.Code); err == nil {
+			utils.WriteError(w, http.StatusUnprocessableEntity, fmt.Errorf("promo code %s already exists", *d.Code))
+			return
+		}
+	}
+
+	discountID, err := h.discountCastle.CreateDiscount(d)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	created, err := h.discountCastle.GetDiscountByID(int(discountID))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, created)
+}
+
+// UpdateDiscount godoc
+// @Summary      Update discount
+// @Description  Updates discount by ID and specifying the new values. Redemptions made so far are kept
+// @Tags         discount
+// @Accept       json
+// @Produce      json
+// @Param        discountID path int true "Discount ID"
+// @Param        payload body types.DiscountPayload true "Discount data"
+// @Success      200  {object}   types.Discount
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "discount not found"
+// @Failure      422  {object}   types.ErrorResponse "promo code already exists"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /discounts/update/{discountID} [put]
+func (h *Handler) handleUpdateDiscount(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.getManagedDiscount(w, r)
+	if !ok {
+		return
+	}
+
+	d, ok := h.parsePayload(w, r, auth.GetViewerFromContext(r.Context()))
+	if !ok {
+		return
+	}
+	d.ID = existing.ID
+	d.FkOrganizerID = existing.FkOrganizerID
+
+	if d.Code != nil {
+		if other, err := h.discountCastle.GetDiscountByCode(*d.Code); err == nil && other.ID != d.ID {
+			utils.WriteError(w, http.StatusUnprocessableEntity, fmt.Errorf("promo code %s already exists", *d.Code))
+			return
+		}
+	}
+
+	if err := h.discountCastle.UpdateDiscount(d); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	updated, err := h.discountCastle.GetDiscountByID(d.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, updated)
+}
+
+// DeleteDiscount godoc
+// @Summary      Delete discount
+// @Description  Deletes discount which wasn't used yet, used discounts can only be deactivated
+// @Tags         discount
+// @Produce      json
+// @Param        discountID path int true "Discount ID"
+// @Success      200  {object}   types.ErrorResponse "Discount with ID %d successfully deleted"
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid discount ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "discount not found"
+// @Failure      409  {object}   types.ErrorResponse "discount was already used"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /discounts/delete/{discountID} [delete]
+func (h *Handler) handleDeleteDiscount(w http.ResponseWriter, r *http.Request) {
+	d, ok := h.getManagedDiscount(w, r)
+	if !ok {
+		return
+	}
+
+	redemptions, err := h.discountCastle.ListDiscountRedemptions(d.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if len(redemptions) > 0 {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("discount was already used, deactivate it instead"))
+		return
+	}
+
+	if err := h.discountCastle.DeleteDiscount(d.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error deleting discount: %w", err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("Discount with ID %d successfully deleted", d.ID))
+}
+
+// GetDiscountReport godoc
+// @Summary      Get redemption report of discount
+// @Description  Returns bookings discount was used in together with number of redemptions and total discount given.
+// @Description  Cancelled bookings are listed but don't count into totals
+// @Tags         discount
+// @Produce      json
+// @Param        discountID path int true "Discount ID"
+// @Success      200  {object}   types.DiscountReport
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid discount ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "discount not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /discounts/{discountID}/report [get]
+func (h *Handler) handleGetDiscountReport(w http.ResponseWriter, r *http.Request) {
+	d, ok := h.getManagedDiscount(w, r)
+	if !ok {
+		return
+	}
+
+	redemptions, err := h.discountCastle.ListDiscountRedemptions(d.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, report(d, redemptions))
+}
+
+// parsePayload reads and validates discount payload and checks user can scope discount to the given target
+func (h *Handler) parsePayload(w http.ResponseWriter, r *http.Request, viewer types.Viewer) (types.Discount, bool) {
+	var payload types.DiscountPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return types.Discount{}, false
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return types.Discount{}, false
+	}
+
+	// Organizers discount their own activities by default
+	if payload.Scope == types.DiscountScopeOrganizer && payload.ScopeID == 0 && viewer.Role != "administrator" {
+		payload.ScopeID = viewer.UserID
+	}
+	if payload.ScopeID == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("scope ID is required for %s scope", payload.Scope))
+		return types.Discount{}, false
+	}
+
+	if err := h.checkScope(r, payload.Scope, payload.ScopeID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return types.Discount{}, false
+	}
+
+	d := types.Discount{
+		Name:           payload.Name,
+		Kind:           payload.Kind,
+		Scope:          payload.Scope,
+		ScopeID:        payload.ScopeID,
+		ValidFrom:      payload.ValidFrom,
+		ValidUntil:     payload.ValidUntil,
+		MaxRedemptions: payload.MaxRedemptions,
+		MaxPerUser:     payload.MaxPerUser,
+		Active:         payload.Active,
+	}
+	if payload.Code != nil {
+		code := strings.ToUpper(*payload.Code)
+		d.Code = &code
+	}
+	if payload.Kind == types.DiscountPercent {
+		d.Percent = payload.Percent
+	} else {
+		d.Amount = payload.Amount
+	}
+
+	return d, true
+}
+
+// checkScope returns error unless target of scope exists and user organizes it. Categories are shared by all organizers
+func (h *Handler) checkScope(r *http.Request, scope string, scopeID int) error {
+	var organizerID int
+	switch scope {
+	case types.DiscountScopeOrganizer:
+		organizer, err := h.userCastle.GetOrganizerByID(scopeID)
+		if err != nil {
+			return fmt.Errorf("organizer %d not found", scopeID)
+		}
+		organizerID = organizer.ID
+	case types.DiscountScopePackage:
+		p, err := h.activityCastle.GetPackageByID(scopeID)
+		if err != nil {
+			return fmt.Errorf("package %d not found", scopeID)
+		}
+		organizerID = p.FkOrganizerID
+	case types.DiscountScopeActivity:
+		organizer, err := h.userCastle.GetOrganizerByActivityID(scopeID)
+		if err != nil {
+			return fmt.Errorf("activity %d not found", scopeID)
+		}
+		organizerID = organizer.ID
+	case types.DiscountScopeCategory:
+		exists, err := h.discountCastle.CategoryExists(scopeID)
+		if err != nil || !exists {
+			return fmt.Errorf("category %d not found", scopeID)
+		}
+		return nil
+	}
+
+	if !auth.CheckOwnership(r, organizerID) {
+		return fmt.Errorf("%s %d is organized by someone else", scope, scopeID)
+	}
+
+	return nil
+}
+
+// getManagedDiscount returns discount from URL, writing error response unless user can manage it.
+// Discounts without organizer are managed by administrators only
+func (h *Handler) getManagedDiscount(w http.ResponseWriter, r *http.Request) (*types.Discount, bool) {
+	discountID, err := strconv.Atoi(mux.Vars(r)["discountID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid discount ID"))
+		return nil, false
+	}
+
+	d, err := h.discountCastle.GetDiscountByID(discountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("discount not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return nil, false
+	}
+
+	ownerID := 0
+	if d.FkOrganizerID != nil {
+		ownerID = *d.FkOrganizerID
+	}
+	if !auth.CheckOwnership(r, ownerID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return nil, false
+	}
+
+	return d, true
+}
+
+// report sums discount given in bookings which are not cancelled, separately for each currency
+func report(d *types.Discount, redemptions []*types.DiscountRedemption) types.DiscountReport {
+	r := types.DiscountReport{
+		Discount:      d,
+		TotalDiscount: []types.Money{},
+		Bookings:      redemptions,
+	}
+	if r.Bookings == nil {
+		r.Bookings = []*types.DiscountRedemption{}
+	}
+
+	totals := make(map[string]int)
+	for _, redemption := range redemptions {
+		if redemption.BookingStatus == types.BookingCancelled {
+			continue
+		}
+		r.Redemptions++
+
+		i, ok := totals[redemption.Discount.Currency]
+		if !ok {
+			i = len(r.TotalDiscount)
+			totals[redemption.Discount.Currency] = i
+			r.TotalDiscount = append(r.TotalDiscount, types.Money{Currency: redemption.Discount.Currency})
+		}
+		r.TotalDiscount[i] = r.TotalDiscount[i].Add(redemption.Discount)
+	}
+
+	return r
+}
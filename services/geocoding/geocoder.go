@@ -0,0 +1,31 @@
+package geocoding
+
+import (
+	"educations-castle/configs"
+	"educations-castle/types"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var ErrNoResults = errors.New("address not found")
+
+// NewGeocoder creates geocoder selected by GEOCODER_PROVIDER,
+// returns nil geocoder when geocoding is disabled
+func NewGeocoder(cfg configs.Config) (types.Geocoder, error) {
+	switch cfg.GeocoderProvider {
+	case "", "none":
+		return nil, nil
+	case ProviderNominatim:
+		client := &http.Client{Timeout: time.Second * time.Duration(cfg.GeocoderTimeoutInSeconds)}
+		return NewNominatimGeocoder(cfg.GeocoderURL, cfg.GeocoderUserAgent, cfg.GeocoderLanguage, client), nil
+	case ProviderStatic:
+		if cfg.GeocoderStaticFile == "" {
+			return NewStaticGeocoder(nil), nil
+		}
+		return LoadStaticGeocoder(cfg.GeocoderStaticFile)
+	default:
+		return nil, fmt.Errorf("unknown geocoder provider '%s'", cfg.GeocoderProvider)
+	}
+}
@@ -0,0 +1,120 @@
+package geocoding
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStaticGeocoder(t *testing.T) {
+	geocoder := NewStaticGeocoder([]StaticEntry{
+		{Address: "Laisvės al. 1, Kaunas", Latitude: 54.897802, Longitude: 23.903597},
+		{Address: "Gedimino pr. 1, Vilnius", Latitude: 54.686070, Longitude: 25.286963},
+	})
+
+	t.Run("Should resolve address ignoring case and punctuation", func(t *testing.T) {
+		result, err := geocoder.Geocode(context.Background(), "laisvės AL 1 kaunas")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Latitude != 54.897802 || result.Longitude != 23.903597 {
+			t.Errorf("unexpected coordinates %f, %f", result.Latitude, result.Longitude)
+		}
+		if result.Confidence != 1 || result.Provider != ProviderStatic {
+			t.Errorf("unexpected confidence %f from %s", result.Confidence, result.Provider)
+		}
+	})
+
+	t.Run("Should lower confidence for partial match", func(t *testing.T) {
+		result, err := geocoder.Geocode(context.Background(), "Vilnius")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Confidence >= 1 {
+			t.Errorf("expected confidence below 1, got %f", result.Confidence)
+		}
+	})
+
+	t.Run("Should fail for unknown address", func(t *testing.T) {
+		_, err := geocoder.Geocode(context.Background(), "Klaipėda")
+		if err != ErrNoResults {
+			t.Errorf("expected %v, got %v", ErrNoResults, err)
+		}
+	})
+
+	t.Run("Should reverse geocode nearest entry", func(t *testing.T) {
+		result, err := geocoder.ReverseGeocode(context.Background(), 54.6861, 25.2870)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Address != "Gedimino pr. 1, Vilnius" {
+			t.Errorf("unexpected address %s", result.Address)
+		}
+	})
+
+	t.Run("Should not reverse geocode far away coordinates", func(t *testing.T) {
+		_, err := geocoder.ReverseGeocode(context.Background(), 55.7033, 21.1443)
+		if err != ErrNoResults {
+			t.Errorf("expected %v, got %v", ErrNoResults, err)
+		}
+	})
+}
+
+func TestNominatimGeocoder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "test-agent" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/search":
+			if r.URL.Query().Get("q") == "nowhere" {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`[{"lat":"54.897802","lon":"23.903597","display_name":"Laisvės alėja 1, Kaunas","importance":0.62}]`))
+		case "/reverse":
+			w.Write([]byte(`{"lat":"54.897802","lon":"23.903597","display_name":"Laisvės alėja 1, Kaunas","place_rank":30}`))
+		}
+	}))
+	defer server.Close()
+
+	geocoder := NewNominatimGeocoder(server.URL, "test-agent", "lt", server.Client())
+
+	t.Run("Should geocode address", func(t *testing.T) {
+		result, err := geocoder.Geocode(context.Background(), "Laisvės al. 1, Kaunas")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Latitude != 54.897802 || result.Longitude != 23.903597 {
+			t.Errorf("unexpected coordinates %f, %f", result.Latitude, result.Longitude)
+		}
+		if result.Confidence != 0.62 || result.Provider != ProviderNominatim {
+			t.Errorf("unexpected confidence %f from %s", result.Confidence, result.Provider)
+		}
+	})
+
+	t.Run("Should fail when nothing is found", func(t *testing.T) {
+		_, err := geocoder.Geocode(context.Background(), "nowhere")
+		if err != ErrNoResults {
+			t.Errorf("expected %v, got %v", ErrNoResults, err)
+		}
+	})
+
+	t.Run("Should reverse geocode coordinates", func(t *testing.T) {
+		result, err := geocoder.ReverseGeocode(context.Background(), 54.897802, 23.903597)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Address != "Laisvės alėja 1, Kaunas" || result.Confidence != 1 {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+}
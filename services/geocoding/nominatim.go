@@ -0,0 +1,150 @@
+package geocoding
+
+import (
+	"context"
+	"educations-castle/types"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const ProviderNominatim = "nominatim"
+
+// NominatimGeocoder talks to any Nominatim-compatible HTTP API
+// (https://nominatim.org/release-docs/latest/api/Overview/)
+type NominatimGeocoder struct {
+	baseURL   string
+	userAgent string
+	language  string
+	client    *http.Client
+}
+
+type nominatimPlace struct {
+	Lat         string  `json:"lat"`
+	Lon         string  `json:"lon"`
+	DisplayName string  `json:"display_name"`
+	Importance  float64 `json:"importance"`
+	PlaceRank   int     `json:"place_rank"`
+	Error       string  `json:"error"`
+}
+
+func NewNominatimGeocoder(baseURL, userAgent, language string, client *http.Client) *NominatimGeocoder {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &NominatimGeocoder{
+		baseURL:   baseURL,
+		userAgent: userAgent,
+		language:  language,
+		client:    client,
+	}
+}
+
+func (g *NominatimGeocoder) Geocode(ctx context.Context, address string) (*types.GeocodeResult, error) {
+	query := url.Values{}
+	query.Set("q", address)
+	query.Set("format", "jsonv2")
+	query.Set("limit", "1")
+
+	var places []nominatimPlace
+	if err := g.get(ctx, "/search", query, &places); err != nil {
+		return nil, err
+	}
+
+	if len(places) == 0 {
+		return nil, ErrNoResults
+	}
+
+	return places[0].toResult()
+}
+
+func (g *NominatimGeocoder) ReverseGeocode(ctx context.Context, latitude, longitude float64) (*types.GeocodeResult, error) {
+	query := url.Values{}
+	query.Set("lat", strconv.FormatFloat(latitude, 'f', -1, 64))
+	query.Set("lon", strconv.FormatFloat(longitude, 'f', -1, 64))
+	query.Set("format", "jsonv2")
+
+	var place nominatimPlace
+	if err := g.get(ctx, "/reverse", query, &place); err != nil {
+		return nil, err
+	}
+
+	// Nominatim answers reverse lookups in the middle of nowhere with 200 and an error message
+	if place.Error != "" {
+		return nil, ErrNoResults
+	}
+
+	return place.toResult()
+}
+
+func (g *NominatimGeocoder) get(ctx context.Context, path string, query url.Values, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	// Public Nominatim instances reject requests without identifying user agent
+	req.Header.Set("User-Agent", g.userAgent)
+	if g.language != "" {
+		req.Header.Set("Accept-Language", g.language)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("nominatim request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nominatim responded with status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode nominatim response: %w", err)
+	}
+
+	return nil
+}
+
+func (p nominatimPlace) toResult() (*types.GeocodeResult, error) {
+	latitude, err := strconv.ParseFloat(p.Lat, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid latitude in nominatim response: %w", err)
+	}
+
+	longitude, err := strconv.ParseFloat(p.Lon, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid longitude in nominatim response: %w", err)
+	}
+
+	return &types.GeocodeResult{
+		Address:    p.DisplayName,
+		Longitude:  longitude,
+		Latitude:   latitude,
+		Confidence: p.confidence(),
+		Provider:   ProviderNominatim,
+	}, nil
+}
+
+// Search results carry importance in range 0..1, reverse results only carry
+// place rank (30 being a single building) so it is scaled into the same range
+func (p nominatimPlace) confidence() float64 {
+	if p.Importance > 0 {
+		return clamp(p.Importance)
+	}
+
+	return clamp(float64(p.PlaceRank) / 30)
+}
+
+func clamp(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,302 @@
+package image
+
+import (
+	"database/sql"
+	"educations-castle/types"
+	"fmt"
+	"strings"
+)
+
+type Castle struct {
+	db *sql.DB
+}
+
+func NewCastle(db *sql.DB) *Castle {
+	return &Castle{db: db}
+}
+
+func scanRowIntoImage(rows *sql.Rows) (*types.Image, error) {
+	i := new(types.Image)
+
+	err := rows.Scan(
+		&i.ID,
+		&i.Description,
+		&i.FilePath,
+		&i.Url,
+		&i.UploadTime,
+		&i.ContentType,
+		&i.Size,
+		&i.FkUserID,
+		&i.Private,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+func scanRowIntoAttachedImage(rows *sql.Rows) (*types.AttachedImage, int, error) {
+	i := new(types.AttachedImage)
+	var entityID int
+	var altText sql.NullString
+
+	err := rows.Scan(
+		&entityID,
+		&i.ID,
+		&i.Url,
+		&altText,
+		&i.ContentType,
+		&i.Position,
+	)
+
+	if err != nil {
+		return nil, 0, err
+	}
+	i.AltText = altText.String
+
+	return i, entityID, nil
+}
+
+func (c *Castle) CreateImage(i types.Image) (int64, error) {
+	result, err := c.db.Exec(
+		"INSERT INTO image (description, filePath, url, contentType, size, fk_Userid, private) VALUES (?,?,?,?,?,?,?)",
+		i.Description, i.FilePath, i.Url, i.ContentType, i.Size, i.FkUserID, i.Private)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (c *Castle) GetImageByID(id int) (*types.Image, error) {
+	rows, err := c.db.Query("SELECT * FROM image WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	i := new(types.Image)
+	for rows.Next() {
+		i, err = scanRowIntoImage(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if i.ID == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return i, nil
+}
+
+func (c *Castle) UpdateImage(i types.Image) error {
+	_, err := c.db.Exec(
+		"UPDATE image SET description = ?, url = ? WHERE id = ?",
+		i.Description, i.Url, i.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Castle) UpdateImageFilePath(id int, filePath string) error {
+	_, err := c.db.Exec("UPDATE image SET filePath = ? WHERE id = ?", filePath, id)
+	return err
+}
+
+func (c *Castle) DeleteImage(id int) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Links have to go first because of mapping foreign key
+	if _, err := tx.Exec("DELETE FROM entityimage WHERE fk_Imageid = ?", id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM imagevariant WHERE fk_Imageid = ?", id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM image WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AttachImage links image to entity, negative position appends image to the end
+func (c *Castle) AttachImage(e types.EntityImage) (int64, error) {
+	if e.Position < 0 {
+		err := c.db.QueryRow(
+			"SELECT COALESCE(MAX(position) + 1, 0) FROM entityimage WHERE entityType = ? AND entityFk = ?",
+			e.EntityType, e.FkEntity).Scan(&e.Position)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	result, err := c.db.Exec(
+		"INSERT INTO entityimage (entityType, entityFk, fk_Imageid, position) VALUES (?,?,?,?)",
+		e.EntityType, e.FkEntity, e.FkImageID, e.Position)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (c *Castle) ListEntityImages(entityType string, entityID int) ([]*types.AttachedImage, error) {
+	images, err := c.ListImagesForEntities(entityType, []int{entityID})
+	if err != nil {
+		return nil, err
+	}
+
+	return images[entityID], nil
+}
+
+// ListImagesForEntities loads images of many entities at once, keyed by entity ID
+func (c *Castle) ListImagesForEntities(entityType string, entityIDs []int) (map[int][]*types.AttachedImage, error) {
+	images := make(map[int][]*types.AttachedImage)
+	if len(entityIDs) == 0 {
+		return images, nil
+	}
+
+	params := []interface{}{entityType}
+	for _, id := range entityIDs {
+		params = append(params, id)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT entityimage.entityFk, image.id, image.url, image.description, image.contentType, entityimage.position
+		FROM entityimage
+		JOIN image ON entityimage.fk_Imageid = image.id
+		WHERE entityimage.entityType = ? AND entityimage.entityFk IN (%s)
+		ORDER BY entityimage.entityFk, entityimage.position, entityimage.id`,
+		strings.TrimSuffix(strings.Repeat("?,", len(entityIDs)), ","))
+
+	rows, err := c.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		i, entityID, err := scanRowIntoAttachedImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images[entityID] = append(images[entityID], i)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// ReorderEntityImages sets positions according to order of given image IDs
+func (c *Castle) ReorderEntityImages(entityType string, entityID int, imageIDs []int) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for position, imageID := range imageIDs {
+		result, err := tx.Exec(
+			"UPDATE entityimage SET position = ? WHERE entityType = ? AND entityFk = ? AND fk_Imageid = ?",
+			position, entityType, entityID, imageID)
+		if err != nil {
+			return err
+		}
+
+		if affected, err := result.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			// MySQL reports 0 for unchanged rows too, so double check that image is linked
+			var exists bool
+			err := tx.QueryRow(
+				"SELECT EXISTS(SELECT 1 FROM entityimage WHERE entityType = ? AND entityFk = ? AND fk_Imageid = ?)",
+				entityType, entityID, imageID).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("image %d is not attached to %s %d", imageID, entityType, entityID)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (c *Castle) ListImages() ([]*types.Image, error) {
+	rows, err := c.db.Query("SELECT * FROM image ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := make([]*types.Image, 0)
+	for rows.Next() {
+		i, err := scanRowIntoImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, i)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// SaveImageVariant creates variant or replaces file information of already existing one
+func (c *Castle) SaveImageVariant(v types.ImageVariant) error {
+	_, err := c.db.Exec(`
+		INSERT INTO imagevariant (fk_Imageid, name, format, filePath, width, height, size) VALUES (?,?,?,?,?,?,?)
+		ON DUPLICATE KEY UPDATE filePath = VALUES(filePath), width = VALUES(width), height = VALUES(height), size = VALUES(size)`,
+		v.FkImageID, v.Name, v.Format, v.FilePath, v.Width, v.Height, v.Size)
+	return err
+}
+
+func (c *Castle) ListImageVariants(imageID int) ([]*types.ImageVariant, error) {
+	rows, err := c.db.Query(
+		"SELECT id, fk_Imageid, name, format, filePath, width, height, size FROM imagevariant WHERE fk_Imageid = ? ORDER BY width, format",
+		imageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := make([]*types.ImageVariant, 0)
+	for rows.Next() {
+		v := new(types.ImageVariant)
+		err := rows.Scan(&v.ID, &v.FkImageID, &v.Name, &v.Format, &v.FilePath, &v.Width, &v.Height, &v.Size)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+func (c *Castle) DeleteImageVariant(id int) error {
+	_, err := c.db.Exec("DELETE FROM imagevariant WHERE id = ?", id)
+	return err
+}
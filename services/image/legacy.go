@@ -0,0 +1,79 @@
+package image
+
+import (
+	"context"
+	"educations-castle/services/storage"
+	"educations-castle/types"
+	"strings"
+)
+
+// HasLegacyKey reports whether image was stored before keys got public/ or private/ prefix
+func HasLegacyKey(image *types.Image) bool {
+	return !strings.HasPrefix(image.FilePath, storage.PublicPrefix) && !strings.HasPrefix(image.FilePath, storage.PrivatePrefix)
+}
+
+// prefixedKey returns key image file should have according to its private flag
+func prefixedKey(image *types.Image, key string) string {
+	if image.Private {
+		return storage.PrivatePrefix + key
+	}
+	return storage.PublicPrefix + key
+}
+
+// RenameLegacyKey moves files of image stored without visibility prefix under
+// public/ or private/ prefix taken from private column, so blob store serves
+// them the same way as newly uploaded images. Old files are removed only after
+// database points to the new ones
+func RenameLegacyKey(ctx context.Context, imageCastle types.ImageCastle, blobStore types.BlobStore, image *types.Image) error {
+	if !HasLegacyKey(image) {
+		return nil
+	}
+
+	variants, err := imageCastle.ListImageVariants(image.ID)
+	if err != nil {
+		return err
+	}
+
+	oldKeys := []string{image.FilePath}
+	newKey := prefixedKey(image, image.FilePath)
+	if err := copyBlob(ctx, blobStore, image.FilePath, newKey, image.ContentType); err != nil {
+		return err
+	}
+
+	for _, v := range variants {
+		if !strings.HasPrefix(v.FilePath, storage.PublicPrefix) && !strings.HasPrefix(v.FilePath, storage.PrivatePrefix) {
+			oldKeys = append(oldKeys, v.FilePath)
+			key := prefixedKey(image, v.FilePath)
+			if err := copyBlob(ctx, blobStore, v.FilePath, key, variantContentType(v.Format)); err != nil {
+				return err
+			}
+			v.FilePath = key
+			if err := imageCastle.SaveImageVariant(*v); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := imageCastle.UpdateImageFilePath(image.ID, newKey); err != nil {
+		return err
+	}
+	image.FilePath = newKey
+
+	for _, key := range oldKeys {
+		if err := blobStore.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyBlob(ctx context.Context, blobStore types.BlobStore, from, to, contentType string) error {
+	file, err := blobStore.Get(ctx, from)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return blobStore.Put(ctx, to, file, contentType)
+}
@@ -0,0 +1,96 @@
+package image
+
+import (
+	"context"
+	"educations-castle/services/storage"
+	"educations-castle/types"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRenameLegacyKey(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should move legacy files under prefix taken from private column", func(t *testing.T) {
+		for _, private := range []bool{false, true} {
+			blobStore, err := storage.NewLocalBlobStore(t.TempDir(), "http://localhost/files", []byte("secret"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			blobStore.Put(ctx, "images/a.jpg", strings.NewReader("original"), "image/jpeg")
+			blobStore.Put(ctx, "images/a_card.webp", strings.NewReader("variant"), "image/webp")
+
+			image := &types.Image{ID: 1, FilePath: "images/a.jpg", ContentType: "image/jpeg", Private: private}
+			castle := &mockImageCastle{variants: []*types.ImageVariant{{ID: 1, FkImageID: 1, Name: "card", Format: FormatWebP, FilePath: "images/a_card.webp"}}}
+
+			if err := RenameLegacyKey(ctx, castle, blobStore, image); err != nil {
+				t.Fatal(err)
+			}
+
+			prefix := storage.PublicPrefix
+			if private {
+				prefix = storage.PrivatePrefix
+			}
+			if image.FilePath != prefix+"images/a.jpg" || castle.filePaths[1] != image.FilePath {
+				t.Errorf("expected image to be moved under %s, got %s", prefix, image.FilePath)
+			}
+			if castle.variants[0].FilePath != prefix+"images/a_card.webp" {
+				t.Errorf("expected variant to be moved under %s, got %s", prefix, castle.variants[0].FilePath)
+			}
+
+			file, err := blobStore.Get(ctx, image.FilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(file)
+			file.Close()
+			if string(data) != "original" {
+				t.Errorf("expected file contents to be copied, got %q", data)
+			}
+
+			if _, err := blobStore.Get(ctx, "images/a.jpg"); err == nil {
+				t.Errorf("expected legacy file to be deleted")
+			}
+		}
+	})
+
+	t.Run("Should leave prefixed images alone", func(t *testing.T) {
+		image := &types.Image{ID: 1, FilePath: "public/images/a.jpg"}
+		castle := &mockImageCastle{}
+
+		if err := RenameLegacyKey(ctx, castle, nil, image); err != nil {
+			t.Fatal(err)
+		}
+		if image.FilePath != "public/images/a.jpg" || len(castle.filePaths) != 0 {
+			t.Errorf("expected image to be unchanged, got %s", image.FilePath)
+		}
+	})
+}
+
+type mockImageCastle struct {
+	types.ImageCastle
+	variants  []*types.ImageVariant
+	filePaths map[int]string
+}
+
+func (m *mockImageCastle) ListImageVariants(imageID int) ([]*types.ImageVariant, error) {
+	return m.variants, nil
+}
+
+func (m *mockImageCastle) SaveImageVariant(v types.ImageVariant) error {
+	for _, existing := range m.variants {
+		if existing.Name == v.Name && existing.Format == v.Format {
+			*existing = v
+		}
+	}
+	return nil
+}
+
+func (m *mockImageCastle) UpdateImageFilePath(id int, filePath string) error {
+	if m.filePaths == nil {
+		m.filePaths = make(map[int]string)
+	}
+	m.filePaths[id] = filePath
+	return nil
+}
@@ -0,0 +1,125 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestStripMetadata(t *testing.T) {
+	t.Run("Should remove EXIF segment from jpeg", func(t *testing.T) {
+		data := withExif(t, encodeJPEG(t, 4, 2), 1)
+
+		stripped, err := StripMetadata("image/jpeg", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if bytes.Contains(stripped, []byte("Exif")) {
+			t.Errorf("expected EXIF to be removed")
+		}
+		if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+			t.Errorf("stripped jpeg is not decodable: %v", err)
+		}
+	})
+
+	t.Run("Should apply EXIF orientation to jpeg", func(t *testing.T) {
+		data := withExif(t, encodeJPEG(t, 4, 2), 6)
+
+		stripped, err := StripMetadata("image/jpeg", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		img, err := jpeg.Decode(bytes.NewReader(stripped))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds().Dx() != 2 || img.Bounds().Dy() != 4 {
+			t.Errorf("expected rotated 2x4 image, got %v", img.Bounds())
+		}
+	})
+
+	t.Run("Should remove text chunks from png", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+			t.Fatal(err)
+		}
+
+		// Insert tEXt chunk right after IHDR
+		data := buf.Bytes()
+		ihdrEnd := len(pngSignature) + 12 + 13
+		chunk := pngChunk("tEXt", []byte("GPS\x0054.89,23.90"))
+		data = append(append(append([]byte{}, data[:ihdrEnd]...), chunk...), data[ihdrEnd:]...)
+
+		stripped, err := StripMetadata("image/png", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if bytes.Contains(stripped, []byte("tEXt")) {
+			t.Errorf("expected tEXt chunk to be removed")
+		}
+		if _, err := png.Decode(bytes.NewReader(stripped)); err != nil {
+			t.Errorf("stripped png is not decodable: %v", err)
+		}
+	})
+
+	t.Run("Should reject unsupported content type", func(t *testing.T) {
+		if _, err := StripMetadata("image/gif", []byte("GIF89a")); err == nil {
+			t.Errorf("expected error for gif")
+		}
+	})
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// withExif inserts minimal APP1 segment with orientation tag after SOI marker
+func withExif(t *testing.T, data []byte, orientation uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("II*\x00")
+	binary.Write(&tiff, binary.LittleEndian, uint32(8))
+	binary.Write(&tiff, binary.LittleEndian, uint16(1))
+	binary.Write(&tiff, binary.LittleEndian, uint16(0x0112))
+	binary.Write(&tiff, binary.LittleEndian, uint16(3))
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, orientation)
+	binary.Write(&tiff, binary.LittleEndian, uint16(0))
+	binary.Write(&tiff, binary.LittleEndian, uint32(0))
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var segment bytes.Buffer
+	segment.Write([]byte{0xFF, 0xE1})
+	binary.Write(&segment, binary.BigEndian, uint16(len(payload)+2))
+	segment.Write(payload)
+
+	return append(append(append([]byte{}, data[:2]...), segment.Bytes()...), data[2:]...)
+}
+
+func pngChunk(chunkType string, data []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+	buf.WriteString(chunkType)
+	buf.Write(data)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(chunkType), data...)))
+
+	return buf.Bytes()
+}
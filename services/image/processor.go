@@ -0,0 +1,157 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"educations-castle/types"
+	"educations-castle/utils/color"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// Generating variants of one image should never take longer than this
+const processTimeout = 2 * time.Minute
+
+// Processor generates image variants in the background with fixed number of workers.
+// Queue is bounded, images which don't fit are left for regenerate command
+type Processor struct {
+	imageCastle types.ImageCastle
+	blobStore   types.BlobStore
+	sizes       []VariantSize
+	maxPixels   int64
+	jobs        chan int
+	wg          sync.WaitGroup
+}
+
+// NewProcessor creates processor of images with at most maxPixels pixels, larger ones are never decoded
+func NewProcessor(imageCastle types.ImageCastle, blobStore types.BlobStore, sizes []VariantSize, maxPixels int64,
+	queueSize int) *Processor {
+	return &Processor{
+		imageCastle: imageCastle,
+		blobStore:   blobStore,
+		sizes:       sizes,
+		maxPixels:   maxPixels,
+		jobs:        make(chan int, queueSize),
+	}
+}
+
+// Start launches workers consuming the queue until Stop is called
+func (p *Processor) Start(workers int) {
+	for i := 0; i < max(1, workers); i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for imageID := range p.jobs {
+				ctx, cancel := context.WithTimeout(context.Background(), processTimeout)
+				if err := p.Process(ctx, imageID); err != nil {
+					log.Println(color.Format(color.RED, fmt.Sprintf("image %d: failed to generate variants: %v", imageID, err)))
+				}
+				cancel()
+			}
+		}()
+	}
+}
+
+// Stop waits for queued images to be processed
+func (p *Processor) Stop() {
+	close(p.jobs)
+	p.wg.Wait()
+}
+
+// Enqueue adds image to the queue without blocking, false is returned when queue is full
+func (p *Processor) Enqueue(imageID int) bool {
+	select {
+	case p.jobs <- imageID:
+		return true
+	default:
+		log.Println(color.Format(color.YELLOW, fmt.Sprintf("image %d: variant queue is full, skipping", imageID)))
+		return false
+	}
+}
+
+// Submit adds image to the queue, waiting for free space
+func (p *Processor) Submit(ctx context.Context, imageID int) error {
+	select {
+	case p.jobs <- imageID:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Process generates all configured variants of image, replacing existing ones
+// and removing variants which are no longer configured
+func (p *Processor) Process(ctx context.Context, imageID int) error {
+	image, err := p.imageCastle.GetImageByID(imageID)
+	if err != nil {
+		return err
+	}
+
+	file, err := p.blobStore.Get(ctx, image.FilePath)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	variants, err := generateVariants(data, p.sizes, p.maxPixels)
+	if err != nil {
+		return err
+	}
+
+	stored := make(map[string]bool)
+	for _, v := range variants {
+		key := variantKey(image.FilePath, v.size.Name, v.format)
+		if err := p.blobStore.Put(ctx, key, bytes.NewReader(v.data), variantContentType(v.format)); err != nil {
+			return err
+		}
+
+		err := p.imageCastle.SaveImageVariant(types.ImageVariant{
+			FkImageID: image.ID,
+			Name:      v.size.Name,
+			Format:    v.format,
+			FilePath:  key,
+			Width:     v.width,
+			Height:    v.height,
+			Size:      int64(len(v.data)),
+		})
+		if err != nil {
+			return err
+		}
+		stored[key] = true
+	}
+
+	existing, err := p.imageCastle.ListImageVariants(image.ID)
+	if err != nil {
+		return err
+	}
+	for _, v := range existing {
+		if stored[v.FilePath] {
+			continue
+		}
+		if err := p.blobStore.Delete(ctx, v.FilePath); err != nil {
+			return err
+		}
+		if err := p.imageCastle.DeleteImageVariant(v.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// HasVariant reports whether variant with the given name is configured
+func (p *Processor) HasVariant(name string) bool {
+	for _, size := range p.sizes {
+		if size.Name == name {
+			return true
+		}
+	}
+	return false
+}
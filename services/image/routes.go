@@ -0,0 +1,701 @@
+package image
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"educations-castle/configs"
+	"educations-castle/services/auth"
+	"educations-castle/services/storage"
+	"educations-castle/types"
+	"educations-castle/utils"
+	"educations-castle/utils/color"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// Extra room for multipart boundaries and text fields on top of file size limit
+const multipartOverhead = 1 << 20
+
+// Accepted image formats detected by content sniffing, mapped to file extension
+var allowedContentTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+type Handler struct {
+	imageCastle    types.ImageCastle
+	activityCastle types.ActivityCastle
+	reviewCastle   types.ReviewCastle
+	userCastle     types.UserCastle
+	blobStore      types.BlobStore
+	processor      *Processor
+}
+
+func NewHandler(imageCastle types.ImageCastle, activityCastle types.ActivityCastle, reviewCastle types.ReviewCastle,
+	userCastle types.UserCastle, blobStore types.BlobStore, processor *Processor) *Handler {
+	return &Handler{
+		imageCastle:    imageCastle,
+		activityCastle: activityCastle,
+		reviewCastle:   reviewCastle,
+		userCastle:     userCastle,
+		blobStore:      blobStore,
+		processor:      processor}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/images/upload", auth.WithJWTAuth(h.handleUploadImage, h.userCastle, "administrator", "organizer", "user")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/images/{imageID:[0-9]+}", h.handleGetImage).Methods("GET")
+	router.HandleFunc("/images/{imageID:[0-9]+}/url", auth.WithJWTAuth(h.handleGetImageURL, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/images/update/{imageID:[0-9]+}", auth.WithJWTAuth(h.handleUpdateImage, h.userCastle, "administrator", "organizer", "user")).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/images/delete/{imageID:[0-9]+}", auth.WithJWTAuth(h.handleDeleteImage, h.userCastle, "administrator", "organizer", "user")).Methods("DELETE", "OPTIONS")
+
+	router.HandleFunc("/images/{entityType:activity|package|organizer|review}/{entityID:[0-9]+}", h.handleListEntityImages).Methods("GET")
+	router.HandleFunc("/images/{entityType:activity|package|organizer|review}/{entityID:[0-9]+}/order", auth.WithJWTAuth(h.handleReorderImages, h.userCastle, "administrator", "organizer", "user")).Methods("PUT", "OPTIONS")
+}
+
+// UploadImage godoc
+// @Summary      Upload image
+// @Description  Upload JPEG, PNG or WebP image and attach it to activity, package, organizer or review. Metadata such as EXIF is removed.
+// @Description  Resized variants are generated in the background
+// @Tags         image
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file        formData  file    true   "Image file"
+// @Param        altText     formData  string  true   "Alternative text"
+// @Param        entityType  formData  string  true   "activity, package, organizer or review"
+// @Param        entityID    formData  int     true   "Entity ID"
+// @Param        position    formData  int     false  "Position among entity images, appended to the end by default"
+// @Param        private     formData  bool    false  "Private images are available only through signed URLs"
+// @Success      201  {object}   types.AttachedImage
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "entity not found"
+// @Failure      413  {object}   types.ErrorResponse "image file or its dimensions are too large"
+// @Failure      415  {object}   types.ErrorResponse "unsupported image type"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /images/upload [post]
+func (h *Handler) handleUploadImage(w http.ResponseWriter, r *http.Request) {
+	maxSize := configs.Envs.MaxImageUploadSize
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+multipartOverhead)
+
+	if err := r.ParseMultipartForm(maxSize); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			utils.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("image is too large, limit is %d bytes", maxSize))
+		} else {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid multipart form: %v", err))
+		}
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	altText := strings.TrimSpace(r.FormValue("altText"))
+	if altText == "" || len(altText) > 255 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("altText is required and must be at most 255 characters"))
+		return
+	}
+
+	entityType := r.FormValue("entityType")
+	entityID, err := strconv.Atoi(r.FormValue("entityID"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid entityID"))
+		return
+	}
+
+	position := -1
+	if str := r.FormValue("position"); str != "" {
+		position, err = strconv.Atoi(str)
+		if err != nil || position < 0 {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid position"))
+			return
+		}
+	}
+
+	private := false
+	if str := r.FormValue("private"); str != "" {
+		private, err = strconv.ParseBool(str)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid private flag"))
+			return
+		}
+	}
+
+	// Check if the user has ownership of the entity
+	ownerID, err := h.entityOwnerID(entityType, entityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if !auth.CheckOwnership(r, ownerID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing file"))
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxSize {
+		utils.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("image is too large, limit is %d bytes", maxSize))
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if int64(len(data)) > maxSize {
+		utils.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("image is too large, limit is %d bytes", maxSize))
+		return
+	}
+
+	// Don't trust client provided Content-Type, sniff it from file contents
+	contentType := http.DetectContentType(data)
+	ext, ok := allowedContentTypes[contentType]
+	if !ok {
+		utils.WriteError(w, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported image type %s", contentType))
+		return
+	}
+
+	// Rotated photos are decoded while stripping metadata, so their dimensions are checked first
+	if err := CheckPixels(data, configs.Envs.MaxImagePixels); err != nil {
+		if errors.Is(err, ErrTooManyPixels) {
+			utils.WriteError(w, http.StatusRequestEntityTooLarge, err)
+		} else {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid image: %v", err))
+		}
+		return
+	}
+
+	data, err = StripMetadata(contentType, data)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid image: %v", err))
+		return
+	}
+
+	key, err := newStorageKey(private, ext)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.blobStore.Put(r.Context(), key, bytes.NewReader(data), contentType); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to store image: %v", err))
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	image := types.Image{
+		Description: &altText,
+		FilePath:    key,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		FkUserID:    &userID,
+		Private:     private,
+	}
+
+	imageID, err := h.imageCastle.CreateImage(image)
+	if err != nil {
+		h.blobStore.Delete(r.Context(), key)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	image.ID = int(imageID)
+	image.Url = imageURL(image.ID)
+
+	if err := h.imageCastle.UpdateImage(image); err != nil {
+		h.discardUpload(r.Context(), image)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	_, err = h.imageCastle.AttachImage(types.EntityImage{
+		EntityType: entityType,
+		FkEntity:   entityID,
+		FkImageID:  image.ID,
+		Position:   position,
+	})
+	if err != nil {
+		h.discardUpload(r.Context(), image)
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.processor.Enqueue(image.ID)
+
+	images, err := h.imageCastle.ListEntityImages(entityType, entityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for _, i := range images {
+		if i.ID == image.ID {
+			utils.WriteJSON(w, http.StatusCreated, i)
+			return
+		}
+	}
+
+	utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("uploaded image was not attached"))
+}
+
+// GetImage godoc
+// @Summary      Get image file
+// @Description  Redirects to public image file. Private images have to be requested through /images/{imageID}/url.
+// @Description  Original is returned until requested variant is generated
+// @Tags         image
+// @Param        imageID path  int    true  "Image ID"
+// @Param        variant query string false "Variant name, e.g. thumbnail, card or full"
+// @Param        format  query string false "webp or jpeg, negotiated from Accept header by default"
+// @Success      302  {string}   string  "Redirect to image file"
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid image ID or variant"
+// @Failure      403  {object}   types.ErrorResponse "image is private"
+// @Failure      404  {object}   types.ErrorResponse "image not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /images/{imageID} [get]
+func (h *Handler) handleGetImage(w http.ResponseWriter, r *http.Request) {
+	imageID, err := strconv.Atoi(mux.Vars(r)["imageID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid image ID"))
+		return
+	}
+
+	image, err := h.imageCastle.GetImageByID(imageID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("image not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if image.Private {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("image is private, request signed URL from /images/%d/url", image.ID))
+		return
+	}
+
+	key, err := h.variantFilePath(r, image)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	url, _, err := h.blobURL(key, image.Private)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// GetImageURL godoc
+// @Summary      Get image download URL
+// @Description  Returns public URL of image, or signed and expiring URL when image is private and user is its uploader
+// @Tags         image
+// @Produce      json
+// @Param        imageID path  int    true  "Image ID"
+// @Param        variant query string false "Variant name, e.g. thumbnail, card or full"
+// @Param        format  query string false "webp or jpeg, negotiated from Accept header by default"
+// @Success      200  {object}   types.ImageURLResponse
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid image ID or variant"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "image not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /images/{imageID}/url [get]
+func (h *Handler) handleGetImageURL(w http.ResponseWriter, r *http.Request) {
+	imageID, err := strconv.Atoi(mux.Vars(r)["imageID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid image ID"))
+		return
+	}
+
+	image, err := h.imageCastle.GetImageByID(imageID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("image not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if image.Private && !auth.CheckOwnership(r, uploaderID(image)) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	key, err := h.variantFilePath(r, image)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	url, expiresAt, err := h.blobURL(key, image.Private)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.ImageURLResponse{
+		Url:       url,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// ListEntityImages godoc
+// @Summary      List entity images
+// @Description  Returns ordered images of activity, package, organizer or review
+// @Tags         image
+// @Produce      json
+// @Param        entityType path string true "activity, package, organizer or review"
+// @Param        entityID   path int    true "Entity ID"
+// @Success      200  {array}    types.AttachedImage
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid entity ID"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /images/{entityType}/{entityID} [get]
+func (h *Handler) handleListEntityImages(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	entityID, err := strconv.Atoi(vars["entityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid entity ID"))
+		return
+	}
+
+	images, err := h.imageCastle.ListEntityImages(vars["entityType"], entityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no images found, return an empty array
+	if len(images) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.AttachedImage{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, images)
+}
+
+// UpdateImage godoc
+// @Summary      Update image alt text
+// @Description  Update alternative text of image, only uploader or administrator can do it
+// @Tags         image
+// @Accept       json
+// @Produce      json
+// @Param        imageID path int true "Image ID"
+// @Param        payload body types.UpdateImagePayload true "Image data"
+// @Success      200  {object}   types.Image
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "image not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /images/update/{imageID} [put]
+func (h *Handler) handleUpdateImage(w http.ResponseWriter, r *http.Request) {
+	imageID, err := strconv.Atoi(mux.Vars(r)["imageID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid image ID"))
+		return
+	}
+
+	// Get JSON payload
+	var payload types.UpdateImagePayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	image, err := h.imageCastle.GetImageByID(imageID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("image not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if !auth.CheckOwnership(r, uploaderID(image)) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	image.Description = &payload.AltText
+	if err := h.imageCastle.UpdateImage(*image); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, image)
+}
+
+// ReorderImages godoc
+// @Summary      Reorder entity images
+// @Description  Sets order of images attached to entity, images are listed in the given order
+// @Tags         image
+// @Accept       json
+// @Produce      json
+// @Param        entityType path string true "activity, package, organizer or review"
+// @Param        entityID   path int    true "Entity ID"
+// @Param        payload body types.ReorderImagesPayload true "Ordered image IDs"
+// @Success      200  {array}    types.AttachedImage
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "entity not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /images/{entityType}/{entityID}/order [put]
+func (h *Handler) handleReorderImages(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	entityType := vars["entityType"]
+	entityID, err := strconv.Atoi(vars["entityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid entity ID"))
+		return
+	}
+
+	// Get JSON payload
+	var payload types.ReorderImagesPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	// Check if the user has ownership of the entity
+	ownerID, err := h.entityOwnerID(entityType, entityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if !auth.CheckOwnership(r, ownerID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	if err := h.imageCastle.ReorderEntityImages(entityType, entityID, payload.ImageIDs); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	images, err := h.imageCastle.ListEntityImages(entityType, entityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, images)
+}
+
+// DeleteImage godoc
+// @Summary      Delete image by ID
+// @Description  Delete image file and detach it from all entities, only uploader or administrator can do it
+// @Tags         image
+// @Produce      json
+// @Param        imageID path int true "Image ID"
+// @Success      200  {object}   types.ErrorResponse "Image with ID %d successfully deleted"
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid image ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /images/delete/{imageID} [delete]
+func (h *Handler) handleDeleteImage(w http.ResponseWriter, r *http.Request) {
+	imageID, err := strconv.Atoi(mux.Vars(r)["imageID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid image ID"))
+		return
+	}
+
+	image, err := h.imageCastle.GetImageByID(imageID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("Image with ID %d doesn't exist", imageID))
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error fetching image: %w", err))
+		return
+	}
+
+	if !auth.CheckOwnership(r, uploaderID(image)) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	variants, err := h.imageCastle.ListImageVariants(image.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.imageCastle.DeleteImage(image.ID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error deleting image: %w", err))
+		return
+	}
+
+	keys := []string{image.FilePath}
+	for _, v := range variants {
+		keys = append(keys, v.FilePath)
+	}
+	// Image is already gone, files which can't be deleted are only logged so they can be removed later
+	for _, key := range keys {
+		if err := h.blobStore.Delete(r.Context(), key); err != nil {
+			log.Println(color.Format(color.RED, fmt.Sprintf("image %d: failed to delete file %s: %v", image.ID, key, err)))
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("Image with ID %d successfully deleted", imageID))
+}
+
+// entityOwnerID returns ID of user allowed to manage images of the entity
+func (h *Handler) entityOwnerID(entityType string, entityID int) (int, error) {
+	switch entityType {
+	case types.EntityTypeActivity:
+		organizer, err := h.userCastle.GetOrganizerByActivityID(entityID)
+		if err != nil {
+			return 0, fmt.Errorf("activity not found")
+		}
+		return organizer.ID, nil
+	case types.EntityTypePackage:
+		p, err := h.activityCastle.GetPackageByID(entityID)
+		if err != nil {
+			return 0, fmt.Errorf("package not found")
+		}
+		return p.FkOrganizerID, nil
+	case types.EntityTypeOrganizer:
+		organizer, err := h.userCastle.GetOrganizerByID(entityID)
+		if err != nil {
+			return 0, fmt.Errorf("organizer not found")
+		}
+		return organizer.ID, nil
+	case types.EntityTypeReview:
+		review, err := h.reviewCastle.GetReviewByID(entityID)
+		if err != nil {
+			return 0, fmt.Errorf("review not found")
+		}
+		return review.FkUserID, nil
+	default:
+		return 0, fmt.Errorf("unknown entity type '%s'", entityType)
+	}
+}
+
+// discardUpload removes row and file of image whose upload couldn't be completed,
+// failures are only logged because request is already failing
+func (h *Handler) discardUpload(ctx context.Context, image types.Image) {
+	if err := h.imageCastle.DeleteImage(image.ID); err != nil {
+		log.Println(color.Format(color.RED, fmt.Sprintf("image %d: failed to delete incomplete upload: %v", image.ID, err)))
+	}
+	if err := h.blobStore.Delete(ctx, image.FilePath); err != nil {
+		log.Println(color.Format(color.RED, fmt.Sprintf("image %d: failed to delete file of incomplete upload: %v", image.ID, err)))
+	}
+}
+
+// Images uploaded before uploader was tracked can be managed only by administrators
+func uploaderID(image *types.Image) int {
+	if image.FkUserID == nil {
+		return -1
+	}
+	return *image.FkUserID
+}
+
+// variantFilePath picks file of variant requested with variant and format query parameters,
+// falling back to original while variant is not generated yet
+func (h *Handler) variantFilePath(r *http.Request, image *types.Image) (string, error) {
+	query := r.URL.Query()
+	name := query.Get("variant")
+	if name == "" || name == "original" {
+		return image.FilePath, nil
+	}
+
+	if !h.processor.HasVariant(name) {
+		return "", fmt.Errorf("unknown image variant %s", name)
+	}
+
+	format := query.Get("format")
+	switch format {
+	case FormatWebP, FormatJPEG:
+	case "":
+		format = FormatJPEG
+		if strings.Contains(r.Header.Get("Accept"), "image/webp") {
+			format = FormatWebP
+		}
+	default:
+		return "", fmt.Errorf("unknown image format %s", format)
+	}
+
+	variants, err := h.imageCastle.ListImageVariants(image.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, v := range variants {
+		if v.Name == name && v.Format == format {
+			return v.FilePath, nil
+		}
+	}
+
+	return image.FilePath, nil
+}
+
+// blobURL returns public URL for public objects, otherwise signed URL with its expiration time
+func (h *Handler) blobURL(key string, private bool) (string, *time.Time, error) {
+	if !private && storage.IsPublicKey(key) {
+		return h.blobStore.PublicURL(key), nil, nil
+	}
+
+	expiration := time.Second * time.Duration(configs.Envs.BlobURLExpirationInSeconds)
+	url, err := h.blobStore.SignedURL(key, expiration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	expiresAt := time.Now().Add(expiration)
+	return url, &expiresAt, nil
+}
+
+func imageURL(imageID int) string {
+	return fmt.Sprintf("/api/v1/images/%d", imageID)
+}
+
+// newStorageKey generates unguessable key grouped by upload month, prefix decides
+// whether blob store serves the file without signature
+func newStorageKey(private bool, ext string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	prefix := storage.PublicPrefix
+	if private {
+		prefix = storage.PrivatePrefix
+	}
+
+	return fmt.Sprintf("%simages/%s/%s%s", prefix, time.Now().Format("2006/01"), hex.EncodeToString(b), ext), nil
+}
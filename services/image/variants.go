@@ -0,0 +1,178 @@
+package image
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	FormatWebP = "webp"
+	FormatJPEG = "jpeg"
+
+	jpegQuality = 82
+)
+
+// ErrTooManyPixels is returned for images whose decoded pixels wouldn't fit into configured limit
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// Every variant is stored in both formats, WebP for clients supporting it and JPEG as fallback
+var variantFormats = []string{FormatWebP, FormatJPEG}
+
+// VariantSize is bounding box image is scaled down to, keeping aspect ratio
+type VariantSize struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+// ParseVariantSizes parses configuration like "thumbnail:160x160,card:640x480"
+func ParseVariantSizes(spec string) ([]VariantSize, error) {
+	var sizes []VariantSize
+	seen := make(map[string]bool)
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, dimensions, ok := strings.Cut(item, ":")
+		w, h, ok2 := strings.Cut(dimensions, "x")
+		width, err1 := strconv.Atoi(w)
+		height, err2 := strconv.Atoi(h)
+		if !ok || !ok2 || err1 != nil || err2 != nil || width <= 0 || height <= 0 || name == "" {
+			return nil, fmt.Errorf("invalid image variant '%s', expected name:WIDTHxHEIGHT", item)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate image variant '%s'", name)
+		}
+		seen[name] = true
+
+		sizes = append(sizes, VariantSize{Name: name, Width: width, Height: height})
+	}
+
+	return sizes, nil
+}
+
+// encodedVariant is generated variant file waiting to be stored
+type encodedVariant struct {
+	size   VariantSize
+	format string
+	width  int
+	height int
+	data   []byte
+}
+
+// CheckPixels reads dimensions from image header and refuses images with more than maxPixels pixels. Small
+// files can declare dimensions which would take gigabytes of memory to decode, so it is called before decoding
+func CheckPixels(data []byte, maxPixels int64) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to read image header: %w", err)
+	}
+	if int64(config.Width)*int64(config.Height) > maxPixels {
+		return fmt.Errorf("%w, limit is %d", ErrTooManyPixels, maxPixels)
+	}
+
+	return nil
+}
+
+// generateVariants decodes original image unless it has more than maxPixels pixels and encodes every size in
+// every format
+func generateVariants(data []byte, sizes []VariantSize, maxPixels int64) ([]encodedVariant, error) {
+	if err := CheckPixels(data, maxPixels); err != nil {
+		return nil, err
+	}
+
+	original, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var variants []encodedVariant
+	for _, size := range sizes {
+		resized := resize(original, size.Width, size.Height)
+		bounds := resized.Bounds()
+
+		for _, format := range variantFormats {
+			encoded, err := encode(resized, format)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode %s variant as %s: %w", size.Name, format, err)
+			}
+
+			variants = append(variants, encodedVariant{
+				size:   size,
+				format: format,
+				width:  bounds.Dx(),
+				height: bounds.Dy(),
+				data:   encoded,
+			})
+		}
+	}
+
+	return variants, nil
+}
+
+// resize scales image down to fit into bounding box, smaller images are never enlarged
+func resize(src image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width > maxWidth || height > maxHeight {
+		if width*maxHeight > height*maxWidth {
+			height = max(1, height*maxWidth/width)
+			width = maxWidth
+		} else {
+			width = max(1, width*maxHeight/height)
+			height = maxHeight
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Src, nil)
+	return dst
+}
+
+func encode(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+
+	switch format {
+	case FormatWebP:
+		err = nativewebp.Encode(&buf, img, nil)
+	case FormatJPEG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	default:
+		err = fmt.Errorf("unknown format %s", format)
+	}
+
+	return buf.Bytes(), err
+}
+
+// variantKey places variant next to original, e.g. public/images/2024/12/ab12_card.webp
+func variantKey(originalKey, name, format string) string {
+	ext := ".webp"
+	if format == FormatJPEG {
+		ext = ".jpg"
+	}
+
+	return strings.TrimSuffix(originalKey, path.Ext(originalKey)) + "_" + name + ext
+}
+
+func variantContentType(format string) string {
+	if format == FormatJPEG {
+		return "image/jpeg"
+	}
+	return "image/webp"
+}
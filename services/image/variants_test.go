@@ -0,0 +1,117 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestParseVariantSizes(t *testing.T) {
+	t.Run("Should parse configured sizes", func(t *testing.T) {
+		sizes, err := ParseVariantSizes("thumbnail:160x160, card:640x480")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(sizes) != 2 || sizes[1] != (VariantSize{Name: "card", Width: 640, Height: 480}) {
+			t.Errorf("unexpected sizes %v", sizes)
+		}
+	})
+
+	t.Run("Should fail on invalid or duplicate sizes", func(t *testing.T) {
+		for _, spec := range []string{"card", "card:640", "card:0x10", "card:1x1,card:2x2"} {
+			if _, err := ParseVariantSizes(spec); err == nil {
+				t.Errorf("expected error for %q", spec)
+			}
+		}
+	})
+}
+
+func TestGenerateVariants(t *testing.T) {
+	sizes := []VariantSize{{Name: "thumbnail", Width: 50, Height: 50}, {Name: "full", Width: 1000, Height: 1000}}
+
+	variants, err := generateVariants(encodeJPEG(t, 200, 100), sizes, 200*100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(variants) != len(sizes)*len(variantFormats) {
+		t.Fatalf("expected %d variants, got %d", len(sizes)*len(variantFormats), len(variants))
+	}
+
+	for _, v := range variants {
+		decoded, format, err := image.Decode(bytes.NewReader(v.data))
+		if err != nil {
+			t.Fatalf("%s %s variant is not decodable: %v", v.size.Name, v.format, err)
+		}
+		if format != v.format {
+			t.Errorf("expected %s, got %s", v.format, format)
+		}
+
+		width, height := decoded.Bounds().Dx(), decoded.Bounds().Dy()
+		switch v.size.Name {
+		case "thumbnail":
+			// Aspect ratio has to be kept
+			if width != 50 || height != 25 {
+				t.Errorf("expected thumbnail 50x25, got %dx%d", width, height)
+			}
+		case "full":
+			// Small images are not enlarged
+			if width != 200 || height != 100 {
+				t.Errorf("expected full 200x100, got %dx%d", width, height)
+			}
+		}
+	}
+}
+
+func TestCheckPixels(t *testing.T) {
+	t.Run("Should accept images within limit", func(t *testing.T) {
+		if err := CheckPixels(encodeJPEG(t, 200, 100), 200*100); err != nil {
+			t.Errorf("expected image to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse tiny file declaring huge dimensions", func(t *testing.T) {
+		bomb := declareSize(t, encodeJPEG(t, 8, 8), 65000, 65000)
+		if len(bomb) > 1<<10 {
+			t.Fatalf("expected tiny file, got %d bytes", len(bomb))
+		}
+
+		if err := CheckPixels(bomb, 50_000_000); !errors.Is(err, ErrTooManyPixels) {
+			t.Errorf("expected too many pixels error, got %v", err)
+		}
+		if _, err := generateVariants(bomb, []VariantSize{{Name: "card", Width: 640, Height: 480}}, 50_000_000); !errors.Is(err, ErrTooManyPixels) {
+			t.Errorf("expected variants to be refused, got %v", err)
+		}
+	})
+
+	t.Run("Should refuse files which aren't images", func(t *testing.T) {
+		if err := CheckPixels([]byte("not an image"), 50_000_000); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+}
+
+func TestVariantKey(t *testing.T) {
+	key := variantKey("public/images/2024/12/ab12.png", "card", FormatJPEG)
+	if key != "public/images/2024/12/ab12_card.jpg" {
+		t.Errorf("unexpected variant key %s", key)
+	}
+}
+
+// declareSize rewrites dimensions in JPEG frame header, leaving image data as it is
+func declareSize(t *testing.T, data []byte, width, height uint16) []byte {
+	data = bytes.Clone(data)
+	i := bytes.Index(data, []byte{0xFF, 0xC0})
+	if i < 0 {
+		t.Fatal("missing jpeg frame header")
+	}
+
+	// Marker is followed by segment length and sample precision
+	binary.BigEndian.PutUint16(data[i+5:], height)
+	binary.BigEndian.PutUint16(data[i+7:], width)
+
+	return data
+}
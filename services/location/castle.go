@@ -0,0 +1,151 @@
+package location
+
+import (
+	"database/sql"
+	"educations-castle/types"
+)
+
+type Castle struct {
+	db *sql.DB
+}
+
+func NewCastle(db *sql.DB) *Castle {
+	return &Castle{db: db}
+}
+
+func scanRowIntoLocation(rows *sql.Rows) (*types.Location, error) {
+	l := new(types.Location)
+
+	err := rows.Scan(
+		&l.ID,
+		&l.Address,
+		&l.Longitude,
+		&l.Latitude,
+		&l.FkActivityID,
+		&l.Confidence,
+		&l.Provider,
+		&l.ManualOverride,
+		&l.City,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (c *Castle) CreateLocation(l types.Location) (int64, error) {
+	result, err := c.db.Exec(
+		`INSERT INTO location (address, longitude, latitude, fk_Activityid, confidence, provider, manualOverride, city)
+		VALUES (?,?,?,?,?,?,?,?)`,
+		l.Address, l.Longitude, l.Latitude, l.FkActivityID, l.Confidence, l.Provider, l.ManualOverride, l.City)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (c *Castle) GetLocationByID(id int) (*types.Location, error) {
+	rows, err := c.db.Query("SELECT * FROM location WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	l := new(types.Location)
+	for rows.Next() {
+		l, err = scanRowIntoLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if l.ID == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return l, nil
+}
+
+func (c *Castle) ListLocationsByActivityID(activityID int) ([]*types.Location, error) {
+	rows, err := c.db.Query("SELECT * FROM location WHERE fk_Activityid = ?", activityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locations []*types.Location
+
+	for rows.Next() {
+		l, err := scanRowIntoLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, l)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+func (c *Castle) UpdateLocation(l types.Location) error {
+	_, err := c.db.Exec(
+		`UPDATE location
+		SET address = ?, longitude = ?, latitude = ?, confidence = ?, provider = ?, manualOverride = ?, city = ?
+		WHERE id = ?`,
+		l.Address, l.Longitude, l.Latitude, l.Confidence, l.Provider, l.ManualOverride, l.City, l.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Castle) DeleteLocation(id int) error {
+	_, err := c.db.Exec(
+		"DELETE FROM location WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FillMissingCities derives city of locations stored without it from their address and returns number of
+// locations which got city. Cities already set, such as those of geocoded addresses, are kept
+func (c *Castle) FillMissingCities() (int, error) {
+	rows, err := c.db.Query("SELECT id, address FROM location WHERE city = ''")
+	if err != nil {
+		return 0, err
+	}
+
+	cities := map[int]string{}
+	for rows.Next() {
+		var id int
+		var address string
+		if err := rows.Scan(&id, &address); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if city := CityFromAddress(address); city != "" {
+			cities[id] = city
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, city := range cities {
+		if _, err := c.db.Exec("UPDATE location SET city = ? WHERE id = ? AND city = ''", city, id); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(cities), nil
+}
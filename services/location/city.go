@@ -0,0 +1,44 @@
+package location
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Postal codes such as LT-44001 or 44001
+var postalCode = regexp.MustCompile(`(?i)\b(LT-?)?\d{5}\b`)
+
+// Parts of geocoded addresses which come after the city
+var regionSuffixes = []string{" sav.", " apskr.", " savivaldybė", " apskritis", " county", " municipality"}
+
+var countries = map[string]bool{"lietuva": true, "lithuania": true}
+
+// CityFromAddress returns city of address such as "Laisvės al. 1, LT-44001 Kaunas" or
+// "Laisvės alėja 1, Kaunas, Kauno m. sav., Lietuva". It is the last part which isn't postal code, region,
+// country or street with house number, empty when there is no such part
+func CityFromAddress(address string) string {
+	parts := strings.Split(address, ",")
+
+	for i := len(parts) - 1; i >= 0; i-- {
+		part := strings.TrimSpace(postalCode.ReplaceAllString(parts[i], ""))
+		lower := strings.ToLower(part)
+
+		if part == "" || countries[lower] || hasRegionSuffix(lower) || strings.ContainsAny(part, "0123456789") {
+			continue
+		}
+
+		return strings.TrimSpace(strings.TrimSuffix(part, " city"))
+	}
+
+	return ""
+}
+
+func hasRegionSuffix(part string) bool {
+	for _, suffix := range regionSuffixes {
+		if strings.HasSuffix(part, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,20 @@
+package location
+
+import "testing"
+
+func TestCityFromAddress(t *testing.T) {
+	tests := map[string]string{
+		"Pilies g. 1, Palanga":           "Palanga",
+		"Laisvės al. 1, LT-44001 Kaunas": "Kaunas",
+		"Kaunas city":                    "Kaunas",
+		"1, Laisvės alėja, Centras, Kaunas, Kauno m. sav., Kauno apskr., 44001, Lietuva": "Kaunas",
+		"Vilnius, Lithuania": "Vilnius",
+		"Pilies g. 1":        "",
+		"":                   "",
+	}
+	for address, expected := range tests {
+		if city := CityFromAddress(address); city != expected {
+			t.Errorf("%q: expected %q, got %q", address, expected, city)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package location
+
+import (
+	"database/sql"
+	"educations-castle/services/auth"
+	"educations-castle/types"
+	"educations-castle/utils"
+	"educations-castle/utils/color"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+const ProviderManual = "manual"
+
+type Handler struct {
+	locationCastle types.LocationCastle
+	userCastle     types.UserCastle
+	geocoder       types.Geocoder
+}
+
+func NewHandler(locationCastle types.LocationCastle, userCastle types.UserCastle, geocoder types.Geocoder) *Handler {
+	return &Handler{
+		locationCastle: locationCastle,
+		userCastle:     userCastle,
+		geocoder:       geocoder}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/activities/{activityID:[0-9]+}/locations", h.handleListActivityLocations).Methods("GET")
+	router.HandleFunc("/locations/{locationID:[0-9]+}", h.handleGetLocation).Methods("GET")
+	router.HandleFunc("/locations/reverse", auth.WithJWTAuth(h.handleReverseGeocode, h.userCastle, "administrator", "organizer")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/locations/create", auth.WithJWTAuth(h.handleCreateLocation, h.userCastle, "administrator", "organizer")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/locations/update/{locationID:[0-9]+}", auth.WithJWTAuth(h.handleUpdateLocation, h.userCastle, "administrator", "organizer")).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/locations/delete/{locationID:[0-9]+}", auth.WithJWTAuth(h.handleDeleteLocation, h.userCastle, "administrator", "organizer")).Methods("DELETE", "OPTIONS")
+}
+
+// ListActivityLocations godoc
+// @Summary      List activity locations
+// @Description  Returns all locations of activity
+// @Tags         location
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Success      200  {array}    types.Location
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid activity ID"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/locations [get]
+func (h *Handler) handleListActivityLocations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	activityID, err := strconv.Atoi(vars["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	locations, err := h.locationCastle.ListLocationsByActivityID(activityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no locations found, return an empty array
+	if len(locations) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.Location{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, locations)
+}
+
+// GetLocation godoc
+// @Summary      Get location by ID
+// @Description  Get location data by ID from the database
+// @Tags         location
+// @Produce      json
+// @Param        locationID path int true "Location ID"
+// @Success      200  {object}   types.Location
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid location ID"
+// @Failure      404  {object}   types.ErrorResponse "location not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /locations/{locationID} [get]
+func (h *Handler) handleGetLocation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	locationID, err := strconv.Atoi(vars["locationID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid location ID"))
+		return
+	}
+
+	location, err := h.locationCastle.GetLocationByID(locationID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("location not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, location)
+}
+
+// CreateLocation godoc
+// @Summary      Create a new location
+// @Description  Create a new activity location. Coordinates are resolved from address unless both longitude and latitude are provided
+// @Tags         location
+// @Accept       json
+// @Produce      json
+// @Param        payload body types.LocationPayload true "Location data"
+// @Success      201  {object}   types.Location
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "activity organizer not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /locations/create [post]
+func (h *Handler) handleCreateLocation(w http.ResponseWriter, r *http.Request) {
+	// get JSON payload
+	var payload types.LocationPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	// Check if the user has ownership of the activity
+	organizer, err := h.userCastle.GetOrganizerByActivityID(payload.FkActivityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity organizer not found"))
+		return
+	}
+	if !auth.CheckOwnership(r, organizer.ID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	location, err := h.resolveLocation(r, payload, nil)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	location.FkActivityID = payload.FkActivityID
+
+	locationID, err := h.locationCastle.CreateLocation(location)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	location.ID = int(locationID)
+
+	utils.WriteJSON(w, http.StatusCreated, location)
+}
+
+// UpdateLocation godoc
+// @Summary      Update location by ID
+// @Description  Update location address. Coordinates are resolved again when address changes unless both longitude and latitude are provided, previous coordinates are kept when address is unchanged. Location of changed address which fails to geocode is saved without coordinates
+// @Tags         location
+// @Accept       json
+// @Produce      json
+// @Param        locationID path int true "Location ID"
+// @Param        payload body types.LocationPayload true "Location data"
+// @Success      200  {object}   types.Location
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "location not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /locations/update/{locationID} [put]
+func (h *Handler) handleUpdateLocation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	locationID, err := strconv.Atoi(vars["locationID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid location ID"))
+		return
+	}
+
+	// Get JSON payload
+	var payload types.LocationPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	existingLocation, err := h.locationCastle.GetLocationByID(locationID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("location not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	// Check if the user has ownership of the activity
+	organizer, err := h.userCastle.GetOrganizerByActivityID(existingLocation.FkActivityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity organizer not found"))
+		return
+	}
+	if !auth.CheckOwnership(r, organizer.ID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	location, err := h.resolveLocation(r, payload, existingLocation)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	location.ID = existingLocation.ID
+	location.FkActivityID = existingLocation.FkActivityID
+
+	err = h.locationCastle.UpdateLocation(location)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, location)
+}
+
+// DeleteLocation godoc
+// @Summary      Delete location by ID
+// @Description  Delete location data by ID from database
+// @Tags         location
+// @Produce      json
+// @Param        locationID path int true "Location ID"
+// @Success      200  {object}   types.ErrorResponse "Location with ID %d successfully deleted"
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid location ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /locations/delete/{locationID} [delete]
+func (h *Handler) handleDeleteLocation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	locationID, err := strconv.Atoi(vars["locationID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid location ID"))
+		return
+	}
+
+	existingLocation, err := h.locationCastle.GetLocationByID(locationID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("Location with ID %d doesn't exist", locationID))
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error fetching location: %w", err))
+		return
+	}
+
+	// Check if the user has ownership of the activity
+	organizer, err := h.userCastle.GetOrganizerByActivityID(existingLocation.FkActivityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity organizer not found"))
+		return
+	}
+	if !auth.CheckOwnership(r, organizer.ID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	err = h.locationCastle.DeleteLocation(existingLocation.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error deleting location: %w", err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("Location with ID %d successfully deleted", locationID))
+}
+
+// ReverseGeocode godoc
+// @Summary      Resolve address from coordinates
+// @Description  Returns address closest to the given coordinates
+// @Tags         location
+// @Produce      json
+// @Param        latitude   query  number  true  "Latitude"
+// @Param        longitude  query  number  true  "Longitude"
+// @Success      200  {object}   types.GeocodeResult
+// @Failure      400  {object}   types.ErrorResponse "invalid coordinates"
+// @Failure      404  {object}   types.ErrorResponse "address not found"
+// @Failure      503  {object}   types.ErrorResponse "geocoding is disabled"
+// @Router       /locations/reverse [get]
+func (h *Handler) handleReverseGeocode(w http.ResponseWriter, r *http.Request) {
+	if h.geocoder == nil {
+		utils.WriteError(w, http.StatusServiceUnavailable, fmt.Errorf("geocoding is disabled"))
+		return
+	}
+
+	latitude, err := utils.ParseStringToFloat64(r.URL.Query().Get("latitude"))
+	if err != nil || latitude < -90 || latitude > 90 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid latitude"))
+		return
+	}
+
+	longitude, err := utils.ParseStringToFloat64(r.URL.Query().Get("longitude"))
+	if err != nil || longitude < -180 || longitude > 180 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid longitude"))
+		return
+	}
+
+	result, err := h.geocoder.ReverseGeocode(r.Context(), latitude, longitude)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, result)
+}
+
+// resolveLocation uses coordinates from payload as manual override when both of them
+// are provided, otherwise asks geocoder. Geocoding failures don't prevent location
+// from being saved, organizer can still provide coordinates by hand later. When existing
+// location is given its coordinates are kept as long as address is unchanged or geocoder fails,
+// so updates don't wipe manual overrides. City is taken from address,
+// or from geocoded address when organizer left it out
+func (h *Handler) resolveLocation(r *http.Request, payload types.LocationPayload, existing *types.Location) (types.Location, error) {
+	location := types.Location{
+		Address: payload.Address,
+		City:    CityFromAddress(payload.Address),
+	}
+
+	if (payload.Longitude == nil) != (payload.Latitude == nil) {
+		return location, fmt.Errorf("both longitude and latitude must be provided")
+	}
+
+	if payload.Longitude != nil {
+		location.Longitude = payload.Longitude
+		location.Latitude = payload.Latitude
+		location.Confidence = 1
+		location.Provider = ProviderManual
+		location.ManualOverride = true
+		return location, nil
+	}
+
+	if existing != nil && existing.Address == payload.Address {
+		keepCoordinates(&location, existing)
+		return location, nil
+	}
+
+	if h.geocoder == nil {
+		return location, nil
+	}
+
+	result, err := h.geocoder.Geocode(r.Context(), payload.Address)
+	if err != nil {
+		// Coordinates of previous address would point to wrong place, location is saved without them
+		log.Println(color.Format(color.YELLOW, fmt.Sprintf("Geocoding '%s' failed: %v", payload.Address, err)))
+		return location, nil
+	}
+
+	location.Longitude = &result.Longitude
+	location.Latitude = &result.Latitude
+	location.Confidence = result.Confidence
+	location.Provider = result.Provider
+	if location.City == "" {
+		location.City = CityFromAddress(result.Address)
+	}
+
+	return location, nil
+}
+
+// keepCoordinates copies coordinates of existing location together with their origin
+func keepCoordinates(location *types.Location, existing *types.Location) {
+	location.Longitude = existing.Longitude
+	location.Latitude = existing.Latitude
+	location.Confidence = existing.Confidence
+	location.Provider = existing.Provider
+	location.ManualOverride = existing.ManualOverride
+	if location.City == "" {
+		location.City = existing.City
+	}
+}
@@ -0,0 +1,107 @@
+package location
+
+import (
+	"context"
+	"educations-castle/types"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestResolveLocation(t *testing.T) {
+	longitude, latitude := 23.9, 54.9
+	existing := &types.Location{
+		ID:             1,
+		Address:        "Laisvės al. 1, Kaunas",
+		Longitude:      &longitude,
+		Latitude:       &latitude,
+		Confidence:     1,
+		Provider:       ProviderManual,
+		ManualOverride: true,
+		City:           "Kaunas",
+	}
+	req, _ := http.NewRequest(http.MethodPut, "/locations/update/1", nil)
+
+	t.Run("Should keep manual coordinates when address is unchanged", func(t *testing.T) {
+		geocoder := &mockGeocoder{}
+		handler := NewHandler(nil, nil, geocoder)
+
+		location, err := handler.resolveLocation(req, types.LocationPayload{Address: existing.Address, FkActivityID: 1}, existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if geocoder.calls != 0 {
+			t.Errorf("expected geocoder not to be called, got %d calls", geocoder.calls)
+		}
+		if !location.ManualOverride || location.Longitude == nil || *location.Longitude != longitude {
+			t.Errorf("expected manual coordinates to be kept, got %+v", location)
+		}
+	})
+
+	t.Run("Should drop previous coordinates when geocoder fails on changed address", func(t *testing.T) {
+		geocoder := &mockGeocoder{err: fmt.Errorf("service unavailable")}
+		handler := NewHandler(nil, nil, geocoder)
+
+		location, err := handler.resolveLocation(req, types.LocationPayload{Address: "Laisvės al. 2, Vilnius", FkActivityID: 1}, existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if geocoder.calls != 1 {
+			t.Errorf("expected geocoder to be called once, got %d calls", geocoder.calls)
+		}
+		if location.Latitude != nil || location.Longitude != nil || location.Provider != "" || location.Confidence != 0 || location.ManualOverride {
+			t.Errorf("expected coordinates of previous address to be dropped, got %+v", location)
+		}
+		if location.Address != "Laisvės al. 2, Vilnius" || location.City != "Vilnius" {
+			t.Errorf("expected address and city to be updated, got %q, %q", location.Address, location.City)
+		}
+	})
+
+	t.Run("Should geocode changed address", func(t *testing.T) {
+		geocoder := &mockGeocoder{result: &types.GeocodeResult{Longitude: 25.28, Latitude: 54.68, Confidence: 0.8, Provider: "static"}}
+		handler := NewHandler(nil, nil, geocoder)
+
+		location, err := handler.resolveLocation(req, types.LocationPayload{Address: "Pilies g. 1, Vilnius", FkActivityID: 1}, existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if location.ManualOverride || location.Provider != "static" || *location.Longitude != 25.28 {
+			t.Errorf("expected geocoded coordinates, got %+v", location)
+		}
+		if location.City != "Vilnius" {
+			t.Errorf("expected city Vilnius, got %q", location.City)
+		}
+	})
+
+	t.Run("Should leave coordinates empty when geocoder fails on create", func(t *testing.T) {
+		handler := NewHandler(nil, nil, &mockGeocoder{err: fmt.Errorf("service unavailable")})
+
+		location, err := handler.resolveLocation(req, types.LocationPayload{Address: "Pilies g. 1, Vilnius", FkActivityID: 1}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if location.Longitude != nil || location.Latitude != nil {
+			t.Errorf("expected no coordinates, got %+v", location)
+		}
+	})
+}
+
+type mockGeocoder struct {
+	result *types.GeocodeResult
+	err    error
+	calls  int
+}
+
+func (m *mockGeocoder) Geocode(ctx context.Context, address string) (*types.GeocodeResult, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	result := *m.result
+	result.Address = address
+	return &result, nil
+}
+
+func (m *mockGeocoder) ReverseGeocode(ctx context.Context, latitude, longitude float64) (*types.GeocodeResult, error) {
+	return nil, fmt.Errorf("not implemented")
+}
@@ -0,0 +1,89 @@
+package moderation
+
+import (
+	"database/sql"
+	"educations-castle/types"
+)
+
+type Castle struct {
+	db *sql.DB
+}
+
+func NewCastle(db *sql.DB) *Castle {
+	return &Castle{db: db}
+}
+
+func scanRowIntoDecision(rows *sql.Rows) (*types.ModerationDecision, error) {
+	d := new(types.ModerationDecision)
+
+	err := rows.Scan(
+		&d.ID,
+		&d.FkActivityID,
+		&d.FkAdministratorID,
+		&d.Decision,
+		&d.Reason,
+		&d.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// CreateModerationDecision stores decision and moves activity into decided status,
+// activity is verified only while it is approved
+func (c *Castle) CreateModerationDecision(d types.ModerationDecision) (int64, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO moderationdecision (fk_Activityid, fk_Administratorid, decision, reason) VALUES (?,?,?,?)",
+		d.FkActivityID, d.FkAdministratorID, d.Decision, d.Reason)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE activity SET moderationStatus = ?, verified = ? WHERE id = ?",
+		d.Decision, d.Decision == types.ModerationApproved, d.FkActivityID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// ListModerationDecisions returns decision history of activity, newest first
+func (c *Castle) ListModerationDecisions(activityID int) ([]*types.ModerationDecision, error) {
+	rows, err := c.db.Query(
+		"SELECT * FROM moderationdecision WHERE fk_Activityid = ? ORDER BY createdAt DESC, id DESC", activityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var decisions []*types.ModerationDecision
+
+	for rows.Next() {
+		d, err := scanRowIntoDecision(rows)
+		if err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return decisions, nil
+}
@@ -0,0 +1,257 @@
+package moderation
+
+import (
+	"database/sql"
+	"educations-castle/services/auth"
+	"educations-castle/types"
+	"educations-castle/utils"
+	"educations-castle/utils/color"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// Decisions administrators can make through moderation routes
+var decisionRoutes = map[string]string{
+	"approve":         types.ModerationApproved,
+	"reject":          types.ModerationRejected,
+	"request-changes": types.ModerationChangesRequested,
+}
+
+type Handler struct {
+	moderationCastle   types.ModerationCastle
+	activityCastle     types.ActivityCastle
+	userCastle         types.UserCastle
+	notificationCastle types.NotificationCastle
+}
+
+func NewHandler(moderationCastle types.ModerationCastle, activityCastle types.ActivityCastle,
+	userCastle types.UserCastle, notificationCastle types.NotificationCastle) *Handler {
+	return &Handler{
+		moderationCastle:   moderationCastle,
+		activityCastle:     activityCastle,
+		userCastle:         userCastle,
+		notificationCastle: notificationCastle}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/moderation/activities", auth.WithJWTAuth(h.handleListModerationQueue, h.userCastle, "administrator")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/moderation/activities/{activityID:[0-9]+}/{decision:approve|reject|request-changes}", auth.WithJWTAuth(h.handleModerateActivity, h.userCastle, "administrator")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/moderation/activities/{activityID:[0-9]+}/decisions", auth.WithJWTAuth(h.handleListDecisions, h.userCastle, "administrator", "organizer")).Methods("GET", "OPTIONS")
+}
+
+// ListModerationQueue godoc
+// @Summary      List activities waiting for moderation
+// @Description  Returns activities with given moderation status, oldest first. Pending activities are returned by default
+// @Tags         moderation
+// @Produce      json
+// @Param        status  query  string  false  "pending, approved, rejected or changes_requested"
+// @Success      200  {array}    types.Activity
+// @Failure      400  {object}   types.ErrorResponse "invalid moderation status"
+// @Failure      401  {object}   types.ErrorResponse "unauthorized"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /moderation/activities [get]
+func (h *Handler) handleListModerationQueue(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = types.ModerationPending
+	case types.ModerationPending, types.ModerationApproved, types.ModerationRejected, types.ModerationChangesRequested:
+	default:
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid moderation status %s", status))
+		return
+	}
+
+	activities, err := h.activityCastle.ListActivitiesByModerationStatus(status)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no activities found, return an empty array
+	if len(activities) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.Activity{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, activities)
+}
+
+// ModerateActivity godoc
+// @Summary      Approve, reject or request changes of activity
+// @Description  Stores administrator decision, updates verification of activity and notifies organizer. Reason is required unless activity is approved
+// @Tags         moderation
+// @Accept       json
+// @Produce      json
+// @Param        activityID path int    true "Activity ID"
+// @Param        decision   path string true "approve, reject or request-changes"
+// @Param        payload    body types.ModerationDecisionPayload false "Decision reason"
+// @Success      201  {object}   types.ModerationDecision
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "unauthorized"
+// @Failure      404  {object}   types.ErrorResponse "activity not found"
+// @Failure      409  {object}   types.ErrorResponse "activity already has this status"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /moderation/activities/{activityID}/{decision} [post]
+func (h *Handler) handleModerateActivity(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	activityID, err := strconv.Atoi(vars["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+	decision := decisionRoutes[vars["decision"]]
+
+	// Body is optional when activity is approved
+	var payload types.ModerationDecisionPayload
+	if err := utils.ParseJSON(r, &payload); err != nil && err != io.EOF {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	payload.Reason = strings.TrimSpace(payload.Reason)
+	if decision != types.ModerationApproved && payload.Reason == "" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("reason is required"))
+		return
+	}
+
+	activity, err := h.activityCastle.GetActivityByID(activityID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if activity.ModerationStatus == decision {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("activity is already %s", decision))
+		return
+	}
+
+	moderatorID := auth.GetUserIDFromContext(r.Context())
+	d := types.ModerationDecision{
+		FkActivityID:      activity.ID,
+		FkAdministratorID: &moderatorID,
+		Decision:          decision,
+	}
+	if payload.Reason != "" {
+		d.Reason = &payload.Reason
+	}
+
+	decisionID, err := h.moderationCastle.CreateModerationDecision(d)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	d.ID = int(decisionID)
+
+	// Only verified activities are suggested by search
+	if err := h.activityCastle.IndexActivity(activity.ID); err != nil {
+		log.Println(color.Format(color.RED, fmt.Sprintf("activity %d: failed to update search index: %v", activity.ID, err)))
+	}
+
+	h.notifyOrganizer(activity, d)
+
+	decisions, err := h.moderationCastle.ListModerationDecisions(activity.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, stored := range decisions {
+		if stored.ID == d.ID {
+			utils.WriteJSON(w, http.StatusCreated, stored)
+			return
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, d)
+}
+
+// ListDecisions godoc
+// @Summary      List moderation decisions of activity
+// @Description  Returns decision history of activity, newest first. Organizers can see history of their own activities only
+// @Tags         moderation
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Success      200  {array}    types.ModerationDecision
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid activity ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "activity not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /moderation/activities/{activityID}/decisions [get]
+func (h *Handler) handleListDecisions(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	// Check if the user has ownership of the activity
+	organizer, err := h.userCastle.GetOrganizerByActivityID(activityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity not found"))
+		return
+	}
+	if !auth.CheckOwnership(r, organizer.ID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	decisions, err := h.moderationCastle.ListModerationDecisions(activityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no decisions found, return an empty array
+	if len(decisions) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.ModerationDecision{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, decisions)
+}
+
+// notifyOrganizer informs activity organizer about decision, failure doesn't revert the decision
+func (h *Handler) notifyOrganizer(activity *types.Activity, d types.ModerationDecision) {
+	organizer, err := h.userCastle.GetOrganizerByActivityID(activity.ID)
+	if err != nil {
+		log.Println(color.Format(color.RED, fmt.Sprintf("activity %d: organizer not found for notification: %v", activity.ID, err)))
+		return
+	}
+
+	var message string
+	switch d.Decision {
+	case types.ModerationApproved:
+		message = fmt.Sprintf("Activity %s was approved and is now public", activity.Name)
+	case types.ModerationRejected:
+		message = fmt.Sprintf("Activity %s was rejected: %s", activity.Name, *d.Reason)
+	case types.ModerationChangesRequested:
+		message = fmt.Sprintf("Changes of activity %s were requested: %s", activity.Name, *d.Reason)
+	}
+
+	_, err = h.notificationCastle.CreateNotification(types.Notification{
+		FkUserID:   organizer.ID,
+		Type:       d.Decision,
+		Message:    message,
+		EntityType: types.EntityTypeActivity,
+		EntityFk:   activity.ID,
+	})
+	if err != nil {
+		log.Println(color.Format(color.RED, fmt.Sprintf("activity %d: failed to notify organizer: %v", activity.ID, err)))
+	}
+}
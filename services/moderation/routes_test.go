@@ -0,0 +1,185 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"educations-castle/services/auth"
+	"educations-castle/types"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestModerationServiceHandler(t *testing.T) {
+	newHandler := func(status string) (*Handler, *mockModerationCastle, *mockNotificationCastle) {
+		moderationCastle := &mockModerationCastle{}
+		notificationCastle := &mockNotificationCastle{}
+		activityCastle := &mockActivityCastle{activity: &types.Activity{ID: 1, Name: "Amber history", ModerationStatus: status}}
+		return NewHandler(moderationCastle, activityCastle, &mockUserCastle{organizerID: 7}, notificationCastle), moderationCastle, notificationCastle
+	}
+
+	moderate := func(handler *Handler, path string, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := context.WithValue(req.Context(), auth.UserKey, 1)
+		ctx = context.WithValue(ctx, auth.RoleKey, "administrator")
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/moderation/activities/{activityID:[0-9]+}/{decision:approve|reject|request-changes}", handler.handleModerateActivity)
+		router.ServeHTTP(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	t.Run("Should approve activity and notify organizer", func(t *testing.T) {
+		handler, moderationCastle, notificationCastle := newHandler(types.ModerationPending)
+
+		rr := moderate(handler, "/moderation/activities/1/approve", "")
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+
+		if len(moderationCastle.decisions) != 1 || moderationCastle.decisions[0].Decision != types.ModerationApproved {
+			t.Errorf("expected approval to be stored, got %+v", moderationCastle.decisions)
+		}
+		if len(notificationCastle.notifications) != 1 {
+			t.Fatalf("expected one notification, got %d", len(notificationCastle.notifications))
+		}
+		n := notificationCastle.notifications[0]
+		if n.FkUserID != 7 || n.Type != types.ModerationApproved || n.EntityFk != 1 || n.EntityType != types.EntityTypeActivity {
+			t.Errorf("unexpected notification %+v", n)
+		}
+	})
+
+	t.Run("Should require reason when rejecting activity", func(t *testing.T) {
+		handler, moderationCastle, notificationCastle := newHandler(types.ModerationPending)
+
+		rr := moderate(handler, "/moderation/activities/1/reject", `{"reason": "  "}`)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+		if len(moderationCastle.decisions) != 0 || len(notificationCastle.notifications) != 0 {
+			t.Errorf("expected nothing to be stored")
+		}
+	})
+
+	t.Run("Should send reason of requested changes to organizer", func(t *testing.T) {
+		handler, moderationCastle, notificationCastle := newHandler(types.ModerationApproved)
+
+		body, _ := json.Marshal(types.ModerationDecisionPayload{Reason: "Add age limits"})
+		rr := moderate(handler, "/moderation/activities/1/request-changes", string(body))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+
+		d := moderationCastle.decisions[0]
+		if d.Decision != types.ModerationChangesRequested || d.Reason == nil || *d.Reason != "Add age limits" {
+			t.Errorf("unexpected decision %+v", d)
+		}
+		n := notificationCastle.notifications[0]
+		if n.Type != types.ModerationChangesRequested || !strings.Contains(n.Message, "Add age limits") {
+			t.Errorf("unexpected notification %+v", n)
+		}
+	})
+
+	t.Run("Should refuse decision activity already has", func(t *testing.T) {
+		handler, moderationCastle, notificationCastle := newHandler(types.ModerationApproved)
+
+		rr := moderate(handler, "/moderation/activities/1/approve", "")
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, rr.Code)
+		}
+		if len(moderationCastle.decisions) != 0 || len(notificationCastle.notifications) != 0 {
+			t.Errorf("expected nothing to be stored")
+		}
+	})
+
+	t.Run("Should fail if activity doesn't exist", func(t *testing.T) {
+		handler, _, _ := newHandler(types.ModerationPending)
+
+		rr := moderate(handler, "/moderation/activities/2/approve", "")
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("Should hide decisions from other organizers", func(t *testing.T) {
+		handler, _, _ := newHandler(types.ModerationPending)
+
+		req, err := http.NewRequest(http.MethodGet, "/moderation/activities/1/decisions", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := context.WithValue(req.Context(), auth.UserKey, 8)
+		ctx = context.WithValue(ctx, auth.RoleKey, "organizer")
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/moderation/activities/{activityID:[0-9]+}/decisions", handler.handleListDecisions)
+		router.ServeHTTP(rr, req.WithContext(ctx))
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+}
+
+type mockModerationCastle struct {
+	decisions []types.ModerationDecision
+}
+
+func (m *mockModerationCastle) CreateModerationDecision(d types.ModerationDecision) (int64, error) {
+	d.ID = len(m.decisions) + 1
+	m.decisions = append(m.decisions, d)
+	return int64(d.ID), nil
+}
+
+func (m *mockModerationCastle) ListModerationDecisions(activityID int) ([]*types.ModerationDecision, error) {
+	var decisions []*types.ModerationDecision
+	for i := range m.decisions {
+		decisions = append(decisions, &m.decisions[i])
+	}
+	return decisions, nil
+}
+
+type mockActivityCastle struct {
+	types.ActivityCastle
+	activity *types.Activity
+}
+
+func (m *mockActivityCastle) GetActivityByID(id int) (*types.Activity, error) {
+	if id != m.activity.ID {
+		return nil, sql.ErrNoRows
+	}
+	return m.activity, nil
+}
+
+func (m *mockActivityCastle) IndexActivity(id int) error {
+	return nil
+}
+
+type mockUserCastle struct {
+	types.UserCastle
+	organizerID int
+}
+
+func (m *mockUserCastle) GetOrganizerByActivityID(id int) (*types.Organizer, error) {
+	return &types.Organizer{ID: m.organizerID}, nil
+}
+
+type mockNotificationCastle struct {
+	types.NotificationCastle
+	notifications []types.Notification
+}
+
+func (m *mockNotificationCastle) CreateNotification(n types.Notification) (int64, error) {
+	m.notifications = append(m.notifications, n)
+	return int64(len(m.notifications)), nil
+}
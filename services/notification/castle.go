@@ -0,0 +1,95 @@
+package notification
+
+import (
+	"database/sql"
+	"educations-castle/types"
+)
+
+type Castle struct {
+	db *sql.DB
+}
+
+func NewCastle(db *sql.DB) *Castle {
+	return &Castle{db: db}
+}
+
+func scanRowIntoNotification(rows *sql.Rows) (*types.Notification, error) {
+	n := new(types.Notification)
+
+	err := rows.Scan(
+		&n.ID,
+		&n.FkUserID,
+		&n.Type,
+		&n.Message,
+		&n.EntityType,
+		&n.EntityFk,
+		&n.CreatedAt,
+		&n.ReadAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (c *Castle) CreateNotification(n types.Notification) (int64, error) {
+	result, err := c.db.Exec(
+		"INSERT INTO notification (fk_Userid, type, message, entityType, entityFk) VALUES (?,?,?,?,?)",
+		n.FkUserID, n.Type, n.Message, n.EntityType, n.EntityFk)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// ListNotificationsByUserID returns newest notifications first
+func (c *Castle) ListNotificationsByUserID(userID int, unreadOnly bool) ([]*types.Notification, error) {
+	query := "SELECT * FROM notification WHERE fk_Userid = ?"
+	if unreadOnly {
+		query += " AND readAt IS NULL"
+	}
+	query += " ORDER BY createdAt DESC, id DESC"
+
+	rows, err := c.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*types.Notification
+
+	for rows.Next() {
+		n, err := scanRowIntoNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// MarkNotificationRead marks notification of the user as read, sql.ErrNoRows is returned
+// when user has no such notification
+func (c *Castle) MarkNotificationRead(id int, userID int) error {
+	var exists bool
+	err := c.db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM notification WHERE id = ? AND fk_Userid = ?)", id, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+
+	_, err = c.db.Exec(
+		"UPDATE notification SET readAt = COALESCE(readAt, NOW()) WHERE id = ? AND fk_Userid = ?", id, userID)
+	return err
+}
@@ -0,0 +1,97 @@
+package notification
+
+import (
+	"database/sql"
+	"educations-castle/services/auth"
+	"educations-castle/types"
+	"educations-castle/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	notificationCastle types.NotificationCastle
+	userCastle         types.UserCastle
+}
+
+func NewHandler(notificationCastle types.NotificationCastle, userCastle types.UserCastle) *Handler {
+	return &Handler{
+		notificationCastle: notificationCastle,
+		userCastle:         userCastle}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/notifications", auth.WithJWTAuth(h.handleListNotifications, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/notifications/read/{notificationID:[0-9]+}", auth.WithJWTAuth(h.handleMarkNotificationRead, h.userCastle, "administrator", "organizer", "user")).Methods("PUT", "OPTIONS")
+}
+
+// ListNotifications godoc
+// @Summary      List notifications
+// @Description  Returns notifications of authenticated user, newest first
+// @Tags         notification
+// @Produce      json
+// @Param        unread  query  bool  false  "Return only unread notifications"
+// @Success      200  {array}    types.Notification
+// @Failure      400  {object}   types.ErrorResponse "invalid unread flag"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /notifications [get]
+func (h *Handler) handleListNotifications(w http.ResponseWriter, r *http.Request) {
+	unreadOnly := false
+	if str := r.URL.Query().Get("unread"); str != "" {
+		var err error
+		unreadOnly, err = strconv.ParseBool(str)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid unread flag"))
+			return
+		}
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	notifications, err := h.notificationCastle.ListNotificationsByUserID(userID, unreadOnly)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no notifications found, return an empty array
+	if len(notifications) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.Notification{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, notifications)
+}
+
+// MarkNotificationRead godoc
+// @Summary      Mark notification as read
+// @Description  Marks notification of authenticated user as read
+// @Tags         notification
+// @Produce      json
+// @Param        notificationID path int true "Notification ID"
+// @Success      200  {object}   types.ErrorResponse "Notification with ID %d marked as read"
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid notification ID"
+// @Failure      404  {object}   types.ErrorResponse "notification not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /notifications/read/{notificationID} [put]
+func (h *Handler) handleMarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	notificationID, err := strconv.Atoi(mux.Vars(r)["notificationID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid notification ID"))
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if err := h.notificationCastle.MarkNotificationRead(notificationID, userID); err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("notification not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("Notification with ID %d marked as read", notificationID))
+}
@@ -0,0 +1,221 @@
+package payment
+
+import (
+	"database/sql"
+	"educations-castle/types"
+)
+
+type Castle struct {
+	db *sql.DB
+}
+
+func NewCastle(db *sql.DB) *Castle {
+	return &Castle{db: db}
+}
+
+func (c *Castle) CreatePayment(p types.Payment) (int64, error) {
+	result, err := c.db.Exec(
+		`INSERT INTO payment (fk_Bookingid, fk_GiftVoucherid, provider, providerRef, amount, currency, status, checkoutUrl)
+		VALUES (?,?,?,?,?,?,?,?)`,
+		p.FkBookingID, p.FkGiftVoucherID, p.Provider, p.ProviderRef, p.Amount.Amount, p.Amount.Currency, p.Status, p.CheckoutURL)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (c *Castle) GetPaymentByID(id int) (*types.Payment, error) {
+	rows, err := c.db.Query("SELECT * FROM payment WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSinglePayment(rows)
+}
+
+func (c *Castle) GetPaymentByProviderRef(provider, ref string) (*types.Payment, error) {
+	rows, err := c.db.Query("SELECT * FROM payment WHERE provider = ? AND providerRef = ?", provider, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSinglePayment(rows)
+}
+
+// ListPaymentsByBookingID returns payments of booking, latest first
+func (c *Castle) ListPaymentsByBookingID(bookingID int) ([]*types.Payment, error) {
+	return c.listPayments("SELECT * FROM payment WHERE fk_Bookingid = ? ORDER BY id DESC", bookingID)
+}
+
+// ListPaymentsByVoucherID returns payments of gift voucher, latest first
+func (c *Castle) ListPaymentsByVoucherID(voucherID int) ([]*types.Payment, error) {
+	return c.listPayments("SELECT * FROM payment WHERE fk_GiftVoucherid = ? ORDER BY id DESC", voucherID)
+}
+
+func (c *Castle) listPayments(query string, params ...interface{}) ([]*types.Payment, error) {
+	rows, err := c.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*types.Payment
+	for rows.Next() {
+		p, err := scanRowIntoPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// ApplyPaymentEvent moves payment into status of webhook event and reports whether it changed. Every event is
+// applied once, redelivered ones and events which would move payment backwards are ignored. Succeeded payment
+// confirms its pending booking and accepts waitlist offer it was made for, or activates its gift voucher
+func (c *Castle) ApplyPaymentEvent(provider string, event types.PaymentEvent) (*types.Payment, bool, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT * FROM payment WHERE provider = ? AND providerRef = ? FOR UPDATE", provider, event.ProviderRef)
+	if err != nil {
+		return nil, false, err
+	}
+	p, err := scanSinglePayment(rows)
+	rows.Close()
+	if err != nil {
+		return nil, false, err
+	}
+
+	result, err := tx.Exec("INSERT IGNORE INTO paymentevent (provider, eventId) VALUES (?, ?)", provider, event.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if inserted == 0 || !CanTransition(p.Status, event.Status) {
+		return p, false, tx.Commit()
+	}
+
+	if _, err := tx.Exec("UPDATE payment SET status = ? WHERE id = ?", event.Status, p.ID); err != nil {
+		return nil, false, err
+	}
+	p.Status = event.Status
+
+	if p.Status == types.PaymentSucceeded && p.FkGiftVoucherID != nil {
+		_, err = tx.Exec("UPDATE giftvoucher SET status = ? WHERE id = ? AND status = ?",
+			types.VoucherActive, *p.FkGiftVoucherID, types.VoucherPending)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if p.Status == types.PaymentSucceeded && p.FkBookingID != nil {
+		_, err = tx.Exec("UPDATE booking SET status = ? WHERE id = ? AND status = ?",
+			types.BookingConfirmed, p.FkBookingID, types.BookingPending)
+		if err != nil {
+			return nil, false, err
+		}
+
+		_, err = tx.Exec("UPDATE waitlist SET status = ? WHERE fk_Bookingid = ? AND status = ?",
+			types.WaitlistAccepted, p.FkBookingID, types.WaitlistOffered)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+
+	return p, true, nil
+}
+
+// AddPaymentRefund records amount refunded from succeeded payment, which becomes refunded once nothing is left
+func (c *Castle) AddPaymentRefund(id int, amount types.Money) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var paid, refunded int64
+	var status string
+	err = tx.QueryRow("SELECT amount, refundedAmount, status FROM payment WHERE id = ? FOR UPDATE", id).Scan(&paid, &refunded, &status)
+	if err != nil {
+		return err
+	}
+
+	refunded += amount.Amount
+	if refunded >= paid && CanTransition(status, types.PaymentRefunded) {
+		status = types.PaymentRefunded
+	}
+
+	_, err = tx.Exec("UPDATE payment SET refundedAmount = ?, status = ? WHERE id = ?", refunded, status, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func scanSinglePayment(rows *sql.Rows) (*types.Payment, error) {
+	p := new(types.Payment)
+	var err error
+	for rows.Next() {
+		p, err = scanRowIntoPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if p.ID == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return p, nil
+}
+
+func scanRowIntoPayment(rows *sql.Rows) (*types.Payment, error) {
+	p := new(types.Payment)
+
+	err := rows.Scan(
+		&p.ID,
+		&p.FkBookingID,
+		&p.Provider,
+		&p.ProviderRef,
+		&p.Amount.Amount,
+		&p.Amount.Currency,
+		&p.Status,
+		&p.RefundedAmount.Amount,
+		&p.CheckoutURL,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+		&p.FkGiftVoucherID,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	p.RefundedAmount.Currency = p.Amount.Currency
+
+	return p, nil
+}
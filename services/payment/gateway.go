@@ -0,0 +1,167 @@
+package payment
+
+import (
+	"context"
+	"database/sql"
+	"educations-castle/types"
+	"educations-castle/utils/color"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// Gateway charges bookings and gift vouchers through payment provider and keeps payments in sync with its webhooks
+type Gateway struct {
+	paymentCastle types.PaymentCastle
+	bookingCastle types.BookingCastle
+	provider      types.PaymentProvider
+	successURL    string
+	cancelURL     string
+}
+
+func NewGateway(paymentCastle types.PaymentCastle, bookingCastle types.BookingCastle, provider types.PaymentProvider,
+	successURL, cancelURL string) *Gateway {
+	return &Gateway{
+		paymentCastle: paymentCastle,
+		bookingCastle: bookingCastle,
+		provider:      provider,
+		successURL:    successURL,
+		cancelURL:     cancelURL,
+	}
+}
+
+// Checkout starts payment of booking amount. Pending payment is reused, so repeated calls don't open new checkouts
+func (g *Gateway) Checkout(ctx context.Context, b *types.Booking, description string) (*types.Payment, error) {
+	payments, err := g.paymentCastle.ListPaymentsByBookingID(b.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.checkout(ctx, payments,
+		types.CheckoutRequest{BookingID: b.ID, Amount: b.Amount, Description: description},
+		types.Payment{FkBookingID: &b.ID, Amount: b.Amount},
+		fmt.Sprintf("booking-%d-checkout-%d", b.ID, len(payments)))
+}
+
+// CheckoutVoucher starts payment of gift voucher amount, pending payment is reused the same way as for bookings
+func (g *Gateway) CheckoutVoucher(ctx context.Context, v *types.GiftVoucher, description string) (*types.Payment, error) {
+	payments, err := g.paymentCastle.ListPaymentsByVoucherID(v.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.checkout(ctx, payments,
+		types.CheckoutRequest{VoucherID: v.ID, Amount: v.Amount, Description: description},
+		types.Payment{FkGiftVoucherID: &v.ID, Amount: v.Amount},
+		fmt.Sprintf("voucher-%d-checkout-%d", v.ID, len(payments)))
+}
+
+func (g *Gateway) checkout(ctx context.Context, payments []*types.Payment, request types.CheckoutRequest,
+	payment types.Payment, key string) (*types.Payment, error) {
+	for _, p := range payments {
+		switch p.Status {
+		case types.PaymentPending:
+			return p, nil
+		case types.PaymentSucceeded, types.PaymentRefunded:
+			return nil, ErrAlreadyPaid
+		}
+	}
+
+	// Key stays the same until payment is stored, so retry after failure doesn't open second checkout
+	request.SuccessURL = g.successURL
+	request.CancelURL = g.cancelURL
+	request.IdempotencyKey = key
+	checkout, err := g.provider.CreateCheckout(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	payment.Provider = g.provider.Name()
+	payment.ProviderRef = checkout.ProviderRef
+	payment.Status = types.PaymentPending
+	payment.CheckoutURL = checkout.URL
+	paymentID, err := g.paymentCastle.CreatePayment(payment)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.paymentCastle.GetPaymentByID(int(paymentID))
+}
+
+// HandleWebhook applies verified provider event to its payment. Events of unknown payments are ignored.
+// Bookings cancelled before their payment succeeded are refunded right away
+func (g *Gateway) HandleWebhook(ctx context.Context, header http.Header, payload []byte) error {
+	event, err := g.provider.ParseWebhook(header, payload)
+	if err != nil {
+		return err
+	}
+	if event.Status == "" {
+		return nil
+	}
+
+	p, changed, err := g.paymentCastle.ApplyPaymentEvent(g.provider.Name(), *event)
+	if err == sql.ErrNoRows {
+		log.Println(color.Format(color.YELLOW, fmt.Sprintf("payment webhook %s: unknown checkout %s", event.ID, event.ProviderRef)))
+		return nil
+	}
+	if err != nil || !changed || p.Status != types.PaymentSucceeded || p.FkBookingID == nil {
+		return err
+	}
+
+	b, err := g.bookingCastle.GetBookingByID(*p.FkBookingID)
+	if err != nil {
+		return err
+	}
+	if b.Status == types.BookingCancelled {
+		// Refund is recorded first, so it is retried if provider fails now
+		if err := g.bookingCastle.OweRefund(b.ID, p.Amount); err != nil {
+			return err
+		}
+		return g.RefundBooking(ctx, b.ID, p.Amount)
+	}
+
+	return nil
+}
+
+// RefundBooking refunds succeeded payment of booking until amount in total is refunded and marks refund of booking
+// completed. Amount refunded before is subtracted, so failed refunds can be repeated. Bookings which weren't paid are
+// skipped
+func (g *Gateway) RefundBooking(ctx context.Context, bookingID int, amount types.Money) error {
+	payments, err := g.paymentCastle.ListPaymentsByBookingID(bookingID)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range payments {
+		if p.Status != types.PaymentSucceeded {
+			continue
+		}
+
+		owed := amount.Sub(p.RefundedAmount)
+		if left := p.Amount.Sub(p.RefundedAmount); owed.Amount > left.Amount {
+			owed = left
+		}
+		if owed.Amount > 0 {
+			if err := g.refund(ctx, p, owed); err != nil {
+				return err
+			}
+		}
+		break
+	}
+
+	return g.bookingCastle.CompleteRefund(bookingID)
+}
+
+func (g *Gateway) refund(ctx context.Context, p *types.Payment, amount types.Money) error {
+	key := fmt.Sprintf("payment-%d-refund-%d", p.ID, p.RefundedAmount.Amount)
+	if err := g.provider.Refund(ctx, p.ProviderRef, amount, key); err != nil {
+		return err
+	}
+
+	return g.paymentCastle.AddPaymentRefund(p.ID, amount)
+}
+
+// Provider returns provider payments are taken through
+func (g *Gateway) Provider() types.PaymentProvider {
+	return g.provider
+}
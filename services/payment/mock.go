@@ -0,0 +1,123 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"educations-castle/types"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	ProviderMock = "mock"
+
+	mockSignatureHeader = "X-Mock-Signature"
+)
+
+// MockProvider takes payments without any external service. Checkouts are paid or failed through mock
+// payment routes, which send webhooks signed the same way as checkout would receive them
+type MockProvider struct {
+	baseURL string
+	secret  []byte
+
+	mu        sync.Mutex
+	checkouts map[string]*types.Checkout
+	refunds   map[string]int64
+}
+
+type mockEvent struct {
+	ID     string `json:"id"`
+	Ref    string `json:"ref"`
+	Status string `json:"status"`
+}
+
+func NewMockProvider(baseURL, secret string) *MockProvider {
+	return &MockProvider{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		secret:    []byte(secret),
+		checkouts: make(map[string]*types.Checkout),
+		refunds:   make(map[string]int64),
+	}
+}
+
+func (p *MockProvider) Name() string {
+	return ProviderMock
+}
+
+// CreateCheckout returns the same checkout for repeated idempotency key
+func (p *MockProvider) CreateCheckout(ctx context.Context, request types.CheckoutRequest) (*types.Checkout, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if checkout, ok := p.checkouts[request.IdempotencyKey]; ok && request.IdempotencyKey != "" {
+		return checkout, nil
+	}
+
+	ref := "mock_" + randomHex()
+	checkout := &types.Checkout{ProviderRef: ref, URL: p.baseURL + "/" + ref}
+	if request.IdempotencyKey != "" {
+		p.checkouts[request.IdempotencyKey] = checkout
+	}
+
+	return checkout, nil
+}
+
+func (p *MockProvider) ParseWebhook(header http.Header, payload []byte) (*types.PaymentEvent, error) {
+	if !hmac.Equal([]byte(header.Get(mockSignatureHeader)), []byte(p.sign(payload))) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event mockEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %v", err)
+	}
+
+	return &types.PaymentEvent{ID: event.ID, ProviderRef: event.Ref, Status: event.Status}, nil
+}
+
+func (p *MockProvider) Refund(ctx context.Context, ref string, amount types.Money, idempotencyKey string) error {
+	if amount.Amount <= 0 {
+		return fmt.Errorf("refund amount has to be positive")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refunds[ref] += amount.Amount
+
+	return nil
+}
+
+// Refunded returns amount in minor units refunded for checkout so far
+func (p *MockProvider) Refunded(ref string) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.refunds[ref]
+}
+
+// Webhook builds signed webhook which moves checkout into status
+func (p *MockProvider) Webhook(ref, status string) (http.Header, []byte) {
+	payload, _ := json.Marshal(mockEvent{ID: "evt_" + randomHex(), Ref: ref, Status: status})
+
+	header := http.Header{}
+	header.Set(mockSignatureHeader, p.sign(payload))
+
+	return header, payload
+}
+
+func (p *MockProvider) sign(payload []byte) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomHex() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
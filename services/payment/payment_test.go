@@ -0,0 +1,266 @@
+package payment
+
+import (
+	"context"
+	"educations-castle/configs"
+	"educations-castle/types"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNewPaymentProvider(t *testing.T) {
+	t.Run("Should refuse missing provider, webhook secret or mock without permission", func(t *testing.T) {
+		for name, cfg := range map[string]configs.Config{
+			"no provider":       {PaymentWebhookSecret: "secret"},
+			"no webhook secret": {PaymentProvider: ProviderStripe, StripeSecretKey: "sk_test"},
+			"mock not allowed":  {PaymentProvider: ProviderMock, PaymentWebhookSecret: "secret"},
+			"unknown provider":  {PaymentProvider: "paypal", PaymentWebhookSecret: "secret"},
+		} {
+			if _, err := NewPaymentProvider(cfg); err == nil {
+				t.Errorf("%s: expected error", name)
+			}
+		}
+	})
+
+	t.Run("Should create mock provider allowed for development", func(t *testing.T) {
+		provider, err := NewPaymentProvider(configs.Config{PaymentProvider: ProviderMock, PaymentWebhookSecret: "secret", PaymentMockAllowed: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := provider.(*MockProvider); !ok {
+			t.Errorf("expected mock provider, got %T", provider)
+		}
+	})
+}
+
+func TestStripeCheckout(t *testing.T) {
+	var form map[string]string
+	var idempotencyKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, _ := r.BasicAuth(); user != "sk_test" || r.URL.Path != "/v1/checkout/sessions" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"Invalid API Key provided"}}`))
+			return
+		}
+
+		r.ParseForm()
+		form = map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		idempotencyKey = r.Header.Get("Idempotency-Key")
+		w.Write([]byte(`{"id":"cs_test_1","url":"https://checkout.stripe.com/c/pay/cs_test_1"}`))
+	}))
+	defer server.Close()
+
+	request := types.CheckoutRequest{BookingID: 7, Amount: types.Money{Amount: 1999, Currency: "EUR"}, Description: "Amber history", IdempotencyKey: "booking-7-checkout-0"}
+
+	t.Run("Should create checkout session in minor units", func(t *testing.T) {
+		provider := NewStripeProvider(server.URL, "sk_test", "whsec", nil)
+
+		checkout, err := provider.CreateCheckout(context.Background(), request)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if checkout.ProviderRef != "cs_test_1" || checkout.URL != "https://checkout.stripe.com/c/pay/cs_test_1" {
+			t.Errorf("unexpected checkout %+v", checkout)
+		}
+		if form["line_items[0][price_data][unit_amount]"] != "1999" || form["line_items[0][price_data][currency]"] != "eur" {
+			t.Errorf("unexpected line item %v", form)
+		}
+		if form["client_reference_id"] != "7" || idempotencyKey != "booking-7-checkout-0" {
+			t.Errorf("unexpected reference %s or idempotency key %s", form["client_reference_id"], idempotencyKey)
+		}
+	})
+
+	t.Run("Should return API error message", func(t *testing.T) {
+		provider := NewStripeProvider(server.URL, "sk_wrong", "whsec", nil)
+
+		_, err := provider.CreateCheckout(context.Background(), request)
+		if err == nil || err.Error() != "stripe: Invalid API Key provided" {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
+
+func TestStripeWebhook(t *testing.T) {
+	now := time.Unix(1733000000, 0)
+	provider := NewStripeProvider("", "sk_test", "whsec", nil)
+	provider.now = func() time.Time { return now }
+
+	signed := func(payload string, at time.Time) http.Header {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		header := http.Header{}
+		header.Set(stripeSignatureHeader, fmt.Sprintf("t=%s,v1=%s", timestamp, stripeSignature([]byte("whsec"), timestamp, []byte(payload))))
+		return header
+	}
+	event := func(eventType, paymentStatus string) string {
+		return fmt.Sprintf(`{"id":"evt_1","type":"%s","data":{"object":{"id":"cs_test_1","payment_status":"%s"}}}`, eventType, paymentStatus)
+	}
+
+	tests := []struct {
+		payload  string
+		expected string
+	}{
+		{event("checkout.session.completed", "paid"), types.PaymentSucceeded},
+		{event("checkout.session.completed", "unpaid"), ""},
+		{event("checkout.session.async_payment_succeeded", "paid"), types.PaymentSucceeded},
+		{event("checkout.session.async_payment_failed", "unpaid"), types.PaymentFailed},
+		{event("checkout.session.expired", "unpaid"), types.PaymentFailed},
+		{event("customer.created", ""), ""},
+	}
+
+	for _, test := range tests {
+		result, err := provider.ParseWebhook(signed(test.payload, now), []byte(test.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.ID != "evt_1" || result.ProviderRef != "cs_test_1" || result.Status != test.expected {
+			t.Errorf("%s: unexpected event %+v", test.payload, result)
+		}
+	}
+
+	t.Run("Should reject tampered payload", func(t *testing.T) {
+		payload := event("checkout.session.completed", "paid")
+		if _, err := provider.ParseWebhook(signed(payload, now), []byte(payload+" ")); err != ErrInvalidSignature {
+			t.Errorf("expected %v, got %v", ErrInvalidSignature, err)
+		}
+	})
+
+	t.Run("Should reject replayed webhook", func(t *testing.T) {
+		payload := event("checkout.session.completed", "paid")
+		if _, err := provider.ParseWebhook(signed(payload, now.Add(-time.Hour)), []byte(payload)); err != ErrInvalidSignature {
+			t.Errorf("expected %v, got %v", ErrInvalidSignature, err)
+		}
+	})
+}
+
+func TestMockProvider(t *testing.T) {
+	provider := NewMockProvider("/api/v1/payments/mock", "secret")
+	request := types.CheckoutRequest{BookingID: 1, Amount: types.Money{Amount: 1000, Currency: "EUR"}, IdempotencyKey: "booking-1-checkout-0"}
+
+	first, err := provider.CreateCheckout(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := provider.CreateCheckout(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second || first.URL != "/api/v1/payments/mock/"+first.ProviderRef {
+		t.Errorf("expected the same checkout for idempotency key, got %+v and %+v", first, second)
+	}
+
+	header, payload := provider.Webhook(first.ProviderRef, types.PaymentSucceeded)
+	event, err := provider.ParseWebhook(header, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ProviderRef != first.ProviderRef || event.Status != types.PaymentSucceeded {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	if _, err := NewMockProvider("", "other").ParseWebhook(header, payload); err != ErrInvalidSignature {
+		t.Errorf("expected %v, got %v", ErrInvalidSignature, err)
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	if !CanTransition(types.PaymentPending, types.PaymentSucceeded) || !CanTransition(types.PaymentSucceeded, types.PaymentRefunded) {
+		t.Errorf("expected forward transitions to be allowed")
+	}
+	if CanTransition(types.PaymentSucceeded, types.PaymentFailed) || CanTransition(types.PaymentFailed, types.PaymentSucceeded) {
+		t.Errorf("expected late events not to move payment backwards")
+	}
+}
+
+func TestRefundBooking(t *testing.T) {
+	paid := func(refunded int64) *mockPaymentCastle {
+		return &mockPaymentCastle{payments: []*types.Payment{{
+			ID: 1, ProviderRef: "mock_1", Status: types.PaymentSucceeded,
+			Amount:         types.Money{Amount: 1000, Currency: "EUR"},
+			RefundedAmount: types.Money{Amount: refunded, Currency: "EUR"},
+		}}}
+	}
+	owed := types.Money{Amount: 600, Currency: "EUR"}
+
+	t.Run("Should refund amount and complete refund", func(t *testing.T) {
+		paymentCastle, bookingCastle, provider := paid(0), &mockBookingCastle{}, NewMockProvider("", "secret")
+		gateway := NewGateway(paymentCastle, bookingCastle, provider, "", "")
+
+		if err := gateway.RefundBooking(context.Background(), 7, owed); err != nil {
+			t.Fatal(err)
+		}
+		if provider.Refunded("mock_1") != 600 || paymentCastle.refunded != 600 {
+			t.Errorf("expected 600 to be refunded, got %d", provider.Refunded("mock_1"))
+		}
+		if fmt.Sprint(bookingCastle.completed) != "[7]" {
+			t.Errorf("expected refund of booking 7 to be completed, got %v", bookingCastle.completed)
+		}
+	})
+
+	t.Run("Should refund only what is still owed when repeated", func(t *testing.T) {
+		paymentCastle, bookingCastle, provider := paid(600), &mockBookingCastle{}, NewMockProvider("", "secret")
+		gateway := NewGateway(paymentCastle, bookingCastle, provider, "", "")
+
+		if err := gateway.RefundBooking(context.Background(), 7, owed); err != nil {
+			t.Fatal(err)
+		}
+		if provider.Refunded("mock_1") != 0 {
+			t.Errorf("expected nothing to be refunded again, got %d", provider.Refunded("mock_1"))
+		}
+		if fmt.Sprint(bookingCastle.completed) != "[7]" {
+			t.Errorf("expected refund of booking 7 to be completed, got %v", bookingCastle.completed)
+		}
+	})
+
+	t.Run("Should keep refund pending when provider fails", func(t *testing.T) {
+		bookingCastle := &mockBookingCastle{}
+		gateway := NewGateway(paid(0), bookingCastle, failingRefunds{NewMockProvider("", "secret")}, "", "")
+
+		if err := gateway.RefundBooking(context.Background(), 7, owed); err == nil {
+			t.Errorf("expected error")
+		}
+		if len(bookingCastle.completed) != 0 {
+			t.Errorf("expected refund to stay pending, got %v", bookingCastle.completed)
+		}
+	})
+}
+
+type failingRefunds struct {
+	*MockProvider
+}
+
+func (failingRefunds) Refund(ctx context.Context, ref string, amount types.Money, idempotencyKey string) error {
+	return fmt.Errorf("provider unavailable")
+}
+
+type mockPaymentCastle struct {
+	types.PaymentCastle
+	payments []*types.Payment
+	refunded int64
+}
+
+func (m *mockPaymentCastle) ListPaymentsByBookingID(bookingID int) ([]*types.Payment, error) {
+	return m.payments, nil
+}
+
+func (m *mockPaymentCastle) AddPaymentRefund(id int, amount types.Money) error {
+	m.refunded += amount.Amount
+	return nil
+}
+
+type mockBookingCastle struct {
+	types.BookingCastle
+	completed []int
+}
+
+func (m *mockBookingCastle) CompleteRefund(bookingID int) error {
+	m.completed = append(m.completed, bookingID)
+	return nil
+}
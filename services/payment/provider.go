@@ -0,0 +1,43 @@
+package payment
+
+import (
+	"educations-castle/configs"
+	"educations-castle/types"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrAlreadyPaid      = errors.New("booking is already paid")
+)
+
+// NewPaymentProvider creates payment provider selected by PAYMENT_PROVIDER. Webhooks confirm bookings, so there are
+// no defaults for provider and webhook secret. Mock provider lets anyone pay their checkout for free, it is meant
+// only for development and has to be allowed explicitly
+func NewPaymentProvider(cfg configs.Config) (types.PaymentProvider, error) {
+	if cfg.PaymentProvider == "" {
+		return nil, fmt.Errorf("PAYMENT_PROVIDER is required")
+	}
+	if cfg.PaymentWebhookSecret == "" {
+		return nil, fmt.Errorf("PAYMENT_WEBHOOK_SECRET is required")
+	}
+
+	switch cfg.PaymentProvider {
+	case ProviderMock:
+		if !cfg.PaymentMockAllowed {
+			return nil, fmt.Errorf("PAYMENT_MOCK_ALLOWED has to be set for mock payment provider, it is meant only for development")
+		}
+		return NewMockProvider(cfg.PaymentMockURL, cfg.PaymentWebhookSecret), nil
+	case ProviderStripe:
+		if cfg.StripeSecretKey == "" {
+			return nil, fmt.Errorf("STRIPE_SECRET_KEY is required for stripe payment provider")
+		}
+		client := &http.Client{Timeout: time.Second * time.Duration(cfg.PaymentTimeoutInSeconds)}
+		return NewStripeProvider(cfg.StripeURL, cfg.StripeSecretKey, cfg.PaymentWebhookSecret, client), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider '%s'", cfg.PaymentProvider)
+	}
+}
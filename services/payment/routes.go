@@ -0,0 +1,254 @@
+package payment
+
+import (
+	"database/sql"
+	"educations-castle/services/auth"
+	"educations-castle/types"
+	"educations-castle/utils"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Webhook payloads of checkout events are a few kilobytes
+const maxWebhookSize = 1 << 20
+
+type Handler struct {
+	paymentCastle  types.PaymentCastle
+	bookingCastle  types.BookingCastle
+	sessionCastle  types.SessionCastle
+	activityCastle types.ActivityCastle
+	userCastle     types.UserCastle
+	gateway        *Gateway
+}
+
+func NewHandler(paymentCastle types.PaymentCastle, bookingCastle types.BookingCastle, sessionCastle types.SessionCastle,
+	activityCastle types.ActivityCastle, userCastle types.UserCastle, gateway *Gateway) *Handler {
+	return &Handler{
+		paymentCastle:  paymentCastle,
+		bookingCastle:  bookingCastle,
+		sessionCastle:  sessionCastle,
+		activityCastle: activityCastle,
+		userCastle:     userCastle,
+		gateway:        gateway}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/bookings/{bookingID:[0-9]+}/checkout", auth.WithJWTAuth(h.handleCheckout, h.userCastle, "administrator", "organizer", "user")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/bookings/{bookingID:[0-9]+}/payments", auth.WithJWTAuth(h.handleListBookingPayments, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/payments/webhook", h.handleWebhook).Methods("POST")
+
+	// Mock checkouts are paid locally, there is no provider page to redirect to. Mock provider exists only when
+	// PAYMENT_MOCK_ALLOWED is set for development, as anyone can confirm their booking here without paying
+	if _, ok := h.gateway.Provider().(*MockProvider); ok {
+		router.HandleFunc("/payments/mock/{ref}/{action:succeed|fail}", h.handleMockPayment).Methods("POST")
+	}
+}
+
+// Checkout godoc
+// @Summary      Pay for booking
+// @Description  Starts payment of pending booking and returns payment with URL of provider checkout. Booking is confirmed
+// @Description  once provider reports successful payment. Repeated calls return the same pending payment
+// @Tags         payment
+// @Produce      json
+// @Param        bookingID path int true "Booking ID"
+// @Success      201  {object}   types.Payment
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid booking ID, booking is free or session already started"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "booking not found"
+// @Failure      409  {object}   types.ErrorResponse "booking is not pending or already paid"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /bookings/{bookingID}/checkout [post]
+func (h *Handler) handleCheckout(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := strconv.Atoi(mux.Vars(r)["bookingID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid booking ID"))
+		return
+	}
+
+	booking, err := h.bookingCastle.GetBookingByID(bookingID)
+	if err != nil {
+		writeNotFoundError(w, err, "booking not found")
+		return
+	}
+
+	if !auth.CheckOwnership(r, booking.FkUserID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	if booking.Status != types.BookingPending {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("booking is %s and can't be paid", booking.Status))
+		return
+	}
+	if booking.Amount.Amount <= 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("booking is free"))
+		return
+	}
+
+	session, err := h.sessionCastle.GetSessionByID(booking.FkActivitySessionID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if session.Cancelled || !session.StartTime.After(time.Now()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("session is cancelled or has already started"))
+		return
+	}
+
+	activity, err := h.activityCastle.GetActivityByID(session.FkActivityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	description := fmt.Sprintf("%s, %s, %d seat(s)", activity.Name, session.StartTime.UTC().Format("2006-01-02 15:04 UTC"), booking.Seats)
+	payment, err := h.gateway.Checkout(r.Context(), booking, description)
+	if err != nil {
+		if errors.Is(err, ErrAlreadyPaid) {
+			utils.WriteError(w, http.StatusConflict, err)
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, payment)
+}
+
+// ListBookingPayments godoc
+// @Summary      List payments of booking
+// @Description  Returns payments of booking, latest first. Payments can be seen by user who made booking and organizer of the activity
+// @Tags         payment
+// @Produce      json
+// @Param        bookingID path int true "Booking ID"
+// @Success      200  {array}    types.Payment
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid booking ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "booking not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /bookings/{bookingID}/payments [get]
+func (h *Handler) handleListBookingPayments(w http.ResponseWriter, r *http.Request) {
+	bookingID, err := strconv.Atoi(mux.Vars(r)["bookingID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid booking ID"))
+		return
+	}
+
+	booking, err := h.bookingCastle.GetBookingByID(bookingID)
+	if err != nil {
+		writeNotFoundError(w, err, "booking not found")
+		return
+	}
+
+	if !auth.CheckOwnership(r, booking.FkUserID) && !h.organizesBooking(r, booking) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return
+	}
+
+	payments, err := h.paymentCastle.ListPaymentsByBookingID(bookingID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no payments found, return an empty array
+	if len(payments) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.Payment{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, payments)
+}
+
+// Webhook godoc
+// @Summary      Receive payment provider webhook
+// @Description  Applies signed event of payment provider. Redelivered events are acknowledged without changes
+// @Tags         payment
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}   map[string]string
+// @Failure      400  {object}   types.ErrorResponse "invalid signature or payload"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /payments/webhook [post]
+func (h *Handler) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookSize))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	h.applyWebhook(w, r, r.Header, payload)
+}
+
+// MockPayment godoc
+// @Summary      Pay or fail mock checkout
+// @Description  Available only with mock payment provider, which has to be allowed for development.
+// @Description  Sends the same webhook provider would send after checkout
+// @Tags         payment
+// @Produce      json
+// @Param        ref    path string true "Checkout reference"
+// @Param        action path string true "succeed or fail"
+// @Success      200  {object}   map[string]string
+// @Failure      404  {object}   types.ErrorResponse "checkout not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /payments/mock/{ref}/{action} [post]
+func (h *Handler) handleMockPayment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	provider := h.gateway.Provider().(*MockProvider)
+
+	if _, err := h.paymentCastle.GetPaymentByProviderRef(ProviderMock, vars["ref"]); err != nil {
+		writeNotFoundError(w, err, "checkout not found")
+		return
+	}
+
+	status := types.PaymentSucceeded
+	if vars["action"] == "fail" {
+		status = types.PaymentFailed
+	}
+
+	header, payload := provider.Webhook(vars["ref"], status)
+	h.applyWebhook(w, r, header, payload)
+}
+
+func (h *Handler) applyWebhook(w http.ResponseWriter, r *http.Request, header http.Header, payload []byte) {
+	if err := h.gateway.HandleWebhook(r.Context(), header, payload); err != nil {
+		if errors.Is(err, ErrInvalidSignature) {
+			utils.WriteError(w, http.StatusBadRequest, err)
+		} else {
+			// Provider retries webhooks which weren't acknowledged
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "webhook received"})
+}
+
+// organizesBooking reports whether user is administrator or organizer of activity booking was made for
+func (h *Handler) organizesBooking(r *http.Request, booking *types.Booking) bool {
+	session, err := h.sessionCastle.GetSessionByID(booking.FkActivitySessionID)
+	if err != nil {
+		return false
+	}
+
+	organizer, err := h.userCastle.GetOrganizerByActivityID(session.FkActivityID)
+	if err != nil {
+		return false
+	}
+
+	return auth.CheckOwnership(r, organizer.ID)
+}
+
+func writeNotFoundError(w http.ResponseWriter, err error, message string) {
+	if err == sql.ErrNoRows {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("%s", message))
+	} else {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,19 @@
+package payment
+
+import "educations-castle/types"
+
+// Statuses payment can move into from its current status. Failed payments are final, booking is paid
+// again through new checkout
+var transitions = map[string][]string{
+	types.PaymentPending:   {types.PaymentSucceeded, types.PaymentFailed},
+	types.PaymentSucceeded: {types.PaymentRefunded},
+}
+
+func CanTransition(from, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
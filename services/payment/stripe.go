@@ -0,0 +1,222 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"educations-castle/types"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderStripe = "stripe"
+
+	stripeSignatureHeader = "Stripe-Signature"
+	// Webhooks signed longer ago are rejected to prevent replays
+	stripeSignatureTolerance = 5 * time.Minute
+)
+
+// StripeProvider takes payments through Stripe Checkout (https://docs.stripe.com/api/checkout/sessions),
+// any API compatible service can be used through baseURL
+type StripeProvider struct {
+	baseURL       string
+	secretKey     string
+	webhookSecret []byte
+	client        *http.Client
+	now           func() time.Time
+}
+
+type stripeCheckoutSession struct {
+	ID            string `json:"id"`
+	URL           string `json:"url"`
+	PaymentStatus string `json:"payment_status"`
+	PaymentIntent string `json:"payment_intent"`
+}
+
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object stripeCheckoutSession `json:"object"`
+	} `json:"data"`
+}
+
+type stripeError struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func NewStripeProvider(baseURL, secretKey, webhookSecret string, client *http.Client) *StripeProvider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &StripeProvider{
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		secretKey:     secretKey,
+		webhookSecret: []byte(webhookSecret),
+		client:        client,
+		now:           time.Now,
+	}
+}
+
+func (p *StripeProvider) Name() string {
+	return ProviderStripe
+}
+
+func (p *StripeProvider) CreateCheckout(ctx context.Context, request types.CheckoutRequest) (*types.Checkout, error) {
+	form := url.Values{}
+	form.Set("mode", "payment")
+	if request.VoucherID != 0 {
+		form.Set("client_reference_id", "voucher-"+strconv.Itoa(request.VoucherID))
+		form.Set("metadata[voucher_id]", strconv.Itoa(request.VoucherID))
+	} else {
+		form.Set("client_reference_id", strconv.Itoa(request.BookingID))
+		form.Set("metadata[booking_id]", strconv.Itoa(request.BookingID))
+	}
+	form.Set("success_url", request.SuccessURL)
+	form.Set("cancel_url", request.CancelURL)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", strings.ToLower(request.Amount.Currency))
+	form.Set("line_items[0][price_data][unit_amount]", strconv.FormatInt(request.Amount.Amount, 10))
+	form.Set("line_items[0][price_data][product_data][name]", request.Description)
+
+	var session stripeCheckoutSession
+	if err := p.do(ctx, http.MethodPost, "/v1/checkout/sessions", form, request.IdempotencyKey, &session); err != nil {
+		return nil, err
+	}
+
+	return &types.Checkout{ProviderRef: session.ID, URL: session.URL}, nil
+}
+
+// ParseWebhook verifies Stripe-Signature header (https://docs.stripe.com/webhooks#verify-manually) and
+// maps checkout session events into payment statuses
+func (p *StripeProvider) ParseWebhook(header http.Header, payload []byte) (*types.PaymentEvent, error) {
+	if err := p.verifySignature(header.Get(stripeSignatureHeader), payload); err != nil {
+		return nil, err
+	}
+
+	var event stripeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %v", err)
+	}
+
+	result := &types.PaymentEvent{ID: event.ID, ProviderRef: event.Data.Object.ID}
+	switch event.Type {
+	case "checkout.session.completed":
+		// Delayed payment methods complete session unpaid and send async event later
+		if event.Data.Object.PaymentStatus == "paid" {
+			result.Status = types.PaymentSucceeded
+		}
+	case "checkout.session.async_payment_succeeded":
+		result.Status = types.PaymentSucceeded
+	case "checkout.session.async_payment_failed", "checkout.session.expired":
+		result.Status = types.PaymentFailed
+	}
+
+	return result, nil
+}
+
+func (p *StripeProvider) verifySignature(header string, payload []byte) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := p.now().Sub(time.Unix(seconds, 0)); age > stripeSignatureTolerance || age < -stripeSignatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	expected := stripeSignature(p.webhookSecret, timestamp, payload)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+func stripeSignature(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Refund refunds payment intent of checkout session, Stripe refunds can't be made against session itself
+func (p *StripeProvider) Refund(ctx context.Context, ref string, amount types.Money, idempotencyKey string) error {
+	var session stripeCheckoutSession
+	if err := p.do(ctx, http.MethodGet, "/v1/checkout/sessions/"+url.PathEscape(ref), nil, "", &session); err != nil {
+		return err
+	}
+	if session.PaymentIntent == "" {
+		return fmt.Errorf("checkout session %s has no payment to refund", ref)
+	}
+
+	form := url.Values{}
+	form.Set("payment_intent", session.PaymentIntent)
+	form.Set("amount", strconv.FormatInt(amount.Amount, 10))
+
+	return p.do(ctx, http.MethodPost, "/v1/refunds", form, idempotencyKey, nil)
+}
+
+func (p *StripeProvider) do(ctx context.Context, method, path string, form url.Values, idempotencyKey string, target any) error {
+	var body *strings.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	} else {
+		body = strings.NewReader("")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(p.secretKey, "")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiError stripeError
+		if err := json.NewDecoder(resp.Body).Decode(&apiError); err == nil && apiError.Error.Message != "" {
+			return fmt.Errorf("stripe: %s", apiError.Error.Message)
+		}
+		return fmt.Errorf("stripe: unexpected status %d", resp.StatusCode)
+	}
+
+	if target == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
@@ -0,0 +1,287 @@
+package schedule
+
+import (
+	"database/sql"
+	"educations-castle/types"
+	"strings"
+	"time"
+)
+
+// Wall times are stored in DATETIME columns without zone, they are read back as UTC
+const wallTimeFormat = "2006-01-02T15:04:05"
+
+type Castle struct {
+	db *sql.DB
+}
+
+func NewCastle(db *sql.DB) *Castle {
+	return &Castle{db: db}
+}
+
+func scanRowIntoSchedule(rows *sql.Rows) (*types.Schedule, error) {
+	s := new(types.Schedule)
+	var startTime time.Time
+	var exDates string
+	var price *int64
+	var currency *string
+
+	err := rows.Scan(
+		&s.ID,
+		&s.FkActivityID,
+		&s.RRule,
+		&startTime,
+		&s.Timezone,
+		&s.DurationMinutes,
+		&s.FkLocationID,
+		&s.Capacity,
+		&s.Language,
+		&price,
+		&currency,
+		&exDates,
+		&s.ExpandedUntil,
+		&s.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.StartTime = startTime.Format(wallTimeFormat)
+	s.Price = types.NullableMoney(price, currency)
+	s.ExDates = []string{}
+	if exDates != "" {
+		s.ExDates = strings.Split(exDates, ",")
+	}
+
+	return s, nil
+}
+
+// wallTime converts wall time string into value stored as is, without any zone conversion
+func wallTime(value string) (time.Time, error) {
+	return time.Parse(wallTimeFormat, value)
+}
+
+func (c *Castle) CreateSchedule(s types.Schedule) (int64, error) {
+	startTime, err := wallTime(s.StartTime)
+	if err != nil {
+		return 0, err
+	}
+
+	price, currency := types.MoneyColumns(s.Price)
+	result, err := c.db.Exec(
+		`INSERT INTO schedule (fk_Activityid, rrule, startTime, timezone, durationMinutes, fk_Locationid, capacity, language, price,
+			currency, exDates)
+		VALUES (?,?,?,?,?,?,?,?,?,?,?)`,
+		s.FkActivityID, s.RRule, startTime, s.Timezone, s.DurationMinutes, s.FkLocationID, s.Capacity, s.Language, price, currency,
+		strings.Join(s.ExDates, ","))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (c *Castle) GetScheduleByID(id int) (*types.Schedule, error) {
+	rows, err := c.db.Query("SELECT * FROM schedule WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s := new(types.Schedule)
+	for rows.Next() {
+		s, err = scanRowIntoSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.ID == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return s, nil
+}
+
+func (c *Castle) ListSchedules() ([]*types.Schedule, error) {
+	return c.listSchedules("SELECT * FROM schedule ORDER BY id")
+}
+
+func (c *Castle) ListSchedulesByActivityID(activityID int) ([]*types.Schedule, error) {
+	return c.listSchedules("SELECT * FROM schedule WHERE fk_Activityid = ? ORDER BY startTime, id", activityID)
+}
+
+func (c *Castle) listSchedules(query string, params ...interface{}) ([]*types.Schedule, error) {
+	rows, err := c.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*types.Schedule
+
+	for rows.Next() {
+		s, err := scanRowIntoSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (c *Castle) UpdateSchedule(s types.Schedule) error {
+	startTime, err := wallTime(s.StartTime)
+	if err != nil {
+		return err
+	}
+
+	price, currency := types.MoneyColumns(s.Price)
+	_, err = c.db.Exec(
+		`UPDATE schedule
+		SET rrule = ?, startTime = ?, timezone = ?, durationMinutes = ?, fk_Locationid = ?, capacity = ?, language = ?, price = ?,
+			currency = ?, exDates = ?, expandedUntil = ?
+		WHERE id = ?`,
+		s.RRule, startTime, s.Timezone, s.DurationMinutes, s.FkLocationID, s.Capacity, s.Language, price, currency,
+		strings.Join(s.ExDates, ","), s.ExpandedUntil, s.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Castle) DeleteSchedule(id int) error {
+	_, err := c.db.Exec("DELETE FROM schedule WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetExpandedUntil records up to when sessions of schedule were generated
+func (c *Castle) SetExpandedUntil(id int, until *time.Time) error {
+	var value interface{}
+	if until != nil {
+		value = until.UTC()
+	}
+
+	_, err := c.db.Exec("UPDATE schedule SET expandedUntil = ? WHERE id = ?", value, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateScheduleSessions inserts generated sessions, occurrences which already exist are skipped
+func (c *Castle) CreateScheduleSessions(sessions []types.ActivitySession) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`INSERT IGNORE INTO activitysession (fk_Activityid, startTime, endTime, fk_Locationid, capacity, language, price, currency,
+			fk_Scheduleid)
+		VALUES (?,?,?,?,?,?,?,?,?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, s := range sessions {
+		price, currency := types.MoneyColumns(s.Price)
+		_, err := stmt.Exec(s.FkActivityID, s.StartTime.UTC(), s.EndTime.UTC(), s.FkLocationID, s.Capacity, s.Language, price, currency,
+			s.FkScheduleID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Sessions which already have bookings are never removed together with schedule
+const unbookedSession = `NOT EXISTS (SELECT 1 FROM booking
+	WHERE booking.fk_ActivitySessionid = activitysession.id AND booking.status <> 'cancelled')`
+
+// DeleteScheduleSessionsFrom removes generated sessions of schedule starting at from or later,
+// booked sessions are kept and later expansion doesn't duplicate them
+func (c *Castle) DeleteScheduleSessionsFrom(scheduleID int, from time.Time) error {
+	_, err := c.db.Exec(
+		"DELETE FROM activitysession WHERE fk_Scheduleid = ? AND startTime >= ? AND "+unbookedSession,
+		scheduleID, from.UTC())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SplitSchedule ends previous schedule before from and creates next schedule taking over the following occurrences.
+// Previous schedule is removed when none of its occurrences remain
+func (c *Castle) SplitSchedule(previous types.Schedule, removePrevious bool, next types.Schedule, from time.Time) (int64, error) {
+	startTime, err := wallTime(next.StartTime)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"DELETE FROM activitysession WHERE fk_Scheduleid = ? AND startTime >= ? AND "+unbookedSession,
+		previous.ID, from.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	price, currency := types.MoneyColumns(next.Price)
+	result, err := tx.Exec(
+		`INSERT INTO schedule (fk_Activityid, rrule, startTime, timezone, durationMinutes, fk_Locationid, capacity, language, price,
+			currency, exDates)
+		VALUES (?,?,?,?,?,?,?,?,?,?,?)`,
+		next.FkActivityID, next.RRule, startTime, next.Timezone, next.DurationMinutes, next.FkLocationID, next.Capacity, next.Language,
+		price, currency, strings.Join(next.ExDates, ","))
+	if err != nil {
+		return 0, err
+	}
+
+	nextID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	// Booked sessions which were kept belong to the following occurrences now
+	_, err = tx.Exec("UPDATE activitysession SET fk_Scheduleid = ? WHERE fk_Scheduleid = ? AND startTime >= ?",
+		nextID, previous.ID, from.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	if removePrevious {
+		_, err = tx.Exec("DELETE FROM schedule WHERE id = ?", previous.ID)
+	} else {
+		_, err = tx.Exec("UPDATE schedule SET rrule = ? WHERE id = ?", previous.RRule, previous.ID)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return nextID, nil
+}
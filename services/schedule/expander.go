@@ -0,0 +1,219 @@
+package schedule
+
+import (
+	"educations-castle/types"
+	"educations-castle/utils/color"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const exDateFormat = "2006-01-02"
+
+// Expander keeps sessions of schedules generated for a rolling horizon ahead of current time
+type Expander struct {
+	scheduleCastle types.ScheduleCastle
+	horizon        time.Duration
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewExpander(scheduleCastle types.ScheduleCastle, horizon time.Duration) *Expander {
+	return &Expander{scheduleCastle: scheduleCastle, horizon: horizon}
+}
+
+// Start expands all schedules right away and then every interval until Stop is called
+func (e *Expander) Start(interval time.Duration) {
+	e.stop = make(chan struct{})
+	e.wg.Add(1)
+
+	go func() {
+		defer e.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := e.ExpandAll(time.Now()); err != nil {
+				log.Println(color.Format(color.RED, fmt.Sprintf("schedule expansion failed: %v", err)))
+			}
+
+			select {
+			case <-e.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (e *Expander) Stop() {
+	if e.stop == nil {
+		return
+	}
+	close(e.stop)
+	e.wg.Wait()
+	e.stop = nil
+}
+
+// ExpandAll expands every schedule, failure of one schedule doesn't stop the others
+func (e *Expander) ExpandAll(now time.Time) error {
+	schedules, err := e.scheduleCastle.ListSchedules()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range schedules {
+		if err := e.Expand(s, now); err != nil {
+			log.Println(color.Format(color.RED, fmt.Sprintf("schedule %d: %v", s.ID, err)))
+		}
+	}
+
+	return nil
+}
+
+// Expand creates sessions of schedule starting after previous expansion and no later than now + horizon.
+// Sessions are never regenerated for already expanded range, so sessions moved or removed by organizer stay that way
+func (e *Expander) Expand(s *types.Schedule, now time.Time) error {
+	from := now.UTC().Truncate(time.Second)
+	if s.ExpandedUntil != nil && !s.ExpandedUntil.Before(from) {
+		from = s.ExpandedUntil.Add(time.Second)
+	}
+	to := now.Add(e.horizon).UTC().Truncate(time.Second)
+	if from.After(to) {
+		return nil
+	}
+
+	starts, err := Occurrences(s, from, to)
+	if err != nil {
+		return err
+	}
+
+	if len(starts) > 0 {
+		scheduleID := s.ID
+		sessions := make([]types.ActivitySession, len(starts))
+		for i, start := range starts {
+			sessions[i] = types.ActivitySession{
+				FkActivityID: s.FkActivityID,
+				StartTime:    start,
+				EndTime:      start.Add(time.Duration(s.DurationMinutes) * time.Minute),
+				FkLocationID: s.FkLocationID,
+				Capacity:     s.Capacity,
+				Language:     s.Language,
+				Price:        s.Price,
+				FkScheduleID: &scheduleID,
+			}
+		}
+
+		if err := e.scheduleCastle.CreateScheduleSessions(sessions); err != nil {
+			return err
+		}
+	}
+
+	if err := e.scheduleCastle.SetExpandedUntil(s.ID, &to); err != nil {
+		return err
+	}
+	s.ExpandedUntil = &to
+
+	return nil
+}
+
+// Occurrences returns start times of schedule sessions between from and to (both inclusive) without exception dates
+func Occurrences(s *types.Schedule, from, to time.Time) ([]time.Time, error) {
+	rule, dtstart, err := parseSchedule(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var starts []time.Time
+	for _, start := range rule.Expand(dtstart, from, to) {
+		if !isExDate(start, s.ExDates) {
+			starts = append(starts, start)
+		}
+	}
+
+	return starts, nil
+}
+
+func parseSchedule(s *types.Schedule) (*RRule, time.Time, error) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid timezone '%s'", s.Timezone)
+	}
+
+	rule, err := ParseRRule(s.RRule, loc)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	dtstart, err := parseWallTime(s.StartTime, loc)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return rule, dtstart, nil
+}
+
+func parseWallTime(value string, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse(wallTimeFormat, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid wall time '%s'", value)
+	}
+
+	return localTime(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), loc), nil
+}
+
+// isExDate reports whether occurrence is excluded by its exact wall time or by whole date
+func isExDate(start time.Time, exDates []string) bool {
+	wall := start.Format(wallTimeFormat)
+	date := start.Format(exDateFormat)
+
+	for _, exDate := range exDates {
+		if exDate == wall || exDate == date {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateExDates checks that every exception date is either a date or a wall time
+func ValidateExDates(exDates []string) error {
+	for _, exDate := range exDates {
+		if _, err := time.Parse(exDateFormat, exDate); err == nil {
+			continue
+		}
+		if _, err := time.Parse(wallTimeFormat, exDate); err == nil {
+			continue
+		}
+		return fmt.Errorf("invalid exception date '%s'", exDate)
+	}
+
+	return nil
+}
+
+// Truncate ends rule of schedule right before occurrence at split, so the following occurrences can be
+// given to a new schedule. False is returned when no occurrence would remain and schedule should be removed
+func Truncate(s *types.Schedule, split time.Time) (bool, error) {
+	rule, dtstart, err := parseSchedule(s)
+	if err != nil {
+		return false, err
+	}
+
+	before := rule.Expand(dtstart, dtstart, split.Add(-time.Second))
+	if len(before) == 0 {
+		return false, nil
+	}
+
+	if rule.Count > 0 {
+		rule.Count = len(before)
+	} else {
+		until := split.Add(-time.Second).UTC()
+		rule.Until = &until
+	}
+	s.RRule = rule.String()
+
+	return true, nil
+}
@@ -0,0 +1,489 @@
+package schedule
+
+import (
+	"database/sql"
+	"educations-castle/configs"
+	"educations-castle/services/auth"
+	"educations-castle/types"
+	"educations-castle/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	scheduleCastle types.ScheduleCastle
+	activityCastle types.ActivityCastle
+	locationCastle types.LocationCastle
+	userCastle     types.UserCastle
+	expander       *Expander
+}
+
+func NewHandler(scheduleCastle types.ScheduleCastle, activityCastle types.ActivityCastle,
+	locationCastle types.LocationCastle, userCastle types.UserCastle, expander *Expander) *Handler {
+	return &Handler{
+		scheduleCastle: scheduleCastle,
+		activityCastle: activityCastle,
+		locationCastle: locationCastle,
+		userCastle:     userCastle,
+		expander:       expander}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/activities/{activityID:[0-9]+}/schedules", auth.WithOptionalJWTAuth(h.handleListSchedules, h.userCastle)).Methods("GET")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/schedules/{scheduleID:[0-9]+}", auth.WithOptionalJWTAuth(h.handleGetSchedule, h.userCastle)).Methods("GET")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/schedules/create", auth.WithJWTAuth(h.handleCreateSchedule, h.userCastle, "administrator", "organizer")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/schedules/update/{scheduleID:[0-9]+}", auth.WithJWTAuth(h.handleUpdateSchedule, h.userCastle, "administrator", "organizer")).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/schedules/split/{scheduleID:[0-9]+}", auth.WithJWTAuth(h.handleSplitSchedule, h.userCastle, "administrator", "organizer")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/schedules/delete/{scheduleID:[0-9]+}", auth.WithJWTAuth(h.handleDeleteSchedule, h.userCastle, "administrator", "organizer")).Methods("DELETE", "OPTIONS")
+}
+
+// ListSchedules godoc
+// @Summary      List recurring schedules of activity
+// @Description  Returns recurrence rules from which sessions of activity are generated
+// @Tags         schedule
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Success      200  {array}    types.Schedule
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid activity ID"
+// @Failure      404  {object}   types.ErrorResponse "activity not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/schedules [get]
+func (h *Handler) handleListSchedules(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	// Schedules of hidden activities are hidden as well
+	if _, err := h.activityCastle.GetVisibleActivityByID(activityID, auth.GetViewerFromContext(r.Context())); err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	schedules, err := h.scheduleCastle.ListSchedulesByActivityID(activityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no schedules found, return an empty array
+	if len(schedules) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.Schedule{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, schedules)
+}
+
+// GetSchedule godoc
+// @Summary      Get recurring schedule by ID
+// @Description  Get schedule data by ID from the database
+// @Tags         schedule
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        scheduleID path int true "Schedule ID"
+// @Success      200  {object}   types.Schedule
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid ID"
+// @Failure      404  {object}   types.ErrorResponse "schedule not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/schedules/{scheduleID} [get]
+func (h *Handler) handleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	activityID, scheduleID, err := parseIDs(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err := h.activityCastle.GetVisibleActivityByID(activityID, auth.GetViewerFromContext(r.Context())); err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("schedule not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	schedule, err := h.getActivitySchedule(activityID, scheduleID)
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, schedule)
+}
+
+// CreateSchedule godoc
+// @Summary      Create recurring schedule
+// @Description  Creates schedule from RRULE (FREQ, INTERVAL, BYDAY, UNTIL and COUNT) and generates its sessions for the upcoming horizon.
+// @Description  Start time and exception dates are wall times in schedule timezone, Europe/Vilnius by default
+// @Tags         schedule
+// @Accept       json
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        payload body types.SchedulePayload true "Schedule data"
+// @Success      201  {object}   types.Schedule
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "activity not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/schedules/create [post]
+func (h *Handler) handleCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	var payload types.SchedulePayload
+	if !h.parsePayload(w, r, activityID, &payload) {
+		return
+	}
+
+	schedule, err := scheduleFromPayload(payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	schedule.FkActivityID = activityID
+
+	if !h.checkActivityOwnership(w, r, activityID) {
+		return
+	}
+
+	scheduleID, err := h.scheduleCastle.CreateSchedule(schedule)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.expandAndWrite(w, int(scheduleID), http.StatusCreated)
+}
+
+// UpdateSchedule godoc
+// @Summary      Update recurring schedule
+// @Description  Replaces rule of the whole series. Upcoming sessions of schedule are removed and generated again, past sessions are kept
+// @Tags         schedule
+// @Accept       json
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        scheduleID path int true "Schedule ID"
+// @Param        payload body types.SchedulePayload true "Schedule data"
+// @Success      200  {object}   types.Schedule
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "schedule not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/schedules/update/{scheduleID} [put]
+func (h *Handler) handleUpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	activityID, scheduleID, err := parseIDs(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var payload types.SchedulePayload
+	if !h.parsePayload(w, r, activityID, &payload) {
+		return
+	}
+
+	schedule, err := scheduleFromPayload(payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !h.checkActivityOwnership(w, r, activityID) {
+		return
+	}
+
+	if _, err := h.getActivitySchedule(activityID, scheduleID); err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+
+	if err := h.scheduleCastle.DeleteScheduleSessionsFrom(scheduleID, time.Now()); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	schedule.ID = scheduleID
+	schedule.FkActivityID = activityID
+	if err := h.scheduleCastle.UpdateSchedule(schedule); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.expandAndWrite(w, scheduleID, http.StatusOK)
+}
+
+// SplitSchedule godoc
+// @Summary      Change occurrence and all following ones
+// @Description  Ends schedule right before occurrence given in from and creates a new schedule from the payload for the rest of the series.
+// @Description  Sessions of the original schedule starting at from or later are replaced
+// @Tags         schedule
+// @Accept       json
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        scheduleID path int true "Schedule ID"
+// @Param        payload body types.SplitSchedulePayload true "Schedule data of following occurrences"
+// @Success      201  {object}   types.Schedule
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload or from is not an upcoming occurrence"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "schedule not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/schedules/split/{scheduleID} [post]
+func (h *Handler) handleSplitSchedule(w http.ResponseWriter, r *http.Request) {
+	activityID, scheduleID, err := parseIDs(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var payload types.SplitSchedulePayload
+	if !h.parsePayload(w, r, activityID, &payload) {
+		return
+	}
+
+	next, err := scheduleFromPayload(payload.SchedulePayload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	next.FkActivityID = activityID
+
+	if !h.checkActivityOwnership(w, r, activityID) {
+		return
+	}
+
+	previous, err := h.getActivitySchedule(activityID, scheduleID)
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+
+	rule, dtstart, err := parseSchedule(previous)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	from, err := parseWallTime(payload.From, dtstart.Location())
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !from.After(time.Now()) || len(rule.Expand(dtstart, from, from)) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("%s is not an upcoming occurrence of schedule", payload.From))
+		return
+	}
+
+	keepPrevious, err := Truncate(previous, from)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	nextID, err := h.scheduleCastle.SplitSchedule(*previous, !keepPrevious, next, from)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.expandAndWrite(w, int(nextID), http.StatusCreated)
+}
+
+// DeleteSchedule godoc
+// @Summary      Delete recurring schedule
+// @Description  Deletes schedule together with its upcoming sessions, past sessions are kept
+// @Tags         schedule
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        scheduleID path int true "Schedule ID"
+// @Success      200  {object}   types.ErrorResponse "Schedule with ID %d successfully deleted"
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "schedule not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/schedules/delete/{scheduleID} [delete]
+func (h *Handler) handleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	activityID, scheduleID, err := parseIDs(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !h.checkActivityOwnership(w, r, activityID) {
+		return
+	}
+
+	if _, err := h.getActivitySchedule(activityID, scheduleID); err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+
+	if err := h.scheduleCastle.DeleteScheduleSessionsFrom(scheduleID, time.Now()); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error deleting sessions: %w", err))
+		return
+	}
+
+	if err := h.scheduleCastle.DeleteSchedule(scheduleID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error deleting schedule: %w", err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("Schedule with ID %d successfully deleted", scheduleID))
+}
+
+// expandAndWrite generates sessions of stored schedule and writes schedule as response
+func (h *Handler) expandAndWrite(w http.ResponseWriter, scheduleID int, status int) {
+	schedule, err := h.scheduleCastle.GetScheduleByID(scheduleID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.expander.Expand(schedule, time.Now()); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error generating sessions: %w", err))
+		return
+	}
+
+	utils.WriteJSON(w, status, schedule)
+}
+
+// parsePayload reads and validates schedule payload, writing error response when it is invalid
+func (h *Handler) parsePayload(w http.ResponseWriter, r *http.Request, activityID int, payload interface{}) bool {
+	if err := utils.ParseJSON(r, payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return false
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return false
+	}
+
+	var locationID *int
+	var price *types.Money
+	switch p := payload.(type) {
+	case *types.SchedulePayload:
+		locationID, price = p.FkLocationID, p.Price
+	case *types.SplitSchedulePayload:
+		locationID, price = p.FkLocationID, p.Price
+	}
+
+	if locationID != nil {
+		location, err := h.locationCastle.GetLocationByID(*locationID)
+		if err != nil || location.FkActivityID != activityID {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("location %d does not belong to activity", *locationID))
+			return false
+		}
+	}
+
+	if price != nil {
+		activity, err := h.activityCastle.GetActivityByID(activityID)
+		if err == nil && activity.BasePrice.Currency != price.Currency {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("price has to be in %s like activity price", activity.BasePrice.Currency))
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkActivityOwnership writes error response unless user organizes the activity
+func (h *Handler) checkActivityOwnership(w http.ResponseWriter, r *http.Request, activityID int) bool {
+	organizer, err := h.userCastle.GetOrganizerByActivityID(activityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity not found"))
+		return false
+	}
+
+	if !auth.CheckOwnership(r, organizer.ID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return false
+	}
+
+	return true
+}
+
+// getActivitySchedule returns schedule only when it belongs to the activity from URL
+func (h *Handler) getActivitySchedule(activityID, scheduleID int) (*types.Schedule, error) {
+	schedule, err := h.scheduleCastle.GetScheduleByID(scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if schedule.FkActivityID != activityID {
+		return nil, sql.ErrNoRows
+	}
+
+	return schedule, nil
+}
+
+func writeScheduleError(w http.ResponseWriter, err error) {
+	if err == sql.ErrNoRows {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("schedule not found"))
+	} else {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func parseIDs(r *http.Request) (int, int, error) {
+	vars := mux.Vars(r)
+
+	activityID, err := strconv.Atoi(vars["activityID"])
+	if err != nil {
+		return 0, 0, fmt.Errorf("missing or invalid activity ID")
+	}
+
+	scheduleID, err := strconv.Atoi(vars["scheduleID"])
+	if err != nil {
+		return 0, 0, fmt.Errorf("missing or invalid schedule ID")
+	}
+
+	return activityID, scheduleID, nil
+}
+
+// scheduleFromPayload builds schedule and checks that its rule and exception dates can be expanded
+func scheduleFromPayload(payload types.SchedulePayload) (types.Schedule, error) {
+	s := types.Schedule{
+		RRule:           payload.RRule,
+		StartTime:       payload.StartTime,
+		Timezone:        payload.Timezone,
+		DurationMinutes: payload.DurationMinutes,
+		FkLocationID:    payload.FkLocationID,
+		Capacity:        payload.Capacity,
+		Language:        payload.Language,
+		Price:           payload.Price,
+		ExDates:         payload.ExDates,
+	}
+	if s.Timezone == "" {
+		s.Timezone = configs.Envs.ScheduleTimezone
+	}
+	if s.ExDates == nil {
+		s.ExDates = []string{}
+	}
+
+	rule, _, err := parseSchedule(&s)
+	if err != nil {
+		return s, err
+	}
+	// Normalized form keeps stored rules comparable
+	s.RRule = rule.String()
+
+	if err := ValidateExDates(s.ExDates); err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,339 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "time/tzdata" // Europe/Vilnius has to be available on hosts without zoneinfo
+)
+
+// Subset of RFC 5545 recurrence rules (https://www.rfc-editor.org/rfc/rfc5545#section-3.3.10)
+// supporting FREQ, INTERVAL, BYDAY, UNTIL and COUNT. Weeks start on Monday.
+
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+
+	// Guards expansion of rules which can never produce occurrence, e.g. 5th Monday of February only
+	maxPeriods = 100000
+
+	untilDateTimeFormat = "20060102T150405Z"
+	untilDateFormat     = "20060102"
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// WeekdayNum is BYDAY entry, N selects n-th weekday of month (negative counts from the end), 0 means every
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+type RRule struct {
+	Freq     string
+	Interval int
+	ByDay    []WeekdayNum
+	Count    int
+	Until    *time.Time // Inclusive
+}
+
+// ParseRRule parses rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;UNTIL=20250630T210000Z".
+// UNTIL without time means end of that day in loc, UNTIL without Z is wall time in loc
+func ParseRRule(value string, loc *time.Location) (*RRule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	r := &RRule{Interval: 1}
+
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule part '%s'", part)
+		}
+
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(val)
+			if r.Freq != FreqDaily && r.Freq != FreqWeekly && r.Freq != FreqMonthly {
+				return nil, fmt.Errorf("unsupported FREQ '%s', expected DAILY, WEEKLY or MONTHLY", val)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL '%s'", val)
+			}
+			r.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid COUNT '%s'", val)
+			}
+			r.Count = count
+		case "UNTIL":
+			until, err := parseUntil(val, loc)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = &until
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				wd, err := parseWeekdayNum(day)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "WKST":
+			if strings.ToUpper(val) != "MO" {
+				return nil, fmt.Errorf("only WKST=MO is supported")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part '%s'", name)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot be combined")
+	}
+	for _, wd := range r.ByDay {
+		if wd.N != 0 && r.Freq != FreqMonthly {
+			return nil, fmt.Errorf("numbered BYDAY is supported only with FREQ=MONTHLY")
+		}
+	}
+
+	return r, nil
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(untilDateTimeFormat, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(strings.TrimSuffix(untilDateTimeFormat, "Z"), value, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(untilDateFormat, value, loc); err == nil {
+		return localTime(t.Year(), t.Month(), t.Day(), 23, 59, 59, loc), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid UNTIL '%s'", value)
+}
+
+func parseWeekdayNum(value string) (WeekdayNum, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY '%s'", value)
+	}
+
+	weekday, ok := weekdays[value[len(value)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY '%s'", value)
+	}
+
+	n := 0
+	if prefix := value[:len(value)-2]; prefix != "" {
+		var err error
+		n, err = strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY '%s'", value)
+		}
+	}
+
+	return WeekdayNum{N: n, Weekday: weekday}, nil
+}
+
+// String formats rule back into RFC 5545 form, UNTIL is always written in UTC
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = wd.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilDateTimeFormat))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func (wd WeekdayNum) String() string {
+	for name, weekday := range weekdays {
+		if weekday == wd.Weekday {
+			if wd.N != 0 {
+				return strconv.Itoa(wd.N) + name
+			}
+			return name
+		}
+	}
+	return ""
+}
+
+// Expand returns occurrences starting between from and to (both inclusive). Occurrences keep wall
+// time of dtstart in its location, so 10:00 stays 10:00 across daylight saving time changes.
+// Rule is expected to be synchronized with dtstart, dtstart itself is returned only if rule matches it
+func (r *RRule) Expand(dtstart, from, to time.Time) []time.Time {
+	var occurrences []time.Time
+	count := 0
+
+	for period := 0; period < maxPeriods; period++ {
+		candidates, periodStart := r.periodCandidates(dtstart, period)
+		if periodStart.After(to) {
+			break
+		}
+
+		for _, t := range candidates {
+			if t.Before(dtstart) {
+				continue
+			}
+			if r.Until != nil && t.After(*r.Until) {
+				return occurrences
+			}
+
+			count++
+			if r.Count > 0 && count > r.Count {
+				return occurrences
+			}
+
+			if !t.Before(from) && !t.After(to) {
+				occurrences = append(occurrences, t)
+			}
+		}
+	}
+
+	return occurrences
+}
+
+// periodCandidates returns sorted occurrences of n-th period (day, week or month) counted from dtstart
+// together with the beginning of that period
+func (r *RRule) periodCandidates(dtstart time.Time, n int) ([]time.Time, time.Time) {
+	loc := dtstart.Location()
+	hour, min, sec := dtstart.Clock()
+	step := n * r.Interval
+
+	var start time.Time
+	var days []time.Time
+	switch r.Freq {
+	case FreqDaily:
+		start = time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day()+step, 0, 0, 0, 0, time.UTC)
+		if r.matchesWeekday(start.Weekday()) {
+			days = append(days, start)
+		}
+	case FreqWeekly:
+		// Monday of the week containing dtstart
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		start = time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day()-offset+7*step, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < 7; i++ {
+			day := start.AddDate(0, 0, i)
+			if len(r.ByDay) > 0 && r.matchesWeekday(day.Weekday()) || len(r.ByDay) == 0 && day.Weekday() == dtstart.Weekday() {
+				days = append(days, day)
+			}
+		}
+	case FreqMonthly:
+		start = time.Date(dtstart.Year(), dtstart.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		days = r.monthDays(start, dtstart.Day())
+	}
+
+	candidates := make([]time.Time, 0, len(days))
+	for _, day := range days {
+		candidates = append(candidates, localTime(day.Year(), day.Month(), day.Day(), hour, min, sec, loc))
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	return candidates, localTime(start.Year(), start.Month(), start.Day(), 0, 0, 0, loc)
+}
+
+func (r *RRule) matchesWeekday(weekday time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDays returns days of the month starting at first, months too short for dtstart day are skipped
+func (r *RRule) monthDays(first time.Time, dayOfMonth int) []time.Time {
+	daysInMonth := first.AddDate(0, 1, -1).Day()
+
+	if len(r.ByDay) == 0 {
+		if dayOfMonth > daysInMonth {
+			return []time.Time{}
+		}
+		return []time.Time{first.AddDate(0, 0, dayOfMonth-1)}
+	}
+
+	var days []time.Time
+	for d := 0; d < daysInMonth; d++ {
+		day := first.AddDate(0, 0, d)
+		for _, wd := range r.ByDay {
+			if wd.Weekday != day.Weekday() {
+				continue
+			}
+
+			// Position of this weekday counted from month start and end
+			fromStart := d/7 + 1
+			fromEnd := -((daysInMonth-d-1)/7 + 1)
+			if wd.N == 0 || wd.N == fromStart || wd.N == fromEnd {
+				days = append(days, day)
+				break
+			}
+		}
+	}
+
+	return days
+}
+
+// localTime builds wall clock time in loc. RFC 5545 requires offset from before the transition,
+// so times skipped in spring are moved forward and repeated times in autumn take the first instance
+func localTime(year int, month time.Month, day, hour, min, sec int, loc *time.Location) time.Time {
+	naive := time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+
+	_, offsetBefore := naive.Add(-12 * time.Hour).In(loc).Zone()
+	first := naive.Add(-time.Duration(offsetBefore) * time.Second).In(loc)
+	if sameWallTime(first, naive) {
+		return first
+	}
+
+	_, offsetAfter := first.Zone()
+	second := naive.Add(-time.Duration(offsetAfter) * time.Second).In(loc)
+	if sameWallTime(second, naive) {
+		return second
+	}
+
+	// Wall time doesn't exist
+	return first
+}
+
+func sameWallTime(t, naive time.Time) bool {
+	y, m, d := t.Date()
+	ny, nm, nd := naive.Date()
+	return y == ny && m == nm && d == nd && t.Hour() == naive.Hour() && t.Minute() == naive.Minute() && t.Second() == naive.Second()
+}
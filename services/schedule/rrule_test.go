@@ -0,0 +1,198 @@
+package schedule
+
+import (
+	"educations-castle/types"
+	"testing"
+	"time"
+)
+
+func vilnius(t *testing.T) *time.Location {
+	loc, err := time.LoadLocation("Europe/Vilnius")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func expand(t *testing.T, rule string, dtstart time.Time, to time.Time) []time.Time {
+	r, err := ParseRRule(rule, dtstart.Location())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r.Expand(dtstart, dtstart, to)
+}
+
+func TestExpandAcrossDST(t *testing.T) {
+	loc := vilnius(t)
+
+	t.Run("Should keep wall time of weekly occurrences", func(t *testing.T) {
+		dtstart := time.Date(2025, 3, 25, 10, 0, 0, 0, loc)
+		starts := expand(t, "FREQ=WEEKLY;BYDAY=TU", dtstart, time.Date(2025, 11, 1, 0, 0, 0, 0, loc))
+
+		for _, start := range starts {
+			if start.Hour() != 10 || start.Weekday() != time.Tuesday {
+				t.Fatalf("unexpected occurrence %s", start)
+			}
+		}
+
+		// Last Sunday of March moves clocks to EEST, last Sunday of October back to EET
+		expectedUTC := map[string]int{"2025-03-25": 8, "2025-04-01": 7, "2025-10-21": 7, "2025-10-28": 8}
+		found := 0
+		for _, start := range starts {
+			if hour, ok := expectedUTC[start.Format("2006-01-02")]; ok {
+				found++
+				if start.UTC().Hour() != hour {
+					t.Errorf("expected %s at %02d:00 UTC, got %s", start.Format("2006-01-02"), hour, start.UTC())
+				}
+			}
+		}
+		if found != len(expectedUTC) {
+			t.Errorf("expected %d checked occurrences, found %d", len(expectedUTC), found)
+		}
+	})
+
+	t.Run("Should move nonexistent wall time forward", func(t *testing.T) {
+		dtstart := time.Date(2025, 3, 29, 3, 30, 0, 0, loc)
+		starts := expand(t, "FREQ=DAILY;COUNT=3", dtstart, time.Date(2025, 4, 30, 0, 0, 0, 0, loc))
+
+		if len(starts) != 3 {
+			t.Fatalf("expected 3 occurrences, got %v", starts)
+		}
+		if got := starts[1].Format("2006-01-02T15:04 MST"); got != "2025-03-30T04:30 EEST" {
+			t.Errorf("unexpected occurrence in DST gap %s", got)
+		}
+		if got := starts[2].Format("15:04"); got != "03:30" {
+			t.Errorf("unexpected occurrence after DST gap %s", got)
+		}
+	})
+
+	t.Run("Should take first instance of repeated wall time", func(t *testing.T) {
+		dtstart := localTime(2025, 10, 26, 3, 30, 0, loc)
+		starts := expand(t, "FREQ=DAILY;COUNT=1", dtstart, dtstart.AddDate(0, 0, 1))
+
+		if len(starts) != 1 || starts[0].UTC().Hour() != 0 {
+			t.Errorf("expected 03:30 EEST, got %v", starts)
+		}
+	})
+}
+
+func TestExpandRules(t *testing.T) {
+	loc := vilnius(t)
+	dtstart := time.Date(2025, 1, 7, 10, 0, 0, 0, loc) // Tuesday
+	to := time.Date(2026, 1, 1, 0, 0, 0, 0, loc)
+
+	tests := []struct {
+		rule     string
+		expected []string
+	}{
+		{"FREQ=WEEKLY;COUNT=3", []string{"2025-01-07", "2025-01-14", "2025-01-21"}},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;COUNT=4", []string{"2025-01-07", "2025-01-09", "2025-01-21", "2025-01-23"}},
+		{"FREQ=DAILY;UNTIL=20250109", []string{"2025-01-07", "2025-01-08", "2025-01-09"}},
+		{"FREQ=DAILY;UNTIL=20250109T080000Z", []string{"2025-01-07", "2025-01-08", "2025-01-09"}},
+		{"FREQ=DAILY;UNTIL=20250109T075959Z", []string{"2025-01-07", "2025-01-08"}},
+		{"FREQ=MONTHLY;BYDAY=2TU;COUNT=3", []string{"2025-01-14", "2025-02-11", "2025-03-11"}},
+		{"FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", []string{"2025-01-31", "2025-02-28", "2025-03-28"}},
+	}
+
+	for _, test := range tests {
+		starts := expand(t, test.rule, dtstart, to)
+
+		var dates []string
+		for _, start := range starts {
+			dates = append(dates, start.Format("2006-01-02"))
+		}
+		if len(dates) != len(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.rule, test.expected, dates)
+			continue
+		}
+		for i := range dates {
+			if dates[i] != test.expected[i] {
+				t.Errorf("%s: expected %v, got %v", test.rule, test.expected, dates)
+				break
+			}
+		}
+	}
+}
+
+func TestMonthlySkipsShortMonths(t *testing.T) {
+	loc := vilnius(t)
+	starts := expand(t, "FREQ=MONTHLY;COUNT=3", time.Date(2025, 1, 31, 10, 0, 0, 0, loc), time.Date(2026, 1, 1, 0, 0, 0, 0, loc))
+
+	if len(starts) != 3 || starts[1].Month() != time.March || starts[2].Month() != time.May {
+		t.Errorf("unexpected occurrences %v", starts)
+	}
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"FREQ=YEARLY",
+		"FREQ=WEEKLY;INTERVAL=0",
+		"FREQ=WEEKLY;COUNT=2;UNTIL=20250101",
+		"FREQ=WEEKLY;BYDAY=2TU",
+		"FREQ=MONTHLY;BYDAY=XX",
+		"FREQ=DAILY;BYHOUR=10",
+	} {
+		if _, err := ParseRRule(rule, time.UTC); err == nil {
+			t.Errorf("expected error for %q", rule)
+		}
+	}
+}
+
+func TestOccurrencesSkipExDates(t *testing.T) {
+	s := &types.Schedule{
+		RRule:     "FREQ=DAILY;COUNT=4",
+		StartTime: "2025-01-07T10:00:00",
+		Timezone:  "Europe/Vilnius",
+		ExDates:   []string{"2025-01-08", "2025-01-09T10:00:00", "2025-01-10T11:00:00"},
+	}
+
+	starts, err := Occurrences(s, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(starts) != 2 || starts[0].Day() != 7 || starts[1].Day() != 10 {
+		t.Errorf("unexpected occurrences %v", starts)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	loc := vilnius(t)
+
+	t.Run("Should limit count to occurrences before split", func(t *testing.T) {
+		s := &types.Schedule{RRule: "FREQ=WEEKLY;COUNT=10", StartTime: "2025-01-07T10:00:00", Timezone: "Europe/Vilnius"}
+
+		keep, err := Truncate(s, time.Date(2025, 1, 28, 10, 0, 0, 0, loc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !keep || s.RRule != "FREQ=WEEKLY;COUNT=3" {
+			t.Errorf("unexpected truncated rule %s", s.RRule)
+		}
+	})
+
+	t.Run("Should end open rule right before split", func(t *testing.T) {
+		s := &types.Schedule{RRule: "FREQ=WEEKLY", StartTime: "2025-01-07T10:00:00", Timezone: "Europe/Vilnius"}
+
+		keep, err := Truncate(s, time.Date(2025, 1, 28, 10, 0, 0, 0, loc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !keep || s.RRule != "FREQ=WEEKLY;UNTIL=20250128T075959Z" {
+			t.Errorf("unexpected truncated rule %s", s.RRule)
+		}
+	})
+
+	t.Run("Should remove schedule split at first occurrence", func(t *testing.T) {
+		s := &types.Schedule{RRule: "FREQ=WEEKLY", StartTime: "2025-01-07T10:00:00", Timezone: "Europe/Vilnius"}
+
+		keep, err := Truncate(s, time.Date(2025, 1, 7, 10, 0, 0, 0, loc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keep {
+			t.Errorf("expected schedule to be removed")
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// minStemLength keeps short words from being cut down to meaningless stems
+const minStemLength = 3
+
+// endings are Lithuanian noun and adjective inflections without diacritics, longest are tried first
+var endings = []string{
+	"ijomis", "iuose", "ijose", "ijoje", "ijoms", "iomis",
+	"iams", "iems", "omis", "iais", "uose", "ioje", "iuje", "ijos", "ijai", "ijas", "ijus", "iaus", "ioms", "iose",
+	"ams", "ems", "ims", "oms", "ums", "ais", "ose", "ese", "yse", "oje", "yje", "ije", "uje", "eje",
+	"ija", "ius", "ias", "ies", "iai", "iui", "ios", "iam", "iem",
+	"as", "is", "ys", "us", "os", "es", "ai", "ei", "ui", "ia", "io", "iu", "am", "em", "im", "om", "um",
+	"a", "e", "i", "o", "u", "y",
+}
+
+func init() {
+	sort.SliceStable(endings, func(i, j int) bool { return len(endings[i]) > len(endings[j]) })
+}
+
+// Fold lowercases text and replaces letters with diacritics by their base letters, so ą, č and ė become a, c and e
+func Fold(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(text)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// Stem removes inflection ending of folded Lithuanian word, so gintaras and gintaro both become gintar
+func Stem(word string) string {
+	n := utf8.RuneCountInString(word)
+	if n <= minStemLength {
+		return word
+	}
+
+	for _, ending := range endings {
+		if strings.HasSuffix(word, ending) && n-len(ending) >= minStemLength {
+			return word[:len(word)-len(ending)]
+		}
+	}
+
+	return word
+}
+
+// Analyze splits text into folded and stemmed terms
+func Analyze(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(Fold(text), isSeparator) {
+		terms = append(terms, Stem(word))
+	}
+
+	return terms
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// tolerance is the number of typos allowed in term, longer terms allow more of them
+func tolerance(term string) int {
+	switch n := len([]rune(term)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Distance returns number of insertions, deletions, substitutions and transpositions of adjacent letters
+// turning a into b
+func Distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+
+	// Three rows are enough, transposition looks two rows back
+	previous2 := make([]int, len(t)+1)
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+
+	return previous[len(t)]
+}
@@ -0,0 +1,206 @@
+package search
+
+import (
+	"educations-castle/types"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kind is type of indexed document
+type Kind string
+
+const (
+	KindActivity  Kind = "activity"
+	KindPackage   Kind = "package"
+	KindOrganizer Kind = "organizer"
+	KindCategory  Kind = "category"
+)
+
+// Field is text of document, matches in fields with larger weight score more
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Document is searchable entity such as activity or package. Documents with title are suggested
+// by it, unless they are hidden from public
+type Document struct {
+	Kind   Kind
+	ID     int
+	Title  string
+	Hidden bool
+	Fields []Field
+}
+
+// Hit is document matching query
+type Hit struct {
+	ID    int
+	Score float64
+}
+
+type key struct {
+	kind Kind
+	id   int
+}
+
+// Index is in-memory inverted index of analyzed terms. It is safe for concurrent use
+type Index struct {
+	mu sync.RWMutex
+	// postings map terms to weights of documents containing them
+	postings map[string]map[key]float64
+	// documents as they were put, so their terms can be removed from postings
+	documents map[key]*document
+	// suggestions of recent queries, dropped whenever documents change
+	suggestions map[string][]types.Suggestion
+}
+
+type document struct {
+	Document
+	terms       []string
+	titleTerms  []string
+	foldedTitle string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings:    map[string]map[key]float64{},
+		documents:   map[key]*document{},
+		suggestions: map[string][]types.Suggestion{}}
+}
+
+// Put adds document to index or replaces its previous version
+func (x *Index) Put(d Document) {
+	weights := map[string]float64{}
+	for _, f := range d.Fields {
+		// Term counts once per field, so repeating words doesn't raise score
+		for _, term := range unique(Analyze(f.Text)) {
+			weights[term] += f.Weight
+		}
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	k := key{d.Kind, d.ID}
+	if stored, ok := x.documents[k]; ok && reflect.DeepEqual(stored.Document, d) {
+		// Unchanged documents keep cached suggestions
+		return
+	}
+	x.remove(k)
+	clear(x.suggestions)
+
+	stored := &document{Document: d, titleTerms: Analyze(d.Title),
+		foldedTitle: strings.Join(strings.FieldsFunc(Fold(d.Title), isSeparator), " ")}
+	for term, weight := range weights {
+		if x.postings[term] == nil {
+			x.postings[term] = map[key]float64{}
+		}
+		x.postings[term][k] = weight
+		stored.terms = append(stored.terms, term)
+	}
+	x.documents[k] = stored
+}
+
+// Remove removes document from index
+func (x *Index) Remove(kind Kind, id int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.remove(key{kind, id})
+}
+
+func (x *Index) remove(k key) {
+	stored, ok := x.documents[k]
+	if !ok {
+		return
+	}
+
+	for _, term := range stored.terms {
+		delete(x.postings[term], k)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+		}
+	}
+	delete(x.documents, k)
+
+	clear(x.suggestions)
+}
+
+// Search returns documents of kind matching any term of query, most relevant first. Rare terms score more
+// than common ones and documents matching more terms of query score more
+func (x *Index) Search(kind Kind, q Query) []Hit {
+	if q.Empty() {
+		return nil
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	documents := 0
+	for k := range x.documents {
+		if k.kind == kind {
+			documents++
+		}
+	}
+
+	scores := map[int]float64{}
+	matched := map[int]int{}
+	for i := range q.terms {
+		// Document scores by the best of terms matching query term, not by all of them
+		best := map[int]float64{}
+		for term, postings := range x.postings {
+			m := q.match(i, term)
+			if m == 0 {
+				continue
+			}
+
+			found := 0
+			for k := range postings {
+				if k.kind == kind {
+					found++
+				}
+			}
+			idf := math.Log(1 + float64(documents)/float64(max(found, 1)))
+
+			for k, weight := range postings {
+				if k.kind == kind {
+					best[k.id] = max(best[k.id], m*idf*weight)
+				}
+			}
+		}
+
+		for id, score := range best {
+			scores[id] += score
+			matched[id]++
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score * float64(matched[id]) / float64(len(q.terms))})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	return hits
+}
+
+func unique(terms []string) []string {
+	seen := map[string]bool{}
+	result := terms[:0]
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			result = append(result, term)
+		}
+	}
+
+	return result
+}
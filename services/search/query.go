@@ -0,0 +1,114 @@
+package search
+
+import (
+	"strings"
+)
+
+// Weights of term matches, exact terms score the most
+const (
+	exactMatch  = 1.0
+	prefixMatch = 0.8
+	typoMatch   = 0.6
+)
+
+// maxEndingLength is length of the longest ending typed word may still be in the middle of
+const maxEndingLength = 3
+
+// Query is analyzed search query. Its last term also matches longer terms starting with it,
+// so results can be shown while user is still typing
+type Query struct {
+	terms []string
+	// typed is the last word as it was typed, without ending removed
+	typed string
+	// folded is the whole query folded, with words separated by single spaces
+	folded string
+}
+
+func ParseQuery(text string) Query {
+	var q Query
+	seen := map[string]bool{}
+	words := strings.FieldsFunc(Fold(text), isSeparator)
+	q.folded = strings.Join(words, " ")
+	for _, word := range words {
+		term := Stem(word)
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		q.terms = append(q.terms, term)
+		q.typed = word
+	}
+
+	return q
+}
+
+func (q Query) Empty() bool {
+	return len(q.terms) == 0
+}
+
+// match returns how well indexed term matches i-th term of query, 0 when it doesn't match
+func (q Query) match(i int, term string) float64 {
+	t := q.terms[i]
+
+	switch {
+	case term == t:
+		return exactMatch
+	case i == len(q.terms)-1 && len(q.typed) >= 2 && strings.HasPrefix(term, q.typed):
+		return prefixMatch
+	case i == len(q.terms)-1 && strings.HasPrefix(q.typed, term) && len(q.typed)-len(term) <= maxEndingLength:
+		// Word typed halfway through its ending, such as istorij of istorija
+		return prefixMatch
+	}
+
+	allowed := tolerance(t)
+	if allowed == 0 || abs(len(term)-len(t)) > allowed {
+		return 0
+	}
+	if d := Distance(t, term); d <= allowed {
+		return typoMatch / float64(d)
+	}
+
+	return 0
+}
+
+// complete returns score of title completing query, false when some word of query matches none of title terms.
+// Unlike in search, last word matches terms starting with it however short it is
+func (q Query) complete(titleTerms []string) (float64, bool) {
+	score := 0.0
+	for i := range q.terms {
+		best := 0.0
+		for _, term := range titleTerms {
+			m := q.match(i, term)
+			if m == 0 && i == len(q.terms)-1 && strings.HasPrefix(term, q.typed) {
+				m = prefixMatch
+			}
+			best = max(best, m)
+		}
+		if best == 0 {
+			return 0, false
+		}
+		score += best
+	}
+
+	return score, true
+}
+
+// Matches reports whether word of text matches any term of query
+func (q Query) Matches(word string) bool {
+	for _, term := range Analyze(word) {
+		for i := range q.terms {
+			if q.match(i, term) > 0 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
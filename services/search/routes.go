@@ -0,0 +1,69 @@
+package search
+
+import (
+	"educations-castle/types"
+	"educations-castle/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultSuggestionLimit = 10
+	maxSuggestionLimit     = 25
+)
+
+type Handler struct {
+	index *Index
+}
+
+func NewHandler(index *Index) *Handler {
+	return &Handler{index: index}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/search/suggest", h.handleSuggest).Methods("GET")
+}
+
+// Suggest godoc
+// @Summary      Suggest search completions
+// @Description  Returns activity names, package names, organizer names and categories completing the query, best first. Meant to be called on every keystroke
+// @Description  Every word of query has to match a word of suggestion, last word matches words starting with it. Diacritics, Lithuanian word endings and small typos are ignored
+// @Description  Only activities of public catalog are suggested. Suggestions are cached until activities, packages or organizers change
+// @Tags         search
+// @Produce      json
+// @Param        q      query  string  true   "Query typed so far"
+// @Param        limit  query  int     false  "Number of suggestions, 10 by default and at most 25"
+// @Success      200  {array}    types.Suggestion
+// @Failure      400  {object}   types.ErrorResponse "Invalid query"
+// @Router       /search/suggest [get]
+func (h *Handler) handleSuggest(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len(query) > 255 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("query must be at most 255 characters"))
+		return
+	}
+
+	limit := defaultSuggestionLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxSuggestionLimit {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", maxSuggestionLimit))
+			return
+		}
+		limit = n
+	}
+
+	suggestions := h.index.Suggest(ParseQuery(query), limit)
+
+	// If no suggestions found, return an empty array
+	if len(suggestions) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.Suggestion{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, suggestions)
+}
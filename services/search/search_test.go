@@ -0,0 +1,149 @@
+package search
+
+import (
+	"educations-castle/types"
+	"strings"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	if folded := Fold("Ąžuolų ĖJIMAS į Šiaulius, Čiurlionis"); folded != "azuolu ejimas i siaulius, ciurlionis" {
+		t.Errorf("unexpected folded text %q", folded)
+	}
+
+	tests := map[string]string{
+		"Gintaras":   "gintar",
+		"gintaro":    "gintar",
+		"istorija":   "istor",
+		"istorijos":  "istor",
+		"edukacijos": "edukac",
+		"muziejuose": "muziej",
+		"kas":        "kas",
+	}
+	for word, expected := range tests {
+		if terms := Analyze(word); len(terms) != 1 || terms[0] != expected {
+			t.Errorf("%s: expected %s, got %v", word, expected, terms)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"edukacija", "edukacija", 0},
+		{"edukacjia", "edukacija", 1},
+		{"gintar", "gintr", 1},
+		{"muziej", "muzej", 1},
+		{"kelione", "kelionės", 2},
+		{"", "abc", 3},
+	}
+	for _, test := range tests {
+		if d := Distance(test.a, test.b); d != test.expected {
+			t.Errorf("%s %s: expected %d, got %d", test.a, test.b, test.expected, d)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	x := NewIndex()
+	x.Put(Document{Kind: KindActivity, ID: 1, Fields: []Field{{"Gintaro istorija", 3}, {"Edukacija apie Baltijos gintarą", 1}}})
+	x.Put(Document{Kind: KindActivity, ID: 2, Fields: []Field{{"Keramikos dirbtuvės", 3}, {"Edukacinė programa vaikams", 1}}})
+	x.Put(Document{Kind: KindActivity, ID: 3, Fields: []Field{{"Kalėdinė edukacija", 3}, {"Šventinės dirbtuvės", 1}}})
+	x.Put(Document{Kind: KindPackage, ID: 1, Fields: []Field{{"Gintaro muziejus", 1}}})
+
+	ids := func(hits []Hit) string {
+		var s []string
+		for _, h := range hits {
+			s = append(s, string(rune('0'+h.ID)))
+		}
+		return strings.Join(s, ",")
+	}
+
+	t.Run("Should find inflected words without diacritics", func(t *testing.T) {
+		if hits := x.Search(KindActivity, ParseQuery("gintaras")); ids(hits) != "1" {
+			t.Errorf("unexpected hits %v", hits)
+		}
+		if hits := x.Search(KindActivity, ParseQuery("kaledine")); ids(hits) != "3" {
+			t.Errorf("unexpected hits %v", hits)
+		}
+	})
+
+	t.Run("Should tolerate typos", func(t *testing.T) {
+		if hits := x.Search(KindActivity, ParseQuery("edukacjia")); ids(hits) != "3,1" {
+			t.Errorf("unexpected hits %v", hits)
+		}
+	})
+
+	t.Run("Should complete last word", func(t *testing.T) {
+		if hits := x.Search(KindActivity, ParseQuery("keram")); ids(hits) != "2" {
+			t.Errorf("unexpected hits %v", hits)
+		}
+		if hits := x.Search(KindActivity, ParseQuery("gintaro istorij")); ids(hits) != "1" {
+			t.Errorf("unexpected hits %v", hits)
+		}
+	})
+
+	t.Run("Should keep kinds apart", func(t *testing.T) {
+		if hits := x.Search(KindPackage, ParseQuery("muziejus")); ids(hits) != "1" {
+			t.Errorf("unexpected hits %v", hits)
+		}
+	})
+
+	t.Run("Should replace and remove documents", func(t *testing.T) {
+		x.Put(Document{Kind: KindActivity, ID: 1, Fields: []Field{{"Duonos kepimas", 3}}})
+		if hits := x.Search(KindActivity, ParseQuery("gintaras")); len(hits) != 0 {
+			t.Errorf("unexpected hits %v", hits)
+		}
+
+		x.Remove(KindActivity, 1)
+		if hits := x.Search(KindActivity, ParseQuery("duona")); len(hits) != 0 {
+			t.Errorf("unexpected hits %v", hits)
+		}
+	})
+}
+
+func TestSuggest(t *testing.T) {
+	x := NewIndex()
+	x.Put(Document{Kind: KindActivity, ID: 1, Title: "Gintaro istorija", Fields: []Field{{"Gintaro istorija", 3}}})
+	x.Put(Document{Kind: KindActivity, ID: 2, Title: "Gintarinės apyrankės", Hidden: true, Fields: []Field{{"Gintarinės apyrankės", 3}}})
+	x.Put(Document{Kind: KindPackage, ID: 1, Title: "Gintaro muziejus", Fields: []Field{{"Gintaro muziejus", 3}}})
+	x.Put(Document{Kind: KindOrganizer, ID: 4, Title: "palangos_gintaras", Fields: []Field{{"palangos_gintaras", 1}}})
+	x.Put(Document{Kind: KindCategory, ID: 1, Title: "Education", Fields: []Field{{"Education", 1}}})
+
+	texts := func(suggestions []types.Suggestion) string {
+		var s []string
+		for _, suggestion := range suggestions {
+			s = append(s, suggestion.Type+":"+suggestion.Text)
+		}
+		return strings.Join(s, ",")
+	}
+
+	t.Run("Should complete titles of every kind except hidden ones", func(t *testing.T) {
+		suggestions := x.Suggest(ParseQuery("gint"), 10)
+		if texts(suggestions) != "activity:Gintaro istorija,package:Gintaro muziejus,organizer:palangos_gintaras" {
+			t.Errorf("unexpected suggestions %v", suggestions)
+		}
+	})
+
+	t.Run("Should match every word of query", func(t *testing.T) {
+		if suggestions := x.Suggest(ParseQuery("gintaras ist"), 10); texts(suggestions) != "activity:Gintaro istorija" {
+			t.Errorf("unexpected suggestions %v", suggestions)
+		}
+		if suggestions := x.Suggest(ParseQuery("e"), 1); texts(suggestions) != "category:Education" {
+			t.Errorf("unexpected suggestions %v", suggestions)
+		}
+	})
+
+	t.Run("Should drop cached suggestions when documents change", func(t *testing.T) {
+		x.Suggest(ParseQuery("gint"), 10)
+		x.Put(Document{Kind: KindActivity, ID: 2, Title: "Gintarinės apyrankės", Fields: []Field{{"Gintarinės apyrankės", 3}}})
+		x.Remove(KindPackage, 1)
+
+		suggestions := x.Suggest(ParseQuery("gint"), 10)
+		if texts(suggestions) != "activity:Gintaro istorija,activity:Gintarinės apyrankės,organizer:palangos_gintaras" {
+			t.Errorf("unexpected suggestions %v", suggestions)
+		}
+	})
+}
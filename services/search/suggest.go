@@ -0,0 +1,82 @@
+package search
+
+import (
+	"educations-castle/types"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// maxCachedQueries bounds memory taken by cached suggestions, cache starts over once it is full
+const maxCachedQueries = 10000
+
+// Suggest returns titles of documents completing query, best first. Every word of query has to match
+// a word of title, last word matches words starting with it. Suggestions are cached until documents change
+func (x *Index) Suggest(q Query, limit int) []types.Suggestion {
+	if q.Empty() {
+		return nil
+	}
+
+	cacheKey := fmt.Sprintf("%d:%s", limit, q.folded)
+
+	x.mu.RLock()
+	suggestions, ok := x.suggestions[cacheKey]
+	x.mu.RUnlock()
+	if ok {
+		return suggestions
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	suggestions = x.suggest(q, limit)
+	if len(x.suggestions) >= maxCachedQueries {
+		clear(x.suggestions)
+	}
+	x.suggestions[cacheKey] = suggestions
+
+	return suggestions
+}
+
+func (x *Index) suggest(q Query, limit int) []types.Suggestion {
+	var suggestions []types.Suggestion
+	for _, d := range x.documents {
+		if d.Title == "" || d.Hidden {
+			continue
+		}
+
+		score, ok := q.complete(d.titleTerms)
+		if !ok {
+			continue
+		}
+		// Titles starting with query as it was typed come first
+		if strings.HasPrefix(d.foldedTitle, q.folded) {
+			score += float64(len(q.terms))
+		}
+
+		suggestions = append(suggestions, types.Suggestion{Type: string(d.Kind), ID: d.ID, Text: d.Title,
+			Score: math.Round(score*100) / 100})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		switch {
+		case a.Score != b.Score:
+			return a.Score > b.Score
+		case len(a.Text) != len(b.Text):
+			return len(a.Text) < len(b.Text)
+		case a.Text != b.Text:
+			return a.Text < b.Text
+		case a.Type != b.Type:
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions
+}
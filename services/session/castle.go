@@ -0,0 +1,148 @@
+package session
+
+import (
+	"database/sql"
+	"educations-castle/types"
+	"time"
+)
+
+// Sessions are selected together with seats held by bookings which are not cancelled and number of waiting users
+const selectSessions = `SELECT activitysession.*,
+	COALESCE((SELECT SUM(booking.seats) FROM booking
+		WHERE booking.fk_ActivitySessionid = activitysession.id AND booking.status <> 'cancelled'), 0) AS bookedSeats,
+	(SELECT COUNT(*) FROM waitlist
+		WHERE waitlist.fk_ActivitySessionid = activitysession.id AND waitlist.status = 'waiting') AS waitlistLength
+	FROM activitysession`
+
+type Castle struct {
+	db *sql.DB
+}
+
+func NewCastle(db *sql.DB) *Castle {
+	return &Castle{db: db}
+}
+
+func scanRowIntoSession(rows *sql.Rows) (*types.ActivitySession, error) {
+	s := new(types.ActivitySession)
+	var price *int64
+	var currency *string
+
+	err := rows.Scan(
+		&s.ID,
+		&s.FkActivityID,
+		&s.StartTime,
+		&s.EndTime,
+		&s.FkLocationID,
+		&s.Capacity,
+		&s.Language,
+		&price,
+		&currency,
+		&s.FkScheduleID,
+		&s.Cancelled,
+		&s.BookedSeats,
+		&s.WaitlistLength,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	s.Price = types.NullableMoney(price, currency)
+
+	return s, nil
+}
+
+func (c *Castle) CreateSession(s types.ActivitySession) (int64, error) {
+	price, currency := types.MoneyColumns(s.Price)
+	result, err := c.db.Exec(
+		`INSERT INTO activitysession (fk_Activityid, startTime, endTime, fk_Locationid, capacity, language, price, currency)
+		VALUES (?,?,?,?,?,?,?,?)`,
+		s.FkActivityID, s.StartTime.UTC(), s.EndTime.UTC(), s.FkLocationID, s.Capacity, s.Language, price, currency)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (c *Castle) GetSessionByID(id int) (*types.ActivitySession, error) {
+	rows, err := c.db.Query(selectSessions+" WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s := new(types.ActivitySession)
+	for rows.Next() {
+		s, err = scanRowIntoSession(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.ID == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return s, nil
+}
+
+// ListSessionsByActivityID returns sessions ordered by start time, optionally only those starting inside range
+func (c *Castle) ListSessionsByActivityID(activityID int, from, to *time.Time) ([]*types.ActivitySession, error) {
+	query := selectSessions + " WHERE fk_Activityid = ?"
+	params := []interface{}{activityID}
+
+	if from != nil {
+		query += " AND startTime >= ?"
+		params = append(params, from.UTC())
+	}
+	if to != nil {
+		query += " AND startTime <= ?"
+		params = append(params, to.UTC())
+	}
+	query += " ORDER BY startTime, id"
+
+	rows, err := c.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*types.ActivitySession
+
+	for rows.Next() {
+		s, err := scanRowIntoSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (c *Castle) UpdateSession(s types.ActivitySession) error {
+	price, currency := types.MoneyColumns(s.Price)
+	_, err := c.db.Exec(
+		`UPDATE activitysession
+		SET startTime = ?, endTime = ?, fk_Locationid = ?, capacity = ?, language = ?, price = ?, currency = ?
+		WHERE id = ?`,
+		s.StartTime.UTC(), s.EndTime.UTC(), s.FkLocationID, s.Capacity, s.Language, price, currency, s.ID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Castle) DeleteSession(id int) error {
+	_, err := c.db.Exec("DELETE FROM activitysession WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
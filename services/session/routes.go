@@ -0,0 +1,498 @@
+package session
+
+import (
+	"database/sql"
+	"educations-castle/services/activity"
+	"educations-castle/services/auth"
+	"educations-castle/types"
+	"educations-castle/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	sessionCastle  types.SessionCastle
+	activityCastle types.ActivityCastle
+	locationCastle types.LocationCastle
+	userCastle     types.UserCastle
+	location       *time.Location
+}
+
+func NewHandler(sessionCastle types.SessionCastle, activityCastle types.ActivityCastle,
+	locationCastle types.LocationCastle, userCastle types.UserCastle, location *time.Location) *Handler {
+	return &Handler{
+		sessionCastle:  sessionCastle,
+		activityCastle: activityCastle,
+		locationCastle: locationCastle,
+		userCastle:     userCastle,
+		location:       location}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/activities/{activityID:[0-9]+}/sessions", auth.WithOptionalJWTAuth(h.handleListSessions, h.userCastle)).Methods("GET")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/sessions/{sessionID:[0-9]+}", auth.WithOptionalJWTAuth(h.handleGetSession, h.userCastle)).Methods("GET")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/sessions/create", auth.WithJWTAuth(h.handleCreateSession, h.userCastle, "administrator", "organizer")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/sessions/update/{sessionID:[0-9]+}", auth.WithJWTAuth(h.handleUpdateSession, h.userCastle, "administrator", "organizer")).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/sessions/delete/{sessionID:[0-9]+}", auth.WithJWTAuth(h.handleDeleteSession, h.userCastle, "administrator", "organizer")).Methods("DELETE", "OPTIONS")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/sessions/{sessionID:[0-9]+}/price-tiers/update", auth.WithJWTAuth(h.handleUpdatePriceTiers, h.userCastle, "administrator", "organizer")).Methods("PUT", "OPTIONS")
+}
+
+// ListSessions godoc
+// @Summary      List activity sessions
+// @Description  Returns sessions of activity ordered by start time, optionally only those starting between from and to. Dates are whole days in schedule timezone
+// @Tags         session
+// @Produce      json
+// @Param        activityID path  int    true  "Activity ID"
+// @Param        from       query string false "Start of range, YYYY-MM-DD or RFC 3339 time"
+// @Param        to         query string false "End of range, YYYY-MM-DD or RFC 3339 time"
+// @Success      200  {array}    types.ActivitySession
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid activity ID or date"
+// @Failure      404  {object}   types.ErrorResponse "activity not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/sessions [get]
+func (h *Handler) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	var from, to *time.Time
+	if str := r.URL.Query().Get("from"); str != "" {
+		t, err := utils.ParseTimeParam(str, h.location, false)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		from = &t
+	}
+	if str := r.URL.Query().Get("to"); str != "" {
+		t, err := utils.ParseTimeParam(str, h.location, true)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		to = &t
+	}
+
+	// Sessions of hidden activities are hidden as well
+	if _, err := h.activityCastle.GetVisibleActivityByID(activityID, auth.GetViewerFromContext(r.Context())); err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	sessions, err := h.sessionCastle.ListSessionsByActivityID(activityID, from, to)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// If no sessions found, return an empty array
+	if len(sessions) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.ActivitySession{})
+		return
+	}
+
+	if err := h.attachPriceTiers(sessions...); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, sessions)
+}
+
+// GetSession godoc
+// @Summary      Get activity session by ID
+// @Description  Get session data by ID from the database
+// @Tags         session
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        sessionID  path int true "Session ID"
+// @Success      200  {object}   types.ActivitySession
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid ID"
+// @Failure      404  {object}   types.ErrorResponse "session not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/sessions/{sessionID} [get]
+func (h *Handler) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	activityID, sessionID, err := parseIDs(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err := h.activityCastle.GetVisibleActivityByID(activityID, auth.GetViewerFromContext(r.Context())); err != nil {
+		if err == sql.ErrNoRows {
+			utils.WriteError(w, http.StatusNotFound, fmt.Errorf("session not found"))
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	session, err := h.getActivitySession(activityID, sessionID)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	if err := h.attachPriceTiers(session); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, session)
+}
+
+// CreateSession godoc
+// @Summary      Create activity session
+// @Description  Create dated session of activity, location has to belong to the same activity
+// @Tags         session
+// @Accept       json
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        payload body types.ActivitySessionPayload true "Session data"
+// @Success      201  {object}   types.ActivitySession
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "activity not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/sessions/create [post]
+func (h *Handler) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	activityID, err := strconv.Atoi(mux.Vars(r)["activityID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid activity ID"))
+		return
+	}
+
+	payload, ok := h.parsePayload(w, r, activityID)
+	if !ok {
+		return
+	}
+
+	if !h.checkActivityOwnership(w, r, activityID) {
+		return
+	}
+
+	session := sessionFromPayload(payload)
+	session.FkActivityID = activityID
+
+	sessionID, err := h.sessionCastle.CreateSession(session)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	session.ID = int(sessionID)
+
+	if err := h.attachPriceTiers(&session); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, session)
+}
+
+// UpdateSession godoc
+// @Summary      Update activity session
+// @Description  Update session data by ID and specifying the new values
+// @Tags         session
+// @Accept       json
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        sessionID  path int true "Session ID"
+// @Param        payload body types.ActivitySessionPayload true "Session data"
+// @Success      200  {object}   types.ActivitySession
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "session not found"
+// @Failure      409  {object}   types.ErrorResponse "capacity is lower than booked seats"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/sessions/update/{sessionID} [put]
+func (h *Handler) handleUpdateSession(w http.ResponseWriter, r *http.Request) {
+	activityID, sessionID, err := parseIDs(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	payload, ok := h.parsePayload(w, r, activityID)
+	if !ok {
+		return
+	}
+
+	if !h.checkActivityOwnership(w, r, activityID) {
+		return
+	}
+
+	existing, err := h.getActivitySession(activityID, sessionID)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	if payload.Capacity < existing.BookedSeats {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("capacity can't be lower than %d already booked seats", existing.BookedSeats))
+		return
+	}
+
+	session := sessionFromPayload(payload)
+	session.ID = sessionID
+	session.FkActivityID = activityID
+	session.FkScheduleID = existing.FkScheduleID
+	session.BookedSeats = existing.BookedSeats
+
+	if err := h.sessionCastle.UpdateSession(session); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.attachPriceTiers(&session); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, session)
+}
+
+// DeleteSession godoc
+// @Summary      Delete activity session
+// @Description  Delete session by ID from database
+// @Tags         session
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        sessionID  path int true "Session ID"
+// @Success      200  {object}   types.ErrorResponse "Session with ID %d successfully deleted"
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "session not found"
+// @Failure      409  {object}   types.ErrorResponse "session has bookings"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/sessions/delete/{sessionID} [delete]
+func (h *Handler) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	activityID, sessionID, err := parseIDs(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !h.checkActivityOwnership(w, r, activityID) {
+		return
+	}
+
+	session, err := h.getActivitySession(activityID, sessionID)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	if session.BookedSeats > 0 {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("session has bookings, cancel them first"))
+		return
+	}
+
+	if err := h.sessionCastle.DeleteSession(sessionID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error deleting session: %w", err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("Session with ID %d successfully deleted", sessionID))
+}
+
+// UpdatePriceTiers godoc
+// @Summary      Set price tiers of session
+// @Description  Replaces price table of activity for this session only. Tiers have to be in currency of activity, empty list
+// @Description  makes session use tiers of its activity again
+// @Tags         session
+// @Accept       json
+// @Produce      json
+// @Param        activityID path int true "Activity ID"
+// @Param        sessionID  path int true "Session ID"
+// @Param        payload body types.PriceTiersPayload true "Price tiers"
+// @Success      200  {object}   types.ActivitySession
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "session not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /activities/{activityID}/sessions/{sessionID}/price-tiers/update [put]
+func (h *Handler) handleUpdatePriceTiers(w http.ResponseWriter, r *http.Request) {
+	activityID, sessionID, err := parseIDs(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var payload types.PriceTiersPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	if !h.checkActivityOwnership(w, r, activityID) {
+		return
+	}
+
+	session, err := h.getActivitySession(activityID, sessionID)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+
+	a, err := h.activityCastle.GetActivityByID(activityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	tiers, err := activity.NewPriceTiers(payload, a.BasePrice.Currency)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.activityCastle.SavePriceTiers(activityID, &sessionID, tiers); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := h.attachPriceTiers(session); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, session)
+}
+
+// attachPriceTiers loads price tables sessions are booked by with single query
+func (h *Handler) attachPriceTiers(sessions ...*types.ActivitySession) error {
+	ids := make([]int, 0, len(sessions))
+	for _, s := range sessions {
+		ids = append(ids, s.ID)
+	}
+
+	tiers, err := h.activityCastle.ListSessionPriceTiers(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		s.PriceTiers = tiers[s.ID]
+		if s.PriceTiers == nil {
+			s.PriceTiers = []*types.PriceTier{}
+		}
+	}
+
+	return nil
+}
+
+// parsePayload reads and validates session payload, writing error response when it is invalid
+func (h *Handler) parsePayload(w http.ResponseWriter, r *http.Request, activityID int) (types.ActivitySessionPayload, bool) {
+	var payload types.ActivitySessionPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return payload, false
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return payload, false
+	}
+
+	if payload.FkLocationID != nil {
+		location, err := h.locationCastle.GetLocationByID(*payload.FkLocationID)
+		if err != nil || location.FkActivityID != activityID {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("location %d does not belong to activity", *payload.FkLocationID))
+			return payload, false
+		}
+	}
+
+	// Session price overrides activity price, so it has to be in the same currency
+	if payload.Price != nil {
+		activity, err := h.activityCastle.GetActivityByID(activityID)
+		if err == nil && activity.BasePrice.Currency != payload.Price.Currency {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("price has to be in %s like activity price", activity.BasePrice.Currency))
+			return payload, false
+		}
+	}
+
+	return payload, true
+}
+
+// checkActivityOwnership writes error response unless user organizes the activity
+func (h *Handler) checkActivityOwnership(w http.ResponseWriter, r *http.Request, activityID int) bool {
+	organizer, err := h.userCastle.GetOrganizerByActivityID(activityID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("activity not found"))
+		return false
+	}
+
+	if !auth.CheckOwnership(r, organizer.ID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return false
+	}
+
+	return true
+}
+
+// getActivitySession returns session only when it belongs to the activity from URL
+func (h *Handler) getActivitySession(activityID, sessionID int) (*types.ActivitySession, error) {
+	session, err := h.sessionCastle.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.FkActivityID != activityID {
+		return nil, sql.ErrNoRows
+	}
+
+	return session, nil
+}
+
+func writeSessionError(w http.ResponseWriter, err error) {
+	if err == sql.ErrNoRows {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("session not found"))
+	} else {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func parseIDs(r *http.Request) (int, int, error) {
+	vars := mux.Vars(r)
+
+	activityID, err := strconv.Atoi(vars["activityID"])
+	if err != nil {
+		return 0, 0, fmt.Errorf("missing or invalid activity ID")
+	}
+
+	sessionID, err := strconv.Atoi(vars["sessionID"])
+	if err != nil {
+		return 0, 0, fmt.Errorf("missing or invalid session ID")
+	}
+
+	return activityID, sessionID, nil
+}
+
+func sessionFromPayload(payload types.ActivitySessionPayload) types.ActivitySession {
+	return types.ActivitySession{
+		StartTime:    payload.StartTime,
+		EndTime:      payload.EndTime,
+		FkLocationID: payload.FkLocationID,
+		Capacity:     payload.Capacity,
+		Language:     payload.Language,
+		Price:        payload.Price,
+	}
+}
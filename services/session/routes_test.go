@@ -0,0 +1,314 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"educations-castle/services/auth"
+	"educations-castle/types"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Activity 1 is hidden and organized by user 3, activity 2 is public and organized by user 4
+const (
+	hiddenActivityID = 1
+	publicActivityID = 2
+)
+
+func TestSessionServiceHandler(t *testing.T) {
+	start := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	payload := types.ActivitySessionPayload{
+		StartTime: start,
+		EndTime:   start.Add(2 * time.Hour),
+		Capacity:  20,
+		Language:  "lt",
+	}
+
+	t.Run("Should create session of own activity", func(t *testing.T) {
+		handler, sessionCastle := newTestHandler()
+
+		rr := serve(handler, http.MethodPost, "/activities/1/sessions/create", payload, organizer(3))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		if s := sessionCastle.sessions[3]; s == nil || s.FkActivityID != hiddenActivityID {
+			t.Errorf("expected session to be created for activity %d, got %+v", hiddenActivityID, s)
+		}
+	})
+
+	t.Run("Should let administrator create session of any activity", func(t *testing.T) {
+		handler, _ := newTestHandler()
+
+		rr := serve(handler, http.MethodPost, "/activities/1/sessions/create", payload, administrator())
+		if rr.Code != http.StatusCreated {
+			t.Errorf("expected status code %d, got %d", http.StatusCreated, rr.Code)
+		}
+	})
+
+	t.Run("Should fail to create session of other organizer's activity", func(t *testing.T) {
+		handler, sessionCastle := newTestHandler()
+
+		rr := serve(handler, http.MethodPost, "/activities/1/sessions/create", payload, organizer(4))
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+		if len(sessionCastle.sessions) != 2 {
+			t.Errorf("expected no session to be created")
+		}
+	})
+
+	t.Run("Should fail if location belongs to other activity", func(t *testing.T) {
+		handler, _ := newTestHandler()
+
+		locationID := 2
+		p := payload
+		p.FkLocationID = &locationID
+		rr := serve(handler, http.MethodPost, "/activities/1/sessions/create", p, organizer(3))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("Should fail to update session through other activity", func(t *testing.T) {
+		handler, _ := newTestHandler()
+
+		// Session 2 belongs to activity 2, user 3 owns activity 1
+		rr := serve(handler, http.MethodPut, "/activities/1/sessions/update/2", payload, organizer(3))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("Should fail to lower capacity below booked seats", func(t *testing.T) {
+		handler, _ := newTestHandler()
+
+		p := payload
+		p.Capacity = 4
+		rr := serve(handler, http.MethodPut, "/activities/1/sessions/update/1", p, organizer(3))
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("Should fail to delete session with bookings", func(t *testing.T) {
+		handler, sessionCastle := newTestHandler()
+
+		rr := serve(handler, http.MethodDelete, "/activities/1/sessions/delete/1", nil, organizer(3))
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d, got %d", http.StatusConflict, rr.Code)
+		}
+		if sessionCastle.sessions[1] == nil {
+			t.Errorf("expected session to be kept")
+		}
+	})
+
+	t.Run("Should fail to delete session of other organizer's activity", func(t *testing.T) {
+		handler, sessionCastle := newTestHandler()
+
+		rr := serve(handler, http.MethodDelete, "/activities/2/sessions/delete/2", nil, organizer(3))
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+		if sessionCastle.sessions[2] == nil {
+			t.Errorf("expected session to be kept")
+		}
+	})
+
+	t.Run("Should hide sessions of hidden activity from guests", func(t *testing.T) {
+		handler, _ := newTestHandler()
+
+		for _, path := range []string{"/activities/1/sessions", "/activities/1/sessions/1"} {
+			rr := serve(handler, http.MethodGet, path, nil, nil)
+			if rr.Code != http.StatusNotFound {
+				t.Errorf("%s: expected status code %d, got %d", path, http.StatusNotFound, rr.Code)
+			}
+		}
+	})
+
+	t.Run("Should show sessions of hidden activity to its organizer", func(t *testing.T) {
+		handler, _ := newTestHandler()
+
+		for _, path := range []string{"/activities/1/sessions", "/activities/1/sessions/1"} {
+			rr := serve(handler, http.MethodGet, path, nil, organizer(3))
+			if rr.Code != http.StatusOK {
+				t.Errorf("%s: expected status code %d, got %d", path, http.StatusOK, rr.Code)
+			}
+		}
+	})
+
+	t.Run("Should fail to get session through other activity", func(t *testing.T) {
+		handler, _ := newTestHandler()
+
+		rr := serve(handler, http.MethodGet, "/activities/2/sessions/1", nil, nil)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("Should pass date range to castle", func(t *testing.T) {
+		handler, sessionCastle := newTestHandler()
+
+		rr := serve(handler, http.MethodGet, "/activities/2/sessions?from=2025-01-01&to=2025-01-31", nil, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+		if !sessionCastle.from.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("unexpected start of range %v", sessionCastle.from)
+		}
+		if !sessionCastle.to.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)) {
+			t.Errorf("unexpected end of range %v", sessionCastle.to)
+		}
+	})
+
+	t.Run("Should fail if date range is invalid", func(t *testing.T) {
+		handler, _ := newTestHandler()
+
+		rr := serve(handler, http.MethodGet, "/activities/2/sessions?from=2025-13-01", nil, nil)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+}
+
+func newTestHandler() (*Handler, *mockSessionCastle) {
+	sessionCastle := &mockSessionCastle{sessions: map[int]*types.ActivitySession{
+		1: {ID: 1, FkActivityID: hiddenActivityID, Capacity: 20, BookedSeats: 5},
+		2: {ID: 2, FkActivityID: publicActivityID, Capacity: 20},
+	}}
+	locationCastle := &mockLocationCastle{locations: map[int]*types.Location{
+		1: {ID: 1, FkActivityID: hiddenActivityID},
+		2: {ID: 2, FkActivityID: publicActivityID},
+	}}
+
+	return NewHandler(sessionCastle, &mockActivityCastle{}, locationCastle, &mockUserCastle{}, time.UTC), sessionCastle
+}
+
+func organizer(id int) *types.Viewer {
+	return &types.Viewer{UserID: id, Role: "organizer"}
+}
+
+func administrator() *types.Viewer {
+	return &types.Viewer{UserID: 1, Role: "administrator"}
+}
+
+// serve routes request to handler as authenticated viewer, or as guest when viewer is nil
+func serve(handler *Handler, method, path string, payload interface{}, viewer *types.Viewer) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+
+	req := httptest.NewRequest(method, path, &body)
+	if viewer != nil {
+		ctx := context.WithValue(req.Context(), auth.UserKey, viewer.UserID)
+		ctx = context.WithValue(ctx, auth.RoleKey, viewer.Role)
+		req = req.WithContext(ctx)
+	}
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/activities/{activityID:[0-9]+}/sessions", handler.handleListSessions).Methods("GET")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/sessions/{sessionID:[0-9]+}", handler.handleGetSession).Methods("GET")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/sessions/create", handler.handleCreateSession).Methods("POST")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/sessions/update/{sessionID:[0-9]+}", handler.handleUpdateSession).Methods("PUT")
+	router.HandleFunc("/activities/{activityID:[0-9]+}/sessions/delete/{sessionID:[0-9]+}", handler.handleDeleteSession).Methods("DELETE")
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+type mockSessionCastle struct {
+	sessions map[int]*types.ActivitySession
+	from, to *time.Time
+}
+
+func (m *mockSessionCastle) CreateSession(s types.ActivitySession) (int64, error) {
+	s.ID = len(m.sessions) + 1
+	m.sessions[s.ID] = &s
+	return int64(s.ID), nil
+}
+
+func (m *mockSessionCastle) GetSessionByID(id int) (*types.ActivitySession, error) {
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *s
+	return &copied, nil
+}
+
+func (m *mockSessionCastle) ListSessionsByActivityID(activityID int, from, to *time.Time) ([]*types.ActivitySession, error) {
+	m.from, m.to = from, to
+
+	var sessions []*types.ActivitySession
+	for _, s := range m.sessions {
+		if s.FkActivityID == activityID {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *mockSessionCastle) UpdateSession(s types.ActivitySession) error {
+	m.sessions[s.ID] = &s
+	return nil
+}
+
+func (m *mockSessionCastle) DeleteSession(id int) error {
+	delete(m.sessions, id)
+	return nil
+}
+
+type mockActivityCastle struct {
+	types.ActivityCastle
+}
+
+func (m *mockActivityCastle) GetActivityByID(id int) (*types.Activity, error) {
+	return &types.Activity{ID: id, BasePrice: types.Money{Currency: "EUR"}}, nil
+}
+
+// GetVisibleActivityByID hides activity 1 from everyone except its organizer and administrators
+func (m *mockActivityCastle) GetVisibleActivityByID(id int, viewer types.Viewer) (*types.Activity, error) {
+	if id == hiddenActivityID && viewer.Role != "administrator" && viewer.UserID != 3 {
+		return nil, sql.ErrNoRows
+	}
+	return m.GetActivityByID(id)
+}
+
+func (m *mockActivityCastle) ListSessionPriceTiers(sessionIDs []int) (map[int][]*types.PriceTier, error) {
+	return map[int][]*types.PriceTier{}, nil
+}
+
+type mockLocationCastle struct {
+	types.LocationCastle
+	locations map[int]*types.Location
+}
+
+func (m *mockLocationCastle) GetLocationByID(id int) (*types.Location, error) {
+	l, ok := m.locations[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return l, nil
+}
+
+type mockUserCastle struct {
+	types.UserCastle
+}
+
+// GetOrganizerByActivityID returns user 3 as organizer of activity 1 and user 4 as organizer of activity 2
+func (m *mockUserCastle) GetOrganizerByActivityID(id int) (*types.Organizer, error) {
+	switch id {
+	case hiddenActivityID:
+		return &types.Organizer{ID: 3}, nil
+	case publicActivityID:
+		return &types.Organizer{ID: 4}, nil
+	}
+	return nil, sql.ErrNoRows
+}
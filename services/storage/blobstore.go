@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"educations-castle/configs"
+	"educations-castle/types"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+
+	// Objects under this prefix are readable without signature
+	PublicPrefix  = "public/"
+	PrivatePrefix = "private/"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// NewBlobStore creates blob store for the given backend using configuration values
+func NewBlobStore(backend string, cfg configs.Config) (types.BlobStore, error) {
+	switch backend {
+	case BackendLocal:
+		return NewLocalBlobStore(cfg.BlobLocalDir, cfg.BlobLocalURL, []byte(cfg.BlobSigningSecret))
+	case BackendS3:
+		return NewS3BlobStore(S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PublicURL: cfg.S3PublicURL,
+			PathStyle: cfg.S3PathStyle,
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown blob store backend '%s'", backend)
+	}
+}
+
+// IsPublicKey reports whether object can be served without signed URL
+func IsPublicKey(key string) bool {
+	return strings.HasPrefix(key, PublicPrefix)
+}
+
+// validateKey rejects empty keys and keys trying to escape their prefix
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "..") || path.Clean(key) != key {
+		return fmt.Errorf("invalid blob key '%s'", key)
+	}
+	return nil
+}
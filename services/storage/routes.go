@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"educations-castle/utils"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/gorilla/mux"
+)
+
+// Handler serves files of local blob store, S3 compatible backends serve files themselves
+type Handler struct {
+	store *LocalBlobStore
+}
+
+func NewHandler(store *LocalBlobStore) *Handler {
+	return &Handler{store: store}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/blobs/{key:.+}", h.handleGetBlob).Methods("GET")
+}
+
+// GetBlob godoc
+// @Summary      Download stored file
+// @Description  Returns file from local blob store. Files outside public/ prefix require signature and expiration from signed URL
+// @Tags         blob
+// @Param        key        path   string  true   "Blob key"
+// @Param        expires    query  int     false  "Signed URL expiration unix timestamp"
+// @Param        signature  query  string  false  "Signed URL signature"
+// @Success      200  {file}     file
+// @Failure      403  {object}   types.ErrorResponse "invalid or expired signature"
+// @Failure      404  {object}   types.ErrorResponse "file not found"
+// @Router       /blobs/{key} [get]
+func (h *Handler) handleGetBlob(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	if err := validateKey(key); err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("file not found"))
+		return
+	}
+
+	public := IsPublicKey(key)
+	if !public {
+		query := r.URL.Query()
+		if !h.store.VerifySignature(key, query.Get("expires"), query.Get("signature")) {
+			utils.WriteError(w, http.StatusForbidden, fmt.Errorf("invalid or expired signature"))
+			return
+		}
+	}
+
+	file, err := h.store.Get(r.Context(), key)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("file not found"))
+		return
+	}
+	defer file.Close()
+
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	if public {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	} else {
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
@@ -3,16 +3,19 @@ package user
 // TODO: Use sqlx
 import (
 	"database/sql"
+	"educations-castle/services/search"
 	"educations-castle/types"
+	"educations-castle/utils"
 	"fmt"
 )
 
 type Castle struct {
-	db *sql.DB
+	db    *sql.DB
+	index *search.Index
 }
 
-func NewCastle(db *sql.DB) *Castle {
-	return &Castle{db: db}
+func NewCastle(db *sql.DB, index *search.Index) *Castle {
+	return &Castle{db: db, index: index}
 }
 
 func scanRowIntoUser(rows *sql.Rows) (*types.User, error) {
@@ -253,12 +256,32 @@ func (c *Castle) CreateUser(user types.User) error {
 }
 
 func (c *Castle) DeleteUser(id int) error {
-	_, err := c.db.Exec(
+	// Packages and activities of organizer are deleted together with user, so they are collected first
+	// to be removed from index too
+	packageIDs, err := utils.QueryIDs(c.db, "SELECT id FROM package WHERE fk_Organizerid = ?", id)
+	if err != nil {
+		return err
+	}
+	activityIDs, err := utils.QueryIDs(c.db,
+		"SELECT activity.id FROM activity JOIN package ON activity.fk_Packageid = package.id WHERE package.fk_Organizerid = ?", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.Exec(
 		"DELETE FROM user WHERE id = ?", id)
 	if err != nil {
 		return err
 	}
 
+	for _, activityID := range activityIDs {
+		c.index.Remove(search.KindActivity, activityID)
+	}
+	for _, packageID := range packageIDs {
+		c.index.Remove(search.KindPackage, packageID)
+	}
+	c.index.Remove(search.KindOrganizer, id)
+
 	return nil
 }
 
@@ -271,7 +294,8 @@ func (c *Castle) UpdateUser(user types.User) error {
 		return err
 	}
 
-	return nil
+	// Organizers are suggested by username
+	return c.indexOrganizers("organizer.id = ?", user.ID)
 }
 
 func (c *Castle) CreateOrganizer(organizer types.Organizer) error {
@@ -281,7 +305,7 @@ func (c *Castle) CreateOrganizer(organizer types.Organizer) error {
 		return err
 	}
 
-	return nil
+	return c.indexOrganizers("organizer.id = ?", organizer.ID)
 }
 
 func (c *Castle) CreateAdministrator(admin types.CreateAdministratorPayload) error {
@@ -0,0 +1,36 @@
+package user
+
+import (
+	"educations-castle/services/search"
+)
+
+// Organizers are suggested by their usernames
+const indexedOrganizers = "SELECT user.id, user.username FROM organizer JOIN user ON organizer.id = user.id"
+
+// BuildSearchIndex loads all organizers into search index, castle keeps it in sync afterwards
+func (c *Castle) BuildSearchIndex() error {
+	return c.indexOrganizers("1 = 1")
+}
+
+// indexOrganizers puts organizers matching condition into search index
+func (c *Castle) indexOrganizers(condition string, params ...interface{}) error {
+	rows, err := c.db.Query(indexedOrganizers+" WHERE "+condition, params...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var username string
+		if err := rows.Scan(&id, &username); err != nil {
+			return err
+		}
+
+		c.index.Put(search.Document{Kind: search.KindOrganizer, ID: id, Title: username, Fields: []search.Field{
+			{Text: username, Weight: 1},
+		}})
+	}
+
+	return rows.Err()
+}
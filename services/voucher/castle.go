@@ -0,0 +1,178 @@
+package voucher
+
+import (
+	"database/sql"
+	"educations-castle/types"
+	"time"
+)
+
+type Castle struct {
+	db *sql.DB
+}
+
+func NewCastle(db *sql.DB) *Castle {
+	return &Castle{db: db}
+}
+
+func scanRowIntoVoucher(rows *sql.Rows) (*types.GiftVoucher, error) {
+	v := new(types.GiftVoucher)
+
+	err := rows.Scan(
+		&v.ID,
+		&v.Code,
+		&v.FkUserID,
+		&v.FkPackageID,
+		&v.Amount.Amount,
+		&v.Balance.Amount,
+		&v.Amount.Currency,
+		&v.RecipientName,
+		&v.RecipientEmail,
+		&v.Message,
+		&v.Status,
+		&v.ExpiresAt,
+		&v.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	v.Balance.Currency = v.Amount.Currency
+
+	return v, nil
+}
+
+// CreateVoucher stores voucher waiting for payment, its whole amount is left to redeem
+func (c *Castle) CreateVoucher(v types.GiftVoucher) (int64, error) {
+	result, err := c.db.Exec(
+		`INSERT INTO giftvoucher (code, fk_Userid, fk_Packageid, amount, balance, currency, recipientName, recipientEmail,
+			message, status, expiresAt)
+		VALUES (?,?,?,?,?,?,?,?,?,?,?)`,
+		v.Code, v.FkUserID, v.FkPackageID, v.Amount.Amount, v.Amount.Amount, v.Amount.Currency, v.RecipientName,
+		v.RecipientEmail, v.Message, types.VoucherPending, v.ExpiresAt.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (c *Castle) GetVoucherByID(id int) (*types.GiftVoucher, error) {
+	return c.getVoucher("SELECT * FROM giftvoucher WHERE id = ?", id)
+}
+
+func (c *Castle) GetVoucherByCode(code string) (*types.GiftVoucher, error) {
+	return c.getVoucher("SELECT * FROM giftvoucher WHERE code = ?", code)
+}
+
+func (c *Castle) getVoucher(query string, params ...interface{}) (*types.GiftVoucher, error) {
+	vouchers, err := c.listVouchers(query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vouchers) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return vouchers[0], nil
+}
+
+// ListVouchersByUserID returns vouchers user bought, latest first
+func (c *Castle) ListVouchersByUserID(userID int) ([]*types.GiftVoucher, error) {
+	return c.listVouchers("SELECT * FROM giftvoucher WHERE fk_Userid = ? ORDER BY createdAt DESC, id DESC", userID)
+}
+
+func (c *Castle) listVouchers(query string, params ...interface{}) ([]*types.GiftVoucher, error) {
+	rows, err := c.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vouchers []*types.GiftVoucher
+
+	for rows.Next() {
+		v, err := scanRowIntoVoucher(rows)
+		if err != nil {
+			return nil, err
+		}
+		vouchers = append(vouchers, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return vouchers, nil
+}
+
+// ListVoucherRedemptions returns bookings voucher was redeemed against, in order they were made
+func (c *Castle) ListVoucherRedemptions(voucherID int) ([]*types.VoucherRedemption, error) {
+	rows, err := c.db.Query(
+		`SELECT booking.id, voucherredemption.amount, voucherredemption.restoredAmount, giftvoucher.currency,
+			booking.status, voucherredemption.createdAt
+		FROM voucherredemption
+		JOIN booking ON booking.id = voucherredemption.fk_Bookingid
+		JOIN giftvoucher ON giftvoucher.id = voucherredemption.fk_GiftVoucherid
+		WHERE voucherredemption.fk_GiftVoucherid = ?
+		ORDER BY voucherredemption.createdAt, booking.id`, voucherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var redemptions []*types.VoucherRedemption
+
+	for rows.Next() {
+		r := new(types.VoucherRedemption)
+		err := rows.Scan(&r.BookingID, &r.Amount.Amount, &r.RestoredAmount.Amount, &r.Amount.Currency, &r.BookingStatus, &r.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		r.RestoredAmount.Currency = r.Amount.Currency
+		redemptions = append(redemptions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return redemptions, nil
+}
+
+// GetVoucherLiability sums paid vouchers by currency. Balance of vouchers valid at given time is outstanding,
+// balance of the others has expired
+func (c *Castle) GetVoucherLiability(at time.Time) ([]*types.VoucherLiability, error) {
+	rows, err := c.db.Query(
+		`SELECT currency, COALESCE(SUM(expiresAt > ? AND balance > 0), 0), COALESCE(SUM(IF(expiresAt > ?, balance, 0)), 0),
+			COALESCE(SUM(amount), 0), COALESCE(SUM(amount - balance), 0), COALESCE(SUM(IF(expiresAt > ?, 0, balance)), 0)
+		FROM giftvoucher
+		WHERE status = ?
+		GROUP BY currency
+		ORDER BY currency`, at.UTC(), at.UTC(), at.UTC(), types.VoucherActive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var liabilities []*types.VoucherLiability
+
+	for rows.Next() {
+		l := new(types.VoucherLiability)
+		err := rows.Scan(&l.Currency, &l.Vouchers, &l.Outstanding.Amount, &l.Sold.Amount, &l.Redeemed.Amount, &l.Expired.Amount)
+		if err != nil {
+			return nil, err
+		}
+		l.Outstanding.Currency = l.Currency
+		l.Sold.Currency = l.Currency
+		l.Redeemed.Currency = l.Currency
+		l.Expired.Currency = l.Currency
+		liabilities = append(liabilities, l)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return liabilities, nil
+}
@@ -0,0 +1,68 @@
+package voucher
+
+import (
+	"educations-castle/types"
+	"html/template"
+	"io"
+)
+
+// Voucher is printed from browser, page fits A5 sheet in landscape
+var printTemplate = template.Must(template.New("voucher").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Gift voucher {{.Code}}</title>
+<style>
+	@page { size: A5 landscape; margin: 0; }
+	body { margin: 0; font-family: Georgia, serif; color: #3b2a14; }
+	.voucher { box-sizing: border-box; width: 210mm; height: 148mm; padding: 14mm; border: 3mm solid #d89a2b; background: #fdf6e7; }
+	h1 { margin: 0 0 6mm; font-size: 26pt; letter-spacing: 1pt; }
+	.amount { font-size: 34pt; font-weight: bold; color: #b5651d; }
+	.package { font-size: 14pt; margin-top: 2mm; }
+	.message { margin: 8mm 0; font-size: 13pt; font-style: italic; white-space: pre-line; }
+	.code { font-family: "Courier New", monospace; font-size: 20pt; letter-spacing: 2pt; border: 1px dashed #3b2a14; display: inline-block; padding: 2mm 4mm; }
+	.details { margin-top: 6mm; font-size: 10pt; }
+</style>
+</head>
+<body>
+<div class="voucher">
+	<h1>Gift voucher for {{.RecipientName}}</h1>
+	<div class="amount">{{.Amount}}</div>
+	{{if .Package}}<div class="package">for {{.Package}} educations</div>{{end}}
+	{{if .Message}}<div class="message">{{.Message}}</div>{{end}}
+	<div class="code">{{.Code}}</div>
+	<div class="details">
+		Enter the code when booking to pay with voucher, it can be used for several bookings until its value runs out.<br>
+		Balance left: {{.Balance}}. Valid until {{.ExpiresAt}}.
+	</div>
+</div>
+</body>
+</html>
+`))
+
+type printData struct {
+	Code          string
+	RecipientName string
+	Amount        string
+	Balance       string
+	Package       string
+	Message       string
+	ExpiresAt     string
+}
+
+// Print writes printable HTML document of voucher. Package name is empty for vouchers of fixed amount
+func Print(w io.Writer, v *types.GiftVoucher, packageName string) error {
+	data := printData{
+		Code:          v.Code,
+		RecipientName: v.RecipientName,
+		Amount:        v.Amount.String(),
+		Balance:       v.Balance.String(),
+		Package:       packageName,
+		ExpiresAt:     v.ExpiresAt.UTC().Format("2006-01-02"),
+	}
+	if v.Message != nil {
+		data.Message = *v.Message
+	}
+
+	return printTemplate.Execute(w, data)
+}
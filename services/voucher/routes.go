@@ -0,0 +1,343 @@
+package voucher
+
+import (
+	"bytes"
+	"database/sql"
+	"educations-castle/services/auth"
+	"educations-castle/services/payment"
+	"educations-castle/types"
+	"educations-castle/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	voucherCastle  types.VoucherCastle
+	activityCastle types.ActivityCastle
+	userCastle     types.UserCastle
+	payments       *payment.Gateway
+	validity       time.Duration
+}
+
+func NewHandler(voucherCastle types.VoucherCastle, activityCastle types.ActivityCastle, userCastle types.UserCastle,
+	payments *payment.Gateway, validity time.Duration) *Handler {
+	return &Handler{
+		voucherCastle:  voucherCastle,
+		activityCastle: activityCastle,
+		userCastle:     userCastle,
+		payments:       payments,
+		validity:       validity}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/vouchers/purchase", auth.WithJWTAuth(h.handlePurchaseVoucher, h.userCastle, "administrator", "organizer", "user")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/vouchers/my", auth.WithJWTAuth(h.handleListMyVouchers, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/vouchers/liability", auth.WithJWTAuth(h.handleGetLiability, h.userCastle, "administrator")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/vouchers/balance/{code}", auth.WithJWTAuth(h.handleGetBalance, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/vouchers/{voucherID:[0-9]+}", auth.WithJWTAuth(h.handleGetVoucher, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+	router.HandleFunc("/vouchers/{voucherID:[0-9]+}/checkout", auth.WithJWTAuth(h.handleCheckout, h.userCastle, "administrator", "organizer", "user")).Methods("POST", "OPTIONS")
+	router.HandleFunc("/vouchers/{voucherID:[0-9]+}/print", auth.WithJWTAuth(h.handlePrintVoucher, h.userCastle, "administrator", "organizer", "user")).Methods("GET", "OPTIONS")
+}
+
+// PurchaseVoucher godoc
+// @Summary      Buy gift voucher
+// @Description  Creates gift voucher for fixed amount or for package, which costs package price and can be redeemed only
+// @Description  against its activities. Voucher is redeemable once paid through its checkout
+// @Tags         voucher
+// @Accept       json
+// @Produce      json
+// @Param        payload body types.GiftVoucherPayload true "Voucher data"
+// @Success      201  {object}   types.GiftVoucher
+// @Failure      400  {object}   types.ErrorResponse "Invalid payload or package not found"
+// @Failure      401  {object}   types.ErrorResponse "unauthorized"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /vouchers/purchase [post]
+func (h *Handler) handlePurchaseVoucher(w http.ResponseWriter, r *http.Request) {
+	var payload types.GiftVoucherPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload %v", errors))
+		return
+	}
+
+	v := types.GiftVoucher{
+		FkUserID:       auth.GetUserIDFromContext(r.Context()),
+		FkPackageID:    payload.PackageID,
+		RecipientName:  payload.RecipientName,
+		RecipientEmail: payload.RecipientEmail,
+		Message:        payload.Message,
+		ExpiresAt:      time.Now().Add(h.validity),
+	}
+
+	if payload.PackageID != nil {
+		p, err := h.activityCastle.GetPackageByID(*payload.PackageID)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("package %d not found", *payload.PackageID))
+			return
+		}
+		v.Amount = p.Price
+	} else {
+		v.Amount = *payload.Amount
+	}
+	if v.Amount.Amount <= 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("voucher amount has to be above zero"))
+		return
+	}
+
+	code, err := NewCode()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	v.Code = code
+
+	voucherID, err := h.voucherCastle.CreateVoucher(v)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	created, err := h.voucherCastle.GetVoucherByID(int(voucherID))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, created)
+}
+
+// CheckoutVoucher godoc
+// @Summary      Pay for gift voucher
+// @Description  Starts payment of voucher and returns payment with URL of provider checkout. Voucher becomes redeemable
+// @Description  once provider reports successful payment. Repeated calls return the same pending payment
+// @Tags         voucher
+// @Produce      json
+// @Param        voucherID path int true "Voucher ID"
+// @Success      201  {object}   types.Payment
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid voucher ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "voucher not found"
+// @Failure      409  {object}   types.ErrorResponse "voucher is already paid"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /vouchers/{voucherID}/checkout [post]
+func (h *Handler) handleCheckout(w http.ResponseWriter, r *http.Request) {
+	v, ok := h.getOwnVoucher(w, r)
+	if !ok {
+		return
+	}
+
+	if v.Status != types.VoucherPending {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("voucher is %s and can't be paid", v.Status))
+		return
+	}
+
+	p, err := h.payments.CheckoutVoucher(r.Context(), v, fmt.Sprintf("Gift voucher for %s, %s", v.RecipientName, v.Amount))
+	if err != nil {
+		if errors.Is(err, payment.ErrAlreadyPaid) {
+			utils.WriteError(w, http.StatusConflict, err)
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, p)
+}
+
+// ListMyVouchers godoc
+// @Summary      List my gift vouchers
+// @Description  Returns gift vouchers bought by the logged in user, latest first
+// @Tags         voucher
+// @Produce      json
+// @Success      200  {array}    types.GiftVoucher
+// @Failure      401  {object}   types.ErrorResponse "unauthorized"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /vouchers/my [get]
+func (h *Handler) handleListMyVouchers(w http.ResponseWriter, r *http.Request) {
+	vouchers, err := h.voucherCastle.ListVouchersByUserID(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if len(vouchers) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.GiftVoucher{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, vouchers)
+}
+
+// GetVoucher godoc
+// @Summary      Get gift voucher
+// @Description  Returns gift voucher together with bookings it was redeemed against. Vouchers can be seen by users who bought them
+// @Tags         voucher
+// @Produce      json
+// @Param        voucherID path int true "Voucher ID"
+// @Success      200  {object}   types.GiftVoucher
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid voucher ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "voucher not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /vouchers/{voucherID} [get]
+func (h *Handler) handleGetVoucher(w http.ResponseWriter, r *http.Request) {
+	v, ok := h.getOwnVoucher(w, r)
+	if !ok {
+		return
+	}
+
+	redemptions, err := h.voucherCastle.ListVoucherRedemptions(v.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	v.Redemptions = redemptions
+	if v.Redemptions == nil {
+		v.Redemptions = []*types.VoucherRedemption{}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, v)
+}
+
+// GetVoucherBalance godoc
+// @Summary      Check gift voucher balance
+// @Description  Returns balance of gift voucher with redemption code, so recipients can check what's left of it before booking.
+// @Description  Buyer, recipient and message of voucher aren't shown
+// @Tags         voucher
+// @Produce      json
+// @Param        code path string true "Redemption code"
+// @Success      200  {object}   types.VoucherBalance
+// @Failure      401  {object}   types.ErrorResponse "unauthorized"
+// @Failure      404  {object}   types.ErrorResponse "voucher not found"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /vouchers/balance/{code} [get]
+func (h *Handler) handleGetBalance(w http.ResponseWriter, r *http.Request) {
+	v, err := h.voucherCastle.GetVoucherByCode(NormalizeCode(mux.Vars(r)["code"]))
+	if err != nil || v.Status == types.VoucherPending {
+		writeVoucherError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, balanceOf(v))
+}
+
+// balanceOf returns part of voucher which is shown to anyone knowing its code
+func balanceOf(v *types.GiftVoucher) types.VoucherBalance {
+	return types.VoucherBalance{
+		Code:        v.Code,
+		FkPackageID: v.FkPackageID,
+		Balance:     v.Balance,
+		Status:      v.Status,
+		ExpiresAt:   v.ExpiresAt,
+	}
+}
+
+// PrintVoucher godoc
+// @Summary      Print gift voucher
+// @Description  Returns printable HTML document of paid gift voucher, to be handed over to its recipient
+// @Tags         voucher
+// @Produce      html
+// @Param        voucherID path int true "Voucher ID"
+// @Success      200  {file}     file
+// @Failure      400  {object}   types.ErrorResponse "missing or invalid voucher ID"
+// @Failure      401  {object}   types.ErrorResponse "permission denied"
+// @Failure      404  {object}   types.ErrorResponse "voucher not found"
+// @Failure      409  {object}   types.ErrorResponse "voucher is not paid"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /vouchers/{voucherID}/print [get]
+func (h *Handler) handlePrintVoucher(w http.ResponseWriter, r *http.Request) {
+	v, ok := h.getOwnVoucher(w, r)
+	if !ok {
+		return
+	}
+
+	if v.Status != types.VoucherActive {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("voucher is %s and can't be printed", v.Status))
+		return
+	}
+
+	packageName := ""
+	if v.FkPackageID != nil {
+		p, err := h.activityCastle.GetPackageByID(*v.FkPackageID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		packageName = p.Name
+	}
+
+	var document bytes.Buffer
+	if err := Print(&document, v, packageName); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(document.Bytes())
+}
+
+// GetVoucherLiability godoc
+// @Summary      Get outstanding gift voucher liability
+// @Description  Sums paid gift vouchers by currency: balance still redeemable, amount sold and redeemed and balance left on expired vouchers
+// @Tags         voucher
+// @Produce      json
+// @Success      200  {array}    types.VoucherLiability
+// @Failure      401  {object}   types.ErrorResponse "unauthorized"
+// @Failure      500  {object}   types.ErrorResponse "Internal server error"
+// @Router       /vouchers/liability [get]
+func (h *Handler) handleGetLiability(w http.ResponseWriter, r *http.Request) {
+	liabilities, err := h.voucherCastle.GetVoucherLiability(time.Now())
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if len(liabilities) == 0 {
+		utils.WriteJSON(w, http.StatusOK, []types.VoucherLiability{})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, liabilities)
+}
+
+// getOwnVoucher returns voucher from URL, writing error response unless user bought it
+func (h *Handler) getOwnVoucher(w http.ResponseWriter, r *http.Request) (*types.GiftVoucher, bool) {
+	voucherID, err := strconv.Atoi(mux.Vars(r)["voucherID"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("missing or invalid voucher ID"))
+		return nil, false
+	}
+
+	v, err := h.voucherCastle.GetVoucherByID(voucherID)
+	if err != nil {
+		writeVoucherError(w, err)
+		return nil, false
+	}
+
+	if !auth.CheckOwnership(r, v.FkUserID) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("permission denied"))
+		return nil, false
+	}
+
+	return v, true
+}
+
+func writeVoucherError(w http.ResponseWriter, err error) {
+	if err == nil || err == sql.ErrNoRows {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("voucher not found"))
+	} else {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,79 @@
+package voucher
+
+import (
+	"crypto/rand"
+	"educations-castle/types"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidVoucher = errors.New("invalid gift voucher code")
+	ErrExpired        = errors.New("gift voucher has expired")
+	ErrEmpty          = errors.New("gift voucher has no balance left")
+	ErrWrongPackage   = errors.New("gift voucher is for activities of another package")
+	ErrWrongCurrency  = errors.New("gift voucher is in another currency")
+)
+
+// Codes leave out letters and digits which are easy to mix up when typed from printed voucher
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const (
+	codeGroups    = 3
+	codeGroupSize = 4
+)
+
+// NewCode returns random redemption code of dash separated groups, e.g. K7QM-3XPA-9WTD
+func NewCode() (string, error) {
+	code := make([]byte, 0, codeGroups*codeGroupSize)
+	for range codeGroups * codeGroupSize {
+		i, err := rand.Int(rand.Reader, big.NewInt(int64(len(codeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code = append(code, codeAlphabet[i.Int64()])
+	}
+
+	return NormalizeCode(string(code)), nil
+}
+
+// NormalizeCode uppercases code typed by user and puts dashes between its groups, whatever separators were used
+func NormalizeCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	var groups []string
+	for len(code) > codeGroupSize {
+		groups = append(groups, code[:codeGroupSize])
+		code = code[codeGroupSize:]
+	}
+
+	return strings.Join(append(groups, code), "-")
+}
+
+// Check returns error unless voucher can be redeemed against booking of activity paid in currency
+func Check(v *types.GiftVoucher, activity *types.Activity, currency string, at time.Time) error {
+	switch {
+	case v.Status != types.VoucherActive:
+		return ErrInvalidVoucher
+	case !v.ExpiresAt.After(at):
+		return ErrExpired
+	case v.Balance.Amount <= 0:
+		return ErrEmpty
+	case v.FkPackageID != nil && *v.FkPackageID != activity.FkPackageID:
+		return ErrWrongPackage
+	case v.Balance.Currency != currency:
+		return ErrWrongCurrency
+	}
+
+	return nil
+}
+
+// Redeemable returns part of voucher balance taken off price, the whole price when balance covers it
+func Redeemable(balance, price types.Money) types.Money {
+	if balance.Amount > price.Amount {
+		return price
+	}
+	return balance
+}
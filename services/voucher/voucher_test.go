@@ -0,0 +1,126 @@
+package voucher
+
+import (
+	"bytes"
+	"educations-castle/types"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCode(t *testing.T) {
+	t.Run("Should generate codes of unambiguous characters", func(t *testing.T) {
+		pattern := regexp.MustCompile(`^[A-HJ-NP-Z2-9]{4}-[A-HJ-NP-Z2-9]{4}-[A-HJ-NP-Z2-9]{4}$`)
+		for range 100 {
+			code, err := NewCode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !pattern.MatchString(code) {
+				t.Errorf("unexpected code %s", code)
+			}
+		}
+	})
+
+	t.Run("Should normalize typed code", func(t *testing.T) {
+		for _, typed := range []string{"k7qm-3xpa-9wtd", "K7QM 3XPA 9WTD", "k7qm3xpa9wtd", " K7QM-3XPA-9WTD "} {
+			if code := NormalizeCode(typed); code != "K7QM-3XPA-9WTD" {
+				t.Errorf("%s: expected K7QM-3XPA-9WTD, got %s", typed, code)
+			}
+		}
+	})
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2024, 12, 17, 12, 0, 0, 0, time.UTC)
+	packageID := 1
+	activity := &types.Activity{ID: 1, FkPackageID: 1}
+
+	valid := func() *types.GiftVoucher {
+		return &types.GiftVoucher{
+			Status:    types.VoucherActive,
+			Balance:   types.Money{Amount: 5000, Currency: "EUR"},
+			ExpiresAt: now.AddDate(1, 0, 0),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		change   func(v *types.GiftVoucher)
+		currency string
+		expected error
+	}{
+		{"Should accept active voucher", func(v *types.GiftVoucher) {}, "EUR", nil},
+		{"Should accept voucher of package", func(v *types.GiftVoucher) { v.FkPackageID = &packageID }, "EUR", nil},
+		{"Should reject unpaid voucher", func(v *types.GiftVoucher) { v.Status = types.VoucherPending }, "EUR", ErrInvalidVoucher},
+		{"Should reject expired voucher", func(v *types.GiftVoucher) { v.ExpiresAt = now }, "EUR", ErrExpired},
+		{"Should reject used up voucher", func(v *types.GiftVoucher) { v.Balance.Amount = 0 }, "EUR", ErrEmpty},
+		{"Should reject voucher of another package", func(v *types.GiftVoucher) { other := 2; v.FkPackageID = &other }, "EUR", ErrWrongPackage},
+		{"Should reject voucher in another currency", func(v *types.GiftVoucher) {}, "USD", ErrWrongCurrency},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := valid()
+			test.change(v)
+			if err := Check(v, activity, test.currency, now); err != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestRedeemable(t *testing.T) {
+	balance := types.Money{Amount: 3000, Currency: "EUR"}
+
+	if part := Redeemable(balance, types.Money{Amount: 1999, Currency: "EUR"}); part.Amount != 1999 {
+		t.Errorf("expected whole price 1999, got %d", part.Amount)
+	}
+	if part := Redeemable(balance, types.Money{Amount: 4500, Currency: "EUR"}); part.Amount != 3000 {
+		t.Errorf("expected whole balance 3000, got %d", part.Amount)
+	}
+}
+
+func TestBalanceOf(t *testing.T) {
+	email, message := "ona@example.com", "Happy birthday!"
+	v := &types.GiftVoucher{ID: 1, Code: "K7QM-3XPA-9WTD", FkUserID: 5, Balance: types.Money{Amount: 2500, Currency: "EUR"},
+		RecipientName: "Ona", RecipientEmail: &email, Message: &message, Status: types.VoucherActive}
+
+	data, err := json.Marshal(balanceOf(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, private := range []string{"fk_Userid", "Ona", email, message} {
+		if strings.Contains(string(data), private) {
+			t.Errorf("expected %q not to be shown, got %s", private, data)
+		}
+	}
+	if !strings.Contains(string(data), `"code":"K7QM-3XPA-9WTD"`) || !strings.Contains(string(data), `"amount":2500`) {
+		t.Errorf("expected code and balance, got %s", data)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	message := "<b>Happy birthday!</b>"
+	v := &types.GiftVoucher{
+		Code:          "K7QM-3XPA-9WTD",
+		RecipientName: "Ona",
+		Amount:        types.Money{Amount: 5000, Currency: "EUR"},
+		Balance:       types.Money{Amount: 5000, Currency: "EUR"},
+		Message:       &message,
+		ExpiresAt:     time.Date(2025, 12, 17, 0, 0, 0, 0, time.UTC),
+	}
+
+	var document bytes.Buffer
+	if err := Print(&document, v, "Amber"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"K7QM-3XPA-9WTD", "50.00 EUR", "for Amber educations", "2025-12-17", "&lt;b&gt;Happy birthday!&lt;/b&gt;"} {
+		if !strings.Contains(document.String(), expected) {
+			t.Errorf("document doesn't contain %s", expected)
+		}
+	}
+}
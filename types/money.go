@@ -0,0 +1,117 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money is amount in minor units of ISO 4217 currency, such as cents of euro. Amounts are exact,
+// so they are never rounded on the way to database or JSON
+// swagger:model
+type Money struct {
+	Amount   int64  `json:"amount" validate:"min=0" example:"2050"`
+	Currency string `json:"currency" validate:"required,iso4217" example:"EUR"`
+}
+
+// Currencies which don't have two minor unit digits, https://www.six-group.com/en/products-services/financial-information/data-standards.html
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// CurrencyExponent returns number of minor unit digits of currency
+func CurrencyExponent(currency string) int {
+	if exponent, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// ParseMoney parses decimal amount such as "20.50" into minor units of currency without going through floats
+func ParseMoney(value, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+	exponent := CurrencyExponent(currency)
+
+	whole, fraction, hasFraction := strings.Cut(strings.TrimSpace(value), ".")
+	if whole == "" || len(fraction) > exponent || (hasFraction && fraction == "") {
+		return Money{}, fmt.Errorf("invalid amount '%s' of %s", value, currency)
+	}
+
+	amount, err := strconv.ParseUint(whole+fraction+strings.Repeat("0", exponent-len(fraction)), 10, 63)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount '%s' of %s", value, currency)
+	}
+
+	return Money{Amount: int64(amount), Currency: currency}, nil
+}
+
+// String formats money as decimal amount followed by currency, e.g. "20.50 EUR"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// Decimal formats amount with all minor unit digits of currency, e.g. "20.50"
+func (m Money) Decimal() string {
+	exponent := CurrencyExponent(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	digits := fmt.Sprintf("%0*d", exponent+1, amount)
+	if exponent == 0 {
+		return sign + digits
+	}
+
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Add sums money of the same currency
+func (m Money) Add(other Money) Money {
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}
+}
+
+func (m Money) Sub(other Money) Money {
+	return Money{Amount: m.Amount - other.Amount, Currency: m.Currency}
+}
+
+// Mul returns price of n units
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// Percent returns percent of money, rounded down to whole minor units
+func (m Money) Percent(percent int) Money {
+	return Money{Amount: m.Amount * int64(percent) / 100, Currency: m.Currency}
+}
+
+// Div splits money into n parts, rounded half up to whole minor units
+func (m Money) Div(n int) Money {
+	if n <= 0 {
+		return Money{Currency: m.Currency}
+	}
+	return Money{Amount: (2*m.Amount + int64(n)) / (2 * int64(n)), Currency: m.Currency}
+}
+
+// NullableMoney builds optional price from nullable amount and currency columns
+func NullableMoney(amount *int64, currency *string) *Money {
+	if amount == nil || currency == nil {
+		return nil
+	}
+	return &Money{Amount: *amount, Currency: *currency}
+}
+
+// MoneyColumns splits optional price into nullable amount and currency columns
+func MoneyColumns(m *Money) (*int64, *string) {
+	if m == nil {
+		return nil, nil
+	}
+	return &m.Amount, &m.Currency
+}
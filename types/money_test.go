@@ -0,0 +1,63 @@
+package types
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency string
+		expected int64
+		valid    bool
+	}{
+		{"20.50", "EUR", 2050, true},
+		{"20.5", "eur", 2050, true},
+		{"20", "EUR", 2000, true},
+		{"20.499", "EUR", 0, false},
+		{"1500", "JPY", 1500, true},
+		{"1.5", "JPY", 0, false},
+		{"1.005", "KWD", 1005, true},
+		{"-1", "EUR", 0, false},
+		{"1.", "EUR", 0, false},
+		{"abc", "EUR", 0, false},
+	}
+
+	for _, test := range tests {
+		money, err := ParseMoney(test.value, test.currency)
+		if (err == nil) != test.valid {
+			t.Errorf("%s %s: unexpected result %v", test.value, test.currency, err)
+			continue
+		}
+		if test.valid && money.Amount != test.expected {
+			t.Errorf("%s %s: expected %d, got %d", test.value, test.currency, test.expected, money.Amount)
+		}
+	}
+}
+
+func TestMoney(t *testing.T) {
+	t.Run("Should format all minor unit digits", func(t *testing.T) {
+		for expected, money := range map[string]Money{
+			"20.50 EUR": {Amount: 2050, Currency: "EUR"},
+			"0.05 EUR":  {Amount: 5, Currency: "EUR"},
+			"1500 JPY":  {Amount: 1500, Currency: "JPY"},
+			"1.005 KWD": {Amount: 1005, Currency: "KWD"},
+		} {
+			if money.String() != expected {
+				t.Errorf("expected %s, got %s", expected, money.String())
+			}
+		}
+	})
+
+	t.Run("Should round percent down and parts half up", func(t *testing.T) {
+		price := Money{Amount: 1999, Currency: "EUR"}
+
+		if refund := price.Percent(50); refund.Amount != 999 {
+			t.Errorf("expected 999, got %d", refund.Amount)
+		}
+		if part := price.Div(2); part.Amount != 1000 {
+			t.Errorf("expected 1000, got %d", part.Amount)
+		}
+		if part := price.Div(0); part.Amount != 0 {
+			t.Errorf("expected 0, got %d", part.Amount)
+		}
+	})
+}
@@ -1,6 +1,9 @@
 package types
 
 import (
+	"context"
+	"io"
+	"net/http"
 	"time"
 )
 
@@ -10,31 +13,107 @@ type Activity struct {
 	ID            int       `json:"id" exapmle:"1"`
 	Name          string    `json:"name" exapmle:"Amber history"`
 	Description   string    `json:"description" exapmle:"Education about amber"`
-	BasePrice     float32   `json:"basePrice" exapmle:"20.50"`
+	BasePrice     Money     `json:"basePrice"`
 	CreationDate  time.Time `json:"creationDate" exapmle:"2024-10-08 14:23:45.6789013 +0000UTC"`
 	Hidden        bool      `json:"hidden" example:"false"`
 	Verified      bool      `json:"verified" exapmle:"true"`
 	Category      string    `json:"category" exapmle:"Education"`
 	AverageRating float32   `json:"averageRating" exapmle:"3.5"`
 	FkPackageID   int       `json:"fk_Packageid" exapmle:"1"`
+
+	ModerationStatus string `json:"moderationStatus" example:"approved"`
+
+	Images     []*AttachedImage `json:"images"`
+	PriceTiers []*PriceTier     `json:"priceTiers"`
+}
+
+// ActivitySession represents dated occurrence of activity, price overrides activity base price when set
+// swagger:model
+type ActivitySession struct {
+	ID             int       `json:"id" example:"1"`
+	FkActivityID   int       `json:"fk_Activityid" example:"1"`
+	StartTime      time.Time `json:"startTime" example:"2025-01-15T10:00:00Z"`
+	EndTime        time.Time `json:"endTime" example:"2025-01-15T12:00:00Z"`
+	FkLocationID   *int      `json:"fk_Locationid" example:"1"`
+	Capacity       int       `json:"capacity" example:"20"`
+	Language       string    `json:"language" example:"lt"`
+	Price          *Money    `json:"price"`
+	FkScheduleID   *int      `json:"fk_Scheduleid" example:"1"`
+	Cancelled      bool      `json:"cancelled" example:"false"`
+	BookedSeats    int       `json:"bookedSeats" example:"12"`
+	WaitlistLength int       `json:"waitlistLength" example:"3"`
+
+	PriceTiers []*PriceTier `json:"priceTiers"`
+}
+
+// PriceTier is named price of activity such as adult, child, student, senior or family ticket. Tiers of session
+// replace tiers of its activity. One unit of tier takes Seats seats, ages and eligibility tell who the tier is for
+// swagger:model
+type PriceTier struct {
+	ID                  int     `json:"id" example:"1"`
+	FkActivityID        int     `json:"fk_Activityid" example:"1"`
+	FkActivitySessionID *int    `json:"fk_ActivitySessionid" example:"1"`
+	Name                string  `json:"name" example:"Student"`
+	Price               Money   `json:"price"`
+	Seats               int     `json:"seats" example:"1"`
+	MinAge              *int    `json:"minAge" example:"7"`
+	MaxAge              *int    `json:"maxAge" example:"17"`
+	Eligibility         *string `json:"eligibility" example:"Valid student card"`
+}
+
+// Schedule represents recurring sessions of activity. StartTime is wall clock time of the first
+// occurrence in Timezone, ExDates are excluded occurrences given as wall time or whole dates
+// swagger:model
+type Schedule struct {
+	ID              int        `json:"id" example:"1"`
+	FkActivityID    int        `json:"fk_Activityid" example:"1"`
+	RRule           string     `json:"rrule" example:"FREQ=WEEKLY;BYDAY=TU;COUNT=50"`
+	StartTime       string     `json:"startTime" example:"2025-01-14T10:00:00"`
+	Timezone        string     `json:"timezone" example:"Europe/Vilnius"`
+	DurationMinutes int        `json:"durationMinutes" example:"90"`
+	FkLocationID    *int       `json:"fk_Locationid" example:"1"`
+	Capacity        int        `json:"capacity" example:"20"`
+	Language        string     `json:"language" example:"lt"`
+	Price           *Money     `json:"price"`
+	ExDates         []string   `json:"exDates" example:"2025-02-18"`
+	ExpandedUntil   *time.Time `json:"expandedUntil" example:"2025-04-14T00:00:00Z"`
+	CreatedAt       time.Time  `json:"createdAt" example:"2025-01-01T00:00:00Z"`
 }
 
 // Activity represents package created by organizer which can be combined of many different activities
 // swagger:model
 type Package struct {
-	ID            int     `json:"id" exapmle:"1"`
-	Name          string  `json:"name" example:"Amber"`
-	Description   string  `json:"description" exapmle:"All educations about amber"`
-	Price         float32 `json:"price" exapmle:"100.20"`
-	FkOrganizerID int     `json:"fk_Organizerid" exapmle:"1"`
+	ID            int    `json:"id" exapmle:"1"`
+	Name          string `json:"name" example:"Amber"`
+	Description   string `json:"description" exapmle:"All educations about amber"`
+	Price         Money  `json:"price"`
+	FkOrganizerID int    `json:"fk_Organizerid" exapmle:"1"`
+
+	Images []*AttachedImage `json:"images"`
 }
 
+// Location represents place where activity takes place
+// swagger:model
 type Location struct {
-	ID           int     `json:"id" exapmle:"1"`
-	Address      string  `json:"address" exapmle:"Kaunas city"`
-	Longitude    float64 `json:"longitude" exapmle:"50.215458"`
-	Latitude     float64 `json:"latitude" exapmle:"50.459414"`
-	FkActivityID int     `json:"fk_Activityid" exapmle:"1"`
+	ID             int      `json:"id" exapmle:"1"`
+	Address        string   `json:"address" exapmle:"Kaunas city"`
+	Longitude      *float64 `json:"longitude" exapmle:"50.215458"`
+	Latitude       *float64 `json:"latitude" exapmle:"50.459414"`
+	FkActivityID   int      `json:"fk_Activityid" exapmle:"1"`
+	Confidence     float64  `json:"confidence" example:"0.85"`
+	Provider       string   `json:"provider" example:"nominatim"`
+	ManualOverride bool     `json:"manualOverride" example:"false"`
+	City           string   `json:"city" example:"Kaunas"` // Derived from address
+}
+
+// GeocodeResult represents coordinates and address resolved by geocoder
+// swagger:model
+type GeocodeResult struct {
+	Address    string  `json:"address" example:"Laisvės al. 1, Kaunas"`
+	Longitude  float64 `json:"longitude" example:"23.903597"`
+	Latitude   float64 `json:"latitude" example:"54.897802"`
+	Confidence float64 `json:"confidence" example:"0.85"`
+	Provider   string  `json:"provider" example:"nominatim"`
 }
 
 // User represents first authorized system role
@@ -73,27 +152,581 @@ type Organizer struct {
 	Description *string `json:"description" exapmle:"Organizes educations about amber"`
 }
 
+// ModerationDecision represents administrator decision about activity
+// swagger:model
+type ModerationDecision struct {
+	ID                int       `json:"id" example:"1"`
+	FkActivityID      int       `json:"fk_Activityid" example:"1"`
+	FkAdministratorID *int      `json:"fk_Administratorid" example:"1"`
+	Decision          string    `json:"decision" example:"rejected"`
+	Reason            *string   `json:"reason" example:"Description is missing schedule"`
+	CreatedAt         time.Time `json:"createdAt" example:"2024-10-08 14:23:45.6789013 +0000UTC"`
+}
+
+// Notification represents message for user about changes of their resources
+// swagger:model
+type Notification struct {
+	ID         int        `json:"id" example:"1"`
+	FkUserID   int        `json:"fk_Userid" example:"1"`
+	Type       string     `json:"type" example:"approved"`
+	Message    string     `json:"message" example:"Activity Amber history was approved"`
+	EntityType string     `json:"entityType" example:"activity"`
+	EntityFk   int        `json:"entityFk" example:"1"`
+	CreatedAt  time.Time  `json:"createdAt" example:"2024-10-08 14:23:45.6789013 +0000UTC"`
+	ReadAt     *time.Time `json:"readAt" example:"2024-10-08 14:23:45.6789013 +0000UTC"`
+}
+
+// Booking represents seats reserved by user in activity session
+// swagger:model
+type Booking struct {
+	ID                  int        `json:"id" example:"1"`
+	FkActivitySessionID int        `json:"fk_ActivitySessionid" example:"1"`
+	FkUserID            int        `json:"fk_Userid" example:"1"`
+	Seats               int        `json:"seats" example:"2"`
+	Status              string     `json:"status" example:"pending"`
+	CreatedAt           time.Time  `json:"createdAt" example:"2024-10-08 14:23:45.6789013 +0000UTC"`
+	UpdatedAt           time.Time  `json:"updatedAt" example:"2024-10-08 14:23:45.6789013 +0000UTC"`
+	Amount              Money      `json:"amount"`
+	OriginalAmount      Money      `json:"originalAmount"`
+	ExpiresAt           *time.Time `json:"expiresAt" example:"2024-10-08 14:53:45.6789013 +0000UTC"` // Unpaid booking is cancelled then
+
+	Group        *BookingGroup        `json:"group"`
+	Cancellation *BookingCancellation `json:"cancellation"`
+	Tiers        []*BookingTier       `json:"tiers"`
+	Discount     *BookingDiscount     `json:"discount"`
+	Voucher      *BookingVoucher      `json:"voucher"`
+}
+
+// BookingTier is quantity of price tier booked, name, seats and price are kept as they were when booking was made
+// swagger:model
+type BookingTier struct {
+	FkPriceTierID *int   `json:"fk_PriceTierid" example:"1"`
+	Name          string `json:"name" example:"Student"`
+	Quantity      int    `json:"quantity" example:"2"`
+	Seats         int    `json:"seats" example:"1"`
+	UnitPrice     Money  `json:"unitPrice"`
+	Total         Money  `json:"total"`
+}
+
+// BookingCancellation records who cancelled booking or marked it as no-show, why and how much is refunded
+// swagger:model
+type BookingCancellation struct {
+	FkUserID      *int      `json:"fk_Userid" example:"1"`
+	Reason        *string   `json:"reason" example:"Child is sick"`
+	RefundPercent int       `json:"refundPercent" example:"50"`
+	RefundAmount  Money     `json:"refundAmount"`
+	RefundStatus  string    `json:"refundStatus" example:"completed"`
+	CreatedAt     time.Time `json:"createdAt" example:"2024-10-08 14:23:45.6789013 +0000UTC"`
+}
+
+// CancellationPolicy sets refunds of bookings cancelled by users. Cancellations at least FreeCancellationHours
+// before session are refunded fully, later ones get percent of the tier with the most days they still meet.
+// Policy of activity takes precedence over policy of its package
+// swagger:model
+type CancellationPolicy struct {
+	ID                    int           `json:"id" example:"1"`
+	FkActivityID          *int          `json:"fk_Activityid" example:"1"`
+	FkPackageID           *int          `json:"fk_Packageid" example:"1"`
+	FreeCancellationHours int           `json:"freeCancellationHours" example:"48"`
+	NoShowRefundPercent   int           `json:"noShowRefundPercent" example:"0"`
+	Tiers                 []*RefundTier `json:"tiers"`
+}
+
+// RefundTier refunds percent of price to cancellations made at least DaysBefore days before session
+// swagger:model
+type RefundTier struct {
+	DaysBefore    int `json:"daysBefore" example:"1"`
+	RefundPercent int `json:"refundPercent" example:"50"`
+}
+
+// BookingGroup describes school class or other group booked together, its seats are students and adults together
+// swagger:model
+type BookingGroup struct {
+	Name     string  `json:"name" example:"5B"`
+	School   *string `json:"school" example:"Vilniaus licėjus"`
+	AgeBand  *string `json:"ageBand" example:"10-11"`
+	Students int     `json:"students" example:"24"`
+	Adults   int     `json:"adults" example:"2"`
+}
+
+// GroupSettings are rules for group bookings of activity. Students pay price of the matching rule with
+// the highest minimum, or session price when none matches. One adult per FreeAdultsPer students is free
+// swagger:model
+type GroupSettings struct {
+	FkActivityID  int               `json:"fk_Activityid" example:"1"`
+	MinGroupSize  int               `json:"minGroupSize" example:"10"`
+	MaxGroupSize  int               `json:"maxGroupSize" example:"30"`
+	AdultPrice    *Money            `json:"adultPrice"`
+	FreeAdultsPer int               `json:"freeAdultsPer" example:"10"`
+	PriceRules    []*GroupPriceRule `json:"priceRules"`
+}
+
+// GroupPriceRule sets student price for groups of at least MinStudents students
+// swagger:model
+type GroupPriceRule struct {
+	MinStudents  int   `json:"minStudents" example:"20"`
+	StudentPrice Money `json:"studentPrice"`
+}
+
+// GroupInvoice is price summary of group booking
+// swagger:model
+type GroupInvoice struct {
+	BookingID     int    `json:"bookingId" example:"1"`
+	GroupName     string `json:"groupName" example:"5B"`
+	Students      int    `json:"students" example:"24"`
+	Adults        int    `json:"adults" example:"3"`
+	FreeAdults    int    `json:"freeAdults" example:"2"`
+	StudentPrice  Money  `json:"studentPrice"`
+	AdultPrice    Money  `json:"adultPrice"`
+	StudentsTotal Money  `json:"studentsTotal"`
+	AdultsTotal   Money  `json:"adultsTotal"`
+	Total         Money  `json:"total"`
+	PerStudent    Money  `json:"perStudent"`
+}
+
+// WaitlistEntry represents user waiting for seats in full session. Offered entries hold seats
+// through pending booking until they are accepted or offer expires
+// swagger:model
+type WaitlistEntry struct {
+	ID                  int        `json:"id" example:"1"`
+	FkActivitySessionID int        `json:"fk_ActivitySessionid" example:"1"`
+	FkUserID            int        `json:"fk_Userid" example:"1"`
+	Seats               int        `json:"seats" example:"2"`
+	Status              string     `json:"status" example:"waiting"`
+	FkBookingID         *int       `json:"fk_Bookingid" example:"1"`
+	OfferExpiresAt      *time.Time `json:"offerExpiresAt" example:"2024-10-09 14:23:45.6789013 +0000UTC"`
+	CreatedAt           time.Time  `json:"createdAt" example:"2024-10-08 14:23:45.6789013 +0000UTC"`
+}
+
+// Payment represents charge of booking or gift voucher through payment provider. Booking is confirmed and voucher
+// activated once its payment succeeds
+// swagger:model
+type Payment struct {
+	ID             int       `json:"id" example:"1"`
+	FkBookingID    *int      `json:"fk_Bookingid" example:"1"`
+	Provider       string    `json:"provider" example:"stripe"`
+	ProviderRef    string    `json:"providerRef" example:"cs_test_a1b2c3"`
+	Amount         Money     `json:"amount"`
+	Status         string    `json:"status" example:"pending"`
+	RefundedAmount Money     `json:"refundedAmount"`
+	CheckoutURL    string    `json:"checkoutUrl" example:"https://checkout.stripe.com/c/pay/cs_test_a1b2c3"`
+	CreatedAt      time.Time `json:"createdAt" example:"2024-10-08 14:23:45.6789013 +0000UTC"`
+	UpdatedAt      time.Time `json:"updatedAt" example:"2024-10-08 14:23:45.6789013 +0000UTC"`
+
+	FkGiftVoucherID *int `json:"fk_GiftVoucherid" example:"1"`
+}
+
+// Discount is promo code or, without code, campaign applied automatically. It takes percent or fixed amount off
+// bookings of activities in its scope made within validity window. Discounts of administrators have no organizer
+// and apply to activities of all organizers
+// swagger:model
+type Discount struct {
+	ID             int       `json:"id" example:"1"`
+	FkOrganizerID  *int      `json:"fk_Organizerid" example:"2"`
+	Code           *string   `json:"code" example:"AMBER20"`
+	Name           string    `json:"name" example:"Amber November"`
+	Kind           string    `json:"kind" example:"percent"`
+	Percent        *int      `json:"percent" example:"20"`
+	Amount         *Money    `json:"amount"`
+	Scope          string    `json:"scope" example:"package"`
+	ScopeID        int       `json:"scopeId" example:"1"`
+	ValidFrom      time.Time `json:"validFrom" example:"2024-11-01T00:00:00Z"`
+	ValidUntil     time.Time `json:"validUntil" example:"2024-12-01T00:00:00Z"`
+	MaxRedemptions *int      `json:"maxRedemptions" example:"100"`
+	MaxPerUser     *int      `json:"maxPerUser" example:"1"`
+	Active         bool      `json:"active" example:"true"`
+	Redemptions    int       `json:"redemptions" example:"12"`
+	CreatedAt      time.Time `json:"createdAt" example:"2024-10-08 14:23:45.6789013 +0000UTC"`
+}
+
+// BookingDiscount is discount taken off booking, original amount of booking is its amount and discount together
+// swagger:model
+type BookingDiscount struct {
+	FkDiscountID int     `json:"fk_Discountid" example:"1"`
+	Code         *string `json:"code" example:"AMBER20"`
+	Name         string  `json:"name" example:"Amber November"`
+	Amount       Money   `json:"amount"`
+}
+
+// DiscountRedemption is booking discount was used in
+// swagger:model
+type DiscountRedemption struct {
+	BookingID     int       `json:"bookingId" example:"1"`
+	UserID        int       `json:"userId" example:"1"`
+	Username      string    `json:"username" example:"john_doe"`
+	Discount      Money     `json:"discount"`
+	BookingAmount Money     `json:"bookingAmount"`
+	BookingStatus string    `json:"bookingStatus" example:"confirmed"`
+	CreatedAt     time.Time `json:"createdAt" example:"2024-11-03 14:23:45.6789013 +0000UTC"`
+}
+
+// DiscountReport summarizes redemptions of discount, cancelled bookings don't count into totals
+// swagger:model
+type DiscountReport struct {
+	Discount      *Discount             `json:"discount"`
+	Redemptions   int                   `json:"redemptions" example:"12"`
+	TotalDiscount []Money               `json:"totalDiscount"`
+	Bookings      []*DiscountRedemption `json:"bookings"`
+}
+
+// GiftVoucher is voucher bought as gift. It's redeemable against bookings until its balance runs out or it expires,
+// vouchers bought for package only against activities of that package
+// swagger:model
+type GiftVoucher struct {
+	ID             int       `json:"id" example:"1"`
+	Code           string    `json:"code" example:"K7QM-3XPA-9WTD"`
+	FkUserID       int       `json:"fk_Userid" example:"1"`
+	FkPackageID    *int      `json:"fk_Packageid" example:"1"`
+	Amount         Money     `json:"amount"`
+	Balance        Money     `json:"balance"`
+	RecipientName  string    `json:"recipientName" example:"Ona"`
+	RecipientEmail *string   `json:"recipientEmail" example:"ona@example.com"`
+	Message        *string   `json:"message" example:"Happy birthday!"`
+	Status         string    `json:"status" example:"active"`
+	ExpiresAt      time.Time `json:"expiresAt" example:"2025-12-17T00:00:00Z"`
+	CreatedAt      time.Time `json:"createdAt" example:"2024-12-17 14:23:45.6789013 +0000UTC"`
+
+	Redemptions []*VoucherRedemption `json:"redemptions"`
+}
+
+// VoucherBalance is what anyone with redemption code can see of gift voucher, buyer and recipient stay private
+// swagger:model
+type VoucherBalance struct {
+	Code        string    `json:"code" example:"K7QM-3XPA-9WTD"`
+	FkPackageID *int      `json:"fk_Packageid" example:"1"`
+	Balance     Money     `json:"balance"`
+	Status      string    `json:"status" example:"active"`
+	ExpiresAt   time.Time `json:"expiresAt" example:"2025-12-17T00:00:00Z"`
+}
+
+// VoucherRedemption is booking part of voucher was redeemed against. Restored amount went back to voucher
+// when booking was cancelled
+// swagger:model
+type VoucherRedemption struct {
+	BookingID      int       `json:"bookingId" example:"1"`
+	Amount         Money     `json:"amount"`
+	RestoredAmount Money     `json:"restoredAmount"`
+	BookingStatus  string    `json:"bookingStatus" example:"confirmed"`
+	CreatedAt      time.Time `json:"createdAt" example:"2024-12-20 14:23:45.6789013 +0000UTC"`
+}
+
+// BookingVoucher is part of gift voucher redeemed against booking, it's taken off amount after discount
+// swagger:model
+type BookingVoucher struct {
+	FkGiftVoucherID int    `json:"fk_GiftVoucherid" example:"1"`
+	Code            string `json:"code" example:"K7QM-3XPA-9WTD"`
+	Amount          Money  `json:"amount"`
+}
+
+// VoucherLiability sums paid vouchers of one currency. Outstanding balance can still be redeemed,
+// expired balance was left on vouchers which can't be redeemed anymore
+// swagger:model
+type VoucherLiability struct {
+	Currency    string `json:"currency" example:"EUR"`
+	Vouchers    int    `json:"vouchers" example:"12"`
+	Outstanding Money  `json:"outstanding"`
+	Sold        Money  `json:"sold"`
+	Redeemed    Money  `json:"redeemed"`
+	Expired     Money  `json:"expired"`
+}
+
+// Certificate confirms booking attended education activity. Details are kept as they were when it was issued
+// swagger:model
+type Certificate struct {
+	ID             int       `json:"id" example:"1"`
+	Code           string    `json:"code" example:"7MZQ-4K2D-XR6P-AVNE"`
+	FkBookingID    int       `json:"fk_Bookingid" example:"42"`
+	RecipientName  string    `json:"recipientName" example:"5B, Vilniaus licėjus"`
+	ActivityName   string    `json:"activityName" example:"Amber history"`
+	OrganizerName  string    `json:"organizerName" example:"amber_museum"`
+	SessionDate    time.Time `json:"sessionDate" example:"2024-10-12T09:00:00Z"`
+	Participants   int       `json:"participants" example:"26"`
+	Template       string    `json:"template" example:"classic"`
+	Title          *string   `json:"title" example:"Certificate of completion"`
+	SignatureName  *string   `json:"signatureName" example:"Ona Jonaitė"`
+	SignatureTitle *string   `json:"signatureTitle" example:"Head of education"`
+	IssuedAt       time.Time `json:"issuedAt" example:"2024-10-13 14:23:45.6789013 +0000UTC"`
+}
+
+// CertificateTemplate is look of certificates of package activities chosen by organizer
+// swagger:model
+type CertificateTemplate struct {
+	FkPackageID    int     `json:"fk_Packageid" example:"1"`
+	Template       string  `json:"template" example:"classic"`
+	Title          *string `json:"title" example:"Certificate of completion"`
+	SignatureName  *string `json:"signatureName" example:"Ona Jonaitė"`
+	SignatureTitle *string `json:"signatureTitle" example:"Head of education"`
+}
+
+// CertificateVerification is public confirmation that certificate of code was issued
+// swagger:model
+type CertificateVerification struct {
+	Valid         bool      `json:"valid" example:"true"`
+	Code          string    `json:"code" example:"7MZQ-4K2D-XR6P-AVNE"`
+	RecipientName string    `json:"recipientName" example:"5B, Vilniaus licėjus"`
+	ActivityName  string    `json:"activityName" example:"Amber history"`
+	OrganizerName string    `json:"organizerName" example:"amber_museum"`
+	SessionDate   time.Time `json:"sessionDate" example:"2024-10-12T09:00:00Z"`
+	Participants  int       `json:"participants" example:"26"`
+	IssuedAt      time.Time `json:"issuedAt" example:"2024-10-13 14:23:45.6789013 +0000UTC"`
+}
+
+// CalendarEvent is session shown in iCalendar feed, booking fields are set in feeds of user bookings.
+// Location falls back to first location of activity when session has none
+type CalendarEvent struct {
+	SessionID     int
+	ActivityID    int
+	ActivityName  string
+	Description   string
+	OrganizerName string
+	StartTime     time.Time
+	EndTime       time.Time
+	Cancelled     bool
+	Address       *string
+	Latitude      *float64
+	Longitude     *float64
+
+	BookingID     *int
+	BookingStatus *string
+	Seats         *int
+	UpdatedAt     *time.Time
+}
+
+// CalendarFeed is private iCalendar feed of user bookings, anyone knowing its URL can read it
+// swagger:model
+type CalendarFeed struct {
+	URL       string    `json:"url" example:"http://localhost:8080/api/v1/calendar/feeds/3qG0uT9xZbXh2sJk1vN8cQ.ics"`
+	CreatedAt time.Time `json:"createdAt" example:"2024-10-13 14:23:45.6789013 +0000UTC"`
+}
+
+// CalendarImport is iCalendar file of organizer whose events are kept in sync with activity sessions.
+// Calendar files carry times only, so capacity, language and location of created sessions come from import
+// swagger:model
+type CalendarImport struct {
+	ID             int        `json:"id" example:"1"`
+	FkActivityID   int        `json:"fk_Activityid" example:"1"`
+	URL            *string    `json:"url" example:"https://calendar.google.com/calendar/ical/amber%40gmail.com/public/basic.ics"`
+	Capacity       int        `json:"capacity" example:"20"`
+	Language       string     `json:"language" example:"lt"`
+	FkLocationID   *int       `json:"fk_Locationid" example:"1"`
+	LastImportedAt *time.Time `json:"lastImportedAt" example:"2024-10-13 14:23:45.6789013 +0000UTC"`
+	CreatedAt      time.Time  `json:"createdAt" example:"2024-10-08 14:23:45.6789013 +0000UTC"`
+}
+
+// ImportedSession links session to event occurrence it was created from. Occurrence is original start
+// of recurring event occurrence, empty for single events
+type ImportedSession struct {
+	UID                 string
+	Occurrence          string
+	FkActivitySessionID int
+	StartTime           time.Time
+	EndTime             time.Time
+	BookedSeats         int
+}
+
+// CalendarImportReport counts sessions changed by import and lists events which couldn't be applied
+// swagger:model
+type CalendarImportReport struct {
+	Created   int               `json:"created" example:"12"`
+	Updated   int               `json:"updated" example:"1"`
+	Deleted   int               `json:"deleted" example:"2"`
+	Unchanged int               `json:"unchanged" example:"30"`
+	Conflicts []*ImportConflict `json:"conflicts"`
+}
+
+// ImportConflict is calendar event left unapplied, session is set when existing session was kept
+// swagger:model
+type ImportConflict struct {
+	UID                 string     `json:"uid" example:"5l2k9c0a8d@google.com"`
+	Summary             string     `json:"summary" example:"Amber history"`
+	StartTime           *time.Time `json:"startTime" example:"2025-01-15T10:00:00Z"`
+	FkActivitySessionID *int       `json:"fk_ActivitySessionid" example:"7"`
+	Reason              string     `json:"reason" example:"moved in calendar, but session has bookings"`
+}
+
+// CheckoutRequest asks payment provider to start payment of booking or gift voucher
+type CheckoutRequest struct {
+	BookingID      int
+	VoucherID      int
+	Amount         Money
+	Description    string
+	SuccessURL     string
+	CancelURL      string
+	IdempotencyKey string
+}
+
+// Checkout is payment started at provider, user pays it at URL
+type Checkout struct {
+	ProviderRef string
+	URL         string
+}
+
+// PaymentEvent is verified webhook of payment provider. Status is empty for events which don't change payment
+type PaymentEvent struct {
+	ID          string
+	ProviderRef string
+	Status      string
+}
+
+// Attendee represents booking together with contacts of user who made it
+// swagger:model
+type Attendee struct {
+	BookingID int       `json:"bookingId" example:"1"`
+	UserID    int       `json:"userId" example:"1"`
+	Username  string    `json:"username" example:"user"`
+	Email     string    `json:"email" example:"user@email.com"`
+	Seats     int       `json:"seats" example:"2"`
+	Status    string    `json:"status" example:"confirmed"`
+	GroupName *string   `json:"groupName" example:"5B"`
+	CreatedAt time.Time `json:"createdAt" example:"2024-10-08 14:23:45.6789013 +0000UTC"`
+
+	CheckedInAt *time.Time `json:"checkedInAt" example:"2024-10-12 09:58:12.6789013 +0000UTC"`
+}
+
+// Ticket is signed code of confirmed booking, shown at entrance as QR image and scanned by staff at check-in
+// swagger:model
+type Ticket struct {
+	BookingID int    `json:"bookingId" example:"42"`
+	SessionID int    `json:"sessionId" example:"7"`
+	Seats     int    `json:"seats" example:"2"`
+	Code      string `json:"code" example:"T1.42.7.mB0rRk2bq9kxV8QzH1eU4w"`
+	QRCodeURL string `json:"qrCodeUrl" example:"/api/v1/bookings/42/ticket/qr"`
+}
+
+// Viewer is user requesting resources, guests have ID -1 and no role
+type Viewer struct {
+	UserID int
+	Role   string
+}
+
 type Subscribers struct {
 	ID               int       `json:"id" exapmle:"1"`
 	Email            string    `json:"email" exapmle:"subscriber@email.com"`
 	SubscriptionDate time.Time `json:"subscriptionDate" exapmle:"2024-10-08 14:23:45.6789013 +0000UTC"`
 }
 
+// Image represents uploaded image file, description is used as alt text
+// swagger:model
 type Image struct {
 	ID          int       `json:"id" exapmle:"1"`
 	Description *string   `json:"description" exapmle:"atl text"`
 	FilePath    string    `json:"filePath" exapmle:"resources/images/"`
 	Url         string    `json:"url" exapmle:"resources/images/url"`
 	UploadTime  time.Time `json:"uploadTime" exapmle:"2024-10-08 14:23:45.6789013 +0000UTC"`
+	ContentType string    `json:"contentType" example:"image/jpeg"`
+	Size        int64     `json:"size" example:"204800"`
+	FkUserID    *int      `json:"fk_Userid" example:"1"`
+	Private     bool      `json:"private" example:"false"`
+}
+
+// ImageVariant is resized copy of image stored next to the original
+// swagger:model
+type ImageVariant struct {
+	ID        int    `json:"id" example:"1"`
+	FkImageID int    `json:"fk_Imageid" example:"1"`
+	Name      string `json:"name" example:"card"`
+	Format    string `json:"format" example:"webp"`
+	FilePath  string `json:"filePath" example:"public/images/2024/12/ab12_card.webp"`
+	Width     int    `json:"width" example:"640"`
+	Height    int    `json:"height" example:"480"`
+	Size      int64  `json:"size" example:"40960"`
 }
 
+// EntityImage links image to activity, package, organizer or review
+// swagger:model
 type EntityImage struct {
 	ID         int    `json:"id" exapmle:"1"`
 	EntityType string `json:"entityType" exapmle:"activity"`
 	FkEntity   int    `json:"fk_entity" exapmle:"1"`
 	FkImageID  int    `json:"fk_Imageid" example:"1"`
+	Position   int    `json:"position" example:"0"`
 }
 
+// AttachedImage represents image as it is shown inside entity responses
+// swagger:model
+type AttachedImage struct {
+	ID          int    `json:"id" example:"1"`
+	Url         string `json:"url" example:"/api/v1/images/1"`
+	AltText     string `json:"altText" example:"Amber necklace"`
+	ContentType string `json:"contentType" example:"image/jpeg"`
+	Position    int    `json:"position" example:"0"`
+}
+
+const (
+	EntityTypeActivity  = "activity"
+	EntityTypePackage   = "package"
+	EntityTypeOrganizer = "organizer"
+	EntityTypeReview    = "review"
+	EntityTypeBooking   = "booking"
+	EntityTypeWaitlist  = "waitlist"
+)
+
+// Moderation statuses of activity, only approved activities are verified
+const (
+	ModerationPending          = "pending"
+	ModerationApproved         = "approved"
+	ModerationRejected         = "rejected"
+	ModerationChangesRequested = "changes_requested"
+)
+
+// Booking statuses, cancelled bookings don't hold seats
+const (
+	BookingPending   = "pending"
+	BookingConfirmed = "confirmed"
+	BookingCancelled = "cancelled"
+	BookingAttended  = "attended"
+	BookingNoShow    = "no_show"
+)
+
+// Refund statuses of cancelled bookings, pending refunds are retried until provider confirms them
+const (
+	RefundNone      = "none"
+	RefundPending   = "pending"
+	RefundCompleted = "completed"
+)
+
+// Waitlist entry statuses, only waiting and offered entries are active
+const (
+	WaitlistWaiting  = "waiting"
+	WaitlistOffered  = "offered"
+	WaitlistAccepted = "accepted"
+	WaitlistExpired  = "expired"
+	WaitlistLeft     = "left"
+)
+
+// Payment statuses, only succeeded payment confirms booking
+const (
+	PaymentPending   = "pending"
+	PaymentSucceeded = "succeeded"
+	PaymentFailed    = "failed"
+	PaymentRefunded  = "refunded"
+)
+
+// Discount kinds and scopes
+const (
+	DiscountPercent = "percent"
+	DiscountFixed   = "fixed"
+
+	DiscountScopeOrganizer = "organizer"
+	DiscountScopePackage   = "package"
+	DiscountScopeActivity  = "activity"
+	DiscountScopeCategory  = "category"
+)
+
+// Certificate templates
+const (
+	CertificateClassic = "classic"
+	CertificateModern  = "modern"
+	CertificateMinimal = "minimal"
+)
+
+// Gift voucher statuses, vouchers are redeemable once paid
+const (
+	VoucherPending   = "pending"
+	VoucherActive    = "active"
+	VoucherCancelled = "cancelled"
+)
+
 type Category string
 
 const (
@@ -134,35 +767,245 @@ type LoginUserPayload struct {
 // ActivityPayload represents the payload for creating activities and updating them.
 // swagger:model
 type ActivityPayload struct {
-	Name        string  `json:"name" validate:"required" example:"Amber history"`
-	Description string  `json:"description" validate:"required" example:"Education about amber"`
-	BasePrice   float32 `json:"basePrice" validate:"required" example:"15.50"`
-	Hidden      bool    `json:"hidden" example:"true"`
-	Category    string  `json:"category" validate:"required" example:"Education"`
-	FkPackageID int     `json:"fk_Packageid" validate:"required" example:"1"`
+	Name        string `json:"name" validate:"required" example:"Amber history"`
+	Description string `json:"description" validate:"required" example:"Education about amber"`
+	BasePrice   Money  `json:"basePrice"`
+	Hidden      bool   `json:"hidden" example:"true"`
+	Category    string `json:"category" validate:"required" example:"Education"`
+	FkPackageID int    `json:"fk_Packageid" validate:"required" example:"1"`
 }
 
 // ActivityFilterPayload represents the payload for filtering activities.
 // swagger:model
 type ActivityFilterPayload struct {
-	Name      string  `json:"name" example:"Amber history"`
-	MinPrice  float32 `json:"minPrice" example:"15.50"`
-	MaxPrice  float32 `json:"maxPrice" example:"15.50"`
-	Category  string  `json:"category" example:"Education"`
-	MinRating int     `json:"minRating" validate:"min=1,max=5" example:"1"`
-	MaxRating int     `json:"maxRating" validate:"min=1,max=5" example:"5"`
-	Organizer string  `json:"organizer" example:"user"`
-	StartDate string  `json:"startDate" example:"2023-01-01T00:00:00Z"`
-	EndDate   string  `json:"endDate" example:"2023-12-31T23:59:59Z"`
+	Name      string `json:"name" example:"Amber history"`
+	MinPrice  int64  `json:"minPrice" example:"1550"` // Minor units of currency
+	MaxPrice  int64  `json:"maxPrice" example:"2000"`
+	Currency  string `json:"currency" validate:"omitempty,iso4217" example:"EUR"`
+	Category  string `json:"category" validate:"omitempty,oneof=Education Event Service Other" example:"Education"`
+	MinRating int    `json:"minRating" validate:"omitempty,min=1,max=5" example:"1"`
+	MaxRating int    `json:"maxRating" validate:"omitempty,min=1,max=5" example:"5"`
+	Organizer string `json:"organizer" example:"user"`
+	StartDate string `json:"startDate" example:"2023-01-01T00:00:00Z"` // Earliest session start
+	EndDate   string `json:"endDate" example:"2023-12-31T23:59:59Z"`   // Latest session start
+	City      string `json:"city" validate:"max=255" example:"Kaunas"`
+	Month     string `json:"month" validate:"omitempty,datetime=2006-01" example:"2025-01"` // Month of upcoming session, in UTC
+}
+
+// ModerationDecisionPayload represents the payload for moderating activities.
+// Reason is required when activity is rejected or changes are requested.
+// swagger:model
+type ModerationDecisionPayload struct {
+	Reason string `json:"reason" validate:"max=1000" example:"Description is missing schedule"`
+}
+
+// ActivitySessionPayload represents the payload for creating activity sessions and updating them.
+// swagger:model
+type ActivitySessionPayload struct {
+	StartTime    time.Time `json:"startTime" validate:"required" example:"2025-01-15T10:00:00Z"`
+	EndTime      time.Time `json:"endTime" validate:"required,gtfield=StartTime" example:"2025-01-15T12:00:00Z"`
+	FkLocationID *int      `json:"fk_Locationid" example:"1"`
+	Capacity     int       `json:"capacity" validate:"required,min=1" example:"20"`
+	Language     string    `json:"language" validate:"required,min=2,max=8" example:"lt"`
+	Price        *Money    `json:"price"`
+}
+
+// SchedulePayload represents the payload for creating recurring schedules and updating them.
+// swagger:model
+type SchedulePayload struct {
+	RRule           string   `json:"rrule" validate:"required" example:"FREQ=WEEKLY;BYDAY=TU;COUNT=50"`
+	StartTime       string   `json:"startTime" validate:"required,datetime=2006-01-02T15:04:05" example:"2025-01-14T10:00:00"`
+	Timezone        string   `json:"timezone" validate:"omitempty,timezone" example:"Europe/Vilnius"`
+	DurationMinutes int      `json:"durationMinutes" validate:"required,min=1,max=1440" example:"90"`
+	FkLocationID    *int     `json:"fk_Locationid" example:"1"`
+	Capacity        int      `json:"capacity" validate:"required,min=1" example:"20"`
+	Language        string   `json:"language" validate:"required,min=2,max=8" example:"lt"`
+	Price           *Money   `json:"price"`
+	ExDates         []string `json:"exDates" validate:"max=366" example:"2025-02-18"`
+}
+
+// SplitSchedulePayload represents the payload for changing occurrence and all following ones.
+// From is wall time of the first occurrence to change
+// swagger:model
+type SplitSchedulePayload struct {
+	SchedulePayload
+	From string `json:"from" validate:"required,datetime=2006-01-02T15:04:05" example:"2025-03-04T10:00:00"`
+}
+
+// BookingPayload represents the payload for booking seats in session.
+// Sessions with price tiers are booked by quantity of each tier, seats are counted from them.
+// swagger:model
+type BookingPayload struct {
+	Seats       int                  `json:"seats" validate:"required_without=Tiers,omitempty,min=1,max=50" example:"2"`
+	Tiers       []BookingTierPayload `json:"tiers" validate:"max=10,dive"`
+	PromoCode   string               `json:"promoCode" validate:"max=64" example:"AMBER20"`
+	VoucherCode string               `json:"voucherCode" validate:"max=32" example:"K7QM-3XPA-9WTD"`
+}
+
+// BookingTierPayload represents quantity of price tier in booking.
+// swagger:model
+type BookingTierPayload struct {
+	PriceTierID int `json:"priceTierId" validate:"required" example:"1"`
+	Quantity    int `json:"quantity" validate:"required,min=1,max=50" example:"2"`
+}
+
+// CancelBookingPayload represents the payload for cancelling bookings and sessions.
+// swagger:model
+type CancelBookingPayload struct {
+	Reason string `json:"reason" validate:"max=1000" example:"Child is sick"`
+}
+
+// CancellationPolicyPayload represents the payload for setting cancellation policy.
+// swagger:model
+type CancellationPolicyPayload struct {
+	FreeCancellationHours int                 `json:"freeCancellationHours" validate:"min=0,max=8760" example:"48"`
+	NoShowRefundPercent   int                 `json:"noShowRefundPercent" validate:"min=0,max=100" example:"0"`
+	Tiers                 []RefundTierPayload `json:"tiers" validate:"max=20,dive"`
+}
+
+// RefundTierPayload represents refund percent for cancellations made at least given days before session.
+// swagger:model
+type RefundTierPayload struct {
+	DaysBefore    int `json:"daysBefore" validate:"min=0,max=365" example:"1"`
+	RefundPercent int `json:"refundPercent" validate:"min=0,max=100" example:"50"`
+}
+
+// GroupBookingPayload represents the payload for booking seats for group.
+// swagger:model
+type GroupBookingPayload struct {
+	Name     string  `json:"name" validate:"required,max=255" example:"5B"`
+	School   *string `json:"school" validate:"omitempty,max=255" example:"Vilniaus licėjus"`
+	AgeBand  *string `json:"ageBand" validate:"omitempty,max=32" example:"10-11"`
+	Students int     `json:"students" validate:"required,min=1,max=500" example:"24"`
+	Adults   int     `json:"adults" validate:"min=0,max=100" example:"2"`
+
+	PromoCode   string `json:"promoCode" validate:"max=64" example:"AMBER20"`
+	VoucherCode string `json:"voucherCode" validate:"max=32" example:"K7QM-3XPA-9WTD"`
+}
+
+// GroupSettingsPayload represents the payload for setting group booking rules of activity.
+// swagger:model
+type GroupSettingsPayload struct {
+	MinGroupSize  int                     `json:"minGroupSize" validate:"required,min=1" example:"10"`
+	MaxGroupSize  int                     `json:"maxGroupSize" validate:"required,gtefield=MinGroupSize" example:"30"`
+	AdultPrice    *Money                  `json:"adultPrice"`
+	FreeAdultsPer int                     `json:"freeAdultsPer" validate:"min=0" example:"10"`
+	PriceRules    []GroupPriceRulePayload `json:"priceRules" validate:"max=20,dive"`
+}
+
+// GroupPriceRulePayload represents student price for groups of at least given size.
+// swagger:model
+type GroupPriceRulePayload struct {
+	MinStudents  int   `json:"minStudents" validate:"required,min=1" example:"20"`
+	StudentPrice Money `json:"studentPrice"`
+}
+
+// PriceTiersPayload represents the payload for replacing price tiers of activity or session.
+// swagger:model
+type PriceTiersPayload struct {
+	Tiers []PriceTierPayload `json:"tiers" validate:"max=20,dive"`
+}
+
+// PriceTierPayload represents named price with optional age limits and eligibility rules.
+// swagger:model
+type PriceTierPayload struct {
+	Name        string  `json:"name" validate:"required,max=64" example:"Student"`
+	Price       Money   `json:"price"`
+	Seats       int     `json:"seats" validate:"omitempty,min=1,max=20" example:"1"`
+	MinAge      *int    `json:"minAge" validate:"omitempty,min=0,max=150" example:"7"`
+	MaxAge      *int    `json:"maxAge" validate:"omitempty,min=0,max=150" example:"17"`
+	Eligibility *string `json:"eligibility" validate:"omitempty,max=255" example:"Valid student card"`
+}
+
+// DiscountPayload represents the payload for creating discounts and updating them. Discounts without code
+// are campaigns applied automatically. Organizer scope defaults to organizer creating discount.
+// swagger:model
+type DiscountPayload struct {
+	Code           *string   `json:"code" validate:"omitempty,min=3,max=64,alphanum" example:"AMBER20"`
+	Name           string    `json:"name" validate:"required,max=255" example:"Amber November"`
+	Kind           string    `json:"kind" validate:"required,oneof=percent fixed" example:"percent"`
+	Percent        *int      `json:"percent" validate:"required_if=Kind percent,omitempty,min=1,max=100" example:"20"`
+	Amount         *Money    `json:"amount" validate:"required_if=Kind fixed"`
+	Scope          string    `json:"scope" validate:"required,oneof=organizer package activity category" example:"package"`
+	ScopeID        int       `json:"scopeId" validate:"min=0" example:"1"`
+	ValidFrom      time.Time `json:"validFrom" validate:"required" example:"2024-11-01T00:00:00Z"`
+	ValidUntil     time.Time `json:"validUntil" validate:"required,gtfield=ValidFrom" example:"2024-12-01T00:00:00Z"`
+	MaxRedemptions *int      `json:"maxRedemptions" validate:"omitempty,min=1" example:"100"`
+	MaxPerUser     *int      `json:"maxPerUser" validate:"omitempty,min=1" example:"1"`
+	Active         bool      `json:"active" example:"true"`
+}
+
+// CheckInPayload represents ticket code scanned at entrance.
+// swagger:model
+type CheckInPayload struct {
+	Code string `json:"code" validate:"required,max=255" example:"T1.42.7.mB0rRk2bq9kxV8QzH1eU4w"`
+}
+
+// CertificateTemplatePayload represents the payload for choosing certificate template of package.
+// swagger:model
+type CertificateTemplatePayload struct {
+	Template       string  `json:"template" validate:"required,oneof=classic modern minimal" example:"classic"`
+	Title          *string `json:"title" validate:"omitempty,max=255" example:"Certificate of completion"`
+	SignatureName  *string `json:"signatureName" validate:"omitempty,max=255" example:"Ona Jonaitė"`
+	SignatureTitle *string `json:"signatureTitle" validate:"omitempty,max=255" example:"Head of education"`
+}
+
+// GiftVoucherPayload represents the payload for buying gift voucher. Vouchers for package cost its price,
+// others the given amount.
+// swagger:model
+type GiftVoucherPayload struct {
+	PackageID      *int    `json:"packageId" validate:"required_without=Amount" example:"1"`
+	Amount         *Money  `json:"amount" validate:"required_without=PackageID"`
+	RecipientName  string  `json:"recipientName" validate:"required,max=255" example:"Ona"`
+	RecipientEmail *string `json:"recipientEmail" validate:"omitempty,email,max=255" example:"ona@example.com"`
+	Message        *string `json:"message" validate:"omitempty,max=1000" example:"Happy birthday!"`
+}
+
+// CalendarImportPayload registers calendar of activity sessions, URL is needed only for syncing without upload
+// swagger:model
+type CalendarImportPayload struct {
+	URL          *string `json:"url" validate:"omitempty,url,max=2048" example:"https://calendar.google.com/calendar/ical/amber%40gmail.com/public/basic.ics"`
+	Capacity     int     `json:"capacity" validate:"required,min=1" example:"20"`
+	Language     string  `json:"language" validate:"required,min=2,max=8" example:"lt"`
+	FkLocationID *int    `json:"fk_Locationid" example:"1"`
 }
 
 // CreatePackagePayload represents the payload for creating packages.
 // swagger:model
 type CreatePackagePayload struct {
-	Name          string  `json:"name" validate:"required" example:"Amber"`
-	Description   string  `json:"description" validate:"required" example:"Everything about amber"`
-	Price         float32 `json:"price" validate:"required" example:"40"`
-	FkOrganizerID int     `json:"fk_Organizerid" validate:"required" example:"1"`
+	Name          string `json:"name" validate:"required" example:"Amber"`
+	Description   string `json:"description" validate:"required" example:"Everything about amber"`
+	Price         Money  `json:"price"`
+	FkOrganizerID int    `json:"fk_Organizerid" validate:"required" example:"1"`
+}
+
+// LocationPayload represents the payload for creating locations and updating them.
+// Coordinates are optional, when both are provided they override geocoder result.
+// swagger:model
+type LocationPayload struct {
+	Address      string   `json:"address" validate:"required" example:"Laisvės al. 1, Kaunas"`
+	Longitude    *float64 `json:"longitude" validate:"omitempty,min=-180,max=180" example:"23.903597"`
+	Latitude     *float64 `json:"latitude" validate:"omitempty,min=-90,max=90" example:"54.897802"`
+	FkActivityID int      `json:"fk_Activityid" validate:"required" example:"1"`
+}
+
+// UpdateImagePayload represents the payload for updating image alt text.
+// swagger:model
+type UpdateImagePayload struct {
+	AltText string `json:"altText" validate:"required,max=255" example:"Amber necklace"`
+}
+
+// ImageURLResponse represents address image can be downloaded from.
+// swagger:model
+type ImageURLResponse struct {
+	Url       string     `json:"url" example:"/api/v1/blobs/private/images/2024/12/a1b2c3.jpg?expires=1734350400&signature=9f86d0"`
+	ExpiresAt *time.Time `json:"expiresAt" example:"2024-12-16T12:00:00Z"`
+}
+
+// ReorderImagesPayload represents the payload for changing order of entity images.
+// swagger:model
+type ReorderImagesPayload struct {
+	ImageIDs []int `json:"imageIds" validate:"required,min=1" example:"3,1,2"`
 }
 
 // ReviewPayload represents the payload for creating reviews and updating them.
@@ -197,12 +1040,18 @@ type UserCastle interface {
 type ActivityCastle interface {
 	CreateActivity(ActivityPayload) error
 	GetActivityByID(id int) (*Activity, error)
+	GetVisibleActivityByID(id int, viewer Viewer) (*Activity, error)
 	UpdateActivity(Activity) error
 	DeleteActivity(id int) error
 	GetActivityInsidePackageByName(activityName string, packageID int) (*Activity, error)
-	ListActivities() ([]*Activity, error)
-	ListActivitiesInPackage(packageID int) ([]*Activity, error)
-	FilterActivities(ActivityFilterPayload) ([]*Activity, error)
+	ListActivities(viewer Viewer) ([]*Activity, error)
+	ListActivitiesInPackage(packageID int, viewer Viewer) ([]*Activity, error)
+	FilterActivities(filter ActivityFilterPayload, viewer Viewer) ([]*Activity, error)
+	SearchActivities(query string, filter ActivityFilterPayload, viewer Viewer, limit, offset int) ([]*ActivitySearchHit, int, error)
+	SearchPackages(query string, limit, offset int) ([]*PackageSearchHit, int, error)
+	FacetActivities(query string, filter ActivityFilterPayload, viewer Viewer) (*ActivityFacets, error)
+	IndexActivity(id int) error
+	ListActivitiesByModerationStatus(status string) ([]*Activity, error)
 
 	ListPackages() ([]*Package, error)
 	GetPackageByID(id int) (*Package, error)
@@ -211,6 +1060,137 @@ type ActivityCastle interface {
 	DeletePackage(id int) error
 	UpdatePackage(p Package) error
 	GetPackageByName(name string) (*Package, error)
+
+	ListPriceTiers(activityIDs []int) (map[int][]*PriceTier, error)
+	ListSessionPriceTiers(sessionIDs []int) (map[int][]*PriceTier, error)
+	SavePriceTiers(activityID int, sessionID *int, tiers []PriceTier) error
+}
+
+type SessionCastle interface {
+	CreateSession(ActivitySession) (int64, error)
+	GetSessionByID(id int) (*ActivitySession, error)
+	ListSessionsByActivityID(activityID int, from, to *time.Time) ([]*ActivitySession, error)
+	UpdateSession(ActivitySession) error
+	DeleteSession(id int) error
+}
+
+type ScheduleCastle interface {
+	CreateSchedule(Schedule) (int64, error)
+	GetScheduleByID(id int) (*Schedule, error)
+	ListSchedules() ([]*Schedule, error)
+	ListSchedulesByActivityID(activityID int) ([]*Schedule, error)
+	UpdateSchedule(Schedule) error
+	DeleteSchedule(id int) error
+	SetExpandedUntil(id int, until *time.Time) error
+	CreateScheduleSessions(sessions []ActivitySession) error
+	DeleteScheduleSessionsFrom(scheduleID int, from time.Time) error
+	SplitSchedule(previous Schedule, removePrevious bool, next Schedule, from time.Time) (int64, error)
+}
+
+type BookingCastle interface {
+	CreateBooking(Booking) (int64, error)
+	CreateGroupBooking(Booking, BookingGroup) (int64, error)
+	GetGroupSettings(activityID int) (*GroupSettings, error)
+	SaveGroupSettings(GroupSettings) error
+	GetBookingByID(id int) (*Booking, error)
+	ListBookingsByUserID(userID int) ([]*Booking, error)
+	ListAttendeesBySessionID(sessionID int) ([]*Attendee, error)
+	GetAttendeeByBookingID(bookingID int) (*Attendee, error)
+	UpdateBookingStatus(id int, status string) error
+	CheckInBooking(id, userID int) error
+	CancelBooking(id int, status string, cancellation BookingCancellation) error
+	CancelSession(sessionID int, cancellation BookingCancellation) ([]*Booking, error)
+	ExpireBookingHolds(now time.Time) ([]*Booking, error)
+	OweRefund(bookingID int, amount Money) error
+	CompleteRefund(bookingID int) error
+	ListPendingRefunds() ([]*Booking, error)
+
+	GetCancellationPolicy(activityID int) (*CancellationPolicy, error)
+	GetPackageCancellationPolicy(packageID int) (*CancellationPolicy, error)
+	SaveCancellationPolicy(CancellationPolicy) error
+}
+
+type WaitlistCastle interface {
+	JoinWaitlist(WaitlistEntry) (int64, error)
+	GetWaitlistEntryByID(id int) (*WaitlistEntry, error)
+	ListWaitlistByUserID(userID int) ([]*WaitlistEntry, error)
+	ListWaitlistBySessionID(sessionID int) ([]*WaitlistEntry, error)
+	ListWaitlistedSessionIDs() ([]int, error)
+	PromoteWaitlist(sessionID int, window time.Duration) ([]*WaitlistEntry, error)
+	AcceptWaitlistOffer(id int, paymentDeadline time.Time) error
+	LeaveWaitlist(id int) error
+	ExpireWaitlistOffers(now time.Time) ([]*WaitlistEntry, error)
+}
+
+type PaymentCastle interface {
+	CreatePayment(Payment) (int64, error)
+	GetPaymentByID(id int) (*Payment, error)
+	GetPaymentByProviderRef(provider, ref string) (*Payment, error)
+	ListPaymentsByBookingID(bookingID int) ([]*Payment, error)
+	ListPaymentsByVoucherID(voucherID int) ([]*Payment, error)
+	ApplyPaymentEvent(provider string, event PaymentEvent) (*Payment, bool, error)
+	AddPaymentRefund(id int, amount Money) error
+}
+
+type DiscountCastle interface {
+	CreateDiscount(Discount) (int64, error)
+	GetDiscountByID(id int) (*Discount, error)
+	GetDiscountByCode(code string) (*Discount, error)
+	ListDiscounts(organizerID *int) ([]*Discount, error)
+	ListApplicableDiscounts(activityID, userID int, code string, at time.Time) ([]*Discount, error)
+	UpdateDiscount(Discount) error
+	DeleteDiscount(id int) error
+	ListDiscountRedemptions(discountID int) ([]*DiscountRedemption, error)
+	CategoryExists(id int) (bool, error)
+}
+
+type CertificateCastle interface {
+	IssueCertificate(Certificate) (*Certificate, error)
+	GetCertificateByBookingID(bookingID int) (*Certificate, error)
+	GetCertificateByCode(code string) (*Certificate, error)
+	GetCertificateTemplate(packageID int) (*CertificateTemplate, error)
+	SaveCertificateTemplate(CertificateTemplate) error
+}
+
+type CalendarCastle interface {
+	ListActivityEvents(activityID int, from time.Time) ([]*CalendarEvent, error)
+	ListPackageEvents(packageID int, from time.Time) ([]*CalendarEvent, error)
+	ListOrganizerEvents(organizerID int, from time.Time) ([]*CalendarEvent, error)
+	ListUserEvents(userID int, from time.Time) ([]*CalendarEvent, error)
+
+	GetFeedToken(userID int) (string, time.Time, error)
+	SetFeedToken(userID int, token string) error
+	GetUserIDByFeedToken(token string) (int, error)
+
+	CreateCalendarImport(CalendarImport) (int64, error)
+	GetCalendarImportByID(id int) (*CalendarImport, error)
+	ListCalendarImports(activityID int) ([]*CalendarImport, error)
+	DeleteCalendarImport(id int) error
+	SetCalendarImported(id int, at time.Time) error
+	ListImportedSessions(importID int) ([]*ImportedSession, error)
+	CreateImportedSession(importID int, uid, occurrence string, session ActivitySession) (int64, error)
+	MoveImportedSession(sessionID int, start, end time.Time) (bool, error)
+	DeleteImportedSession(sessionID int) (bool, error)
+}
+
+type VoucherCastle interface {
+	CreateVoucher(GiftVoucher) (int64, error)
+	GetVoucherByID(id int) (*GiftVoucher, error)
+	GetVoucherByCode(code string) (*GiftVoucher, error)
+	ListVouchersByUserID(userID int) ([]*GiftVoucher, error)
+	ListVoucherRedemptions(voucherID int) ([]*VoucherRedemption, error)
+	GetVoucherLiability(at time.Time) ([]*VoucherLiability, error)
+}
+
+type ModerationCastle interface {
+	CreateModerationDecision(ModerationDecision) (int64, error)
+	ListModerationDecisions(activityID int) ([]*ModerationDecision, error)
+}
+
+type NotificationCastle interface {
+	CreateNotification(Notification) (int64, error)
+	ListNotificationsByUserID(userID int, unreadOnly bool) ([]*Notification, error)
+	MarkNotificationRead(id int, userID int) error
 }
 
 type ReviewCastle interface {
@@ -223,6 +1203,58 @@ type ReviewCastle interface {
 	GetReviewFromActivityByID(idActivity int, idUser int) (*Review, error)
 }
 
+type LocationCastle interface {
+	CreateLocation(Location) (int64, error)
+	GetLocationByID(id int) (*Location, error)
+	ListLocationsByActivityID(activityID int) ([]*Location, error)
+	UpdateLocation(Location) error
+	DeleteLocation(id int) error
+}
+
+type ImageCastle interface {
+	CreateImage(Image) (int64, error)
+	GetImageByID(id int) (*Image, error)
+	UpdateImage(Image) error
+	UpdateImageFilePath(id int, filePath string) error
+	DeleteImage(id int) error
+
+	AttachImage(EntityImage) (int64, error)
+	ListEntityImages(entityType string, entityID int) ([]*AttachedImage, error)
+	ListImagesForEntities(entityType string, entityIDs []int) (map[int][]*AttachedImage, error)
+	ReorderEntityImages(entityType string, entityID int, imageIDs []int) error
+
+	ListImages() ([]*Image, error)
+	SaveImageVariant(ImageVariant) error
+	ListImageVariants(imageID int) ([]*ImageVariant, error)
+	DeleteImageVariant(id int) error
+}
+
+// BlobStore stores uploaded files under keys. Keys starting with "public/" can be
+// downloaded by anyone, other keys are private and need signed, expiring URL.
+// Images stored without prefix are moved under it with cmd/blobs -rename-legacy
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]string, error)
+	PublicURL(key string) string
+	SignedURL(key string, expiration time.Duration) (string, error)
+}
+
+// Geocoder resolves addresses into coordinates and coordinates back into addresses
+type Geocoder interface {
+	Geocode(ctx context.Context, address string) (*GeocodeResult, error)
+	ReverseGeocode(ctx context.Context, latitude, longitude float64) (*GeocodeResult, error)
+}
+
+// PaymentProvider takes payments of bookings. Results of checkouts arrive asynchronously through webhooks
+type PaymentProvider interface {
+	Name() string
+	CreateCheckout(ctx context.Context, request CheckoutRequest) (*Checkout, error)
+	ParseWebhook(header http.Header, payload []byte) (*PaymentEvent, error)
+	Refund(ctx context.Context, ref string, amount Money, idempotencyKey string) error
+}
+
 // Responses
 
 // UserResponse represents the response structure for a user.
@@ -241,3 +1273,82 @@ type ErrorResponse struct {
 	Code    int    `json:"code" example:"400"`
 	Message string `json:"message" example:"Invalid payload or user already exists"`
 }
+
+// ActivitySearchHit represents activity found by search with its relevance score.
+// Highlights are HTML-escaped snippets of name, description, package and organizer with matched words wrapped in <mark>
+// swagger:model
+type ActivitySearchHit struct {
+	Activity   *Activity         `json:"activity"`
+	Score      float64           `json:"score" example:"7.25"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// ActivitySearchResults represents page of search hits, most relevant first.
+// swagger:model
+type ActivitySearchResults struct {
+	Query string               `json:"query" example:"amber"`
+	Total int                  `json:"total" example:"12"`
+	Hits  []*ActivitySearchHit `json:"hits"`
+}
+
+// PackageSearchHit represents package found by search with its relevance score.
+// Highlights are HTML-escaped snippets of name and description with matched words wrapped in <mark>
+// swagger:model
+type PackageSearchHit struct {
+	Package    *Package          `json:"package"`
+	Score      float64           `json:"score" example:"4.5"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// PackageSearchResults represents page of package search hits, most relevant first.
+// swagger:model
+type PackageSearchResults struct {
+	Query string              `json:"query" example:"gintaras"`
+	Total int                 `json:"total" example:"3"`
+	Hits  []*PackageSearchHit `json:"hits"`
+}
+
+// Suggestion represents completion of search query. Type is activity, package, organizer or category
+// and ID is identifier of the entity of that type
+// swagger:model
+type Suggestion struct {
+	Type  string  `json:"type" example:"activity"`
+	ID    int     `json:"id" example:"1"`
+	Text  string  `json:"text" example:"Gintaro istorija"`
+	Score float64 `json:"score" example:"2.8"`
+}
+
+// FacetCount represents number of activities having value of facet
+// swagger:model
+type FacetCount struct {
+	Value string `json:"value" example:"Education"`
+	Count int    `json:"count" example:"42"`
+}
+
+// PriceFacetCount represents number of activities priced from MinPrice up to MaxPrice, exclusive.
+// Prices are in minor units of currency, last bucket has no MaxPrice
+// swagger:model
+type PriceFacetCount struct {
+	MinPrice int64  `json:"minPrice" example:"1000"`
+	MaxPrice *int64 `json:"maxPrice" example:"2500"`
+	Count    int    `json:"count" example:"7"`
+}
+
+// RatingFacetCount represents number of activities rated at least MinRating
+// swagger:model
+type RatingFacetCount struct {
+	MinRating int `json:"minRating" example:"4"`
+	Count     int `json:"count" example:"18"`
+}
+
+// ActivityFacets represents counts of activities by values of filters. Counts of each facet respect
+// every active filter except the filter of that facet, so selecting another value widens results by its count
+// swagger:model
+type ActivityFacets struct {
+	Category  []FacetCount       `json:"category"`
+	Price     []PriceFacetCount  `json:"price"`
+	Rating    []RatingFacetCount `json:"rating"`
+	Organizer []FacetCount       `json:"organizer"`
+	City      []FacetCount       `json:"city"`
+	Month     []FacetCount       `json:"month"`
+}
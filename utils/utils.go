@@ -1,10 +1,12 @@
 package utils
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -56,3 +58,42 @@ func ParseStringToFloat64(myString string) (float64, error) {
 	}
 	return value, nil
 }
+
+// ParseTimeParam parses RFC 3339 time or YYYY-MM-DD date. Dates are days in given location and mean their start,
+// or the last moment before next local midnight when endOfDay is set, so they can be used as inclusive range bounds
+func ParseTimeParam(value string, loc *time.Location, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %s, expected YYYY-MM-DD or RFC 3339 time", value)
+	}
+	if endOfDay {
+		// Days around DST changes aren't 24 hours long
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	return t, nil
+}
+
+// QueryIDs returns IDs selected by query, such as of rows which are about to be deleted together with their parent
+func QueryIDs(db *sql.DB, query string, params ...interface{}) ([]int, error) {
+	rows, err := db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeParam(t *testing.T) {
+	vilnius, err := time.LoadLocation("Europe/Vilnius")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Should parse RFC 3339 time as is", func(t *testing.T) {
+		for _, endOfDay := range []bool{false, true} {
+			parsed, err := ParseTimeParam("2025-01-15T10:30:00+02:00", vilnius, endOfDay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !parsed.Equal(time.Date(2025, 1, 15, 8, 30, 0, 0, time.UTC)) {
+				t.Errorf("unexpected time %v", parsed)
+			}
+		}
+	})
+
+	t.Run("Should parse date as start or end of local day", func(t *testing.T) {
+		start, err := ParseTimeParam("2025-01-15", vilnius, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !start.Equal(time.Date(2025, 1, 14, 22, 0, 0, 0, time.UTC)) {
+			t.Errorf("unexpected start of day %v", start)
+		}
+
+		end, err := ParseTimeParam("2025-01-15", vilnius, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !end.Equal(time.Date(2025, 1, 15, 22, 0, 0, 0, time.UTC).Add(-time.Nanosecond)) {
+			t.Errorf("unexpected end of day %v", end)
+		}
+	})
+
+	t.Run("Should end day at next local midnight when clocks change", func(t *testing.T) {
+		// Clocks go forward on 30 March 2025 in Vilnius, so the day is 23 hours long
+		end, err := ParseTimeParam("2025-03-30", vilnius, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !end.Equal(time.Date(2025, 3, 30, 21, 0, 0, 0, time.UTC).Add(-time.Nanosecond)) {
+			t.Errorf("unexpected end of day %v", end)
+		}
+	})
+
+	t.Run("Should fail on invalid dates", func(t *testing.T) {
+		for _, value := range []string{"", "2025-13-01", "15/01/2025", "2025-01-15 10:00"} {
+			if _, err := ParseTimeParam(value, vilnius, false); err == nil {
+				t.Errorf("expected error for %q", value)
+			}
+		}
+	})
+}